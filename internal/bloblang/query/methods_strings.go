@@ -1046,6 +1046,99 @@ func parseXMLMethod(target Function, _ ...interface{}) (Function, error) {
 
 //------------------------------------------------------------------------------
 
+var _ = RegisterMethod(
+	NewMethodSpec(
+		"format_xml", "",
+	).InCategory(
+		MethodCategoryParsing,
+		`Serializes a target value into an XML document, following the same conventions as the output of `+"`parse_xml`"+`:
+
+- Keys prefixed with a hyphen, `+"`-`"+`, are encoded as attributes of the parent element.
+- The key `+"`#text`"+` is encoded as the text content of the parent element, and the key `+"`#cdata`"+` is encoded as a CDATA section.
+- Arrays are encoded as repeated elements of the same name.
+- Child elements are written in alphabetical order of their keys.
+
+Namespaces can be expressed with prefixed keys such as `+"`soap:Envelope`"+` and declared with attributes such as `+"`-xmlns:soap`"+`. If the target is an object with a single key then that key is used as the root element, otherwise the document is wrapped within a root element `+"`doc`"+`. An optional string argument can be provided in order to set the root element explicitly, and a second optional string argument specifies an indentation for pretty printing.`,
+		NewExampleSpec("",
+			`root = this.format_xml()`,
+			`{"root":{"-id":"1","title":"This is a title","content":{"#cdata":"<p>This is some content</p>"}}}`,
+			`<root id="1"><content><![CDATA[<p>This is some content</p>]]></content><title>This is a title</title></root>`,
+		),
+		NewExampleSpec("",
+			`root = this.format_xml("soap:Envelope")`,
+			`{"-xmlns:soap":"http://schemas.xmlsoap.org/soap/envelope/","soap:Body":{"GetBalance":{"AccountId":"12345"}}}`,
+			`<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body><GetBalance><AccountId>12345</AccountId></GetBalance></soap:Body></soap:Envelope>`,
+		),
+	).Beta(),
+	false, formatXMLMethod,
+	ExpectBetweenNAndMArgs(0, 2),
+	ExpectStringArg(0),
+	ExpectStringArg(1),
+)
+
+func formatXMLMethod(target Function, args ...interface{}) (Function, error) {
+	rootTag, indent := "", ""
+	if len(args) > 0 {
+		rootTag = args[0].(string)
+	}
+	if len(args) > 1 {
+		indent = args[1].(string)
+	}
+	return simpleMethod(target, func(v interface{}, ctx FunctionContext) (interface{}, error) {
+		xmlBytes, err := xml.FromMap(v, rootTag, indent)
+		if err != nil {
+			return nil, fmt.Errorf("failed to format value as XML: %w", err)
+		}
+		return string(xmlBytes), nil
+	}), nil
+}
+
+//------------------------------------------------------------------------------
+
+var _ = RegisterMethod(
+	NewMethodSpec(
+		"xpath", "",
+	).InCategory(
+		MethodCategoryParsing,
+		"Evaluates an [XPath 1.0](https://www.w3.org/TR/xpath/) expression against a string parsed as an XML document, without converting the document into a structured value. Expressions that select nodes return an array of the text content of each selected node, whereas expressions that evaluate to a string, number or boolean return that value directly.",
+		NewExampleSpec("",
+			`root.ids = content().xpath("//item/@id")
+root.count = content().xpath("count(//item)")
+root.first = content().xpath("string(//item[1])")`,
+			`<items><item id="a">foo</item><item id="b">bar</item></items>`,
+			`{"count":2,"first":"foo","ids":["a","b"]}`,
+		),
+	).Beta(),
+	false, xpathMethod,
+	ExpectNArgs(1),
+	ExpectStringArg(0),
+)
+
+func xpathMethod(target Function, args ...interface{}) (Function, error) {
+	expr, err := xml.CompileXPath(args[0].(string))
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile xpath expression: %w", err)
+	}
+	return simpleMethod(target, func(v interface{}, ctx FunctionContext) (interface{}, error) {
+		var xmlBytes []byte
+		switch t := v.(type) {
+		case string:
+			xmlBytes = []byte(t)
+		case []byte:
+			xmlBytes = t
+		default:
+			return nil, NewTypeError(v, ValueString)
+		}
+		res, err := expr.Query(xmlBytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse value as XML: %w", err)
+		}
+		return res, nil
+	}), nil
+}
+
+//------------------------------------------------------------------------------
+
 var _ = RegisterMethod(
 	NewMethodSpec(
 		"parse_timestamp_unix", "",
@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/Jeffail/gabs/v2"
+	"github.com/google/go-cmp/cmp"
 	jsonschema "github.com/xeipuuv/gojsonschema"
 )
 
@@ -157,6 +158,48 @@ func appendMethod(target Function, args ...interface{}) (Function, error) {
 
 //------------------------------------------------------------------------------
 
+var _ = RegisterMethod(
+	NewMethodSpec(
+		"chunk",
+		"Splits an array into an array of arrays, where each array contains a number of elements equal to the argument, except for the last array which contains the remaining elements.",
+	).InCategory(
+		MethodCategoryObjectAndArray, "",
+		NewExampleSpec("",
+			`root.chunks = this.foo.chunk(2)`,
+			`{"foo":["a","b","c","d","e"]}`,
+			`{"chunks":[["a","b"],["c","d"],["e"]]}`,
+		),
+	),
+	true, chunkMethod,
+	ExpectNArgs(1),
+	ExpectIntArg(0),
+)
+
+func chunkMethod(target Function, args ...interface{}) (Function, error) {
+	size := args[0].(int64)
+	if size <= 0 {
+		return nil, fmt.Errorf("chunk size must be greater than zero, received: %v", size)
+	}
+	return simpleMethod(target, func(v interface{}, ctx FunctionContext) (interface{}, error) {
+		arr, ok := v.([]interface{})
+		if !ok {
+			return nil, NewTypeError(v, ValueArray)
+		}
+		chunks := make([]interface{}, 0, (len(arr)+int(size)-1)/int(size))
+		for i := 0; i < len(arr); i += int(size) {
+			end := i + int(size)
+			if end > len(arr) {
+				end = len(arr)
+			}
+			chunk := make([]interface{}, 0, end-i)
+			chunks = append(chunks, append(chunk, arr[i:end]...))
+		}
+		return chunks, nil
+	}), nil
+}
+
+//------------------------------------------------------------------------------
+
 var _ = RegisterMethod(
 	NewMethodSpec(
 		"collapse", "",
@@ -258,6 +301,62 @@ func containsMethod(target Function, args ...interface{}) (Function, error) {
 
 //------------------------------------------------------------------------------
 
+var _ = RegisterMethod(
+	NewMethodSpec(
+		"distinct_by", "",
+	).InCategory(
+		MethodCategoryObjectAndArray,
+		"Removes elements of an array that share a key with a previous element, where the key of each element is obtained by executing a query argument on it. The first element of each key is kept. Keys may be of any type, although numbers and strings are compared separately (`\"5\"` is a different key to `5`).",
+		NewExampleSpec("",
+			`root.users = this.users.distinct_by(this.id)`,
+			`{"users":[{"id":1,"name":"foo"},{"id":2,"name":"bar"},{"id":1,"name":"baz"}]}`,
+			`{"users":[{"id":1,"name":"foo"},{"id":2,"name":"bar"}]}`,
+		),
+	),
+	false, distinctByMethod,
+	ExpectNArgs(1),
+	ExpectFunctionArg(0),
+)
+
+// comparableKey returns a string that uniquely identifies a value for the
+// purpose of grouping values by equality.
+func comparableKey(v interface{}) string {
+	v = restrictForComparison(v)
+	return string(ITypeOf(v)) + ":" + IToString(v)
+}
+
+func distinctByMethod(target Function, args ...interface{}) (Function, error) {
+	queryFn, ok := args[0].(Function)
+	if !ok {
+		return nil, fmt.Errorf("expected query argument, received %T", args[0])
+	}
+
+	return simpleMethod(target, func(v interface{}, ctx FunctionContext) (interface{}, error) {
+		arr, ok := v.([]interface{})
+		if !ok {
+			return nil, NewTypeError(v, ValueArray)
+		}
+
+		seen := make(map[string]struct{}, len(arr))
+		distinct := make([]interface{}, 0, len(arr))
+		for i, ele := range arr {
+			key, err := queryFn.Exec(ctx.WithValue(ele))
+			if err != nil {
+				return nil, fmt.Errorf("element %v: %w", i, err)
+			}
+			k := comparableKey(key)
+			if _, exists := seen[k]; exists {
+				continue
+			}
+			seen[k] = struct{}{}
+			distinct = append(distinct, ele)
+		}
+		return distinct, nil
+	}), nil
+}
+
+//------------------------------------------------------------------------------
+
 var _ = RegisterMethod(
 	NewMethodSpec(
 		"enumerated",
@@ -566,6 +665,96 @@ func foldMethod(target Function, args ...interface{}) (Function, error) {
 
 //------------------------------------------------------------------------------
 
+var _ = RegisterMethod(
+	NewMethodSpec(
+		"from_key_values", "",
+	).InCategory(
+		MethodCategoryObjectAndArray,
+		"Converts an array of objects, each containing a field `key` and a field `value`, into an object. This is the inverse of the method [`key_values`](#key_values). When multiple elements share a key the last element wins.",
+		NewExampleSpec("",
+			`root = this.pairs.from_key_values()`,
+			`{"pairs":[{"key":"foo","value":1},{"key":"bar","value":2}]}`,
+			`{"bar":2,"foo":1}`,
+		),
+	),
+	false, fromKeyValuesMethod,
+	ExpectNArgs(0),
+)
+
+func fromKeyValuesMethod(target Function, _ ...interface{}) (Function, error) {
+	return simpleMethod(target, func(v interface{}, ctx FunctionContext) (interface{}, error) {
+		arr, ok := v.([]interface{})
+		if !ok {
+			return nil, NewTypeError(v, ValueArray)
+		}
+		obj := make(map[string]interface{}, len(arr))
+		for i, ele := range arr {
+			pair, ok := ele.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("element %v: %w", i, NewTypeError(ele, ValueObject))
+			}
+			key, ok := pair["key"].(string)
+			if !ok {
+				return nil, fmt.Errorf("element %v: field key: %w", i, NewTypeError(pair["key"], ValueString))
+			}
+			obj[key] = pair["value"]
+		}
+		return obj, nil
+	}), nil
+}
+
+//------------------------------------------------------------------------------
+
+var _ = RegisterMethod(
+	NewMethodSpec(
+		"group_by", "",
+	).InCategory(
+		MethodCategoryObjectAndArray,
+		"Groups the elements of an array into an object of arrays, where the key of each element is the result of executing a query argument on it. Keys must be strings or numbers, and the elements of each group retain their original order.",
+		NewExampleSpec("",
+			`root.by_type = this.events.group_by(this.type)`,
+			`{"events":[{"type":"click","id":1},{"type":"view","id":2},{"type":"click","id":3}]}`,
+			`{"by_type":{"click":[{"id":1,"type":"click"},{"id":3,"type":"click"}],"view":[{"id":2,"type":"view"}]}}`,
+		),
+	),
+	false, groupByMethod,
+	ExpectNArgs(1),
+	ExpectFunctionArg(0),
+)
+
+func groupByMethod(target Function, args ...interface{}) (Function, error) {
+	queryFn, ok := args[0].(Function)
+	if !ok {
+		return nil, fmt.Errorf("expected query argument, received %T", args[0])
+	}
+
+	return simpleMethod(target, func(v interface{}, ctx FunctionContext) (interface{}, error) {
+		arr, ok := v.([]interface{})
+		if !ok {
+			return nil, NewTypeError(v, ValueArray)
+		}
+
+		groups := map[string]interface{}{}
+		for i, ele := range arr {
+			key, err := queryFn.Exec(ctx.WithValue(ele))
+			if err != nil {
+				return nil, fmt.Errorf("element %v: %w", i, err)
+			}
+			switch ISanitize(key).(type) {
+			case string, []byte, int64, uint64, float64, json.Number:
+			default:
+				return nil, fmt.Errorf("element %v: %w", i, NewTypeError(key, ValueString, ValueNumber))
+			}
+			k := IToString(key)
+			group, _ := groups[k].([]interface{})
+			groups[k] = append(group, ele)
+		}
+		return groups, nil
+	}), nil
+}
+
+//------------------------------------------------------------------------------
+
 var _ = RegisterMethod(
 	NewMethodSpec(
 		"index",
@@ -618,6 +807,57 @@ func indexMethod(target Function, args ...interface{}) (Function, error) {
 
 //------------------------------------------------------------------------------
 
+var _ = RegisterMethod(
+	NewMethodSpec(
+		"index_of", "",
+	).InCategory(
+		MethodCategoryObjectAndArray,
+		"Returns the index of the first element of an array that is equal to the argument, or `-1` if no element matches.",
+		NewExampleSpec("",
+			`root.index = this.things.index_of("bar")`,
+			`{"things":["foo","bar","baz"]}`,
+			`{"index":1}`,
+			`{"things":["foo","baz"]}`,
+			`{"index":-1}`,
+		),
+	).InCategory(
+		MethodCategoryStrings,
+		"Returns the starting index of the first occurrence of a substring, or `-1` if the substring is not present.",
+		NewExampleSpec("",
+			`root.index = this.thing.index_of("bar")`,
+			`{"thing":"foobar"}`,
+			`{"index":3}`,
+			`{"thing":"foobaz"}`,
+			`{"index":-1}`,
+		),
+	),
+	true, indexOfMethod,
+	ExpectNArgs(1),
+)
+
+func indexOfMethod(target Function, args ...interface{}) (Function, error) {
+	compareRight := restrictForComparison(args[0])
+	sub := IToString(args[0])
+	return simpleMethod(target, func(v interface{}, ctx FunctionContext) (interface{}, error) {
+		switch t := v.(type) {
+		case string:
+			return int64(strings.Index(t, sub)), nil
+		case []byte:
+			return int64(bytes.Index(t, []byte(sub))), nil
+		case []interface{}:
+			for i, ele := range t {
+				if cmp.Equal(restrictForComparison(ele), compareRight) {
+					return int64(i), nil
+				}
+			}
+			return int64(-1), nil
+		}
+		return nil, NewTypeError(v, ValueArray, ValueString)
+	}), nil
+}
+
+//------------------------------------------------------------------------------
+
 var _ = RegisterMethod(
 	NewMethodSpec(
 		"json_schema",
@@ -679,6 +919,52 @@ func jsonSchemaMethod(target Function, args ...interface{}) (Function, error) {
 
 //------------------------------------------------------------------------------
 
+var _ = RegisterMethod(
+	NewMethodSpec(
+		"key_values", "",
+	).InCategory(
+		MethodCategoryObjectAndArray,
+		"Converts an object into an array of objects, each containing a field `key` and a field `value`, sorted by key. The method [`from_key_values`](#from_key_values) can be used to convert the array back into an object.",
+		NewExampleSpec("",
+			`root.pairs = this.foo.key_values()`,
+			`{"foo":{"bar":1,"baz":2}}`,
+			`{"pairs":[{"key":"bar","value":1},{"key":"baz","value":2}]}`,
+		),
+		NewExampleSpec("This is useful for filtering or transforming the keys of an object with array methods.",
+			`root = this.key_values().filter(!this.key.has_prefix("_")).from_key_values()`,
+			`{"_internal":"foo","name":"bar","id":"baz"}`,
+			`{"id":"baz","name":"bar"}`,
+		),
+	),
+	false, keyValuesMethod,
+	ExpectNArgs(0),
+)
+
+func keyValuesMethod(target Function, _ ...interface{}) (Function, error) {
+	return simpleMethod(target, func(v interface{}, ctx FunctionContext) (interface{}, error) {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, NewTypeError(v, ValueObject)
+		}
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		pairs := make([]interface{}, 0, len(keys))
+		for _, k := range keys {
+			pairs = append(pairs, map[string]interface{}{
+				"key":   k,
+				"value": m[k],
+			})
+		}
+		return pairs, nil
+	}), nil
+}
+
+//------------------------------------------------------------------------------
+
 var _ = RegisterMethod(
 	NewMethodSpec(
 		"keys",
@@ -867,6 +1153,90 @@ func mapEachMethod(target Function, args ...interface{}) (Function, error) {
 
 //------------------------------------------------------------------------------
 
+var _ = RegisterMethod(
+	NewMethodSpec(
+		"max_by", "",
+	).InCategory(
+		MethodCategoryObjectAndArray,
+		"Returns the element of an array with the largest key, where the key of each element is obtained by executing a query argument on it. Keys must all be either numbers or strings. When multiple elements share the largest key the first is returned. An error occurs if the array is empty.",
+		NewExampleSpec("",
+			`root.oldest = this.people.max_by(this.age).name`,
+			`{"people":[{"name":"foo","age":23},{"name":"bar","age":41},{"name":"baz","age":17}]}`,
+			`{"oldest":"bar"}`,
+		),
+	),
+	false, maxByMethod,
+	ExpectNArgs(1),
+	ExpectFunctionArg(0),
+)
+
+func maxByMethod(target Function, args ...interface{}) (Function, error) {
+	return extremeByMethod(target, args[0], func(lhs, rhs interface{}) (bool, error) {
+		return lessThan(rhs, lhs)
+	})
+}
+
+var _ = RegisterMethod(
+	NewMethodSpec(
+		"min_by", "",
+	).InCategory(
+		MethodCategoryObjectAndArray,
+		"Returns the element of an array with the smallest key, where the key of each element is obtained by executing a query argument on it. Keys must all be either numbers or strings. When multiple elements share the smallest key the first is returned. An error occurs if the array is empty.",
+		NewExampleSpec("",
+			`root.youngest = this.people.min_by(this.age).name`,
+			`{"people":[{"name":"foo","age":23},{"name":"bar","age":41},{"name":"baz","age":17}]}`,
+			`{"youngest":"baz"}`,
+		),
+	),
+	false, minByMethod,
+	ExpectNArgs(1),
+	ExpectFunctionArg(0),
+)
+
+func minByMethod(target Function, args ...interface{}) (Function, error) {
+	return extremeByMethod(target, args[0], lessThan)
+}
+
+// extremeByMethod returns the first element of an array where no other element
+// has a key that is preferred according to the provided function.
+func extremeByMethod(target Function, arg interface{}, prefer func(lhs, rhs interface{}) (bool, error)) (Function, error) {
+	queryFn, ok := arg.(Function)
+	if !ok {
+		return nil, fmt.Errorf("expected query argument, received %T", arg)
+	}
+
+	return simpleMethod(target, func(v interface{}, ctx FunctionContext) (interface{}, error) {
+		arr, ok := v.([]interface{})
+		if !ok {
+			return nil, NewTypeError(v, ValueArray)
+		}
+		if len(arr) == 0 {
+			return nil, errors.New("array value is empty")
+		}
+
+		var result, resultKey interface{}
+		for i, ele := range arr {
+			key, err := queryFn.Exec(ctx.WithValue(ele))
+			if err != nil {
+				return nil, fmt.Errorf("element %v: %w", i, err)
+			}
+			if i > 0 {
+				preferred, err := prefer(key, resultKey)
+				if err != nil {
+					return nil, fmt.Errorf("element %v: %w", i, err)
+				}
+				if !preferred {
+					continue
+				}
+			}
+			result, resultKey = ele, key
+		}
+		return result, nil
+	}), nil
+}
+
+//------------------------------------------------------------------------------
+
 var _ = RegisterMethod(
 	NewMethodSpec(
 		"merge", "Merge a source object into an existing destination object. When a collision is found within the merged structures (both a source and destination object contain the same non-object keys) the result will be an array containing both values, where values that are already arrays will be expanded into the resulting array.",
@@ -1008,31 +1378,37 @@ var _ = RegisterMethod(
 	ExpectFunctionArg(0),
 )
 
+// lessThan returns whether a value is less than another, where both values
+// must either be numbers or strings.
+func lessThan(left, right interface{}) (bool, error) {
+	switch left.(type) {
+	case float64, int, int64, uint64, json.Number:
+		var lhs, rhs float64
+		var err error
+		if lhs, err = IGetNumber(left); err == nil {
+			rhs, err = IGetNumber(right)
+		}
+		if err != nil {
+			return false, err
+		}
+		return lhs < rhs, nil
+	case string, []byte:
+		var lhs, rhs string
+		var err error
+		if lhs, err = IGetString(left); err == nil {
+			rhs, err = IGetString(right)
+		}
+		if err != nil {
+			return false, err
+		}
+		return lhs < rhs, nil
+	}
+	return false, NewTypeError(left, ValueNumber, ValueString)
+}
+
 func sortMethod(target Function, args ...interface{}) (Function, error) {
 	compareFn := func(ctx FunctionContext, values []interface{}, i, j int) (bool, error) {
-		switch values[i].(type) {
-		case float64, int, int64, uint64, json.Number:
-			var lhs, rhs float64
-			var err error
-			if lhs, err = IGetNumber(values[i]); err == nil {
-				rhs, err = IGetNumber(values[j])
-			}
-			if err != nil {
-				return false, err
-			}
-			return lhs < rhs, nil
-		case string, []byte:
-			var lhs, rhs string
-			var err error
-			if lhs, err = IGetString(values[i]); err == nil {
-				rhs, err = IGetString(values[j])
-			}
-			if err != nil {
-				return false, err
-			}
-			return lhs < rhs, nil
-		}
-		return false, NewTypeError(values[i], ValueNumber, ValueString)
+		return lessThan(values[i], values[j])
 	}
 	var mapFn Function
 	if len(args) > 0 {
@@ -1087,6 +1463,73 @@ func sortMethod(target Function, args ...interface{}) (Function, error) {
 
 //------------------------------------------------------------------------------
 
+var _ = RegisterMethod(
+	NewMethodSpec(
+		"sort_by", "",
+	).InCategory(
+		MethodCategoryObjectAndArray,
+		"Sorts the elements of an array in increasing order of a key, where the key of each element is obtained by executing a query argument on it. Keys must all be either numbers or strings. The sort is stable, and so elements with equal keys retain their original order.",
+		NewExampleSpec("",
+			`root.sorted = this.foo.sort_by(this.v)`,
+			`{"foo":[{"id":"foo","v":"bbb"},{"id":"bar","v":"ccc"},{"id":"baz","v":"aaa"}]}`,
+			`{"sorted":[{"id":"baz","v":"aaa"},{"id":"foo","v":"bbb"},{"id":"bar","v":"ccc"}]}`,
+		),
+		NewExampleSpec("Sorting in decreasing order can be achieved by reversing the key of numerical values, or by reversing the sorted array.",
+			`root.sorted = this.foo.sort_by(0 - this.score).map_each(this.id)`,
+			`{"foo":[{"id":"foo","score":5},{"id":"bar","score":12},{"id":"baz","score":7}]}`,
+			`{"sorted":["bar","baz","foo"]}`,
+		),
+	),
+	false, sortByMethod,
+	ExpectNArgs(1),
+	ExpectFunctionArg(0),
+)
+
+func sortByMethod(target Function, args ...interface{}) (Function, error) {
+	queryFn, ok := args[0].(Function)
+	if !ok {
+		return nil, fmt.Errorf("expected query argument, received %T", args[0])
+	}
+
+	return simpleMethod(target, func(v interface{}, ctx FunctionContext) (interface{}, error) {
+		arr, ok := v.([]interface{})
+		if !ok {
+			return nil, NewTypeError(v, ValueArray)
+		}
+
+		keys := make([]interface{}, len(arr))
+		indexes := make([]int, len(arr))
+		for i, ele := range arr {
+			key, err := queryFn.Exec(ctx.WithValue(ele))
+			if err != nil {
+				return nil, fmt.Errorf("element %v: %w", i, err)
+			}
+			keys[i], indexes[i] = key, i
+		}
+
+		var err error
+		sort.SliceStable(indexes, func(i, j int) bool {
+			if err != nil {
+				return false
+			}
+			var b bool
+			b, err = lessThan(keys[indexes[i]], keys[indexes[j]])
+			return b
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		sorted := make([]interface{}, 0, len(arr))
+		for _, i := range indexes {
+			sorted = append(sorted, arr[i])
+		}
+		return sorted, nil
+	}), nil
+}
+
+//------------------------------------------------------------------------------
+
 var _ = RegisterMethod(
 	NewMethodSpec(
 		"slice", "",
@@ -1447,3 +1890,56 @@ func withoutMethod(target Function, args ...interface{}) (Function, error) {
 }
 
 //------------------------------------------------------------------------------
+
+var _ = RegisterMethod(
+	NewMethodSpec(
+		"zip", "",
+	).InCategory(
+		MethodCategoryObjectAndArray,
+		"Combines an array with one or more array arguments into an array of arrays, where each array contains the elements of each input array at the same index. The length of the result matches the shortest input array.",
+		NewExampleSpec("",
+			`root.pairs = this.names.zip(this.ages)`,
+			`{"names":["foo","bar","baz"],"ages":[23,41,17]}`,
+			`{"pairs":[["foo",23],["bar",41],["baz",17]]}`,
+		),
+	),
+	true, zipMethod,
+	ExpectAtLeastOneArg(),
+)
+
+func zipMethod(target Function, args ...interface{}) (Function, error) {
+	arrays := make([][]interface{}, 0, len(args)+1)
+	for i, arg := range args {
+		arr, ok := arg.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("argument %v: %w", i, NewTypeError(arg, ValueArray))
+		}
+		arrays = append(arrays, arr)
+	}
+	return simpleMethod(target, func(v interface{}, ctx FunctionContext) (interface{}, error) {
+		arr, ok := v.([]interface{})
+		if !ok {
+			return nil, NewTypeError(v, ValueArray)
+		}
+		inputs := append([][]interface{}{arr}, arrays...)
+
+		length := len(arr)
+		for _, input := range inputs {
+			if len(input) < length {
+				length = len(input)
+			}
+		}
+
+		zipped := make([]interface{}, 0, length)
+		for i := 0; i < length; i++ {
+			tuple := make([]interface{}, 0, len(inputs))
+			for _, input := range inputs {
+				tuple = append(tuple, input[i])
+			}
+			zipped = append(zipped, tuple)
+		}
+		return zipped, nil
+	}), nil
+}
+
+//------------------------------------------------------------------------------
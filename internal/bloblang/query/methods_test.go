@@ -254,6 +254,140 @@ func TestMethods(t *testing.T) {
 			),
 			output: []interface{}{3.0, "a", "5", "b", 5.0, "c", "d"},
 		},
+		"check sort_by": {
+			input: methods(
+				jsonFn(`[{"v":3,"id":"a"},{"v":1,"id":"b"},{"v":3,"id":"c"},{"v":2,"id":"d"}]`),
+				method("sort_by", NewFieldFunction("v")),
+				method("map_each", NewFieldFunction("id")),
+			),
+			output: []interface{}{"b", "d", "a", "c"},
+		},
+		"check sort_by mixed keys": {
+			input: methods(
+				jsonFn(`[{"v":3},{"v":"1"}]`),
+				method("sort_by", NewFieldFunction("v")),
+			),
+			err: "expected string value, found number: 3",
+		},
+		"check group_by": {
+			input: methods(
+				jsonFn(`[{"v":3,"id":"a"},{"v":1,"id":"b"},{"v":3,"id":"c"}]`),
+				method("group_by", NewFieldFunction("v")),
+			),
+			output: map[string]interface{}{
+				"1": []interface{}{map[string]interface{}{"v": 1.0, "id": "b"}},
+				"3": []interface{}{
+					map[string]interface{}{"v": 3.0, "id": "a"},
+					map[string]interface{}{"v": 3.0, "id": "c"},
+				},
+			},
+		},
+		"check group_by bad key": {
+			input: methods(
+				jsonFn(`[{"v":{}}]`),
+				method("group_by", NewFieldFunction("v")),
+			),
+			err: "element 0: expected string or number value, found object",
+		},
+		"check zip": {
+			input: methods(
+				jsonFn(`["a","b","c"]`),
+				method("zip", []interface{}{1.0, 2.0}, []interface{}{true, false, true}),
+			),
+			output: []interface{}{
+				[]interface{}{"a", 1.0, true},
+				[]interface{}{"b", 2.0, false},
+			},
+		},
+		"check zip not array": {
+			input: methods(
+				literalFn("nope"),
+				method("zip", []interface{}{"a"}),
+			),
+			err: "expected array value, found string: nope",
+		},
+		"check key_values round trip": {
+			input: methods(
+				jsonFn(`{"b":2,"a":1}`),
+				method("key_values"),
+				method("from_key_values"),
+			),
+			output: map[string]interface{}{"a": 1.0, "b": 2.0},
+		},
+		"check from_key_values bad key": {
+			input: methods(
+				jsonFn(`[{"key":5,"value":1}]`),
+				method("from_key_values"),
+			),
+			err: "element 0: field key: expected string value, found number: 5",
+		},
+		"check min_by": {
+			input: methods(
+				jsonFn(`[{"v":3,"id":"a"},{"v":1,"id":"b"},{"v":1,"id":"c"}]`),
+				method("min_by", NewFieldFunction("v")),
+			),
+			output: map[string]interface{}{"v": 1.0, "id": "b"},
+		},
+		"check max_by": {
+			input: methods(
+				jsonFn(`[{"v":"b","id":"a"},{"v":"c","id":"b"},{"v":"c","id":"c"}]`),
+				method("max_by", NewFieldFunction("v")),
+			),
+			output: map[string]interface{}{"v": "c", "id": "b"},
+		},
+		"check max_by empty": {
+			input: methods(
+				jsonFn(`[]`),
+				method("max_by", NewFieldFunction("v")),
+			),
+			err: "array value is empty",
+		},
+		"check distinct_by": {
+			input: methods(
+				jsonFn(`[{"v":1,"id":"a"},{"v":"1","id":"b"},{"v":1,"id":"c"},{"v":{"a":1},"id":"d"},{"v":{"a":1},"id":"e"}]`),
+				method("distinct_by", NewFieldFunction("v")),
+				method("map_each", NewFieldFunction("id")),
+			),
+			output: []interface{}{"a", "b", "d"},
+		},
+		"check chunk": {
+			input: methods(
+				jsonFn(`[1,2,3,4]`),
+				method("chunk", int64(2)),
+			),
+			output: []interface{}{
+				[]interface{}{1.0, 2.0},
+				[]interface{}{3.0, 4.0},
+			},
+		},
+		"check chunk empty": {
+			input: methods(
+				jsonFn(`[]`),
+				method("chunk", int64(3)),
+			),
+			output: []interface{}{},
+		},
+		"check index_of": {
+			input: methods(
+				jsonFn(`["a",5,{"b":"c"}]`),
+				method("index_of", int64(5)),
+			),
+			output: int64(1),
+		},
+		"check index_of object": {
+			input: methods(
+				jsonFn(`["a",5,{"b":"c"}]`),
+				method("index_of", map[string]interface{}{"b": "c"}),
+			),
+			output: int64(2),
+		},
+		"check index_of missing": {
+			input: methods(
+				jsonFn(`["a",5]`),
+				method("index_of", "5"),
+			),
+			output: int64(-1),
+		},
 		"check html escape query": {
 			input: methods(
 				literalFn("foo & bar"),
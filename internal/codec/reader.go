@@ -7,6 +7,7 @@ import (
 	"compress/gzip"
 	"context"
 	"encoding/csv"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -15,10 +16,14 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/parquet"
 	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/linkedin/goavro/v2"
+	"github.com/scritchley/orc"
 )
 
 // ReaderDocs is a static field documentation for input codecs.
@@ -27,11 +32,14 @@ var ReaderDocs = docs.FieldCommon(
 ).HasAnnotatedOptions(
 	"auto", "EXPERIMENTAL: Attempts to derive a codec for each file based on information such as the extension. For example, a .tar.gz file would be consumed with the tar-gzip codec. Defaults to all-bytes.",
 	"all-bytes", "Consume the entire file as a single binary message.",
+	"avro-ocf", "EXPERIMENTAL: Parse the file as an [Avro Object Container File](https://avro.apache.org/docs/current/spec.html#Object+Container+Files), and consume each record as a JSON document using the schema embedded within the file. The schema is added to each message as the metadata field `avro_schema`.",
 	"csv", "Consume structured rows as comma separated values, the first row must be a header row.",
 	"csv-gzip", "Consume structured rows as comma separated values from a gzip compressed file, the first row must be a header row.",
 	"delim:x", "Consume the file in segments divided by a custom delimiter.",
 	"chunker:x", "Consume the file in chunks of a given number of bytes.",
 	"lines", "Consume the file in segments divided by linebreaks.",
+	"orc", "EXPERIMENTAL: Parse the file as an [Apache ORC](https://orc.apache.org/) file, and consume each row as a JSON document. The entire file is loaded into memory as the footer of an ORC file is required in order to read it.",
+	"parquet", "EXPERIMENTAL: Parse the file as an [Apache Parquet](https://parquet.apache.org/) file, and consume each row as a JSON document. The entire file is loaded into memory as the footer of a Parquet file is required in order to read it.",
 	"tar", "Parse the file as a tar archive, and consume each file of the archive as a message.",
 	"tar-gzip", "Parse the file as a gzip compressed tar archive, and consume each file of the archive as a message.",
 )
@@ -88,6 +96,8 @@ func GetReader(codec string, conf ReaderConfig) (ReaderConstructor, error) {
 		return func(path string, r io.ReadCloser, fn ReaderAckFn) (Reader, error) {
 			return &allBytesReader{r, fn, false}, nil
 		}, nil
+	case "avro-ocf":
+		return newAvroOCFReader, nil
 	case "lines":
 		return func(path string, r io.ReadCloser, fn ReaderAckFn) (Reader, error) {
 			return newLinesReader(conf, r, fn)
@@ -105,6 +115,10 @@ func GetReader(codec string, conf ReaderConfig) (ReaderConstructor, error) {
 			}
 			return newCSVReader(g, fn)
 		}, nil
+	case "orc":
+		return newORCReader, nil
+	case "parquet":
+		return newParquetReader, nil
 	case "tar":
 		return newTarReader, nil
 	case "tar-gzip":
@@ -142,10 +156,16 @@ func autoCodec(conf ReaderConfig) ReaderConstructor {
 	return func(path string, r io.ReadCloser, fn ReaderAckFn) (Reader, error) {
 		codec := "all-bytes"
 		switch filepath.Ext(path) {
+		case ".avro":
+			codec = "avro-ocf"
 		case ".csv":
 			codec = "csv"
 		case ".csv.gz", ".csv.gzip":
 			codec = "csv-gzip"
+		case ".orc":
+			codec = "orc"
+		case ".parquet":
+			codec = "parquet"
 		case ".tar":
 			codec = "tar"
 		case ".tgz":
@@ -600,3 +620,359 @@ func (a *tarReader) Close(ctx context.Context) error {
 	}
 	return a.r.Close()
 }
+
+//------------------------------------------------------------------------------
+
+type parquetReader struct {
+	pr        *parquet.Reader
+	r         io.ReadCloser
+	sourceAck ReaderAckFn
+
+	mut      sync.Mutex
+	finished bool
+	pending  int32
+}
+
+func newParquetReader(path string, r io.ReadCloser, ackFn ReaderAckFn) (Reader, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+	pr, err := parquet.NewReader(data)
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+	return &parquetReader{
+		pr:        pr,
+		r:         r,
+		sourceAck: ackOnce(ackFn),
+	}, nil
+}
+
+func (a *parquetReader) ack(ctx context.Context, err error) error {
+	a.mut.Lock()
+	a.pending--
+	doAck := a.pending == 0 && a.finished
+	a.mut.Unlock()
+
+	if err != nil {
+		return a.sourceAck(ctx, err)
+	}
+	if doAck {
+		return a.sourceAck(ctx, nil)
+	}
+	return nil
+}
+
+func (a *parquetReader) Next(ctx context.Context) (types.Part, ReaderAckFn, error) {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	row, err := a.pr.Next()
+	if err != nil {
+		if err == io.EOF {
+			a.finished = true
+		} else {
+			a.sourceAck(ctx, err)
+		}
+		return nil, nil, err
+	}
+
+	a.pending++
+
+	part := message.NewPart(nil)
+	if err = part.SetJSON(row); err != nil {
+		a.pending--
+		a.sourceAck(ctx, err)
+		return nil, nil, err
+	}
+	return part, a.ack, nil
+}
+
+func (a *parquetReader) Close(ctx context.Context) error {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	if !a.finished {
+		a.sourceAck(ctx, errors.New("service shutting down"))
+	}
+	if a.pending == 0 {
+		a.sourceAck(ctx, nil)
+	}
+	a.pr.Close()
+	return a.r.Close()
+}
+
+//------------------------------------------------------------------------------
+
+type avroOCFReader struct {
+	ocf       *goavro.OCFReader
+	schema    string
+	r         io.ReadCloser
+	sourceAck ReaderAckFn
+
+	mut      sync.Mutex
+	finished bool
+	pending  int32
+}
+
+func newAvroOCFReader(path string, r io.ReadCloser, ackFn ReaderAckFn) (Reader, error) {
+	ocf, err := goavro.NewOCFReader(bufio.NewReader(r))
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+	return &avroOCFReader{
+		ocf:       ocf,
+		schema:    ocf.Codec().Schema(),
+		r:         r,
+		sourceAck: ackOnce(ackFn),
+	}, nil
+}
+
+func (a *avroOCFReader) ack(ctx context.Context, err error) error {
+	a.mut.Lock()
+	a.pending--
+	doAck := a.pending == 0 && a.finished
+	a.mut.Unlock()
+
+	if err != nil {
+		return a.sourceAck(ctx, err)
+	}
+	if doAck {
+		return a.sourceAck(ctx, nil)
+	}
+	return nil
+}
+
+func (a *avroOCFReader) Next(ctx context.Context) (types.Part, ReaderAckFn, error) {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	if !a.ocf.Scan() {
+		err := a.ocf.Err()
+		if err == nil {
+			err = io.EOF
+			a.finished = true
+		} else {
+			a.sourceAck(ctx, err)
+		}
+		return nil, nil, err
+	}
+
+	datum, err := a.ocf.Read()
+	if err == nil {
+		var jBytes []byte
+		if jBytes, err = a.ocf.Codec().TextualFromNative(nil, datum); err == nil {
+			// Decoding the textual form keeps numbers intact while giving
+			// the document a deterministic key order.
+			dec := json.NewDecoder(bytes.NewReader(jBytes))
+			dec.UseNumber()
+
+			var jObj interface{}
+			if err = dec.Decode(&jObj); err == nil {
+				a.pending++
+
+				part := message.NewPart(nil)
+				if err = part.SetJSON(jObj); err == nil {
+					part.Metadata().Set("avro_schema", a.schema)
+					return part, a.ack, nil
+				}
+				a.pending--
+			}
+		}
+	}
+	a.sourceAck(ctx, err)
+	return nil, nil, err
+}
+
+func (a *avroOCFReader) Close(ctx context.Context) error {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	if !a.finished {
+		a.sourceAck(ctx, errors.New("service shutting down"))
+	}
+	if a.pending == 0 {
+		a.sourceAck(ctx, nil)
+	}
+	return a.r.Close()
+}
+
+//------------------------------------------------------------------------------
+
+type orcReader struct {
+	or        *orc.Reader
+	cursor    *orc.Cursor
+	columns   []string
+	inStripe  bool
+	r         io.ReadCloser
+	sourceAck ReaderAckFn
+
+	mut      sync.Mutex
+	finished bool
+	pending  int32
+}
+
+func newORCReader(path string, r io.ReadCloser, ackFn ReaderAckFn) (Reader, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+	or, err := newORCFileReader(data)
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+	columns := or.Schema().Columns()
+	return &orcReader{
+		or:        or,
+		cursor:    or.Select(columns...),
+		columns:   columns,
+		r:         r,
+		sourceAck: ackOnce(ackFn),
+	}, nil
+}
+
+// newORCFileReader parses the footer of an ORC file, recovering from the panics
+// that the underlying library produces when given malformed data.
+func newORCFileReader(data []byte) (or *orc.Reader, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to parse ORC file: %v", r)
+		}
+	}()
+	return orc.NewReader(bytes.NewReader(data))
+}
+
+func (a *orcReader) ack(ctx context.Context, err error) error {
+	a.mut.Lock()
+	a.pending--
+	doAck := a.pending == 0 && a.finished
+	a.mut.Unlock()
+
+	if err != nil {
+		return a.sourceAck(ctx, err)
+	}
+	if doAck {
+		return a.sourceAck(ctx, nil)
+	}
+	return nil
+}
+
+// nextRow reads the next row of the file, recovering from the panics that the
+// underlying library produces when reading malformed stripes.
+func (a *orcReader) nextRow() (row []interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to read ORC row: %v", r)
+		}
+	}()
+	for {
+		if a.inStripe {
+			if a.cursor.Next() {
+				return a.cursor.Row(), nil
+			}
+			if err := a.cursor.Err(); err != nil {
+				return nil, err
+			}
+		}
+		if a.inStripe = a.cursor.Stripes(); !a.inStripe {
+			if err := a.cursor.Err(); err != nil {
+				return nil, err
+			}
+			return nil, io.EOF
+		}
+	}
+}
+
+// orcToJSON converts values read from an ORC file into structures that are
+// marshalled as plain JSON documents.
+func orcToJSON(v interface{}) interface{} {
+	switch t := v.(type) {
+	case orc.Struct:
+		obj := make(map[string]interface{}, len(t))
+		for k, fv := range t {
+			obj[k] = orcToJSON(fv)
+		}
+		return obj
+	case []orc.MapEntry:
+		obj := make(map[string]interface{}, len(t))
+		for _, e := range t {
+			k, ok := e.Key.(string)
+			if !ok {
+				entries := make([]interface{}, len(t))
+				for i, e := range t {
+					entries[i] = map[string]interface{}{
+						"key":   orcToJSON(e.Key),
+						"value": orcToJSON(e.Value),
+					}
+				}
+				return entries
+			}
+			obj[k] = orcToJSON(e.Value)
+		}
+		return obj
+	case []interface{}:
+		arr := make([]interface{}, len(t))
+		for i, ev := range t {
+			arr[i] = orcToJSON(ev)
+		}
+		return arr
+	case orc.UnionValue:
+		return orcToJSON(t.Value)
+	case orc.Date:
+		return t.Format("2006-01-02")
+	case time.Time:
+		return t.Format(time.RFC3339Nano)
+	}
+	return v
+}
+
+func (a *orcReader) Next(ctx context.Context) (types.Part, ReaderAckFn, error) {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	row, err := a.nextRow()
+	if err != nil {
+		if err == io.EOF {
+			a.finished = true
+		} else {
+			a.sourceAck(ctx, err)
+		}
+		return nil, nil, err
+	}
+
+	obj := make(map[string]interface{}, len(row))
+	for i, v := range row {
+		obj[a.columns[i]] = orcToJSON(v)
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err = enc.Encode(obj); err != nil {
+		a.sourceAck(ctx, err)
+		return nil, nil, err
+	}
+
+	a.pending++
+	return message.NewPart(bytes.TrimSuffix(buf.Bytes(), []byte("\n"))), a.ack, nil
+}
+
+func (a *orcReader) Close(ctx context.Context) error {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	if !a.finished {
+		a.sourceAck(ctx, errors.New("service shutting down"))
+	}
+	if a.pending == 0 {
+		a.sourceAck(ctx, nil)
+	}
+	a.or.Close()
+	return a.r.Close()
+}
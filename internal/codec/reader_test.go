@@ -11,6 +11,9 @@ import (
 	"sync"
 	"testing"
 
+	"github.com/Jeffail/benthos/v3/internal/parquet"
+	"github.com/linkedin/goavro/v2"
+	"github.com/scritchley/orc"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -394,3 +397,152 @@ func TestTarGzipReader(t *testing.T) {
 	testReaderSuite(t, "auto", "foo.tar.gzip", gzipBuf.Bytes(), input...)
 	testReaderSuite(t, "auto", "foo.tgz", gzipBuf.Bytes(), input...)
 }
+
+func TestParquetReader(t *testing.T) {
+	enc, err := parquet.NewEncoder([]parquet.Field{
+		{Name: "id", Type: "INT64"},
+		{Name: "name", Type: "UTF8", Optional: true},
+	}, "snappy")
+	require.NoError(t, err)
+
+	data, err := enc.Encode([][]byte{
+		[]byte(`{"id":1,"name":"foo"}`),
+		[]byte(`{"id":2}`),
+		[]byte(`{"id":3,"name":"baz"}`),
+	})
+	require.NoError(t, err)
+
+	testReaderSuite(
+		t, "parquet", "", data,
+		`{"id":1,"name":"foo"}`,
+		`{"id":2,"name":null}`,
+		`{"id":3,"name":"baz"}`,
+	)
+	testReaderSuite(
+		t, "auto", "foo.parquet", data,
+		`{"id":1,"name":"foo"}`,
+		`{"id":2,"name":null}`,
+		`{"id":3,"name":"baz"}`,
+	)
+
+	_, err = newParquetReader("", noopCloser{bytes.NewReader([]byte("not parquet")), false}, func(ctx context.Context, err error) error {
+		return nil
+	})
+	assert.Error(t, err)
+}
+
+func TestAvroOCFReader(t *testing.T) {
+	schema := `{
+  "type": "record",
+  "name": "foo",
+  "fields": [
+    {"name": "id", "type": "long"},
+    {"name": "name", "type": ["null", "string"]}
+  ]
+}`
+
+	var buf bytes.Buffer
+	w, err := goavro.NewOCFWriter(goavro.OCFConfig{
+		W:      &buf,
+		Schema: schema,
+	})
+	require.NoError(t, err)
+	require.NoError(t, w.Append([]interface{}{
+		map[string]interface{}{"id": 1, "name": goavro.Union("string", "foo")},
+		map[string]interface{}{"id": 2, "name": nil},
+		map[string]interface{}{"id": 3, "name": goavro.Union("string", "baz")},
+	}))
+	data := buf.Bytes()
+
+	testReaderSuite(
+		t, "avro-ocf", "", data,
+		`{"id":1,"name":{"string":"foo"}}`,
+		`{"id":2,"name":null}`,
+		`{"id":3,"name":{"string":"baz"}}`,
+	)
+	testReaderSuite(
+		t, "auto", "foo.avro", data,
+		`{"id":1,"name":{"string":"foo"}}`,
+		`{"id":2,"name":null}`,
+		`{"id":3,"name":{"string":"baz"}}`,
+	)
+
+	r, err := newAvroOCFReader("", noopCloser{bytes.NewReader(data), false}, func(ctx context.Context, err error) error {
+		return nil
+	})
+	require.NoError(t, err)
+	p, _, err := r.Next(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, schema, p.Metadata().Get("avro_schema"))
+
+	_, err = newAvroOCFReader("", noopCloser{bytes.NewReader([]byte("not avro")), false}, func(ctx context.Context, err error) error {
+		return nil
+	})
+	assert.Error(t, err)
+}
+
+func TestORCReader(t *testing.T) {
+	schema, err := orc.ParseSchema("struct<id:bigint,name:string,tags:array<string>,attrs:map<string,string>>")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	w, err := orc.NewWriter(&buf, orc.SetSchema(schema))
+	require.NoError(t, err)
+	require.NoError(t, w.Write(int64(1), "foo", []interface{}{"a", "b"}, map[string]string{"c": "d"}))
+	require.NoError(t, w.Write(int64(2), nil, []interface{}{}, map[string]string{}))
+	require.NoError(t, w.Write(int64(3), "baz", []interface{}{"e"}, map[string]string{"f": "<g>"}))
+	require.NoError(t, w.Close())
+	data := buf.Bytes()
+
+	testReaderSuite(
+		t, "orc", "", data,
+		`{"attrs":{"c":"d"},"id":1,"name":"foo","tags":["a","b"]}`,
+		`{"attrs":null,"id":2,"name":null,"tags":[]}`,
+		`{"attrs":{"f":"<g>"},"id":3,"name":"baz","tags":["e"]}`,
+	)
+	testReaderSuite(
+		t, "auto", "foo.orc", data,
+		`{"attrs":{"c":"d"},"id":1,"name":"foo","tags":["a","b"]}`,
+		`{"attrs":null,"id":2,"name":null,"tags":[]}`,
+		`{"attrs":{"f":"<g>"},"id":3,"name":"baz","tags":["e"]}`,
+	)
+
+	_, err = newORCReader("", noopCloser{bytes.NewReader([]byte("not orc")), false}, func(ctx context.Context, err error) error {
+		return nil
+	})
+	assert.Error(t, err)
+}
+
+func TestORCReaderCorruptStripes(t *testing.T) {
+	schema, err := orc.ParseSchema("struct<id:bigint,name:string,tags:array<string>>")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	w, err := orc.NewWriter(&buf, orc.SetSchema(schema))
+	require.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		require.NoError(t, w.Write(int64(i), "foo", []interface{}{"a", "b"}))
+	}
+	require.NoError(t, w.Close())
+	data := buf.Bytes()
+
+	// Corrupt each byte following the three byte header, and ensure that
+	// reading rows from the file never panics.
+	for i := 3; i < len(data); i++ {
+		corrupt := append([]byte(nil), data...)
+		corrupt[i] ^= 0xff
+
+		r, err := newORCReader("", noopCloser{bytes.NewReader(corrupt), false}, func(ctx context.Context, err error) error {
+			return nil
+		})
+		if err != nil {
+			continue
+		}
+		for j := 0; j < 20; j++ {
+			if _, _, err = r.Next(context.Background()); err != nil {
+				break
+			}
+		}
+		require.NoError(t, r.Close(context.Background()))
+	}
+}
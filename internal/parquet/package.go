@@ -0,0 +1,84 @@
+// Package parquet converts between JSON documents and Apache Parquet files,
+// which is shared by the parquet processors and the parquet reader codec.
+package parquet
+
+import (
+	"errors"
+	"io"
+
+	"github.com/xitongsys/parquet-go/source"
+)
+
+//------------------------------------------------------------------------------
+
+// buffer is an in memory implementation of source.ParquetFile. Files opened
+// from a buffer share its contents but have their own read offset.
+type buffer struct {
+	data   []byte
+	offset int64
+}
+
+func (b *buffer) Read(p []byte) (int, error) {
+	if b.offset >= int64(len(b.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, b.data[b.offset:])
+	b.offset += int64(n)
+	return n, nil
+}
+
+func (b *buffer) Write(p []byte) (int, error) {
+	end := b.offset + int64(len(p))
+	if end > int64(len(b.data)) {
+		b.data = append(b.data, make([]byte, end-int64(len(b.data)))...)
+	}
+	copy(b.data[b.offset:], p)
+	b.offset = end
+	return len(p), nil
+}
+
+func (b *buffer) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += b.offset
+	case io.SeekEnd:
+		offset += int64(len(b.data))
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+	b.offset = offset
+	return offset, nil
+}
+
+func (b *buffer) Close() error {
+	return nil
+}
+
+func (b *buffer) Open(name string) (source.ParquetFile, error) {
+	return &buffer{data: b.data}, nil
+}
+
+func (b *buffer) Create(name string) (source.ParquetFile, error) {
+	return &buffer{}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// recoverErr converts a panic into an error, the parquet library panics on many
+// malformed inputs rather than returning errors.
+func recoverErr(err *error) {
+	if r := recover(); r != nil {
+		switch x := r.(type) {
+		case error:
+			*err = x
+		case string:
+			*err = errors.New(x)
+		default:
+			*err = errors.New("unknown parquet error")
+		}
+	}
+}
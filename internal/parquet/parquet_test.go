@@ -0,0 +1,119 @@
+package parquet
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func docsToJSON(t *testing.T, docs []interface{}) []string {
+	t.Helper()
+
+	var res []string
+	for _, d := range docs {
+		b, err := json.Marshal(d)
+		require.NoError(t, err)
+		res = append(res, string(b))
+	}
+	return res
+}
+
+func TestEncodeDecodeSchema(t *testing.T) {
+	enc, err := NewEncoder([]Field{
+		{Name: "id", Type: "INT64"},
+		{Name: "name", Type: "UTF8", Optional: true},
+		{Name: "score", Type: "DOUBLE", Optional: true},
+		{Name: "tags", Type: "UTF8", Repeated: true},
+		{Name: "meta", Optional: true, Fields: []Field{
+			{Name: "active", Type: "BOOLEAN"},
+			{Name: "count", Type: "INT32", Optional: true},
+		}},
+		{Name: "events", Repeated: true, Fields: []Field{
+			{Name: "kind", Type: "UTF8"},
+		}},
+	}, "snappy")
+	require.NoError(t, err)
+
+	data, err := enc.Encode([][]byte{
+		[]byte(`{"id":1,"name":"foo","score":1.5,"tags":["a","b"],"meta":{"active":true,"count":5},"events":[{"kind":"x"},{"kind":"y"}],"ignored":"yes"}`),
+		[]byte(`{"id":2,"tags":[],"meta":{"active":false}}`),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "PAR1", string(data[:4]))
+
+	docs, err := Decode(data)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		`{"events":[{"kind":"x"},{"kind":"y"}],"id":1,"meta":{"active":true,"count":5},"name":"foo","score":1.5,"tags":["a","b"]}`,
+		`{"events":[],"id":2,"meta":{"active":false,"count":null},"name":null,"score":null,"tags":[]}`,
+	}, docsToJSON(t, docs))
+}
+
+func TestEncodeDecodeInferred(t *testing.T) {
+	enc, err := NewEncoder(nil, "uncompressed")
+	require.NoError(t, err)
+
+	data, err := enc.Encode([][]byte{
+		[]byte(`{"a":1,"b":"foo","c":[1,2],"d":{"e":true},"f":null}`),
+		[]byte(`{"a":2.5,"d":{"e":false,"g":"bar"}}`),
+	})
+	require.NoError(t, err)
+
+	docs, err := Decode(data)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		`{"a":1,"b":"foo","c":[1,2],"d":{"e":true,"g":null}}`,
+		`{"a":2.5,"b":null,"c":[],"d":{"e":false,"g":"bar"}}`,
+	}, docsToJSON(t, docs))
+}
+
+func TestEncodeErrors(t *testing.T) {
+	_, err := NewEncoder(nil, "nope")
+	assert.Error(t, err)
+
+	_, err = NewEncoder([]Field{{Name: "foo", Type: "nope"}}, "snappy")
+	assert.EqualError(t, err, "field 'foo' has unrecognised type 'nope'")
+
+	_, err = NewEncoder([]Field{{Name: "foo-bar", Type: "UTF8"}}, "snappy")
+	assert.Error(t, err)
+
+	_, err = NewEncoder([]Field{{Name: "foo", Type: "UTF8", Repeated: true, Optional: true}}, "snappy")
+	assert.Error(t, err)
+
+	enc, err := NewEncoder([]Field{
+		{Name: "id", Type: "INT32"},
+		{Name: "tags", Type: "UTF8", Repeated: true},
+	}, "snappy")
+	require.NoError(t, err)
+
+	tests := map[string]string{
+		`{"tags":[]}`:            "document 0: required field 'id' is missing",
+		`{"id":"foo"}`:           "document 0: field 'id' of type INT32 cannot be set to string value",
+		`{"id":1.5}`:             "document 0: field 'id' expected integer value, found 1.5",
+		`{"id":5000000000}`:      "document 0: field 'id' value 5000000000 overflows INT32",
+		`{"id":1,"tags":"a"}`:    "document 0: repeated field 'tags' expected array value, found string",
+		`{"id":1,"tags":[1]}`:    "document 0: field 'tags.0' of type UTF8 cannot be set to number value",
+		`{"id":1,"tags":[null]}`: "document 0: repeated field 'tags.0' cannot contain null values",
+		`[]`:                     "document 0: failed to parse as JSON object: json: cannot unmarshal array into Go value of type map[string]interface {}",
+	}
+	for input, exp := range tests {
+		_, err := enc.Encode([][]byte{[]byte(input)})
+		assert.EqualError(t, err, exp, input)
+	}
+
+	enc, err = NewEncoder(nil, "snappy")
+	require.NoError(t, err)
+
+	_, err = enc.Encode([][]byte{[]byte(`{"a":1}`), []byte(`{"a":"foo"}`)})
+	assert.EqualError(t, err, "field 'a' contains values of mismatched types")
+
+	_, err = enc.Encode([][]byte{[]byte(`{"a":[[1]]}`)})
+	assert.EqualError(t, err, "field 'a' contains nested arrays, which are not supported")
+}
+
+func TestDecodeErrors(t *testing.T) {
+	_, err := Decode([]byte("not a parquet file"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,171 @@
+package parquet
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+
+	pqparquet "github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/reader"
+)
+
+// readBatchSize is the number of rows read from a file at a time.
+const readBatchSize = 1000
+
+// Reader reads the rows of a parquet file as JSON compatible documents.
+type Reader struct {
+	pr *reader.ParquetReader
+
+	names    []string
+	children [][]int32
+
+	remaining int64
+	rows      []interface{}
+}
+
+// NewReader creates a reader of the rows of a parquet file.
+func NewReader(data []byte) (r *Reader, err error) {
+	defer recoverErr(&err)
+
+	pr, err := reader.NewParquetReader(&buffer{data: data}, nil, 1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read parquet file: %w", err)
+	}
+
+	sh := pr.SchemaHandler
+	r = &Reader{
+		pr:        pr,
+		names:     make([]string, len(sh.SchemaElements)),
+		children:  make([][]int32, len(sh.SchemaElements)),
+		remaining: pr.GetNumRows(),
+	}
+
+	// Schema elements are listed depth first, and so the child indexes of
+	// each element are resolved with a stack of elements yet to be filled.
+	type pending struct {
+		index     int32
+		remaining int32
+	}
+	var stack []pending
+	for i, e := range sh.SchemaElements {
+		r.names[i] = sh.Infos[i].ExName
+		for len(stack) > 0 && stack[len(stack)-1].remaining == 0 {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) > 0 {
+			parent := &stack[len(stack)-1]
+			r.children[parent.index] = append(r.children[parent.index], int32(i))
+			parent.remaining--
+		}
+		if n := e.GetNumChildren(); n > 0 {
+			stack = append(stack, pending{index: int32(i), remaining: n})
+		}
+	}
+	return r, nil
+}
+
+// Next returns the next row of the file as a document, or io.EOF once all rows
+// have been read.
+func (r *Reader) Next() (doc interface{}, err error) {
+	if len(r.rows) == 0 {
+		if r.remaining <= 0 {
+			return nil, io.EOF
+		}
+		n := int64(readBatchSize)
+		if n > r.remaining {
+			n = r.remaining
+		}
+		if r.rows, err = r.readRows(int(n)); err != nil {
+			return nil, err
+		}
+		if len(r.rows) == 0 {
+			return nil, io.EOF
+		}
+		r.remaining -= int64(len(r.rows))
+	}
+	row := r.rows[0]
+	r.rows = r.rows[1:]
+	return r.toDocument(reflect.ValueOf(row), 0), nil
+}
+
+func (r *Reader) readRows(n int) (rows []interface{}, err error) {
+	defer recoverErr(&err)
+	if rows, err = r.pr.ReadByNumber(n); err != nil {
+		return nil, fmt.Errorf("failed to read rows: %w", err)
+	}
+	return rows, nil
+}
+
+// Close the reader.
+func (r *Reader) Close() {
+	r.pr.ReadStop()
+}
+
+// toDocument converts a value read from the file, which is a dynamically
+// created struct type, into a JSON compatible value using the original names of
+// the schema element it belongs to.
+func (r *Reader) toDocument(v reflect.Value, index int32) interface{} {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return r.toDocument(v.Elem(), index)
+	case reflect.Struct:
+		children := r.children[index]
+		obj := make(map[string]interface{}, len(children))
+		for i, c := range children {
+			obj[r.names[c]] = r.toDocument(v.Field(i), c)
+		}
+		return obj
+	case reflect.Slice:
+		// Lists are either repeated elements, where each value belongs to
+		// the element itself, or groups annotated as a LIST that contain a
+		// single repeated group of the list element.
+		elemIndex := index
+		if len(r.children[index]) > 0 && !r.isRepeated(index) {
+			elemIndex = r.children[r.children[index][0]][0]
+		}
+		arr := make([]interface{}, v.Len())
+		for i := range arr {
+			arr[i] = r.toDocument(v.Index(i), elemIndex)
+		}
+		return arr
+	case reflect.Map:
+		keyValue := r.children[r.children[index][0]]
+		obj := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			key := fmt.Sprintf("%v", r.toDocument(iter.Key(), keyValue[0]))
+			obj[key] = r.toDocument(iter.Value(), keyValue[1])
+		}
+		return obj
+	}
+	return v.Interface()
+}
+
+func (r *Reader) isRepeated(index int32) bool {
+	rt := r.pr.SchemaHandler.SchemaElements[index].RepetitionType
+	return rt != nil && *rt == pqparquet.FieldRepetitionType_REPEATED
+}
+
+// Decode reads all rows of a parquet file as documents.
+func Decode(data []byte) ([]interface{}, error) {
+	r, err := NewReader(data)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var docs []interface{}
+	for {
+		doc, err := r.Next()
+		if err == io.EOF {
+			return docs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+}
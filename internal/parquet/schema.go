@@ -0,0 +1,352 @@
+package parquet
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Field describes a column of a parquet schema, or a group of columns when it
+// has child fields.
+type Field struct {
+	Name     string
+	Type     string
+	Repeated bool
+	Optional bool
+	Fields   []Field
+}
+
+// Types lists the column types that are supported by the encoder.
+var Types = []string{
+	"BOOLEAN", "INT32", "INT64", "FLOAT", "DOUBLE", "BYTE_ARRAY", "UTF8",
+}
+
+func isType(t string) bool {
+	for _, v := range Types {
+		if v == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Field names are converted into struct fields by the parquet library, and
+// therefore they are restricted to those that are valid exported identifiers.
+var fieldNameRegexp = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]*$`)
+
+// CheckSchema returns an error if a schema is invalid.
+func CheckSchema(fields []Field) error {
+	if len(fields) == 0 {
+		return errors.New("schema must contain at least one field")
+	}
+	return checkFields("", fields)
+}
+
+func checkFields(prefix string, fields []Field) error {
+	seen := map[string]struct{}{}
+	for _, f := range fields {
+		path := prefix + f.Name
+		if !fieldNameRegexp.MatchString(f.Name) {
+			return fmt.Errorf("field name '%v' must begin with a letter and contain only alphanumeric characters and underscores", path)
+		}
+		key := strings.ToUpper(f.Name[:1]) + f.Name[1:]
+		if _, exists := seen[key]; exists {
+			return fmt.Errorf("field name '%v' is not unique", path)
+		}
+		seen[key] = struct{}{}
+
+		if f.Repeated && f.Optional {
+			return fmt.Errorf("field '%v' cannot be both repeated and optional", path)
+		}
+		if len(f.Fields) > 0 {
+			if len(f.Type) > 0 {
+				return fmt.Errorf("field '%v' has child fields and therefore cannot have a type", path)
+			}
+			if err := checkFields(path+".", f.Fields); err != nil {
+				return err
+			}
+			continue
+		}
+		if !isType(f.Type) {
+			return fmt.Errorf("field '%v' has unrecognised type '%v'", path, f.Type)
+		}
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+type jsonSchemaItem struct {
+	Tag    string
+	Fields []jsonSchemaItem `json:",omitempty"`
+}
+
+func repetitionType(f Field) string {
+	if f.Repeated {
+		return "REPEATED"
+	}
+	if f.Optional {
+		return "OPTIONAL"
+	}
+	return "REQUIRED"
+}
+
+func toSchemaItems(fields []Field) []jsonSchemaItem {
+	items := make([]jsonSchemaItem, 0, len(fields))
+	for _, f := range fields {
+		if len(f.Fields) > 0 {
+			items = append(items, jsonSchemaItem{
+				Tag:    fmt.Sprintf("name=%v, repetitiontype=%v", f.Name, repetitionType(f)),
+				Fields: toSchemaItems(f.Fields),
+			})
+			continue
+		}
+		items = append(items, jsonSchemaItem{
+			Tag: fmt.Sprintf("name=%v, type=%v, repetitiontype=%v", f.Name, f.Type, repetitionType(f)),
+		})
+	}
+	return items
+}
+
+// schemaJSON converts a schema into the JSON format of the parquet library.
+func schemaJSON(fields []Field) (string, error) {
+	b, err := json.Marshal(jsonSchemaItem{
+		Tag:    "name=benthos, repetitiontype=REQUIRED",
+		Fields: toSchemaItems(fields),
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+//------------------------------------------------------------------------------
+
+func jsonType(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case string:
+		return "string"
+	case json.Number:
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// validate returns an error if a document, parsed with numbers as json.Number
+// values, does not conform to the fields of a schema. Values that are not
+// within the schema are ignored.
+func validate(path string, obj map[string]interface{}, fields []Field) error {
+	for _, f := range fields {
+		fPath := path + f.Name
+		v := obj[f.Name]
+		if v == nil {
+			if f.Optional || f.Repeated {
+				continue
+			}
+			return fmt.Errorf("required field '%v' is missing", fPath)
+		}
+		if !f.Repeated {
+			if err := validateValue(fPath, v, f); err != nil {
+				return err
+			}
+			continue
+		}
+		arr, ok := v.([]interface{})
+		if !ok {
+			return fmt.Errorf("repeated field '%v' expected array value, found %v", fPath, jsonType(v))
+		}
+		for i, e := range arr {
+			ePath := fmt.Sprintf("%v.%v", fPath, i)
+			if e == nil {
+				return fmt.Errorf("repeated field '%v' cannot contain null values", ePath)
+			}
+			if err := validateValue(ePath, e, f); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateValue(path string, v interface{}, f Field) error {
+	if len(f.Fields) > 0 {
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("field '%v' expected object value, found %v", path, jsonType(v))
+		}
+		return validate(path+".", obj, f.Fields)
+	}
+
+	var ok bool
+	switch f.Type {
+	case "BOOLEAN":
+		_, ok = v.(bool)
+	case "INT32", "INT64":
+		var n json.Number
+		if n, ok = v.(json.Number); ok {
+			i, err := n.Int64()
+			if err != nil {
+				return fmt.Errorf("field '%v' expected integer value, found %v", path, n)
+			}
+			if f.Type == "INT32" && (i > math.MaxInt32 || i < math.MinInt32) {
+				return fmt.Errorf("field '%v' value %v overflows INT32", path, i)
+			}
+		}
+	case "FLOAT", "DOUBLE":
+		_, ok = v.(json.Number)
+	case "BYTE_ARRAY", "UTF8":
+		_, ok = v.(string)
+	}
+	if !ok {
+		return fmt.Errorf("field '%v' of type %v cannot be set to %v value", path, f.Type, jsonType(v))
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+type inferNode struct {
+	typ      string
+	repeated bool
+	single   bool
+	children map[string]*inferNode
+}
+
+func (n *inferNode) merge(path string, v interface{}) error {
+	if v == nil {
+		return nil
+	}
+	if arr, ok := v.([]interface{}); ok {
+		if n.single {
+			return fmt.Errorf("field '%v' contains both array and non-array values", path)
+		}
+		n.repeated = true
+		for _, e := range arr {
+			if _, isArr := e.([]interface{}); isArr {
+				return fmt.Errorf("field '%v' contains nested arrays, which are not supported", path)
+			}
+			if err := n.mergeValue(path, e); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if n.repeated {
+		return fmt.Errorf("field '%v' contains both array and non-array values", path)
+	}
+	n.single = true
+	return n.mergeValue(path, v)
+}
+
+func (n *inferNode) mergeValue(path string, v interface{}) error {
+	var typ string
+	switch t := v.(type) {
+	case nil:
+		return nil
+	case bool:
+		typ = "BOOLEAN"
+	case string:
+		typ = "UTF8"
+	case json.Number:
+		typ = "DOUBLE"
+		if _, err := t.Int64(); err == nil {
+			typ = "INT64"
+		}
+	case map[string]interface{}:
+		typ = "GROUP"
+	default:
+		return fmt.Errorf("field '%v' has unsupported value type %v", path, jsonType(v))
+	}
+
+	switch {
+	case n.typ == "" || n.typ == typ:
+		n.typ = typ
+	case n.typ == "INT64" && typ == "DOUBLE":
+		n.typ = "DOUBLE"
+	case n.typ == "DOUBLE" && typ == "INT64":
+	default:
+		return fmt.Errorf("field '%v' contains values of mismatched types", path)
+	}
+
+	if obj, ok := v.(map[string]interface{}); ok {
+		return n.mergeObject(path+".", obj)
+	}
+	return nil
+}
+
+func (n *inferNode) mergeObject(prefix string, obj map[string]interface{}) error {
+	if n.children == nil {
+		n.children = map[string]*inferNode{}
+	}
+	for k, v := range obj {
+		child, exists := n.children[k]
+		if !exists {
+			child = &inferNode{}
+			n.children[k] = child
+		}
+		if err := child.merge(prefix+k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *inferNode) fields() []Field {
+	keys := make([]string, 0, len(n.children))
+	for k := range n.children {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var fields []Field
+	for _, k := range keys {
+		child := n.children[k]
+		f := Field{
+			Name:     k,
+			Repeated: child.repeated,
+			Optional: !child.repeated,
+		}
+		switch child.typ {
+		case "":
+			// Fields that only contain null values have no known type.
+			continue
+		case "GROUP":
+			if f.Fields = child.fields(); len(f.Fields) == 0 {
+				continue
+			}
+		default:
+			f.Type = child.typ
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// inferSchema derives a schema from a slice of documents, parsed with numbers
+// as json.Number values. All inferred fields are optional, or repeated when
+// they contain arrays, and fields that are only ever null are omitted.
+func inferSchema(docs []map[string]interface{}) ([]Field, error) {
+	root := &inferNode{}
+	for _, doc := range docs {
+		if err := root.mergeObject("", doc); err != nil {
+			return nil, err
+		}
+	}
+	fields := root.fields()
+	if err := CheckSchema(fields); err != nil {
+		return nil, fmt.Errorf("failed to infer schema: %w", err)
+	}
+	return fields, nil
+}
@@ -0,0 +1,103 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	pqparquet "github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// CompressionCodecs lists the supported compression codecs of the encoder.
+var CompressionCodecs = []string{"uncompressed", "snappy", "gzip", "zstd"}
+
+func getCompressionCodec(name string) (pqparquet.CompressionCodec, error) {
+	switch name {
+	case "uncompressed":
+		return pqparquet.CompressionCodec_UNCOMPRESSED, nil
+	case "snappy":
+		return pqparquet.CompressionCodec_SNAPPY, nil
+	case "gzip":
+		return pqparquet.CompressionCodec_GZIP, nil
+	case "zstd":
+		return pqparquet.CompressionCodec_ZSTD, nil
+	}
+	return 0, fmt.Errorf("compression codec '%v' not recognised", name)
+}
+
+// Encoder writes JSON documents into parquet files.
+type Encoder struct {
+	fields      []Field
+	compression pqparquet.CompressionCodec
+}
+
+// NewEncoder creates an encoder that writes documents using a schema and a
+// compression codec. When the schema is empty it is inferred from the
+// documents of each file.
+func NewEncoder(fields []Field, compression string) (*Encoder, error) {
+	if len(fields) > 0 {
+		if err := CheckSchema(fields); err != nil {
+			return nil, err
+		}
+	}
+	codec, err := getCompressionCodec(compression)
+	if err != nil {
+		return nil, err
+	}
+	return &Encoder{
+		fields:      fields,
+		compression: codec,
+	}, nil
+}
+
+// Encode writes a slice of JSON documents into a single parquet file. An error
+// is returned if any document does not conform to the schema.
+func (e *Encoder) Encode(docs [][]byte) (data []byte, err error) {
+	parsed := make([]map[string]interface{}, len(docs))
+	for i, doc := range docs {
+		dec := json.NewDecoder(bytes.NewReader(doc))
+		dec.UseNumber()
+		if err = dec.Decode(&parsed[i]); err != nil {
+			return nil, fmt.Errorf("document %v: failed to parse as JSON object: %w", i, err)
+		}
+		if parsed[i] == nil {
+			return nil, fmt.Errorf("document %v: expected JSON object, found null", i)
+		}
+	}
+
+	fields := e.fields
+	if len(fields) == 0 {
+		if fields, err = inferSchema(parsed); err != nil {
+			return nil, err
+		}
+	}
+	for i, doc := range parsed {
+		if err = validate("", doc, fields); err != nil {
+			return nil, fmt.Errorf("document %v: %w", i, err)
+		}
+	}
+
+	schema, err := schemaJSON(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	defer recoverErr(&err)
+
+	buf := &buffer{}
+	pw, err := writer.NewJSONWriter(schema, buf, 1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create writer: %w", err)
+	}
+	pw.CompressionType = e.compression
+	for _, doc := range docs {
+		if err = pw.Write(string(doc)); err != nil {
+			return nil, fmt.Errorf("failed to write document: %w", err)
+		}
+	}
+	if err = pw.WriteStop(); err != nil {
+		return nil, fmt.Errorf("failed to write file: %w", err)
+	}
+	return buf.data, nil
+}
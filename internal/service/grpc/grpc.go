@@ -0,0 +1,377 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	btls "github.com/Jeffail/benthos/v3/lib/util/tls"
+	"github.com/golang/protobuf/proto"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/jhump/protoreflect/dynamic/grpcdynamic"
+	"github.com/jhump/protoreflect/grpcreflect"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+)
+
+// ParseProtoFiles walks each import path and parses all .proto files found. If
+// no import paths are provided the current directory is used.
+func ParseProtoFiles(importPaths []string) ([]*desc.FileDescriptor, error) {
+	var parser protoparse.Parser
+	if len(importPaths) == 0 {
+		importPaths = []string{"."}
+	} else {
+		parser.ImportPaths = importPaths
+	}
+
+	var files []string
+	for _, importPath := range importPaths {
+		if err := filepath.Walk(importPath, func(path string, info os.FileInfo, ferr error) error {
+			if ferr != nil || info.IsDir() {
+				return ferr
+			}
+			if filepath.Ext(info.Name()) == ".proto" {
+				rPath, ferr := filepath.Rel(importPath, path)
+				if ferr != nil {
+					return fmt.Errorf("failed to get relative path: %v", ferr)
+				}
+				files = append(files, rPath)
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	fds, err := parser.ParseFiles(files...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse .proto file: %v", err)
+	}
+	if len(fds) == 0 {
+		return nil, fmt.Errorf("no .proto files were found in the paths '%v'", importPaths)
+	}
+	return fds, nil
+}
+
+// FindServices returns the services of a set of proto files by their fully
+// qualified names, or all services when no names are provided.
+func FindServices(fds []*desc.FileDescriptor, names []string) ([]*desc.ServiceDescriptor, error) {
+	if len(names) == 0 {
+		var services []*desc.ServiceDescriptor
+		for _, fd := range fds {
+			services = append(services, fd.GetServices()...)
+		}
+		if len(services) == 0 {
+			return nil, errors.New("no services were found within the .proto files")
+		}
+		return services, nil
+	}
+
+	services := make([]*desc.ServiceDescriptor, 0, len(names))
+	for _, name := range names {
+		var service *desc.ServiceDescriptor
+		for _, fd := range fds {
+			if service = fd.FindService(name); service != nil {
+				break
+			}
+		}
+		if service == nil {
+			return nil, fmt.Errorf("unable to find service '%v' definition", name)
+		}
+		services = append(services, service)
+	}
+	return services, nil
+}
+
+// MethodPath returns the path of a method as it is called over the wire, in the
+// form /package.Service/Method.
+func MethodPath(method *desc.MethodDescriptor) string {
+	return "/" + method.GetService().GetFullyQualifiedName() + "/" + method.GetName()
+}
+
+func splitMethod(method string) (service, name string, err error) {
+	method = strings.TrimPrefix(method, "/")
+	i := strings.LastIndex(method, "/")
+	if i <= 0 || i == len(method)-1 {
+		return "", "", fmt.Errorf("method '%v' must be of the form package.Service/Method", method)
+	}
+	return method[:i], method[i+1:], nil
+}
+
+func checkMethod(method *desc.MethodDescriptor) (*desc.MethodDescriptor, error) {
+	if method.IsServerStreaming() {
+		return nil, fmt.Errorf("method '%v' is server streaming, which is not supported", MethodPath(method))
+	}
+	return method, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ClientConfig is a config struct for calling a gRPC method.
+type ClientConfig struct {
+	Address     string            `json:"address" yaml:"address"`
+	Method      string            `json:"method" yaml:"method"`
+	ImportPaths []string          `json:"import_paths" yaml:"import_paths"`
+	Metadata    map[string]string `json:"metadata" yaml:"metadata"`
+	Timeout     string            `json:"timeout" yaml:"timeout"`
+	TLS         btls.Config       `json:"tls" yaml:"tls"`
+}
+
+// NewClientConfig returns a ClientConfig with default values.
+func NewClientConfig() ClientConfig {
+	return ClientConfig{
+		Address:     "localhost:50051",
+		Method:      "",
+		ImportPaths: []string{},
+		Metadata:    map[string]string{},
+		Timeout:     "5s",
+		TLS:         btls.NewConfig(),
+	}
+}
+
+// ClientConfigDocs returns a documentation field spec for the fields of a
+// ClientConfig.
+func ClientConfigDocs() docs.FieldSpecs {
+	return docs.FieldSpecs{
+		docs.FieldCommon("address", "The address of the gRPC server.", "localhost:50051"),
+		docs.FieldCommon("method", "The fully qualified name of the method to call, in the form `package.Service/Method`.", "helloworld.Greeter/SayHello"),
+		docs.FieldCommon("import_paths", "A list of directories containing .proto files, including all definitions required for the method. Each directory listed will be walked with all found .proto files imported. If left empty the definitions are obtained from the server using [server reflection](https://github.com/grpc/grpc/blob/master/doc/server-reflection.md)."),
+		docs.FieldAdvanced("metadata", "A map of metadata to add to each call.", map[string]interface{}{
+			"authorization": `Bearer ${! meta("token") }`,
+		}).HasType("object").SupportsInterpolation(false),
+		docs.FieldAdvanced("timeout", "The maximum period to wait for each call to complete."),
+		btls.FieldSpec(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Client calls a gRPC method with requests built from the JSON documents of
+// messages, where the method definition is obtained either from local .proto
+// files or from the server via reflection.
+type Client struct {
+	conf     ClientConfig
+	timeout  time.Duration
+	metadata map[string]field.Expression
+
+	conn *grpc.ClientConn
+	stub grpcdynamic.Stub
+
+	methodMut sync.Mutex
+	method    *desc.MethodDescriptor
+}
+
+// NewClient creates a client from a config. Connections are established
+// lazily, and therefore a client can be created while the server is down.
+func NewClient(conf ClientConfig) (*Client, error) {
+	if len(conf.Address) == 0 {
+		return nil, errors.New("an address must be specified")
+	}
+	c := &Client{
+		conf:     conf,
+		metadata: map[string]field.Expression{},
+	}
+
+	service, name, err := splitMethod(conf.Method)
+	if err != nil {
+		return nil, err
+	}
+	if len(conf.ImportPaths) > 0 {
+		fds, err := ParseProtoFiles(conf.ImportPaths)
+		if err != nil {
+			return nil, err
+		}
+		services, err := FindServices(fds, []string{service})
+		if err != nil {
+			return nil, err
+		}
+		method := services[0].FindMethodByName(name)
+		if method == nil {
+			return nil, fmt.Errorf("unable to find method '%v' within service '%v'", name, service)
+		}
+		if c.method, err = checkMethod(method); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(conf.Timeout) > 0 {
+		if c.timeout, err = time.ParseDuration(conf.Timeout); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout string: %v", err)
+		}
+	}
+	for k, v := range conf.Metadata {
+		if c.metadata[k], err = bloblang.NewField(v); err != nil {
+			return nil, fmt.Errorf("failed to parse metadata '%v' expression: %v", k, err)
+		}
+	}
+
+	opts := []grpc.DialOption{grpc.WithInsecure()}
+	if conf.TLS.Enabled {
+		tlsConf, err := conf.TLS.Get()
+		if err != nil {
+			return nil, err
+		}
+		opts = []grpc.DialOption{grpc.WithTransportCredentials(credentials.NewTLS(tlsConf))}
+	}
+	if c.conn, err = grpc.Dial(conf.Address, opts...); err != nil {
+		return nil, err
+	}
+	c.stub = grpcdynamic.NewStub(c.conn)
+	return c, nil
+}
+
+// Method returns the descriptor of the target method, which is obtained from
+// the server via reflection on first use when no import paths are configured.
+func (c *Client) Method(ctx context.Context) (*desc.MethodDescriptor, error) {
+	c.methodMut.Lock()
+	defer c.methodMut.Unlock()
+
+	if c.method != nil {
+		return c.method, nil
+	}
+
+	service, name, err := splitMethod(c.conf.Method)
+	if err != nil {
+		return nil, err
+	}
+
+	refCtx, done := context.WithCancel(ctx)
+	defer done()
+
+	refClient := grpcreflect.NewClient(refCtx, rpb.NewServerReflectionClient(c.conn))
+	defer refClient.Reset()
+
+	serviceDesc, err := refClient.ResolveService(service)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve service '%v' via reflection: %w", service, err)
+	}
+	method := serviceDesc.FindMethodByName(name)
+	if method == nil {
+		return nil, fmt.Errorf("unable to find method '%v' within service '%v'", name, service)
+	}
+	if c.method, err = checkMethod(method); err != nil {
+		return nil, err
+	}
+	return c.method, nil
+}
+
+func (c *Client) callContext(ctx context.Context, index int, msg types.Message) (context.Context, context.CancelFunc) {
+	if len(c.metadata) > 0 {
+		md := metadata.MD{}
+		for k, v := range c.metadata {
+			md.Set(k, v.String(index, msg))
+		}
+		ctx = metadata.NewOutgoingContext(ctx, md)
+	}
+	if c.timeout > 0 {
+		return context.WithTimeout(ctx, c.timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+func newRequest(method *desc.MethodDescriptor, p types.Part) (*dynamic.Message, error) {
+	req := dynamic.NewMessage(method.GetInputType())
+	if err := req.UnmarshalJSON(p.Get()); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal JSON message: %w", err)
+	}
+	return req, nil
+}
+
+func responseJSON(res proto.Message) ([]byte, error) {
+	dynRes, err := dynamic.AsDynamicMessage(res)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	data, err := dynRes.MarshalJSON()
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal response: %w", err)
+	}
+	return data, nil
+}
+
+// Unary calls a unary method with a request built from a message part and
+// returns the response as a JSON document.
+func (c *Client) Unary(ctx context.Context, index int, msg types.Message) ([]byte, error) {
+	method, err := c.Method(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if method.IsClientStreaming() {
+		return nil, fmt.Errorf("method '%v' is client streaming", MethodPath(method))
+	}
+
+	req, err := newRequest(method, msg.Get(index))
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, done := c.callContext(ctx, index, msg)
+	defer done()
+
+	res, err := c.stub.InvokeRpc(ctx, method, req)
+	if err != nil {
+		return nil, err
+	}
+	return responseJSON(res)
+}
+
+// ClientStream calls a client streaming method with a request built from each
+// message part in order and returns the response as a JSON document.
+func (c *Client) ClientStream(ctx context.Context, msg types.Message) ([]byte, error) {
+	method, err := c.Method(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !method.IsClientStreaming() {
+		return nil, fmt.Errorf("method '%v' is not client streaming", MethodPath(method))
+	}
+
+	reqs := make([]*dynamic.Message, msg.Len())
+	for i := range reqs {
+		if reqs[i], err = newRequest(method, msg.Get(i)); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, done := c.callContext(ctx, 0, msg)
+	defer done()
+
+	stream, err := c.stub.InvokeRpcClientStream(ctx, method)
+	if err != nil {
+		return nil, err
+	}
+	for _, req := range reqs {
+		if err = stream.SendMsg(req); err != nil {
+			if err != io.EOF {
+				return nil, err
+			}
+			// The real error is obtained by closing the stream.
+			break
+		}
+	}
+
+	res, err := stream.CloseAndReceive()
+	if err != nil {
+		return nil, err
+	}
+	return responseJSON(res)
+}
+
+// Close closes the connection of the client.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
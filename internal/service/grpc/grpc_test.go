@@ -0,0 +1,197 @@
+package grpc
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+const testProto = `
+syntax = "proto3";
+package testing;
+
+service Greeter {
+  rpc SayHello (HelloRequest) returns (HelloReply) {}
+  rpc SayHellos (stream HelloRequest) returns (HelloReply) {}
+  rpc Spam (HelloRequest) returns (stream HelloReply) {}
+}
+
+message HelloRequest {
+  string name = 1;
+}
+
+message HelloReply {
+  string message = 1;
+}
+`
+
+func writeTestProto(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "greeter.proto"), []byte(testProto), 0o644))
+	return dir
+}
+
+func startHealthServer(t *testing.T) string {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	server := grpc.NewServer()
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	reflection.Register(server)
+
+	go func() {
+		_ = server.Serve(lis)
+	}()
+	t.Cleanup(server.Stop)
+	return lis.Addr().String()
+}
+
+func TestFindServices(t *testing.T) {
+	fds, err := ParseProtoFiles([]string{writeTestProto(t)})
+	require.NoError(t, err)
+
+	services, err := FindServices(fds, nil)
+	require.NoError(t, err)
+	require.Len(t, services, 1)
+	assert.Equal(t, "testing.Greeter", services[0].GetFullyQualifiedName())
+
+	services, err = FindServices(fds, []string{"testing.Greeter"})
+	require.NoError(t, err)
+	require.Len(t, services, 1)
+	assert.Equal(t, "/testing.Greeter/SayHello", MethodPath(services[0].GetMethods()[0]))
+
+	_, err = FindServices(fds, []string{"testing.Nope"})
+	require.EqualError(t, err, "unable to find service 'testing.Nope' definition")
+
+	_, err = ParseProtoFiles([]string{t.TempDir()})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no .proto files were found")
+}
+
+func TestNewClientErrors(t *testing.T) {
+	dir := writeTestProto(t)
+
+	tests := map[string]struct {
+		conf func(c *ClientConfig)
+		err  string
+	}{
+		"no address": {
+			conf: func(c *ClientConfig) {
+				c.Address = ""
+			},
+			err: "an address must be specified",
+		},
+		"bad method": {
+			conf: func(c *ClientConfig) {
+				c.Method = "SayHello"
+			},
+			err: "method 'SayHello' must be of the form package.Service/Method",
+		},
+		"unknown service": {
+			conf: func(c *ClientConfig) {
+				c.Method = "testing.Nope/SayHello"
+			},
+			err: "unable to find service 'testing.Nope' definition",
+		},
+		"unknown method": {
+			conf: func(c *ClientConfig) {
+				c.Method = "testing.Greeter/Nope"
+			},
+			err: "unable to find method 'Nope' within service 'testing.Greeter'",
+		},
+		"server streaming": {
+			conf: func(c *ClientConfig) {
+				c.Method = "testing.Greeter/Spam"
+			},
+			err: "method '/testing.Greeter/Spam' is server streaming, which is not supported",
+		},
+		"bad timeout": {
+			conf: func(c *ClientConfig) {
+				c.Timeout = "nope"
+			},
+			err: `failed to parse timeout string: time: invalid duration "nope"`,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			conf := NewClientConfig()
+			conf.Method = "/testing.Greeter/SayHello"
+			conf.ImportPaths = []string{dir}
+			test.conf(&conf)
+
+			_, err := NewClient(conf)
+			require.EqualError(t, err, test.err)
+		})
+	}
+}
+
+func TestClientReflection(t *testing.T) {
+	conf := NewClientConfig()
+	conf.Address = startHealthServer(t)
+	conf.Method = "grpc.health.v1.Health/Check"
+
+	client, err := NewClient(conf)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, client.Close())
+	})
+
+	method, err := client.Method(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "/grpc.health.v1.Health/Check", MethodPath(method))
+
+	msg := message.New([][]byte{
+		[]byte(`{}`),
+		[]byte(`{"service":"nope"}`),
+		[]byte(`not json`),
+	})
+
+	res, err := client.Unary(context.Background(), 0, msg)
+	require.NoError(t, err)
+	assert.Equal(t, `{"status":"SERVING"}`, string(res))
+
+	_, err = client.Unary(context.Background(), 1, msg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown service")
+
+	_, err = client.Unary(context.Background(), 2, msg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to unmarshal JSON message")
+
+	_, err = client.ClientStream(context.Background(), msg)
+	require.EqualError(t, err, "method '/grpc.health.v1.Health/Check' is not client streaming")
+}
+
+func TestClientReflectionUnknownMethod(t *testing.T) {
+	conf := NewClientConfig()
+	conf.Address = startHealthServer(t)
+	conf.Method = "grpc.health.v1.Health/Nope"
+
+	client, err := NewClient(conf)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, client.Close())
+	})
+
+	_, err = client.Method(context.Background())
+	require.EqualError(t, err, "unable to find method 'Nope' within service 'grpc.health.v1.Health'")
+
+	_, err = client.Unary(context.Background(), 0, message.New([][]byte{[]byte(`{}`)}))
+	require.Error(t, err)
+}
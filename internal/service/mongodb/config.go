@@ -0,0 +1,340 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+// Config is a config struct for a MongoDB connection.
+type Config struct {
+	URL      string `json:"url" yaml:"url"`
+	Database string `json:"database" yaml:"database"`
+	Username string `json:"username" yaml:"username"`
+	Password string `json:"password" yaml:"password"`
+}
+
+// NewConfig returns a Config with default values.
+func NewConfig() Config {
+	return Config{
+		URL:      "mongodb://localhost:27017",
+		Database: "",
+		Username: "",
+		Password: "",
+	}
+}
+
+// ConfigDocs returns a documentation field spec for fields within a Config.
+func ConfigDocs() docs.FieldSpecs {
+	return docs.FieldSpecs{
+		docs.FieldCommon("url", "The URL of the target MongoDB deployment.", "mongodb://localhost:27017", "mongodb+srv://cluster0.example.net/?replicaSet=rs0"),
+		docs.FieldCommon("database", "The name of the target database."),
+		docs.FieldCommon("username", "An optional username to authenticate with."),
+		docs.FieldCommon("password", "An optional password to authenticate with."),
+	}
+}
+
+// Client returns a new MongoDB client based on the configuration parameters.
+// The client is not yet connected.
+func (c Config) Client() (*mongo.Client, error) {
+	if len(c.URL) == 0 {
+		return nil, errors.New("a url must be specified")
+	}
+	if len(c.Database) == 0 {
+		return nil, errors.New("a database must be specified")
+	}
+	opts := options.Client().ApplyURI(c.URL)
+	if len(c.Username) > 0 || len(c.Password) > 0 {
+		opts.SetAuth(options.Credential{
+			Username: c.Username,
+			Password: c.Password,
+		})
+	}
+	return mongo.NewClient(opts)
+}
+
+// Connect creates a client and connects it to the MongoDB deployment, returning
+// the target database.
+func (c Config) Connect(ctx context.Context) (*mongo.Client, *mongo.Database, error) {
+	client, err := c.Client()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = client.Connect(ctx); err != nil {
+		return nil, nil, err
+	}
+	if err = client.Ping(ctx, nil); err != nil {
+		_ = client.Disconnect(context.Background())
+		return nil, nil, err
+	}
+	return client, client.Database(c.Database), nil
+}
+
+//------------------------------------------------------------------------------
+
+// WriteConcern describes the level of acknowledgement requested from MongoDB
+// for write operations.
+type WriteConcern struct {
+	W        string `json:"w" yaml:"w"`
+	J        bool   `json:"j" yaml:"j"`
+	WTimeout string `json:"w_timeout" yaml:"w_timeout"`
+}
+
+// NewWriteConcern returns a WriteConcern with default values.
+func NewWriteConcern() WriteConcern {
+	return WriteConcern{
+		W:        "",
+		J:        false,
+		WTimeout: "",
+	}
+}
+
+// WriteConcernDocs returns a documentation field spec for a WriteConcern.
+func WriteConcernDocs() docs.FieldSpec {
+	return docs.FieldAdvanced("write_concern", "The [write concern](https://docs.mongodb.com/manual/reference/write-concern/) to request for write operations. When left empty the default of the deployment is used.").WithChildren(
+		docs.FieldCommon("w", "The number of instances, or `majority`, that must acknowledge a write.", "majority", "1"),
+		docs.FieldCommon("j", "Whether a write must be written to the on-disk journal before it is acknowledged."),
+		docs.FieldCommon("w_timeout", "An optional time limit for the write concern to be satisfied.", "10s"),
+	)
+}
+
+// Get returns the driver write concern described by the config, or nil if the
+// config is empty.
+func (w WriteConcern) Get() (*writeconcern.WriteConcern, error) {
+	if len(w.W) == 0 && !w.J && len(w.WTimeout) == 0 {
+		return nil, nil
+	}
+	var opts []writeconcern.Option
+	if w.W == "majority" {
+		opts = append(opts, writeconcern.WMajority())
+	} else if len(w.W) > 0 {
+		n, err := strconv.Atoi(w.W)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse write concern w '%v': expected a number or majority", w.W)
+		}
+		opts = append(opts, writeconcern.W(n))
+	}
+	if w.J {
+		opts = append(opts, writeconcern.J(true))
+	}
+	if len(w.WTimeout) > 0 {
+		d, err := time.ParseDuration(w.WTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse write concern w_timeout: %v", err)
+		}
+		opts = append(opts, writeconcern.WTimeout(d))
+	}
+	return writeconcern.New(opts...), nil
+}
+
+//------------------------------------------------------------------------------
+
+// Operation is a MongoDB operation to perform for each message.
+type Operation string
+
+// Operations supported by MongoDB components.
+const (
+	OperationInsertOne  Operation = "insert-one"
+	OperationDeleteOne  Operation = "delete-one"
+	OperationDeleteMany Operation = "delete-many"
+	OperationReplaceOne Operation = "replace-one"
+	OperationUpdateOne  Operation = "update-one"
+	OperationFindOne    Operation = "find-one"
+)
+
+// NewOperation parses an operation from a string, returning an error if it is
+// not amongst the allowed operations.
+func NewOperation(op string, allowed ...Operation) (Operation, error) {
+	for _, a := range allowed {
+		if Operation(op) == a {
+			return a, nil
+		}
+	}
+	return "", fmt.Errorf("operation %v was not recognised", op)
+}
+
+// NeedsDocument returns whether the operation requires a document mapping.
+func (o Operation) NeedsDocument() bool {
+	switch o {
+	case OperationInsertOne, OperationReplaceOne, OperationUpdateOne:
+		return true
+	}
+	return false
+}
+
+// NeedsFilter returns whether the operation requires a filter mapping.
+func (o Operation) NeedsFilter() bool {
+	return o != OperationInsertOne
+}
+
+// Mappings contains the parsed Bloblang mappings used to construct the
+// documents of an operation.
+type Mappings struct {
+	Document *mapping.Executor
+	Filter   *mapping.Executor
+	Hint     *mapping.Executor
+}
+
+// NewMappings parses the mappings of an operation and checks that those
+// required by the operation are present.
+func NewMappings(op Operation, documentMap, filterMap, hintMap string) (m Mappings, err error) {
+	if op.NeedsDocument() && len(documentMap) == 0 {
+		return m, fmt.Errorf("a document_map is required for the %v operation", op)
+	}
+	if op.NeedsFilter() && len(filterMap) == 0 {
+		return m, fmt.Errorf("a filter_map is required for the %v operation", op)
+	}
+	if len(documentMap) > 0 {
+		if m.Document, err = bloblang.NewMapping("", documentMap); err != nil {
+			return m, fmt.Errorf("failed to parse document_map: %v", err)
+		}
+	}
+	if len(filterMap) > 0 {
+		if m.Filter, err = bloblang.NewMapping("", filterMap); err != nil {
+			return m, fmt.Errorf("failed to parse filter_map: %v", err)
+		}
+	}
+	if len(hintMap) > 0 {
+		if m.Hint, err = bloblang.NewMapping("", hintMap); err != nil {
+			return m, fmt.Errorf("failed to parse hint_map: %v", err)
+		}
+	}
+	return m, nil
+}
+
+// MappingDocs returns documentation field specs for the mappings of an
+// operation.
+func MappingDocs() docs.FieldSpecs {
+	return docs.FieldSpecs{
+		docs.FieldCommon(
+			"document_map",
+			"A [Bloblang mapping](/docs/guides/bloblang/about) that results in the document to insert or replace with, or the update to apply, for the `insert-one`, `replace-one` and `update-one` operations.",
+			`root.a = this.foo
+root.b = this.bar`,
+			`root = { "$set": { "a": this.foo } }`,
+		),
+		docs.FieldCommon(
+			"filter_map",
+			"A [Bloblang mapping](/docs/guides/bloblang/about) that results in the filter used to select the documents of any operation other than `insert-one`.",
+			`root.id = this.id`,
+		),
+		docs.FieldAdvanced(
+			"hint_map",
+			"An optional [Bloblang mapping](/docs/guides/bloblang/about) that results in the index hint of an operation.",
+			`root.id = 1`,
+		),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// MapDocument executes a mapping against a message part and parses the result
+// as a MongoDB document. Values can be expressed using extended JSON, such as
+// `{"$oid": "..."}`.
+func MapDocument(exec *mapping.Executor, index int, msg types.Message) (bson.D, error) {
+	part, err := exec.MapPart(index, msg)
+	if err != nil {
+		return nil, err
+	}
+	if part == nil {
+		return nil, errors.New("mapping resulted in a deleted document")
+	}
+	var doc bson.D
+	if err = bson.UnmarshalExtJSON(part.Get(), false, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse mapping result as a document: %v", err)
+	}
+	return doc, nil
+}
+
+// MarshalModes lists the supported modes for marshalling documents into JSON.
+var MarshalModes = []string{"canonical", "relaxed"}
+
+// MarshalJSON marshals a document into extended JSON using the given mode.
+func MarshalJSON(doc interface{}, mode string) ([]byte, error) {
+	return bson.MarshalExtJSON(doc, mode == "canonical", false)
+}
+
+// WriteModel constructs the write model of an operation for a message part.
+func (m Mappings) WriteModel(op Operation, upsert bool, index int, msg types.Message) (mongo.WriteModel, error) {
+	var doc, filter, hint bson.D
+	var err error
+	if m.Document != nil && op.NeedsDocument() {
+		if doc, err = MapDocument(m.Document, index, msg); err != nil {
+			return nil, fmt.Errorf("document_map: %w", err)
+		}
+	}
+	if m.Filter != nil && op.NeedsFilter() {
+		if filter, err = MapDocument(m.Filter, index, msg); err != nil {
+			return nil, fmt.Errorf("filter_map: %w", err)
+		}
+	}
+	if m.Hint != nil {
+		if hint, err = MapDocument(m.Hint, index, msg); err != nil {
+			return nil, fmt.Errorf("hint_map: %w", err)
+		}
+	}
+
+	switch op {
+	case OperationInsertOne:
+		return mongo.NewInsertOneModel().SetDocument(doc), nil
+	case OperationDeleteOne:
+		model := mongo.NewDeleteOneModel().SetFilter(filter)
+		if hint != nil {
+			model.SetHint(hint)
+		}
+		return model, nil
+	case OperationDeleteMany:
+		model := mongo.NewDeleteManyModel().SetFilter(filter)
+		if hint != nil {
+			model.SetHint(hint)
+		}
+		return model, nil
+	case OperationReplaceOne:
+		model := mongo.NewReplaceOneModel().SetFilter(filter).SetReplacement(doc).SetUpsert(upsert)
+		if hint != nil {
+			model.SetHint(hint)
+		}
+		return model, nil
+	case OperationUpdateOne:
+		model := mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(doc).SetUpsert(upsert)
+		if hint != nil {
+			model.SetHint(hint)
+		}
+		return model, nil
+	}
+	return nil, fmt.Errorf("operation %v is not a write operation", op)
+}
+
+// BulkWriteErrors returns the error of each model of an unordered bulk write,
+// where a nil error means the model was written successfully. An error that
+// isn't specific to individual models is returned for all of them.
+func BulkWriteErrors(err error, models int) []error {
+	errs := make([]error, models)
+	if err == nil {
+		return errs
+	}
+	var bulkErr mongo.BulkWriteException
+	if !errors.As(err, &bulkErr) || bulkErr.WriteConcernError != nil || len(bulkErr.WriteErrors) == 0 {
+		for i := range errs {
+			errs[i] = err
+		}
+		return errs
+	}
+	for _, werr := range bulkErr.WriteErrors {
+		if werr.Index >= 0 && werr.Index < models {
+			errs[werr.Index] = werr.WriteError
+		}
+	}
+	return errs
+}
@@ -0,0 +1,132 @@
+package mongodb
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestConfigClient(t *testing.T) {
+	conf := NewConfig()
+	_, err := conf.Client()
+	require.EqualError(t, err, "a database must be specified")
+
+	conf.URL = ""
+	conf.Database = "foo"
+	_, err = conf.Client()
+	require.EqualError(t, err, "a url must be specified")
+
+	conf.URL = "mongodb://localhost:27017"
+	conf.Username = "foo"
+	conf.Password = "bar"
+	_, err = conf.Client()
+	require.NoError(t, err)
+}
+
+func TestWriteConcern(t *testing.T) {
+	wc, err := NewWriteConcern().Get()
+	require.NoError(t, err)
+	assert.Nil(t, wc)
+
+	wc, err = WriteConcern{W: "majority", J: true, WTimeout: "5s"}.Get()
+	require.NoError(t, err)
+	assert.Equal(t, "majority", wc.GetW())
+	assert.True(t, wc.GetJ())
+	assert.Equal(t, time.Second*5, wc.GetWTimeout())
+
+	wc, err = WriteConcern{W: "2"}.Get()
+	require.NoError(t, err)
+	assert.Equal(t, 2, wc.GetW())
+
+	_, err = WriteConcern{W: "nope"}.Get()
+	require.EqualError(t, err, "failed to parse write concern w 'nope': expected a number or majority")
+
+	_, err = WriteConcern{WTimeout: "nope"}.Get()
+	require.Error(t, err)
+}
+
+func TestNewMappings(t *testing.T) {
+	_, err := NewOperation("find-one", OperationInsertOne)
+	require.EqualError(t, err, "operation find-one was not recognised")
+
+	_, err = NewMappings(OperationInsertOne, "", "", "")
+	require.EqualError(t, err, "a document_map is required for the insert-one operation")
+
+	_, err = NewMappings(OperationDeleteOne, "", "", "")
+	require.EqualError(t, err, "a filter_map is required for the delete-one operation")
+
+	_, err = NewMappings(OperationUpdateOne, "root = this", "root.id = this.id", "root = nope(")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to parse hint_map")
+
+	m, err := NewMappings(OperationInsertOne, "root = this", "", "")
+	require.NoError(t, err)
+	assert.NotNil(t, m.Document)
+	assert.Nil(t, m.Filter)
+}
+
+func TestMapDocument(t *testing.T) {
+	m, err := NewMappings(OperationReplaceOne, `root = this.doc`, `root._id = {"$oid": this.id}`, `root.id = 1`)
+	require.NoError(t, err)
+
+	msg := message.New([][]byte{
+		[]byte(`{"id":"5f1b5a1e8f1b2c3d4e5f6a7b","doc":{"a":"foo","b":10}}`),
+		[]byte(`{"id":"5f1b5a1e8f1b2c3d4e5f6a7b","doc":"nope"}`),
+	})
+
+	filter, err := MapDocument(m.Filter, 0, msg)
+	require.NoError(t, err)
+	oid, err := primitive.ObjectIDFromHex("5f1b5a1e8f1b2c3d4e5f6a7b")
+	require.NoError(t, err)
+	assert.Equal(t, bson.D{{Key: "_id", Value: oid}}, filter)
+
+	model, err := m.WriteModel(OperationReplaceOne, true, 0, msg)
+	require.NoError(t, err)
+	replace, ok := model.(*mongo.ReplaceOneModel)
+	require.True(t, ok)
+	assert.Equal(t, bson.D{{Key: "_id", Value: oid}}, replace.Filter)
+	assert.Equal(t, bson.D{{Key: "a", Value: "foo"}, {Key: "b", Value: int32(10)}}, replace.Replacement)
+	assert.Equal(t, bson.D{{Key: "id", Value: int32(1)}}, replace.Hint)
+	require.NotNil(t, replace.Upsert)
+	assert.True(t, *replace.Upsert)
+
+	_, err = m.WriteModel(OperationReplaceOne, true, 1, msg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "document_map: failed to parse mapping result as a document")
+}
+
+func TestMarshalJSON(t *testing.T) {
+	doc := bson.D{{Key: "a", Value: int64(5)}}
+
+	b, err := MarshalJSON(doc, "relaxed")
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":5}`, string(b))
+
+	b, err = MarshalJSON(doc, "canonical")
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":{"$numberLong":"5"}}`, string(b))
+}
+
+func TestBulkWriteErrors(t *testing.T) {
+	assert.Equal(t, []error{nil, nil}, BulkWriteErrors(nil, 2))
+
+	errTest := errors.New("test err")
+	assert.Equal(t, []error{errTest, errTest}, BulkWriteErrors(errTest, 2))
+
+	errs := BulkWriteErrors(mongo.BulkWriteException{
+		WriteErrors: []mongo.BulkWriteError{
+			{WriteError: mongo.WriteError{Index: 1, Code: 11000, Message: "duplicate"}},
+		},
+	}, 3)
+	require.Len(t, errs, 3)
+	assert.NoError(t, errs[0])
+	assert.EqualError(t, errs[1], "duplicate")
+	assert.NoError(t, errs[2])
+}
@@ -0,0 +1,321 @@
+package xml
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultRootTag is the name of the root element used when encoding a
+// structure that cannot be represented by a single root element and a root
+// tag has not been specified.
+const DefaultRootTag = "doc"
+
+const (
+	attrPrefix = "-"
+	textKey    = "#text"
+	cdataKey   = "#cdata"
+)
+
+var (
+	textEscaper = strings.NewReplacer(
+		"&", "&amp;",
+		"<", "&lt;",
+		">", "&gt;",
+	)
+	attrEscaper = strings.NewReplacer(
+		"&", "&amp;",
+		"<", "&lt;",
+		">", "&gt;",
+		`"`, "&quot;",
+		"\n", "&#xA;",
+		"\r", "&#xD;",
+		"\t", "&#x9;",
+	)
+)
+
+// FromMap encodes a generic structure as an XML document, following the same
+// conventions as the output of ToMap. Keys prefixed with a hyphen are encoded as
+// attributes of the parent element, the key `#text` is encoded as the text
+// content of the parent element and the key `#cdata` as a CDATA section. Arrays
+// are encoded as repeated elements of the same name, and child elements are
+// written in alphabetical order of their keys.
+//
+// When rootTag is empty and the structure is an object with a single key that
+// key is used as the root element, otherwise the root element is DefaultRootTag.
+// When indent is non-empty the document is pretty printed using it.
+func FromMap(root interface{}, rootTag, indent string) ([]byte, error) {
+	e := &encoder{indent: indent}
+
+	if rootTag != "" {
+		if err := e.element(rootTag, root, 0); err != nil {
+			return nil, err
+		}
+		return []byte(e.b.String()), nil
+	}
+
+	obj, ok := root.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected object value, found %T", root)
+	}
+
+	if len(obj) == 1 {
+		for k, v := range obj {
+			if _, isArray := v.([]interface{}); !isArray && !isSpecialKey(k) {
+				if err := e.element(k, v, 0); err != nil {
+					return nil, err
+				}
+				return []byte(e.b.String()), nil
+			}
+		}
+	}
+
+	if err := e.element(DefaultRootTag, obj, 0); err != nil {
+		return nil, err
+	}
+	return []byte(e.b.String()), nil
+}
+
+//------------------------------------------------------------------------------
+
+type encoder struct {
+	indent string
+	b      strings.Builder
+}
+
+func isSpecialKey(k string) bool {
+	return k == textKey || k == cdataKey || (len(k) > len(attrPrefix) && strings.HasPrefix(k, attrPrefix))
+}
+
+// isNameStartChar returns whether r is a NameStartChar of the XML 1.0
+// specification: https://www.w3.org/TR/xml/#NT-NameStartChar
+func isNameStartChar(r rune) bool {
+	switch {
+	case r == ':' || r == '_',
+		r >= 'A' && r <= 'Z',
+		r >= 'a' && r <= 'z',
+		r >= 0xC0 && r <= 0xD6,
+		r >= 0xD8 && r <= 0xF6,
+		r >= 0xF8 && r <= 0x2FF,
+		r >= 0x370 && r <= 0x37D,
+		r >= 0x37F && r <= 0x1FFF,
+		r >= 0x200C && r <= 0x200D,
+		r >= 0x2070 && r <= 0x218F,
+		r >= 0x2C00 && r <= 0x2FEF,
+		r >= 0x3001 && r <= 0xD7FF,
+		r >= 0xF900 && r <= 0xFDCF,
+		r >= 0xFDF0 && r <= 0xFFFD,
+		r >= 0x10000 && r <= 0xEFFFF:
+		return true
+	}
+	return false
+}
+
+// isNameChar returns whether r is a NameChar of the XML 1.0 specification:
+// https://www.w3.org/TR/xml/#NT-NameChar
+func isNameChar(r rune) bool {
+	switch {
+	case isNameStartChar(r),
+		r == '-' || r == '.' || r == 0xB7,
+		r >= '0' && r <= '9',
+		r >= 0x300 && r <= 0x36F,
+		r >= 0x203F && r <= 0x2040:
+		return true
+	}
+	return false
+}
+
+// isChar returns whether r is a character permitted within an XML 1.0
+// document: https://www.w3.org/TR/xml/#NT-Char
+func isChar(r rune) bool {
+	switch {
+	case r == 0x9 || r == 0xA || r == 0xD,
+		r >= 0x20 && r <= 0xD7FF,
+		r >= 0xE000 && r <= 0xFFFD,
+		r >= 0x10000 && r <= 0x10FFFF:
+		return true
+	}
+	return false
+}
+
+func validateName(name string) error {
+	if name == "" {
+		return errors.New("element and attribute names must not be empty")
+	}
+	if !utf8.ValidString(name) {
+		return fmt.Errorf("invalid element or attribute name: %q", name)
+	}
+	for i, r := range name {
+		if (i == 0 && !isNameStartChar(r)) || !isNameChar(r) {
+			return fmt.Errorf("invalid element or attribute name: %q", name)
+		}
+	}
+	return nil
+}
+
+// textString returns the string form of a scalar value to be written as text,
+// an attribute value or a CDATA section, rejecting characters that cannot be
+// represented within an XML document, even when escaped.
+func textString(v interface{}) (string, error) {
+	str, err := scalarString(v)
+	if err != nil {
+		return "", err
+	}
+	if !utf8.ValidString(str) {
+		return "", errors.New("invalid UTF-8 encoding")
+	}
+	for i, r := range str {
+		if !isChar(r) {
+			return "", fmt.Errorf("invalid XML character %U at byte %v", r, i)
+		}
+	}
+	return str, nil
+}
+
+func scalarString(v interface{}) (string, error) {
+	switch t := v.(type) {
+	case string:
+		return t, nil
+	case []byte:
+		return string(t), nil
+	case bool:
+		return strconv.FormatBool(t), nil
+	case json.Number:
+		return t.String(), nil
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64), nil
+	case float32:
+		return strconv.FormatFloat(float64(t), 'f', -1, 32), nil
+	case int:
+		return strconv.Itoa(t), nil
+	case int32:
+		return strconv.FormatInt(int64(t), 10), nil
+	case int64:
+		return strconv.FormatInt(t, 10), nil
+	case uint64:
+		return strconv.FormatUint(t, 10), nil
+	}
+	return "", fmt.Errorf("unsupported value type: %T", v)
+}
+
+func (e *encoder) newline(depth int) {
+	if e.indent == "" {
+		return
+	}
+	e.b.WriteByte('\n')
+	for i := 0; i < depth; i++ {
+		e.b.WriteString(e.indent)
+	}
+}
+
+func (e *encoder) cdata(s string) {
+	e.b.WriteString("<![CDATA[")
+	e.b.WriteString(strings.ReplaceAll(s, "]]>", "]]]]><![CDATA[>"))
+	e.b.WriteString("]]>")
+}
+
+func (e *encoder) element(name string, value interface{}, depth int) error {
+	if err := validateName(name); err != nil {
+		return err
+	}
+
+	if arr, ok := value.([]interface{}); ok {
+		for i, v := range arr {
+			if i > 0 {
+				e.newline(depth)
+			}
+			if _, nested := v.([]interface{}); nested {
+				return fmt.Errorf("element '%v': nested arrays cannot be encoded", name)
+			}
+			if err := e.element(name, v, depth); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	e.b.WriteString("<" + name)
+
+	obj, isObj := value.(map[string]interface{})
+	if !isObj {
+		if value == nil {
+			e.b.WriteString("/>")
+			return nil
+		}
+		str, err := textString(value)
+		if err != nil {
+			return fmt.Errorf("element '%v': %w", name, err)
+		}
+		e.b.WriteString(">")
+		textEscaper.WriteString(&e.b, str)
+		e.b.WriteString("</" + name + ">")
+		return nil
+	}
+
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var children []string
+	for _, k := range keys {
+		if k == textKey || k == cdataKey {
+			continue
+		}
+		if !strings.HasPrefix(k, attrPrefix) || len(k) == len(attrPrefix) {
+			children = append(children, k)
+			continue
+		}
+		attrName := k[len(attrPrefix):]
+		if err := validateName(attrName); err != nil {
+			return err
+		}
+		str, err := textString(obj[k])
+		if err != nil {
+			return fmt.Errorf("element '%v' attribute '%v': %w", name, attrName, err)
+		}
+		e.b.WriteString(" " + attrName + `="`)
+		attrEscaper.WriteString(&e.b, str)
+		e.b.WriteString(`"`)
+	}
+
+	text, hasText := obj[textKey]
+	cdata, hasCDATA := obj[cdataKey]
+	if !hasText && !hasCDATA && len(children) == 0 {
+		e.b.WriteString("/>")
+		return nil
+	}
+	e.b.WriteString(">")
+
+	if hasText && text != nil {
+		str, err := textString(text)
+		if err != nil {
+			return fmt.Errorf("element '%v' text: %w", name, err)
+		}
+		textEscaper.WriteString(&e.b, str)
+	}
+	if hasCDATA && cdata != nil {
+		str, err := textString(cdata)
+		if err != nil {
+			return fmt.Errorf("element '%v' cdata: %w", name, err)
+		}
+		e.cdata(str)
+	}
+
+	for _, k := range children {
+		e.newline(depth + 1)
+		if err := e.element(k, obj[k], depth+1); err != nil {
+			return err
+		}
+	}
+	if len(children) > 0 {
+		e.newline(depth)
+	}
+	e.b.WriteString("</" + name + ">")
+	return nil
+}
@@ -0,0 +1,190 @@
+package xml
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		rootTag string
+		indent  string
+		output  string
+	}{
+		{
+			name:   "single root key",
+			input:  `{"root":{"title":"foo","count":5,"ok":true}}`,
+			output: `<root><count>5</count><ok>true</ok><title>foo</title></root>`,
+		},
+		{
+			name:   "multiple root keys",
+			input:  `{"a":"foo","b":"bar"}`,
+			output: `<doc><a>foo</a><b>bar</b></doc>`,
+		},
+		{
+			name:   "root array",
+			input:  `{"item":["foo","bar"]}`,
+			output: `<doc><item>foo</item><item>bar</item></doc>`,
+		},
+		{
+			name:   "namespaced and unicode names",
+			input:  `{"ns:root":{"-xml:lang":"en","_a.b-c":"foo","über":"bar"}}`,
+			output: `<ns:root xml:lang="en"><_a.b-c>foo</_a.b-c><über>bar</über></ns:root>`,
+		},
+		{
+			name:   "attributes and text",
+			input:  `{"root":{"-id":"1","next":{"#text":"foo","-withinRoot":"yes"}}}`,
+			output: `<root id="1"><next withinRoot="yes">foo</next></root>`,
+		},
+		{
+			name:   "cdata",
+			input:  `{"root":{"body":{"#cdata":"<p>foo]]>bar</p>"}}}`,
+			output: `<root><body><![CDATA[<p>foo]]]]><![CDATA[>bar</p>]]></body></root>`,
+		},
+		{
+			name:   "escapes",
+			input:  `{"root":{"-attr":"a \"b\" & <c>","text":"foo & <bar>"}}`,
+			output: `<root attr="a &quot;b&quot; &amp; &lt;c&gt;"><text>foo &amp; &lt;bar&gt;</text></root>`,
+		},
+		{
+			name:   "empty elements",
+			input:  `{"root":{"a":null,"b":{"-id":"1"},"c":{}}}`,
+			output: `<root><a/><b id="1"/><c/></root>`,
+		},
+		{
+			name:    "explicit root tag",
+			input:   `{"a":"foo","b":"bar"}`,
+			rootTag: "things",
+			output:  `<things><a>foo</a><b>bar</b></things>`,
+		},
+		{
+			name:    "namespaces and indent",
+			input:   `{"-xmlns:soap":"http://schemas.xmlsoap.org/soap/envelope/","soap:Body":{"GetBalance":{"AccountId":"12345","Currency":["GBP","USD"]}}}`,
+			rootTag: "soap:Envelope",
+			indent:  "  ",
+			output: `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+  <soap:Body>
+    <GetBalance>
+      <AccountId>12345</AccountId>
+      <Currency>GBP</Currency>
+      <Currency>USD</Currency>
+    </GetBalance>
+  </soap:Body>
+</soap:Envelope>`,
+		},
+		{
+			name:    "scalar with root tag",
+			input:   `10.5`,
+			rootTag: "value",
+			output:  `<value>10.5</value>`,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			var root interface{}
+			require.NoError(t, json.Unmarshal([]byte(test.input), &root))
+
+			res, err := FromMap(root, test.rootTag, test.indent)
+			require.NoError(t, err)
+			assert.Equal(t, test.output, string(res))
+		})
+	}
+}
+
+func TestFromMapErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		errStr string
+	}{
+		{
+			name:   "non object root",
+			input:  `"foo"`,
+			errStr: "expected object value, found string",
+		},
+		{
+			name:   "object attribute",
+			input:  `{"root":{"-id":{"foo":"bar"}}}`,
+			errStr: "element 'root' attribute 'id': unsupported value type: map[string]interface {}",
+		},
+		{
+			name:   "nested arrays",
+			input:  `{"root":{"a":[["foo"]]}}`,
+			errStr: "element 'a': nested arrays cannot be encoded",
+		},
+		{
+			name:   "invalid name",
+			input:  `{"root":{"foo bar":"baz"}}`,
+			errStr: `invalid element or attribute name: "foo bar"`,
+		},
+		{
+			name:   "name starting with a digit",
+			input:  `{"root":{"1abc":"baz"}}`,
+			errStr: `invalid element or attribute name: "1abc"`,
+		},
+		{
+			name:   "processing instruction name",
+			input:  `{"root":{"?xml":"baz"}}`,
+			errStr: `invalid element or attribute name: "?xml"`,
+		},
+		{
+			name:   "comment name",
+			input:  `{"root":{"!--":"baz"}}`,
+			errStr: `invalid element or attribute name: "!--"`,
+		},
+		{
+			name:   "hyphen name",
+			input:  `{"root":{"-":"baz"}}`,
+			errStr: `invalid element or attribute name: "-"`,
+		},
+		{
+			name:   "invalid attribute name",
+			input:  `{"root":{"--id":"baz"}}`,
+			errStr: `invalid element or attribute name: "-id"`,
+		},
+		{
+			name:   "invalid text character",
+			input:  `{"root":{"a":"foo\u0001bar"}}`,
+			errStr: "element 'a': invalid XML character U+0001 at byte 3",
+		},
+		{
+			name:   "invalid attribute character",
+			input:  `{"root":{"-id":"\u0000"}}`,
+			errStr: "element 'root' attribute 'id': invalid XML character U+0000 at byte 0",
+		},
+		{
+			name:   "invalid cdata character",
+			input:  `{"root":{"#cdata":"foo\u001b"}}`,
+			errStr: "element 'root' cdata: invalid XML character U+001B at byte 3",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			var root interface{}
+			require.NoError(t, json.Unmarshal([]byte(test.input), &root))
+
+			_, err := FromMap(root, "", "")
+			require.EqualError(t, err, test.errStr)
+		})
+	}
+}
+
+func TestFromMapRoundTrip(t *testing.T) {
+	input := `<root isRooted="true"><inner><thing someAttr="is boring">10</thing></inner><next>foo1</next><next>foo2</next></root>`
+
+	m, err := ToMap([]byte(input))
+	require.NoError(t, err)
+
+	res, err := FromMap(m, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, input, string(res))
+}
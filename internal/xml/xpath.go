@@ -0,0 +1,62 @@
+package xml
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+
+	"github.com/antchfx/xmlquery"
+	"github.com/antchfx/xpath"
+)
+
+// XPath is a compiled XPath expression that can be evaluated against raw XML
+// documents without converting them into a generic structure first. It is safe
+// to evaluate concurrently.
+type XPath struct {
+	// Compiled expressions hold query state whilst being evaluated, and
+	// therefore each evaluation takes its own from the pool.
+	exprs sync.Pool
+}
+
+// CompileXPath parses an XPath expression.
+func CompileXPath(expr string) (*XPath, error) {
+	e, err := xpath.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	x := &XPath{}
+	x.exprs.New = func() interface{} {
+		// The expression is already known to be valid.
+		e, _ := xpath.Compile(expr)
+		return e
+	}
+	x.exprs.Put(e)
+	return x, nil
+}
+
+// Query parses a byte slice as XML and evaluates the XPath expression against
+// it. Expressions that select nodes return a slice of the text content of each
+// selected node, whereas expressions that evaluate to a string, number or
+// boolean return that value directly.
+func (x *XPath) Query(xmlBytes []byte) (interface{}, error) {
+	doc, err := xmlquery.Parse(bytes.NewReader(xmlBytes))
+	if err != nil {
+		return nil, err
+	}
+
+	expr := x.exprs.Get().(*xpath.Expr)
+	defer x.exprs.Put(expr)
+
+	switch t := expr.Evaluate(xmlquery.CreateXPathNavigator(doc)).(type) {
+	case *xpath.NodeIterator:
+		values := []interface{}{}
+		for t.MoveNext() {
+			values = append(values, t.Current().Value())
+		}
+		return values, nil
+	case string, float64, bool:
+		return t, nil
+	default:
+		return nil, fmt.Errorf("unexpected xpath result type: %T", t)
+	}
+}
@@ -0,0 +1,79 @@
+package xml
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestXPathQuery(t *testing.T) {
+	doc := []byte(`<?xml version="1.0" encoding="ISO-8859-1"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+  <soap:Body>
+    <Accounts>
+      <Account id="a"><Balance>10.5</Balance></Account>
+      <Account id="b"><Balance>20</Balance></Account>
+    </Accounts>
+  </soap:Body>
+</soap:Envelope>`)
+
+	tests := []struct {
+		expr   string
+		output interface{}
+	}{
+		{expr: "//Account/@id", output: []interface{}{"a", "b"}},
+		{expr: "//soap:Body//Balance", output: []interface{}{"10.5", "20"}},
+		{expr: "//Account[@id='c']", output: []interface{}{}},
+		{expr: "string(//Account[@id='b']/Balance)", output: "20"},
+		{expr: "sum(//Balance)", output: 30.5},
+		{expr: "count(//Account) > 1", output: true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.expr, func(t *testing.T) {
+			x, err := CompileXPath(test.expr)
+			require.NoError(t, err)
+
+			res, err := x.Query(doc)
+			require.NoError(t, err)
+			assert.Equal(t, test.output, res)
+		})
+	}
+}
+
+func TestXPathErrors(t *testing.T) {
+	_, err := CompileXPath("//foo[")
+	require.Error(t, err)
+
+	x, err := CompileXPath("//foo")
+	require.NoError(t, err)
+
+	_, err = x.Query([]byte(`<foo>bar</baz>`))
+	require.Error(t, err)
+}
+
+func TestXPathConcurrent(t *testing.T) {
+	x, err := CompileXPath(`//item[@id="b"]/@id | //item[1]`)
+	require.NoError(t, err)
+
+	doc := []byte(`<root><item id="a">foo</item><item id="b">bar</item></root>`)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				res, err := x.Query(doc)
+				if !assert.NoError(t, err) {
+					return
+				}
+				assert.Equal(t, []interface{}{"b", "foo"}, res)
+			}
+		}()
+	}
+	wg.Wait()
+}
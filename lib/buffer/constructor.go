@@ -38,6 +38,7 @@ var Constructors = map[string]TypeSpec{}
 const (
 	TypeMemory = "memory"
 	TypeNone   = "none"
+	TypeWAL    = "wal"
 )
 
 //------------------------------------------------------------------------------
@@ -47,6 +48,7 @@ type Config struct {
 	Type   string       `json:"type" yaml:"type"`
 	Memory MemoryConfig `json:"memory" yaml:"memory"`
 	None   struct{}     `json:"none" yaml:"none"`
+	WAL    WALConfig    `json:"wal" yaml:"wal"`
 }
 
 // NewConfig returns a configuration struct fully populated with default values.
@@ -55,6 +57,7 @@ func NewConfig() Config {
 		Type:   "none",
 		Memory: NewMemoryConfig(),
 		None:   struct{}{},
+		WAL:    NewWALConfig(),
 	}
 }
 
@@ -156,15 +159,23 @@ different options and their qualities:
 | Type      | Throughput | Consumers | Capacity |
 | --------- | ---------- | --------- | -------- |
 | Memory    | Highest    | Parallel  | RAM      |
+| WAL       | High       | Parallel  | RAM      |
 
 #### Delivery Guarantees
 
-| Event     | Shutdown  | Crash     | Disk Corruption |
-| --------- | --------- | --------- | --------------- |
-| Memory    | Flushed\* | Lost      | Lost            |
+| Event     | Shutdown  | Crash         | Disk Corruption |
+| --------- | --------- | ------------- | --------------- |
+| Memory    | Flushed\* | Lost          | Lost            |
+| WAL       | Persisted | Persisted\*\* | Partial\*\*\*    |
 
 \* Makes a best attempt at flushing the remaining messages before closing
-  gracefully.`
+  gracefully.
+
+\*\* Depending on the sync policy, batches are replayed after a restart and may
+  be delivered more than once.
+
+\*\*\* Only batches within corrupted records are lost, the remaining log is
+  replayed. Batches with corrupted acknowledgement records are delivered again.`
 
 // Descriptions returns a formatted string of collated descriptions of each type.
 func Descriptions() string {
@@ -0,0 +1,713 @@
+package parallel
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// Sync policies supported by the WAL buffer.
+const (
+	WALSyncAlways   = "always"
+	WALSyncInterval = "interval"
+	WALSyncNone     = "none"
+)
+
+// WALConfig is config options for a write-ahead-log based parallel buffer.
+type WALConfig struct {
+	Directory    string `json:"directory" yaml:"directory"`
+	SegmentSize  int    `json:"segment_size" yaml:"segment_size"`
+	Limit        int    `json:"limit" yaml:"limit"`
+	SyncPolicy   string `json:"sync_policy" yaml:"sync_policy"`
+	SyncInterval string `json:"sync_interval" yaml:"sync_interval"`
+}
+
+// NewWALConfig creates a WALConfig with default values.
+func NewWALConfig() WALConfig {
+	return WALConfig{
+		Directory:    "",
+		SegmentSize:  64 * 1024 * 1024,  // 64MiB
+		Limit:        1024 * 1024 * 500, // 500MB
+		SyncPolicy:   WALSyncAlways,
+		SyncInterval: "1s",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+/*
+Each segment file is a sequence of records with the following layout:
+
+| length (u32) | crc32c of body (u32) | body (length bytes) |
+
+Where the body begins with a record type byte followed by a sequence ID (u64),
+and for batch records the serialised message follows. Acknowledgement records
+have no further content. All integers are big endian.
+
+Segments are named after their index and are only ever deleted from the front
+of the log, which guarantees that the acknowledgement records for any batch
+that still exists on disk are also still on disk.
+*/
+
+const (
+	walRecordBatch byte = 1
+	walRecordAck   byte = 2
+
+	walHeaderLen = 8
+	walSuffix    = ".wal"
+)
+
+var (
+	walCRCTable = crc32.MakeTable(crc32.Castagnoli)
+
+	errWALCorrupt = errors.New("record failed checksum")
+)
+
+type walEntry struct {
+	seq     uint64
+	segment uint64
+	size    int
+	msg     types.Message
+}
+
+// WAL is a parallel buffer implementation that appends batches to segmented,
+// checksummed log files on disk before acknowledging them. Batches that have
+// not been acknowledged downstream are replayed from disk when the buffer is
+// restarted, and segments are removed once every batch within them has been
+// acknowledged.
+type WAL struct {
+	conf WALConfig
+	log  log.Modular
+
+	mCorrupt   metrics.StatCounter
+	mReplayed  metrics.StatCounter
+	mSyncErr   metrics.StatCounter
+	mSegDelete metrics.StatCounter
+
+	pending      []*walEntry
+	bytes        int
+	pendingBytes int
+
+	nextSeq      uint64
+	activeIndex  uint64
+	activeFile   *os.File
+	activeWriter *bufio.Writer
+	activeSize   int
+	dirty        bool
+
+	// Tracks the number of unacknowledged batches within each segment.
+	segments map[uint64]int
+
+	cond *sync.Cond
+
+	closed     bool
+	closedChan chan struct{}
+}
+
+// NewWAL creates a write-ahead-log based parallel buffer, replaying any
+// unacknowledged batches found within the configured directory.
+func NewWAL(conf WALConfig, log log.Modular, stats metrics.Type) (*WAL, error) {
+	if len(conf.Directory) == 0 {
+		return nil, errors.New("a directory must be specified")
+	}
+	if conf.SegmentSize <= 0 {
+		return nil, errors.New("segment_size must be greater than zero")
+	}
+
+	var syncInterval time.Duration
+	switch conf.SyncPolicy {
+	case WALSyncAlways, WALSyncNone:
+	case WALSyncInterval:
+		var err error
+		if syncInterval, err = time.ParseDuration(conf.SyncInterval); err != nil {
+			return nil, fmt.Errorf("failed to parse sync interval: %v", err)
+		}
+		if syncInterval <= 0 {
+			return nil, errors.New("sync_interval must be greater than zero")
+		}
+	default:
+		return nil, fmt.Errorf("sync policy not recognised: %v", conf.SyncPolicy)
+	}
+
+	if err := os.MkdirAll(conf.Directory, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %v", err)
+	}
+
+	w := &WAL{
+		conf:       conf,
+		log:        log,
+		mCorrupt:   stats.GetCounter("wal.corrupted"),
+		mReplayed:  stats.GetCounter("wal.replayed"),
+		mSyncErr:   stats.GetCounter("wal.sync.error"),
+		mSegDelete: stats.GetCounter("wal.segment.deleted"),
+		nextSeq:    1,
+		segments:   map[uint64]int{},
+		cond:       sync.NewCond(&sync.Mutex{}),
+		closedChan: make(chan struct{}),
+	}
+
+	if err := w.replay(); err != nil {
+		return nil, err
+	}
+	if err := w.rotate(); err != nil {
+		return nil, err
+	}
+	w.deleteAckedSegments()
+
+	if syncInterval > 0 {
+		go w.syncLoop(syncInterval)
+	}
+	return w, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (w *WAL) segmentPath(index uint64) string {
+	return filepath.Join(w.conf.Directory, fmt.Sprintf("%020d%v", index, walSuffix))
+}
+
+func (w *WAL) listSegments() ([]uint64, error) {
+	files, err := ioutil.ReadDir(w.conf.Directory)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory: %v", err)
+	}
+	var indexes []uint64
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || !strings.HasSuffix(name, walSuffix) {
+			continue
+		}
+		index, err := strconv.ParseUint(strings.TrimSuffix(name, walSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		indexes = append(indexes, index)
+	}
+	sort.Slice(indexes, func(i, j int) bool {
+		return indexes[i] < indexes[j]
+	})
+	return indexes, nil
+}
+
+// replay reads all existing segments and reconstructs the list of batches that
+// are yet to be acknowledged.
+func (w *WAL) replay() error {
+	indexes, err := w.listSegments()
+	if err != nil {
+		return err
+	}
+
+	entries := map[uint64]*walEntry{}
+	for i, index := range indexes {
+		isLast := i == len(indexes)-1
+		w.segments[index] = 0
+		w.activeIndex = index
+		if err := w.replaySegment(index, isLast, entries); err != nil {
+			return err
+		}
+	}
+
+	for _, e := range entries {
+		w.pending = append(w.pending, e)
+		w.segments[e.segment]++
+		w.bytes += e.size
+	}
+	sort.Slice(w.pending, func(i, j int) bool {
+		return w.pending[i].seq < w.pending[j].seq
+	})
+	if len(w.pending) > 0 {
+		w.log.Infof("Replaying %v unacknowledged batches from write-ahead log\n", len(w.pending))
+		w.mReplayed.Incr(int64(len(w.pending)))
+	}
+	return nil
+}
+
+func (w *WAL) replaySegment(index uint64, isLast bool, entries map[uint64]*walEntry) error {
+	path := w.segmentPath(index)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read segment '%v': %v", path, err)
+	}
+
+	for offset := 0; offset < len(data); {
+		rType, seq, body, n, err := parseWALRecord(data[offset:])
+		if err != nil {
+			w.mCorrupt.Incr(1)
+			if next := nextWALRecord(data, offset+1); next > 0 {
+				// Only the corrupted record is skipped, the records that
+				// follow it are still valid.
+				w.log.Errorf("Segment '%v' is corrupted between offsets %v and %v, skipping: %v\n", path, offset, next, err)
+				offset = next
+				continue
+			}
+			if !isLast {
+				w.log.Errorf("Segment '%v' is corrupted from offset %v, skipping remaining data: %v\n", path, offset, err)
+				return nil
+			}
+			// A broken record at the tail of the final segment is the result
+			// of a partial write, and is truncated so that it isn't reported
+			// again on the next restart.
+			w.log.Warnf("Truncating partially written record from segment '%v' at offset %v: %v\n", path, offset, err)
+			if err = os.Truncate(path, int64(offset)); err != nil {
+				return fmt.Errorf("failed to truncate segment '%v': %v", path, err)
+			}
+			return nil
+		}
+		offset += n
+
+		if seq >= w.nextSeq {
+			w.nextSeq = seq + 1
+		}
+		switch rType {
+		case walRecordBatch:
+			msg, err := walDecodeMessage(body)
+			if err != nil {
+				w.mCorrupt.Incr(1)
+				w.log.Errorf("Failed to decode batch %v from segment '%v': %v\n", seq, path, err)
+				continue
+			}
+			entries[seq] = &walEntry{
+				seq:     seq,
+				segment: index,
+				size:    walMessageSize(msg),
+				msg:     msg,
+			}
+		case walRecordAck:
+			delete(entries, seq)
+		}
+	}
+	return nil
+}
+
+// rotate syncs and closes the active segment and opens a new one.
+func (w *WAL) rotate() error {
+	if w.activeFile != nil {
+		if err := w.flushAndSync(); err != nil {
+			return err
+		}
+		w.activeFile.Close()
+		w.activeFile = nil
+		w.activeIndex++
+	} else if _, exists := w.segments[w.activeIndex]; exists {
+		// Never append to a segment that existed before we started, it may
+		// already be closed off.
+		w.activeIndex++
+	}
+
+	f, err := os.OpenFile(w.segmentPath(w.activeIndex), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create segment: %v", err)
+	}
+	w.activeFile = f
+	w.activeWriter = bufio.NewWriter(f)
+	w.activeSize = 0
+	w.segments[w.activeIndex] = 0
+	return nil
+}
+
+func (w *WAL) flushAndSync() error {
+	if w.activeFile == nil {
+		return nil
+	}
+	if err := w.activeWriter.Flush(); err != nil {
+		w.abandonSegment()
+		return err
+	}
+	if !w.dirty {
+		return nil
+	}
+	if w.conf.SyncPolicy != WALSyncNone {
+		if err := w.activeFile.Sync(); err != nil {
+			w.abandonSegment()
+			return err
+		}
+	}
+	w.dirty = false
+	return nil
+}
+
+// abandonSegment closes the active segment after a failed write, flush or
+// sync. Errors of the buffered writer are permanent and so the next record is
+// appended to a new segment instead, and any partially written record at the
+// end of the abandoned segment is skipped during replay. Must be called with
+// the lock held.
+func (w *WAL) abandonSegment() {
+	w.activeFile.Close()
+	w.activeFile = nil
+	w.dirty = false
+}
+
+func (w *WAL) syncLoop(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+		case <-w.closedChan:
+			return
+		}
+		w.cond.L.Lock()
+		if !w.closed {
+			if err := w.flushAndSync(); err != nil {
+				w.mSyncErr.Incr(1)
+				w.log.Errorf("Failed to sync write-ahead log: %v\n", err)
+			}
+		}
+		w.cond.L.Unlock()
+	}
+}
+
+// appendRecord writes a record to the active segment, rotating it first if it
+// has reached its size limit. Must be called with the lock held.
+func (w *WAL) appendRecord(rType byte, seq uint64, content []byte) error {
+	// The active segment is missing when a previous rotation failed.
+	if w.activeFile == nil || w.activeSize >= w.conf.SegmentSize {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := writeWALRecord(w.activeWriter, rType, seq, content)
+	w.activeSize += n
+	w.dirty = true
+	if err != nil {
+		w.abandonSegment()
+	}
+	return err
+}
+
+// deleteAckedSegments removes segments from the front of the log for as long
+// as they contain no unacknowledged batches. Must be called with the lock held.
+func (w *WAL) deleteAckedSegments() {
+	var indexes []uint64
+	for index := range w.segments {
+		indexes = append(indexes, index)
+	}
+	sort.Slice(indexes, func(i, j int) bool {
+		return indexes[i] < indexes[j]
+	})
+	for _, index := range indexes {
+		if index == w.activeIndex || w.segments[index] > 0 {
+			return
+		}
+		path := w.segmentPath(index)
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			w.log.Errorf("Failed to remove acknowledged segment '%v': %v\n", path, err)
+			return
+		}
+		delete(w.segments, index)
+		w.mSegDelete.Incr(1)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NextMessage reads the next oldest message, the message is preserved until the
+// returned AckFunc is called.
+func (w *WAL) NextMessage() (types.Message, AckFunc, error) {
+	w.cond.L.Lock()
+	for len(w.pending) == 0 && !w.closed {
+		w.cond.Wait()
+	}
+
+	if w.closed {
+		w.cond.L.Unlock()
+		return nil, nil, types.ErrTypeClosed
+	}
+
+	e := w.pending[0]
+	w.pending[0] = nil
+	w.pending = w.pending[1:]
+	w.pendingBytes += e.size
+
+	w.cond.Broadcast()
+	w.cond.L.Unlock()
+
+	return e.msg.DeepCopy(), func(ack bool) (int, error) {
+		w.cond.L.Lock()
+		defer w.cond.L.Unlock()
+		if w.closed {
+			return 0, types.ErrTypeClosed
+		}
+		var err error
+		if ack {
+			if err = w.appendRecord(walRecordAck, e.seq, nil); err == nil {
+				w.bytes -= e.size
+				w.segments[e.segment]--
+				w.deleteAckedSegments()
+			}
+		}
+		if !ack || err != nil {
+			// Batches that could not be acknowledged on disk would be replayed
+			// after a restart, and are therefore delivered again.
+			w.pending = append([]*walEntry{e}, w.pending...)
+		}
+		w.pendingBytes -= e.size
+		w.cond.Broadcast()
+		if err != nil {
+			return 0, fmt.Errorf("failed to write acknowledgement: %v", err)
+		}
+		return w.bytes, nil
+	}, nil
+}
+
+// PushMessage writes a new message to the log and, depending on the sync
+// policy, waits for it to be flushed to disk. Returns the backlog in bytes.
+func (w *WAL) PushMessage(msg types.Message) (int, error) {
+	size := walMessageSize(msg)
+	if size > w.conf.Limit {
+		return 0, types.ErrMessageTooLarge
+	}
+
+	w.cond.L.Lock()
+	defer w.cond.L.Unlock()
+
+	if w.closed {
+		return 0, types.ErrTypeClosed
+	}
+	for (w.bytes + size) > w.conf.Limit {
+		w.cond.Wait()
+		if w.closed {
+			return 0, types.ErrTypeClosed
+		}
+	}
+
+	e := &walEntry{
+		seq:     w.nextSeq,
+		segment: w.activeIndex,
+		size:    size,
+		msg:     msg.DeepCopy(),
+	}
+	if err := w.appendRecord(walRecordBatch, e.seq, walEncodeMessage(msg)); err != nil {
+		return 0, fmt.Errorf("failed to write batch: %v", err)
+	}
+	// The record may reach the disk even if the sync fails, and so its
+	// sequence must never be reused.
+	w.nextSeq++
+
+	// The segment may have been rotated during the append.
+	e.segment = w.activeIndex
+	if w.conf.SyncPolicy == WALSyncAlways {
+		if err := w.flushAndSync(); err != nil {
+			w.mSyncErr.Incr(1)
+			return 0, fmt.Errorf("failed to sync batch: %v", err)
+		}
+	}
+
+	w.pending = append(w.pending, e)
+	w.segments[e.segment]++
+	w.bytes += size
+
+	w.cond.Broadcast()
+	return w.bytes, nil
+}
+
+// CloseOnceEmpty closes the Buffer once the buffer has been emptied, this is a
+// way for a writer to signal to a reader that it is finished writing messages,
+// and therefore the reader can close once it is caught up. This call blocks
+// until the close is completed.
+func (w *WAL) CloseOnceEmpty() {
+	w.cond.L.Lock()
+	for (w.bytes-w.pendingBytes > 0) && !w.closed {
+		w.cond.Wait()
+	}
+	w.cond.L.Unlock()
+	w.Close()
+}
+
+// Close syncs and closes the active segment so that blocked readers or writers
+// become unblocked. Unacknowledged batches remain on disk and are replayed the
+// next time the buffer is created.
+func (w *WAL) Close() {
+	w.cond.L.Lock()
+	defer w.cond.L.Unlock()
+	if w.closed {
+		return
+	}
+	w.closed = true
+	close(w.closedChan)
+	if err := w.flushAndSync(); err != nil {
+		w.mSyncErr.Incr(1)
+		w.log.Errorf("Failed to sync write-ahead log: %v\n", err)
+	}
+	if w.activeFile != nil {
+		w.activeFile.Close()
+	}
+	w.cond.Broadcast()
+}
+
+//------------------------------------------------------------------------------
+
+func writeWALRecord(w io.Writer, rType byte, seq uint64, content []byte) (int, error) {
+	body := make([]byte, 9+len(content))
+	body[0] = rType
+	binary.BigEndian.PutUint64(body[1:9], seq)
+	copy(body[9:], content)
+
+	var header [walHeaderLen]byte
+	binary.BigEndian.PutUint32(header[0:4], uint32(len(body)))
+	binary.BigEndian.PutUint32(header[4:8], crc32.Checksum(body, walCRCTable))
+
+	if _, err := w.Write(header[:]); err != nil {
+		return 0, err
+	}
+	if _, err := w.Write(body); err != nil {
+		return 0, err
+	}
+	return walHeaderLen + len(body), nil
+}
+
+// parseWALRecord parses the record at the start of b, returning errWALCorrupt
+// if it is incomplete, of an unknown type or fails its checksum.
+func parseWALRecord(b []byte) (rType byte, seq uint64, content []byte, n int, err error) {
+	if len(b) < walHeaderLen {
+		err = errWALCorrupt
+		return
+	}
+	length := uint64(binary.BigEndian.Uint32(b[0:4]))
+	if length < 9 || length > uint64(len(b)-walHeaderLen) {
+		err = errWALCorrupt
+		return
+	}
+	body := b[walHeaderLen : walHeaderLen+int(length)]
+	if body[0] != walRecordBatch && body[0] != walRecordAck {
+		err = errWALCorrupt
+		return
+	}
+	if crc32.Checksum(body, walCRCTable) != binary.BigEndian.Uint32(b[4:8]) {
+		err = errWALCorrupt
+		return
+	}
+	return body[0], binary.BigEndian.Uint64(body[1:9]), body[9:], walHeaderLen + int(length), nil
+}
+
+// nextWALRecord returns the first offset from start at which a valid record
+// begins, or -1 if there are none.
+func nextWALRecord(data []byte, start int) int {
+	for offset := start; offset+walHeaderLen < len(data); offset++ {
+		if _, _, _, _, err := parseWALRecord(data[offset:]); err == nil {
+			return offset
+		}
+	}
+	return -1
+}
+
+// walEncodeMessage serialises a message including the metadata of each part,
+// which message.ToBytes does not preserve.
+func walEncodeMessage(msg types.Message) []byte {
+	var buf []byte
+	putBytes := func(b []byte) {
+		var l [4]byte
+		binary.BigEndian.PutUint32(l[:], uint32(len(b)))
+		buf = append(buf, l[:]...)
+		buf = append(buf, b...)
+	}
+	putUint32 := func(v int) {
+		var l [4]byte
+		binary.BigEndian.PutUint32(l[:], uint32(v))
+		buf = append(buf, l[:]...)
+	}
+
+	putUint32(msg.Len())
+	msg.Iter(func(i int, p types.Part) error {
+		var keys []string
+		p.Metadata().Iter(func(k, v string) error {
+			keys = append(keys, k)
+			return nil
+		})
+		sort.Strings(keys)
+		putUint32(len(keys))
+		for _, k := range keys {
+			putBytes([]byte(k))
+			putBytes([]byte(p.Metadata().Get(k)))
+		}
+		putBytes(p.Get())
+		return nil
+	})
+	return buf
+}
+
+func walDecodeMessage(b []byte) (types.Message, error) {
+	getUint32 := func() (int, error) {
+		if len(b) < 4 {
+			return 0, types.ErrBlockCorrupted
+		}
+		v := binary.BigEndian.Uint32(b[:4])
+		b = b[4:]
+		return int(v), nil
+	}
+	getBytes := func() ([]byte, error) {
+		l, err := getUint32()
+		if err != nil {
+			return nil, err
+		}
+		if len(b) < l {
+			return nil, types.ErrBlockCorrupted
+		}
+		v := b[:l]
+		b = b[l:]
+		return v, nil
+	}
+
+	nParts, err := getUint32()
+	if err != nil {
+		return nil, err
+	}
+	msg := message.New(nil)
+	for i := 0; i < nParts; i++ {
+		nMeta, err := getUint32()
+		if err != nil {
+			return nil, err
+		}
+		meta := make(map[string]string, nMeta)
+		for j := 0; j < nMeta; j++ {
+			k, err := getBytes()
+			if err != nil {
+				return nil, err
+			}
+			v, err := getBytes()
+			if err != nil {
+				return nil, err
+			}
+			meta[string(k)] = string(v)
+		}
+		content, err := getBytes()
+		if err != nil {
+			return nil, err
+		}
+		part := message.NewPart(content)
+		for k, v := range meta {
+			part.Metadata().Set(k, v)
+		}
+		msg.Append(part)
+	}
+	return msg, nil
+}
+
+func walMessageSize(msg types.Message) int {
+	size := 0
+	msg.Iter(func(i int, p types.Part) error {
+		size += len(p.Get())
+		return nil
+	})
+	return size
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,408 @@
+package parallel
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestWAL(t *testing.T, dir string, segmentSize int) *WAL {
+	t.Helper()
+
+	conf := NewWALConfig()
+	conf.Directory = dir
+	if segmentSize > 0 {
+		conf.SegmentSize = segmentSize
+	}
+
+	w, err := NewWAL(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	return w
+}
+
+func walSegments(t *testing.T, dir string) []string {
+	t.Helper()
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.wal"))
+	require.NoError(t, err)
+	return files
+}
+
+func TestWALBasic(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_wal_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	w := newTestWAL(t, dir, 0)
+	defer w.Close()
+
+	n := 100
+	for i := 0; i < n; i++ {
+		msg := message.New([][]byte{
+			[]byte("hello"),
+			[]byte(fmt.Sprintf("test%v", i)),
+		})
+		msg.Get(1).Metadata().Set("index", fmt.Sprintf("%v", i))
+		_, err := w.PushMessage(msg)
+		require.NoError(t, err)
+	}
+
+	for i := 0; i < n; i++ {
+		m, ackFunc, err := w.NextMessage()
+		require.NoError(t, err)
+		require.Equal(t, 2, m.Len())
+		assert.Equal(t, fmt.Sprintf("test%v", i), string(m.Get(1).Get()))
+		assert.Equal(t, fmt.Sprintf("%v", i), m.Get(1).Metadata().Get("index"))
+		_, err = ackFunc(true)
+		require.NoError(t, err)
+	}
+}
+
+func TestWALNack(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_wal_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	w := newTestWAL(t, dir, 0)
+	defer w.Close()
+
+	_, err = w.PushMessage(message.New([][]byte{[]byte("first")}))
+	require.NoError(t, err)
+	_, err = w.PushMessage(message.New([][]byte{[]byte("second")}))
+	require.NoError(t, err)
+
+	m, ackFunc, err := w.NextMessage()
+	require.NoError(t, err)
+	assert.Equal(t, "first", string(m.Get(0).Get()))
+	_, err = ackFunc(false)
+	require.NoError(t, err)
+
+	m, ackFunc, err = w.NextMessage()
+	require.NoError(t, err)
+	assert.Equal(t, "first", string(m.Get(0).Get()))
+	_, err = ackFunc(true)
+	require.NoError(t, err)
+
+	m, ackFunc, err = w.NextMessage()
+	require.NoError(t, err)
+	assert.Equal(t, "second", string(m.Get(0).Get()))
+	_, err = ackFunc(true)
+	require.NoError(t, err)
+}
+
+func TestWALReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_wal_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	w := newTestWAL(t, dir, 0)
+	for i := 0; i < 5; i++ {
+		msg := message.New([][]byte{[]byte(fmt.Sprintf("test%v", i))})
+		msg.Get(0).Metadata().Set("foo", "bar")
+		_, err = w.PushMessage(msg)
+		require.NoError(t, err)
+	}
+
+	// Ack the second message only, and leave the first in flight.
+	_, _, err = w.NextMessage()
+	require.NoError(t, err)
+	_, ackFunc, err := w.NextMessage()
+	require.NoError(t, err)
+	_, err = ackFunc(true)
+	require.NoError(t, err)
+
+	w.Close()
+
+	w = newTestWAL(t, dir, 0)
+	defer w.Close()
+
+	for _, exp := range []string{"test0", "test2", "test3", "test4"} {
+		m, ackFunc, err := w.NextMessage()
+		require.NoError(t, err)
+		assert.Equal(t, exp, string(m.Get(0).Get()))
+		assert.Equal(t, "bar", m.Get(0).Metadata().Get("foo"))
+		_, err = ackFunc(true)
+		require.NoError(t, err)
+	}
+
+	_, err = w.PushMessage(message.New([][]byte{[]byte("test5")}))
+	require.NoError(t, err)
+
+	m, ackFunc, err := w.NextMessage()
+	require.NoError(t, err)
+	assert.Equal(t, "test5", string(m.Get(0).Get()))
+	_, err = ackFunc(true)
+	require.NoError(t, err)
+}
+
+func TestWALTruncatesPartialWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_wal_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	w := newTestWAL(t, dir, 0)
+	for i := 0; i < 2; i++ {
+		_, err = w.PushMessage(message.New([][]byte{[]byte(fmt.Sprintf("test%v", i))}))
+		require.NoError(t, err)
+	}
+	w.Close()
+
+	segments := walSegments(t, dir)
+	require.Len(t, segments, 1)
+
+	// Simulate a crash during the write of the final record.
+	info, err := os.Stat(segments[0])
+	require.NoError(t, err)
+	require.NoError(t, os.Truncate(segments[0], info.Size()-3))
+
+	w = newTestWAL(t, dir, 0)
+	defer w.Close()
+
+	m, ackFunc, err := w.NextMessage()
+	require.NoError(t, err)
+	assert.Equal(t, "test0", string(m.Get(0).Get()))
+	_, err = ackFunc(true)
+	require.NoError(t, err)
+
+	assert.Equal(t, 0, w.bytes)
+	assert.Empty(t, w.pending)
+}
+
+func TestWALSkipsCorruptRecord(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_wal_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	w := newTestWAL(t, dir, 0)
+	for i := 0; i < 3; i++ {
+		_, err = w.PushMessage(message.New([][]byte{[]byte(fmt.Sprintf("test%v", i))}))
+		require.NoError(t, err)
+	}
+	_, ackFunc, err := w.NextMessage()
+	require.NoError(t, err)
+	_, err = ackFunc(true)
+	require.NoError(t, err)
+	w.Close()
+
+	// Restarting results in new batches being written to a new segment,
+	// leaving the first one closed off.
+	w = newTestWAL(t, dir, 0)
+	_, err = w.PushMessage(message.New([][]byte{[]byte("test3")}))
+	require.NoError(t, err)
+	w.Close()
+
+	segments := walSegments(t, dir)
+	require.Len(t, segments, 2)
+
+	data, err := ioutil.ReadFile(segments[0])
+	require.NoError(t, err)
+	i := bytes.Index(data, []byte("test1"))
+	require.True(t, i > 0)
+	data[i] = 'T'
+	require.NoError(t, ioutil.WriteFile(segments[0], data, 0644))
+
+	// Only the corrupted batch is lost, the acknowledgement of test0 that
+	// follows it must still be honoured.
+	w = newTestWAL(t, dir, 0)
+	defer w.Close()
+
+	for _, exp := range []string{"test2", "test3"} {
+		m, ackFunc, err := w.NextMessage()
+		require.NoError(t, err)
+		assert.Equal(t, exp, string(m.Get(0).Get()))
+		_, err = ackFunc(true)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, 0, w.bytes)
+	assert.Empty(t, w.pending)
+}
+
+func TestWALAckFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_wal_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// A tiny segment size forces the acknowledgement to rotate segments.
+	w := newTestWAL(t, dir, 1)
+	defer w.Close()
+
+	_, err = w.PushMessage(message.New([][]byte{[]byte("test0")}))
+	require.NoError(t, err)
+
+	_, ackFunc, err := w.NextMessage()
+	require.NoError(t, err)
+
+	// Creating the next segment fails when the directory is missing.
+	w.cond.L.Lock()
+	w.conf.Directory = filepath.Join(dir, "missing")
+	w.cond.L.Unlock()
+
+	_, err = ackFunc(true)
+	require.Error(t, err)
+
+	// The batch must be delivered again rather than leaking.
+	w.cond.L.Lock()
+	assert.Len(t, w.pending, 1)
+	assert.Equal(t, 0, w.pendingBytes)
+	assert.True(t, w.bytes > 0)
+	w.conf.Directory = dir
+	w.cond.L.Unlock()
+
+	m, ackFunc, err := w.NextMessage()
+	require.NoError(t, err)
+	assert.Equal(t, "test0", string(m.Get(0).Get()))
+	_, err = ackFunc(true)
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		w.CloseOnceEmpty()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out waiting for WAL to close")
+	}
+}
+
+type errWriter struct{}
+
+func (errWriter) Write([]byte) (int, error) {
+	return 0, errors.New("no space left on device")
+}
+
+func TestWALWriteFailureRecovers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_wal_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	w := newTestWAL(t, dir, 0)
+
+	_, err = w.PushMessage(message.New([][]byte{[]byte("test0")}))
+	require.NoError(t, err)
+
+	_, ackFunc, err := w.NextMessage()
+	require.NoError(t, err)
+
+	// Simulate a transient failure, errors of a buffered writer are permanent.
+	w.cond.L.Lock()
+	w.activeWriter = bufio.NewWriter(errWriter{})
+	w.cond.L.Unlock()
+
+	_, err = w.PushMessage(message.New([][]byte{[]byte("test1")}))
+	require.Error(t, err)
+
+	// Later writes go to a new segment.
+	_, err = ackFunc(true)
+	require.NoError(t, err)
+	_, err = w.PushMessage(message.New([][]byte{[]byte("test2")}))
+	require.NoError(t, err)
+	w.Close()
+
+	w = newTestWAL(t, dir, 0)
+	defer w.Close()
+
+	m, ackFunc, err := w.NextMessage()
+	require.NoError(t, err)
+	assert.Equal(t, "test2", string(m.Get(0).Get()))
+	_, err = ackFunc(true)
+	require.NoError(t, err)
+	assert.Empty(t, w.pending)
+
+	// The sequence of the failed batch is not reused.
+	assert.Equal(t, uint64(4), w.nextSeq)
+}
+
+func TestWALSegmentDeletion(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_wal_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// A tiny segment size results in a new segment per batch.
+	w := newTestWAL(t, dir, 1)
+	defer w.Close()
+
+	n := 5
+	for i := 0; i < n; i++ {
+		_, err = w.PushMessage(message.New([][]byte{[]byte(fmt.Sprintf("test%v", i))}))
+		require.NoError(t, err)
+	}
+	assert.Len(t, walSegments(t, dir), n)
+
+	var acks []AckFunc
+	for i := 0; i < n; i++ {
+		_, ackFunc, err := w.NextMessage()
+		require.NoError(t, err)
+		acks = append(acks, ackFunc)
+	}
+
+	// Acknowledging out of order must not remove segments ahead of an
+	// unacknowledged one.
+	_, err = acks[1](true)
+	require.NoError(t, err)
+	assert.FileExists(t, w.segmentPath(0))
+	assert.FileExists(t, w.segmentPath(1))
+
+	_, err = acks[0](true)
+	require.NoError(t, err)
+	assert.NoFileExists(t, w.segmentPath(0))
+	assert.NoFileExists(t, w.segmentPath(1))
+	assert.FileExists(t, w.segmentPath(2))
+
+	for _, ackFunc := range acks[2:] {
+		_, err = ackFunc(true)
+		require.NoError(t, err)
+	}
+	assert.Len(t, walSegments(t, dir), 1)
+}
+
+func TestWALClose(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_wal_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	w := newTestWAL(t, dir, 0)
+
+	_, err = w.PushMessage(message.New([][]byte{[]byte("hello")}))
+	require.NoError(t, err)
+
+	_, ackFunc, err := w.NextMessage()
+	require.NoError(t, err)
+
+	w.Close()
+
+	_, err = ackFunc(true)
+	assert.Equal(t, types.ErrTypeClosed, err)
+
+	_, _, err = w.NextMessage()
+	assert.Equal(t, types.ErrTypeClosed, err)
+
+	_, err = w.PushMessage(message.New([][]byte{[]byte("hello")}))
+	assert.Equal(t, types.ErrTypeClosed, err)
+}
+
+func TestWALBadConfig(t *testing.T) {
+	conf := NewWALConfig()
+	_, err := NewWAL(conf, log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+
+	conf.Directory = "/tmp"
+	conf.SyncPolicy = "nope"
+	_, err = NewWAL(conf, log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+}
@@ -0,0 +1,129 @@
+package buffer
+
+import (
+	"fmt"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/buffer/parallel"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message/batch"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeWAL] = TypeSpec{
+		constructor: NewWAL,
+		Status:      docs.StatusExperimental,
+		Summary: `
+Stores consumed messages in a write-ahead log on disk and acknowledges them at
+the input level once they are persisted. Messages that are not acknowledged by
+the output are replayed after a restart.`,
+		Description: `
+Each batch is appended to a segmented log of checksummed records within the
+configured directory. Input level acknowledgements are only sent once a batch
+has been written, and when ` + "`sync_policy`" + ` is ` + "`always`" + ` only
+once it has been flushed to disk with fsync. This means that it is safe to
+acknowledge messages from the source early, as a crash between the input
+acknowledgement and output delivery will not result in data loss.
+
+Once a batch has been delivered by the output an acknowledgement record is
+appended to the log, and segments are deleted once every batch within them has
+been acknowledged. When the buffer is started all existing segments are read
+and any batches that were never acknowledged are delivered again before new
+data. Records that fail their checksum at the end of the final segment, which
+is the result of a partial write during a crash, are truncated. Corrupted
+records elsewhere are skipped and the rest of the segment is replayed, which
+means the batch of a corrupted record is lost, and a batch whose
+acknowledgement record is corrupted is delivered again.
+
+Since acknowledgements may be lost during a crash the guarantee provided is
+at-least-once, and some batches may be delivered more than once after a
+restart. Unacknowledged batches are also held in memory, and therefore the
+` + "`limit`" + ` should be set significantly below the amount of RAM available.
+
+### Sync Policies
+
+- ` + "`always`" + `: Each batch is synced to disk before it is acknowledged. This is the safest option.
+- ` + "`interval`" + `: The log is synced to disk periodically according to ` + "`sync_interval`" + `, batches written since the last sync may be lost on a machine failure.
+- ` + "`none`" + `: Syncing is left to the operating system, batches may be lost on a machine failure but survive a crash of the process.
+
+### Batching
+
+It is possible to batch up messages sent from this buffer using a
+[batch policy](/docs/configuration/batching#batch-policy).`,
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("directory", "The directory to store log segments within, which is created if it does not exist."),
+			docs.FieldAdvanced("segment_size", "The size (in bytes) a log segment can reach before a new one is started."),
+			docs.FieldCommon("limit", "The maximum backlog (in bytes) of unacknowledged messages to allow before applying backpressure upstream."),
+			docs.FieldCommon("sync_policy", "The policy for syncing written batches to disk.").HasOptions(
+				parallel.WALSyncAlways, parallel.WALSyncInterval, parallel.WALSyncNone,
+			),
+			docs.FieldAdvanced("sync_interval", "The period at which the log is synced to disk when `sync_policy` is `interval`."),
+			docs.FieldCommon("batch_policy", "Optionally configure a policy to flush buffered messages in batches.").WithChildren(
+				append(docs.FieldSpecs{
+					docs.FieldCommon("enabled", "Whether to batch messages as they are flushed."),
+				}, batch.FieldSpec().Children...)...,
+			),
+		},
+		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
+			bSanit, err := batch.SanitisePolicyConfig(batch.PolicyConfig(conf.WAL.BatchPolicy.PolicyConfig))
+			if err != nil {
+				return nil, err
+			}
+			if bSanitObj, ok := bSanit.(map[string]interface{}); ok {
+				bSanitObj["enabled"] = conf.WAL.BatchPolicy.Enabled
+			}
+			return map[string]interface{}{
+				"directory":     conf.WAL.Directory,
+				"segment_size":  conf.WAL.SegmentSize,
+				"limit":         conf.WAL.Limit,
+				"sync_policy":   conf.WAL.SyncPolicy,
+				"sync_interval": conf.WAL.SyncInterval,
+				"batch_policy":  bSanit,
+			}, nil
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// WALConfig is config values for a write-ahead-log based buffer type.
+type WALConfig struct {
+	parallel.WALConfig `json:",inline" yaml:",inline"`
+	BatchPolicy        EnabledBatchPolicyConfig `json:"batch_policy" yaml:"batch_policy"`
+}
+
+// NewWALConfig creates a new WALConfig with default values.
+func NewWALConfig() WALConfig {
+	return WALConfig{
+		WALConfig: parallel.NewWALConfig(),
+		BatchPolicy: EnabledBatchPolicyConfig{
+			Enabled:      false,
+			PolicyConfig: batch.NewPolicyConfig(),
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewWAL creates a buffer persisted to a write-ahead log on disk.
+func NewWAL(config Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	wal, err := parallel.NewWAL(config.WAL.WALConfig, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	wrap := NewParallelWrapper(config, wal, log, stats)
+	if !config.WAL.BatchPolicy.Enabled {
+		return wrap, nil
+	}
+	pol, err := batch.NewPolicy(config.WAL.BatchPolicy.PolicyConfig, mgr, log, stats)
+	if err != nil {
+		return nil, fmt.Errorf("batch policy config error: %v", err)
+	}
+	return NewParallelBatcher(pol, wrap, log, stats), nil
+}
+
+//------------------------------------------------------------------------------
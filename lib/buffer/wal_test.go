@@ -0,0 +1,79 @@
+package buffer
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWALBufferRestart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_wal_buffer_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	conf := NewConfig()
+	conf.Type = TypeWAL
+	conf.WAL.Directory = dir
+
+	newBuf := func() (Type, chan types.Transaction) {
+		buf, err := New(conf, nil, log.Noop(), metrics.Noop())
+		require.NoError(t, err)
+
+		tChan := make(chan types.Transaction)
+		require.NoError(t, buf.Consume(tChan))
+		return buf, tChan
+	}
+
+	buf, tChan := newBuf()
+
+	resChan := make(chan types.Response)
+	select {
+	case tChan <- types.NewTransaction(message.New([][]byte{[]byte("hello world")}), resChan):
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	select {
+	case res := <-resChan:
+		require.NoError(t, res.Error())
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	// Read the message but shut down before acknowledging it.
+	select {
+	case outTr := <-buf.TransactionChan():
+		assert.Equal(t, "hello world", string(outTr.Payload.Get(0).Get()))
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	buf.CloseAsync()
+	require.NoError(t, buf.WaitForClose(time.Second*5))
+
+	buf, _ = newBuf()
+
+	var outTr types.Transaction
+	select {
+	case outTr = <-buf.TransactionChan():
+		assert.Equal(t, "hello world", string(outTr.Payload.Get(0).Get()))
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	select {
+	case outTr.ResponseChan <- response.NewAck():
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	buf.CloseAsync()
+	require.NoError(t, buf.WaitForClose(time.Second*5))
+}
@@ -44,10 +44,12 @@ const (
 	TypeFile        = "file"
 	TypeMemcached   = "memcached"
 	TypeMemory      = "memory"
+	TypeMongoDB     = "mongodb"
 	TypeMultilevel  = "multilevel"
 	TypeRedis       = "redis"
 	TypeRistretto   = "ristretto"
 	TypeS3          = "s3"
+	TypeSQL         = "sql"
 )
 
 //------------------------------------------------------------------------------
@@ -61,11 +63,13 @@ type Config struct {
 	File        FileConfig       `json:"file" yaml:"file"`
 	Memcached   MemcachedConfig  `json:"memcached" yaml:"memcached"`
 	Memory      MemoryConfig     `json:"memory" yaml:"memory"`
+	MongoDB     MongoDBConfig    `json:"mongodb" yaml:"mongodb"`
 	Multilevel  MultilevelConfig `json:"multilevel" yaml:"multilevel"`
 	Plugin      interface{}      `json:"plugin,omitempty" yaml:"plugin,omitempty"`
 	Redis       RedisConfig      `json:"redis" yaml:"redis"`
 	Ristretto   RistrettoConfig  `json:"ristretto" yaml:"ristretto"`
 	S3          S3Config         `json:"s3" yaml:"s3"`
+	SQL         SQLConfig        `json:"sql" yaml:"sql"`
 }
 
 // NewConfig returns a configuration struct fully populated with default values.
@@ -78,11 +82,13 @@ func NewConfig() Config {
 		File:        NewFileConfig(),
 		Memcached:   NewMemcachedConfig(),
 		Memory:      NewMemoryConfig(),
+		MongoDB:     NewMongoDBConfig(),
 		Multilevel:  NewMultilevelConfig(),
 		Plugin:      nil,
 		Redis:       NewRedisConfig(),
 		Ristretto:   NewRistrettoConfig(),
 		S3:          NewS3Config(),
+		SQL:         NewSQLConfig(),
 	}
 }
 
@@ -0,0 +1,196 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/service/mongodb"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeMongoDB] = TypeSpec{
+		constructor: NewMongoDB,
+		Status:      docs.StatusExperimental,
+		Version:     "3.41.0",
+		Summary: `
+Use a MongoDB collection as a cache, where each item is stored as a document.`,
+		Description: `
+Each item is a document where the key is stored within the field ` + "`key_field`" + `
+and the value is stored as a string within the field ` + "`value_field`" + `. A
+unique index on the key field is recommended, which also guarantees that the
+` + "`add`" + ` operation of concurrent writers cannot create duplicates.
+
+This cache does not support item expiry, although items can be expired using a
+[TTL index](https://docs.mongodb.com/manual/core/index-ttl/) on a field added to
+documents by other means.`,
+		FieldSpecs: mongodb.ConfigDocs().Add(
+			docs.FieldCommon("collection", "The name of the target collection."),
+			docs.FieldCommon("key_field", "The field of each document containing the key of the item."),
+			docs.FieldCommon("value_field", "The field of each document containing the value of the item."),
+		),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// MongoDBConfig is a config struct for a MongoDB cache.
+type MongoDBConfig struct {
+	mongodb.Config `json:",inline" yaml:",inline"`
+	Collection     string `json:"collection" yaml:"collection"`
+	KeyField       string `json:"key_field" yaml:"key_field"`
+	ValueField     string `json:"value_field" yaml:"value_field"`
+}
+
+// NewMongoDBConfig returns a MongoDBConfig with default values.
+func NewMongoDBConfig() MongoDBConfig {
+	return MongoDBConfig{
+		Config:     mongodb.NewConfig(),
+		Collection: "",
+		KeyField:   "key",
+		ValueField: "value",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// MongoDB is a cache that stores items as documents of a MongoDB collection.
+type MongoDB struct {
+	conf MongoDBConfig
+
+	client     *mongo.Client
+	collection *mongo.Collection
+}
+
+// NewMongoDB returns a MongoDB cache.
+func NewMongoDB(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (types.Cache, error) {
+	if len(conf.MongoDB.Collection) == 0 {
+		return nil, errors.New("a collection must be specified")
+	}
+	if len(conf.MongoDB.KeyField) == 0 {
+		return nil, errors.New("a key_field must be specified")
+	}
+	if len(conf.MongoDB.ValueField) == 0 {
+		return nil, errors.New("a value_field must be specified")
+	}
+	if conf.MongoDB.KeyField == conf.MongoDB.ValueField {
+		return nil, errors.New("key_field and value_field must be different")
+	}
+
+	client, err := conf.MongoDB.Config.Client()
+	if err != nil {
+		return nil, err
+	}
+	if err = client.Connect(context.Background()); err != nil {
+		return nil, err
+	}
+	return &MongoDB{
+		conf:       conf.MongoDB,
+		client:     client,
+		collection: client.Database(conf.MongoDB.Database).Collection(conf.MongoDB.Collection),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Get attempts to locate and return a cached value by its key, returns an error
+// if the key does not exist.
+func (m *MongoDB) Get(key string) ([]byte, error) {
+	opts := options.FindOne().SetProjection(bson.M{m.conf.ValueField: 1})
+	doc, err := m.collection.FindOne(context.Background(), bson.M{m.conf.KeyField: key}, opts).DecodeBytes()
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, types.ErrKeyNotFound
+		}
+		return nil, err
+	}
+	value, err := doc.LookupErr(m.conf.ValueField)
+	if err != nil {
+		return nil, types.ErrKeyNotFound
+	}
+	if str, ok := value.StringValueOK(); ok {
+		return []byte(str), nil
+	}
+	if _, data, ok := value.BinaryOK(); ok {
+		return data, nil
+	}
+	return nil, errors.New("value field is not a string")
+}
+
+// Set attempts to set the value of a key.
+func (m *MongoDB) Set(key string, value []byte) error {
+	_, err := m.collection.UpdateOne(
+		context.Background(),
+		bson.M{m.conf.KeyField: key},
+		bson.M{"$set": bson.M{m.conf.ValueField: string(value)}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// SetMulti attempts to set the value of multiple keys, returns an error if any
+// keys fail.
+func (m *MongoDB) SetMulti(items map[string][]byte) error {
+	if len(items) == 0 {
+		return nil
+	}
+	models := make([]mongo.WriteModel, 0, len(items))
+	for k, v := range items {
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{m.conf.KeyField: k}).
+			SetUpdate(bson.M{"$set": bson.M{m.conf.ValueField: string(v)}}).
+			SetUpsert(true))
+	}
+	_, err := m.collection.BulkWrite(context.Background(), models, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+// Add attempts to set the value of a key only if the key does not already exist
+// and returns an error if the key already exists.
+func (m *MongoDB) Add(key string, value []byte) error {
+	res, err := m.collection.UpdateOne(
+		context.Background(),
+		bson.M{m.conf.KeyField: key},
+		bson.M{"$setOnInsert": bson.M{m.conf.ValueField: string(value)}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return types.ErrKeyAlreadyExists
+		}
+		return err
+	}
+	if res.MatchedCount > 0 {
+		return types.ErrKeyAlreadyExists
+	}
+	return nil
+}
+
+// Delete attempts to remove a key.
+func (m *MongoDB) Delete(key string) error {
+	_, err := m.collection.DeleteOne(context.Background(), bson.M{m.conf.KeyField: key})
+	return err
+}
+
+// CloseAsync shuts down the cache.
+func (m *MongoDB) CloseAsync() {
+	go func() {
+		_ = m.client.Disconnect(context.Background())
+	}()
+}
+
+// WaitForClose blocks until the cache has closed down.
+func (m *MongoDB) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMongoDBConfigErrors(t *testing.T) {
+	tests := map[string]struct {
+		conf func(c *MongoDBConfig)
+		err  string
+	}{
+		"no collection": {
+			conf: func(c *MongoDBConfig) {
+				c.Collection = ""
+			},
+			err: "a collection must be specified",
+		},
+		"no key field": {
+			conf: func(c *MongoDBConfig) {
+				c.KeyField = ""
+			},
+			err: "a key_field must be specified",
+		},
+		"same fields": {
+			conf: func(c *MongoDBConfig) {
+				c.ValueField = c.KeyField
+			},
+			err: "key_field and value_field must be different",
+		},
+		"no database": {
+			conf: func(c *MongoDBConfig) {
+				c.Database = ""
+			},
+			err: "a database must be specified",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			conf := NewConfig()
+			conf.Type = TypeMongoDB
+			conf.MongoDB.Database = "foo"
+			conf.MongoDB.Collection = "bar"
+			test.conf(&conf.MongoDB)
+
+			_, err := NewMongoDB(conf, nil, log.Noop(), metrics.Noop())
+			require.EqualError(t, err, test.err)
+		})
+	}
+}
@@ -0,0 +1,10 @@
+// +build !wasm
+
+package cache
+
+// Import extra drivers that aren't supported by WASM builds.
+import (
+	// SQL Drivers
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
+)
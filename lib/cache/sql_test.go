@@ -0,0 +1,210 @@
+package cache
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSQLiteCache(t *testing.T, fn func(c *SQLConfig)) (types.CacheWithTTL, string) {
+	t.Helper()
+
+	dsn := "file:" + filepath.Join(t.TempDir(), "cache.db")
+
+	conf := NewConfig()
+	conf.Type = TypeSQL
+	conf.SQL.Driver = "sqlite"
+	conf.SQL.DataSourceName = dsn
+	conf.SQL.Table = "footable"
+	conf.SQL.CreateTable = true
+	if fn != nil {
+		fn(&conf.SQL)
+	}
+
+	c, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		c.CloseAsync()
+		require.NoError(t, c.WaitForClose(time.Second))
+	})
+
+	cTTL, ok := c.(types.CacheWithTTL)
+	require.True(t, ok, "sql cache should implement CacheWithTTL interface")
+	return cTTL, dsn
+}
+
+func TestSQLCacheSQLite(t *testing.T) {
+	c, _ := newTestSQLiteCache(t, nil)
+
+	_, err := c.Get("foo")
+	assert.Equal(t, types.ErrKeyNotFound, err)
+
+	require.NoError(t, c.Set("foo", []byte("1")))
+
+	res, err := c.Get("foo")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("1"), res)
+
+	require.NoError(t, c.Set("foo", []byte("2")))
+
+	res, err = c.Get("foo")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("2"), res)
+
+	assert.Equal(t, types.ErrKeyAlreadyExists, c.Add("foo", []byte("3")))
+	require.NoError(t, c.Add("bar", []byte("3")))
+
+	res, err = c.Get("bar")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("3"), res)
+
+	require.NoError(t, c.SetMulti(map[string][]byte{
+		"foo": []byte("4"),
+		"baz": []byte("5"),
+	}))
+
+	for k, v := range map[string]string{"foo": "4", "bar": "3", "baz": "5"} {
+		res, err = c.Get(k)
+		require.NoError(t, err, k)
+		assert.Equal(t, []byte(v), res, k)
+	}
+
+	require.NoError(t, c.Delete("foo"))
+
+	_, err = c.Get("foo")
+	assert.Equal(t, types.ErrKeyNotFound, err)
+}
+
+func TestSQLCacheSQLiteTTL(t *testing.T) {
+	c, dsn := newTestSQLiteCache(t, func(c *SQLConfig) {
+		c.TTL = "1h"
+		c.CleanupInterval = "10ms"
+	})
+
+	ttl := time.Millisecond * 50
+	require.NoError(t, c.SetWithTTL("foo", []byte("1"), &ttl))
+	require.NoError(t, c.AddWithTTL("bar", []byte("2"), &ttl))
+	require.NoError(t, c.SetMultiWithTTL(map[string]types.CacheTTLItem{
+		"baz": {Value: []byte("3"), TTL: &ttl},
+	}))
+	require.NoError(t, c.Set("buz", []byte("4")))
+
+	res, err := c.Get("foo")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("1"), res)
+
+	assert.Equal(t, types.ErrKeyAlreadyExists, c.Add("bar", []byte("5")))
+
+	time.Sleep(ttl * 2)
+
+	for _, k := range []string{"foo", "bar", "baz"} {
+		_, err = c.Get(k)
+		assert.Equal(t, types.ErrKeyNotFound, err, k)
+	}
+
+	// The default TTL applies to items set without one.
+	res, err = c.Get("buz")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("4"), res)
+
+	// Expired keys can be added again.
+	require.NoError(t, c.Add("bar", []byte("5")))
+	res, err = c.Get("bar")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("5"), res)
+
+	// Expired rows are eventually removed from the table.
+	db, err := sql.Open("sqlite", dsn)
+	require.NoError(t, err)
+	defer db.Close()
+
+	assert.Eventually(t, func() bool {
+		var count int
+		if err := db.QueryRow("SELECT COUNT(*) FROM footable").Scan(&count); err != nil {
+			return false
+		}
+		return count == 2
+	}, time.Second*5, time.Millisecond*50)
+}
+
+func TestSQLCacheQueries(t *testing.T) {
+	conf := NewSQLConfig()
+	conf.Driver = "postgres"
+	conf.Table = "footable"
+
+	q, createTable, err := newSQLCacheQueries(conf)
+	require.NoError(t, err)
+
+	assert.Equal(t, "SELECT cache_value FROM footable WHERE cache_key = $1 AND (expires_at IS NULL OR expires_at > $2)", q.get)
+	assert.Equal(t, "INSERT INTO footable (cache_key, cache_value, expires_at) VALUES ($1, $2, $3) ON CONFLICT (cache_key) DO UPDATE SET cache_value = excluded.cache_value, expires_at = excluded.expires_at", q.set)
+	assert.Equal(t, "INSERT INTO footable (cache_key, cache_value, expires_at) VALUES ($1, $2, $3) ON CONFLICT (cache_key) DO NOTHING", q.add)
+	assert.Equal(t, "CREATE TABLE IF NOT EXISTS footable (cache_key TEXT PRIMARY KEY, cache_value BYTEA, expires_at BIGINT)", createTable)
+
+	conf.Driver = "mysql"
+
+	q, createTable, err = newSQLCacheQueries(conf)
+	require.NoError(t, err)
+
+	assert.Equal(t, "SELECT cache_value FROM footable WHERE cache_key = ? AND (expires_at IS NULL OR expires_at > ?)", q.get)
+	assert.Equal(t, "INSERT INTO footable (cache_key, cache_value, expires_at) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE cache_value = VALUES(cache_value), expires_at = VALUES(expires_at)", q.set)
+	assert.Equal(t, "INSERT IGNORE INTO footable (cache_key, cache_value, expires_at) VALUES (?, ?, ?)", q.add)
+	assert.Equal(t, "CREATE TABLE IF NOT EXISTS footable (cache_key VARCHAR(255) PRIMARY KEY, cache_value LONGBLOB, expires_at BIGINT)", createTable)
+}
+
+func TestSQLConfigErrors(t *testing.T) {
+	tests := map[string]struct {
+		conf func(c *SQLConfig)
+		err  string
+	}{
+		"no table": {
+			conf: func(c *SQLConfig) {
+				c.Table = ""
+			},
+			err: "a table must be specified",
+		},
+		"no key column": {
+			conf: func(c *SQLConfig) {
+				c.KeyColumn = ""
+			},
+			err: "key_column, value_column and expires_column must be specified",
+		},
+		"clickhouse driver": {
+			conf: func(c *SQLConfig) {
+				c.Driver = "clickhouse"
+			},
+			err: "driver not supported: clickhouse",
+		},
+		"bad ttl": {
+			conf: func(c *SQLConfig) {
+				c.TTL = "nope"
+			},
+			err: `failed to parse ttl duration: time: invalid duration "nope"`,
+		},
+		"bad cleanup interval": {
+			conf: func(c *SQLConfig) {
+				c.CleanupInterval = "nope"
+			},
+			err: `failed to parse cleanup interval duration: time: invalid duration "nope"`,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			conf := NewConfig()
+			conf.Type = TypeSQL
+			conf.SQL.Table = "footable"
+			test.conf(&conf.SQL)
+
+			_, err := NewSQL(conf, nil, log.Noop(), metrics.Noop())
+			require.EqualError(t, err, test.err)
+		})
+	}
+}
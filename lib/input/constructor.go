@@ -153,6 +153,7 @@ const (
 	TypeFiles            = "files"
 	TypeGCPPubSub        = "gcp_pubsub"
 	TypeGenerate         = "generate"
+	TypeGRPCServer       = "grpc_server"
 	TypeHDFS             = "hdfs"
 	TypeHTTPClient       = "http_client"
 	TypeHTTPServer       = "http_server"
@@ -161,11 +162,14 @@ const (
 	TypeKafkaBalanced    = "kafka_balanced"
 	TypeKinesis          = "kinesis"
 	TypeKinesisBalanced  = "kinesis_balanced"
+	TypeMongoDB          = "mongodb"
 	TypeMQTT             = "mqtt"
 	TypeNanomsg          = "nanomsg"
 	TypeNATS             = "nats"
+	TypeNATSJetStream    = "nats_jetstream"
 	TypeNATSStream       = "nats_stream"
 	TypeNSQ              = "nsq"
+	TypePostgresCDC      = "postgres_cdc"
 	TypeReadUntil        = "read_until"
 	TypeRedisList        = "redis_list"
 	TypeRedisPubSub      = "redis_pubsub"
@@ -206,6 +210,7 @@ type Config struct {
 	Files            reader.FilesConfig           `json:"files" yaml:"files"`
 	GCPPubSub        reader.GCPPubSubConfig       `json:"gcp_pubsub" yaml:"gcp_pubsub"`
 	Generate         BloblangConfig               `json:"generate" yaml:"generate"`
+	GRPCServer       GRPCServerConfig             `json:"grpc_server" yaml:"grpc_server"`
 	HDFS             reader.HDFSConfig            `json:"hdfs" yaml:"hdfs"`
 	HTTPClient       HTTPClientConfig             `json:"http_client" yaml:"http_client"`
 	HTTPServer       HTTPServerConfig             `json:"http_server" yaml:"http_server"`
@@ -214,12 +219,15 @@ type Config struct {
 	KafkaBalanced    reader.KafkaBalancedConfig   `json:"kafka_balanced" yaml:"kafka_balanced"`
 	Kinesis          reader.KinesisConfig         `json:"kinesis" yaml:"kinesis"`
 	KinesisBalanced  reader.KinesisBalancedConfig `json:"kinesis_balanced" yaml:"kinesis_balanced"`
+	MongoDB          MongoDBConfig                `json:"mongodb" yaml:"mongodb"`
 	MQTT             reader.MQTTConfig            `json:"mqtt" yaml:"mqtt"`
 	Nanomsg          reader.ScaleProtoConfig      `json:"nanomsg" yaml:"nanomsg"`
 	NATS             reader.NATSConfig            `json:"nats" yaml:"nats"`
+	NATSJetStream    NATSJetStreamConfig          `json:"nats_jetstream" yaml:"nats_jetstream"`
 	NATSStream       reader.NATSStreamConfig      `json:"nats_stream" yaml:"nats_stream"`
 	NSQ              reader.NSQConfig             `json:"nsq" yaml:"nsq"`
 	Plugin           interface{}                  `json:"plugin,omitempty" yaml:"plugin,omitempty"`
+	PostgresCDC      PostgresCDCConfig            `json:"postgres_cdc" yaml:"postgres_cdc"`
 	ReadUntil        ReadUntilConfig              `json:"read_until" yaml:"read_until"`
 	RedisList        reader.RedisListConfig       `json:"redis_list" yaml:"redis_list"`
 	RedisPubSub      reader.RedisPubSubConfig     `json:"redis_pubsub" yaml:"redis_pubsub"`
@@ -260,6 +268,7 @@ func NewConfig() Config {
 		Files:            reader.NewFilesConfig(),
 		GCPPubSub:        reader.NewGCPPubSubConfig(),
 		Generate:         NewBloblangConfig(),
+		GRPCServer:       NewGRPCServerConfig(),
 		HDFS:             reader.NewHDFSConfig(),
 		HTTPClient:       NewHTTPClientConfig(),
 		HTTPServer:       NewHTTPServerConfig(),
@@ -268,12 +277,15 @@ func NewConfig() Config {
 		KafkaBalanced:    reader.NewKafkaBalancedConfig(),
 		Kinesis:          reader.NewKinesisConfig(),
 		KinesisBalanced:  reader.NewKinesisBalancedConfig(),
+		MongoDB:          NewMongoDBConfig(),
 		MQTT:             reader.NewMQTTConfig(),
 		Nanomsg:          reader.NewScaleProtoConfig(),
 		NATS:             reader.NewNATSConfig(),
+		NATSJetStream:    NewNATSJetStreamConfig(),
 		NATSStream:       reader.NewNATSStreamConfig(),
 		NSQ:              reader.NewNSQConfig(),
 		Plugin:           nil,
+		PostgresCDC:      NewPostgresCDCConfig(),
 		ReadUntil:        NewReadUntilConfig(),
 		RedisList:        reader.NewRedisListConfig(),
 		RedisPubSub:      reader.NewRedisPubSubConfig(),
@@ -0,0 +1,429 @@
+package input
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	bgrpc "github.com/Jeffail/benthos/v3/internal/service/grpc"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/message/roundtrip"
+	"github.com/Jeffail/benthos/v3/lib/message/tracing"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeGRPCServer] = TypeSpec{
+		constructor: fromSimpleConstructor(NewGRPCServer),
+		Status:      docs.StatusExperimental,
+		Version:     "3.41.0",
+		Summary: `
+Hosts a gRPC server that exposes the services defined within .proto files,
+where each request is consumed as a JSON message.`,
+		Description: `
+The service definitions are parsed from the .proto files found within
+` + "`import_paths`" + `, and requests are converted into JSON documents
+following the
+[JSON mapping of protobuf messages](https://developers.google.com/protocol-buffers/docs/proto3#json).
+
+Unary methods produce a message for each request. Client streaming methods
+consume all requests of a stream as a single batch, where each request is a
+message of the batch. Server streaming and bidirectional streaming methods are
+not supported and calls to them are rejected.
+
+A call is only responded to once its messages have been delivered, and if the
+delivery fails the call returns an error with the code ` + "`UNAVAILABLE`" + `.
+
+### Responses
+
+It's possible to return a response for each call using
+[synchronous responses](/docs/guides/sync_responses), where the first message of
+the response is parsed as a JSON document of the output type of the method. When
+no response is set an empty message of the output type is returned.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "``` text" + `
+- grpc_server_method
+- All request metadata (only first values are taken)
+` + "```" + `
+
+You can access these metadata fields using
+[function interpolation](/docs/configuration/interpolation#metadata).`,
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("address", "The address to listen on.", "0.0.0.0:50051"),
+			docs.FieldCommon("import_paths", "A list of directories containing .proto files, including all definitions required for parsing the services. If left empty the current directory is used. Each directory listed will be walked with all found .proto files imported."),
+			docs.FieldCommon("services", "An optional list of fully qualified service names to expose. If left empty all services found within `import_paths` are exposed.", []string{"helloworld.Greeter"}),
+			docs.FieldCommon("timeout", "Timeout for calls. If the messages of a call take longer than this to be delivered an error is returned, but the messages may still be delivered."),
+			docs.FieldAdvanced("cert_file", "An optional certificate file for enabling TLS."),
+			docs.FieldAdvanced("key_file", "An optional key file for enabling TLS."),
+		},
+		Examples: []docs.AnnotatedExample{
+			{
+				Title: "Greeter",
+				Summary: `
+If we have the following service defined within a directory called
+` + "`protos`" + `:
+
+` + "```protobuf" + `
+syntax = "proto3";
+package helloworld;
+
+service Greeter {
+  rpc SayHello (HelloRequest) returns (HelloReply) {}
+}
+
+message HelloRequest {
+  string name = 1;
+}
+
+message HelloReply {
+  string message = 1;
+}
+` + "```" + `
+
+We can reply to each call with a greeting with the following config:`,
+				Config: `
+input:
+  grpc_server:
+    address: 0.0.0.0:50051
+    import_paths: [ protos ]
+
+pipeline:
+  processors:
+    - bloblang: 'root.message = "Hello " + this.name'
+
+output:
+  sync_response: {}
+`,
+			},
+		},
+		Categories: []Category{
+			CategoryNetwork,
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// GRPCServerConfig contains configuration for the GRPCServer input type.
+type GRPCServerConfig struct {
+	Address     string   `json:"address" yaml:"address"`
+	ImportPaths []string `json:"import_paths" yaml:"import_paths"`
+	Services    []string `json:"services" yaml:"services"`
+	Timeout     string   `json:"timeout" yaml:"timeout"`
+	CertFile    string   `json:"cert_file" yaml:"cert_file"`
+	KeyFile     string   `json:"key_file" yaml:"key_file"`
+}
+
+// NewGRPCServerConfig creates a new GRPCServerConfig with default values.
+func NewGRPCServerConfig() GRPCServerConfig {
+	return GRPCServerConfig{
+		Address:     "0.0.0.0:50051",
+		ImportPaths: []string{},
+		Services:    []string{},
+		Timeout:     "5s",
+		CertFile:    "",
+		KeyFile:     "",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// GRPCServer is an input type that hosts a gRPC server exposing the services
+// defined within .proto files, where each call is consumed as a message.
+type GRPCServer struct {
+	running int32
+
+	conf  GRPCServerConfig
+	stats metrics.Type
+	log   log.Modular
+
+	server   *grpc.Server
+	listener net.Listener
+	timeout  time.Duration
+	methods  map[string]*desc.MethodDescriptor
+
+	transactions chan types.Transaction
+
+	closeChan  chan struct{}
+	closedChan chan struct{}
+
+	mCount     metrics.StatCounter
+	mLatency   metrics.StatTimer
+	mRcvd      metrics.StatCounter
+	mPartsRcvd metrics.StatCounter
+	mTimeout   metrics.StatCounter
+	mErr       metrics.StatCounter
+	mSucc      metrics.StatCounter
+	mAsyncErr  metrics.StatCounter
+	mAsyncSucc metrics.StatCounter
+}
+
+// NewGRPCServer creates a new GRPCServer input type.
+func NewGRPCServer(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	if len(conf.GRPCServer.Address) == 0 {
+		return nil, errors.New("an address must be specified")
+	}
+
+	var timeout time.Duration
+	if len(conf.GRPCServer.Timeout) > 0 {
+		var err error
+		if timeout, err = time.ParseDuration(conf.GRPCServer.Timeout); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout string: %v", err)
+		}
+	}
+
+	fds, err := bgrpc.ParseProtoFiles(conf.GRPCServer.ImportPaths)
+	if err != nil {
+		return nil, err
+	}
+	services, err := bgrpc.FindServices(fds, conf.GRPCServer.Services)
+	if err != nil {
+		return nil, err
+	}
+
+	methods := map[string]*desc.MethodDescriptor{}
+	for _, service := range services {
+		for _, method := range service.GetMethods() {
+			if method.IsServerStreaming() {
+				log.Warnf("Method '%v' is server streaming and will not be served\n", bgrpc.MethodPath(method))
+				continue
+			}
+			methods[bgrpc.MethodPath(method)] = method
+		}
+	}
+
+	g := &GRPCServer{
+		running:      1,
+		conf:         conf.GRPCServer,
+		stats:        stats,
+		log:          log,
+		timeout:      timeout,
+		methods:      methods,
+		transactions: make(chan types.Transaction),
+		closeChan:    make(chan struct{}),
+		closedChan:   make(chan struct{}),
+
+		mCount:     stats.GetCounter("count"),
+		mLatency:   stats.GetTimer("latency"),
+		mRcvd:      stats.GetCounter("batch.received"),
+		mPartsRcvd: stats.GetCounter("received"),
+		mTimeout:   stats.GetCounter("send.timeout"),
+		mErr:       stats.GetCounter("send.error"),
+		mSucc:      stats.GetCounter("send.success"),
+		mAsyncErr:  stats.GetCounter("send.async_error"),
+		mAsyncSucc: stats.GetCounter("send.async_success"),
+	}
+
+	opts := []grpc.ServerOption{grpc.UnknownServiceHandler(g.handler)}
+	if len(g.conf.CertFile) > 0 || len(g.conf.KeyFile) > 0 {
+		creds, err := credentials.NewServerTLSFromFile(g.conf.CertFile, g.conf.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS credentials: %v", err)
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+	g.server = grpc.NewServer(opts...)
+
+	if g.listener, err = net.Listen("tcp", g.conf.Address); err != nil {
+		return nil, err
+	}
+
+	go g.loop()
+	return g, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (g *GRPCServer) readRequests(method *desc.MethodDescriptor, stream grpc.ServerStream) (types.Message, error) {
+	msg := message.New(nil)
+	for {
+		req := dynamic.NewMessage(method.GetInputType())
+		if err := stream.RecvMsg(req); err != nil {
+			if err == io.EOF && method.IsClientStreaming() {
+				break
+			}
+			return nil, err
+		}
+		reqBytes, err := req.MarshalJSON()
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to marshal request: %v", err)
+		}
+		msg.Append(message.NewPart(reqBytes))
+		if !method.IsClientStreaming() {
+			break
+		}
+	}
+	if msg.Len() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "at least one request must be sent")
+	}
+
+	md, _ := metadata.FromIncomingContext(stream.Context())
+	msg.Iter(func(i int, p types.Part) error {
+		meta := p.Metadata()
+		for k, v := range md {
+			if len(v) > 0 {
+				meta.Set(k, v[0])
+			}
+		}
+		meta.Set("grpc_server_method", bgrpc.MethodPath(method))
+		return nil
+	})
+	return msg, nil
+}
+
+func (g *GRPCServer) handler(srv interface{}, stream grpc.ServerStream) error {
+	methodPath, _ := grpc.MethodFromServerStream(stream)
+	method, exists := g.methods[methodPath]
+	if !exists {
+		return status.Errorf(codes.Unimplemented, "method %v not implemented", methodPath)
+	}
+
+	msg, err := g.readRequests(method, stream)
+	if err != nil {
+		return err
+	}
+
+	tracing.InitSpans("input_grpc_server", msg)
+	defer tracing.FinishSpans(msg)
+
+	store := roundtrip.NewResultStore()
+	roundtrip.AddResultStore(msg, store)
+
+	g.mCount.Incr(1)
+	g.mPartsRcvd.Incr(int64(msg.Len()))
+	g.mRcvd.Incr(1)
+
+	var timeoutChan <-chan time.Time
+	if g.timeout > 0 {
+		timeoutChan = time.After(g.timeout)
+	}
+
+	resChan := make(chan types.Response)
+	select {
+	case g.transactions <- types.NewTransaction(msg, resChan):
+	case <-timeoutChan:
+		g.mTimeout.Incr(1)
+		return status.Error(codes.DeadlineExceeded, "request timed out")
+	case <-stream.Context().Done():
+		return status.FromContextError(stream.Context().Err()).Err()
+	case <-g.closeChan:
+		return status.Error(codes.Unavailable, "server closing")
+	}
+
+	select {
+	case res, open := <-resChan:
+		if !open {
+			return status.Error(codes.Unavailable, "server closing")
+		} else if res.Error() != nil {
+			g.mErr.Incr(1)
+			return status.Error(codes.Unavailable, res.Error().Error())
+		}
+		g.mLatency.Timing(time.Since(msg.CreatedAt()).Nanoseconds())
+		g.mSucc.Incr(1)
+	case <-timeoutChan:
+		g.mTimeout.Incr(1)
+		go func() {
+			// Even if the request times out, we still need to drain a response.
+			if resAsync := <-resChan; resAsync.Error() != nil {
+				g.mAsyncErr.Incr(1)
+				g.mErr.Incr(1)
+			} else {
+				g.mLatency.Timing(time.Since(msg.CreatedAt()).Nanoseconds())
+				g.mAsyncSucc.Incr(1)
+				g.mSucc.Incr(1)
+			}
+		}()
+		return status.Error(codes.DeadlineExceeded, "request timed out")
+	case <-g.closeChan:
+		return status.Error(codes.Unavailable, "server closing")
+	}
+
+	res := dynamic.NewMessage(method.GetOutputType())
+	for _, resMsg := range store.Get() {
+		if resMsg.Len() == 0 {
+			continue
+		}
+		if err := res.UnmarshalJSON(resMsg.Get(0).Get()); err != nil {
+			g.log.Errorf("Failed to parse sync response as '%v': %v\n", method.GetOutputType().GetFullyQualifiedName(), err)
+			return status.Errorf(codes.Internal, "failed to parse response: %v", err)
+		}
+		break
+	}
+	return stream.SendMsg(res)
+}
+
+//------------------------------------------------------------------------------
+
+func (g *GRPCServer) loop() {
+	mRunning := g.stats.GetGauge("running")
+
+	defer func() {
+		atomic.StoreInt32(&g.running, 0)
+
+		g.server.GracefulStop()
+		mRunning.Decr(1)
+
+		close(g.transactions)
+		close(g.closedChan)
+	}()
+	mRunning.Incr(1)
+
+	go func() {
+		g.log.Infof("Receiving gRPC calls at: %v\n", g.listener.Addr())
+		if err := g.server.Serve(g.listener); err != nil {
+			g.log.Errorf("Server error: %v\n", err)
+		}
+	}()
+
+	<-g.closeChan
+}
+
+// TransactionChan returns a transactions channel for consuming messages from
+// this input.
+func (g *GRPCServer) TransactionChan() <-chan types.Transaction {
+	return g.transactions
+}
+
+// Connected returns a boolean indicating whether this input is currently
+// connected to its target.
+func (g *GRPCServer) Connected() bool {
+	return true
+}
+
+// CloseAsync shuts down the GRPCServer input and stops processing requests.
+func (g *GRPCServer) CloseAsync() {
+	if atomic.CompareAndSwapInt32(&g.running, 1, 0) {
+		close(g.closeChan)
+	}
+}
+
+// WaitForClose blocks until the GRPCServer input has closed down.
+func (g *GRPCServer) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-g.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
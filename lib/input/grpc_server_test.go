@@ -0,0 +1,225 @@
+package input
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	bgrpc "github.com/Jeffail/benthos/v3/internal/service/grpc"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/message/roundtrip"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const grpcServerTestProto = `
+syntax = "proto3";
+package testing;
+
+service Greeter {
+  rpc SayHello (HelloRequest) returns (HelloReply) {}
+  rpc SayHellos (stream HelloRequest) returns (HelloReply) {}
+  rpc Spam (HelloRequest) returns (stream HelloReply) {}
+}
+
+message HelloRequest {
+  string name = 1;
+}
+
+message HelloReply {
+  string message = 1;
+}
+`
+
+func newGRPCServerTestInput(t *testing.T) (string, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "greeter.proto"), []byte(grpcServerTestProto), 0o644))
+
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	addr := lis.Addr().String()
+	require.NoError(t, lis.Close())
+
+	return dir, addr
+}
+
+func TestGRPCServerConfigErrors(t *testing.T) {
+	dir, addr := newGRPCServerTestInput(t)
+
+	tests := map[string]struct {
+		conf func(c *GRPCServerConfig)
+		err  string
+	}{
+		"no address": {
+			conf: func(c *GRPCServerConfig) {
+				c.Address = ""
+			},
+			err: "an address must be specified",
+		},
+		"unknown service": {
+			conf: func(c *GRPCServerConfig) {
+				c.Services = []string{"testing.Nope"}
+			},
+			err: "unable to find service 'testing.Nope' definition",
+		},
+		"bad timeout": {
+			conf: func(c *GRPCServerConfig) {
+				c.Timeout = "nope"
+			},
+			err: `failed to parse timeout string: time: invalid duration "nope"`,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			conf := NewConfig()
+			conf.GRPCServer.Address = addr
+			conf.GRPCServer.ImportPaths = []string{dir}
+			test.conf(&conf.GRPCServer)
+
+			_, err := NewGRPCServer(conf, types.NoopMgr(), log.Noop(), metrics.Noop())
+			require.EqualError(t, err, test.err)
+		})
+	}
+}
+
+func TestGRPCServerCalls(t *testing.T) {
+	dir, addr := newGRPCServerTestInput(t)
+
+	conf := NewConfig()
+	conf.GRPCServer.Address = addr
+	conf.GRPCServer.ImportPaths = []string{dir}
+
+	server, err := NewGRPCServer(conf, types.NoopMgr(), log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		server.CloseAsync()
+		assert.NoError(t, server.WaitForClose(time.Second*5))
+	})
+
+	newClient := func(method string) *bgrpc.Client {
+		clientConf := bgrpc.NewClientConfig()
+		clientConf.Address = addr
+		clientConf.Method = method
+		clientConf.ImportPaths = []string{dir}
+		clientConf.Metadata = map[string]string{"foo": "bar"}
+
+		client, err := bgrpc.NewClient(clientConf)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			assert.NoError(t, client.Close())
+		})
+		return client
+	}
+
+	type callRes struct {
+		res []byte
+		err error
+	}
+
+	call := func(fn func() ([]byte, error)) <-chan callRes {
+		resChan := make(chan callRes, 1)
+		go func() {
+			res, err := fn()
+			resChan <- callRes{res, err}
+		}()
+		return resChan
+	}
+
+	receive := func() types.Transaction {
+		select {
+		case tran, open := <-server.TransactionChan():
+			require.True(t, open)
+			return tran
+		case <-time.After(time.Second * 5):
+			t.Fatal("timed out")
+		}
+		return types.Transaction{}
+	}
+
+	ack := func(tran types.Transaction, err error) {
+		select {
+		case tran.ResponseChan <- response.NewError(err):
+		case <-time.After(time.Second * 5):
+			t.Fatal("timed out")
+		}
+	}
+
+	sayHello := newClient("testing.Greeter/SayHello")
+	msg := message.New([][]byte{[]byte(`{"name":"foo"}`)})
+
+	// A unary call with a sync response.
+	resChan := call(func() ([]byte, error) {
+		return sayHello.Unary(context.Background(), 0, msg)
+	})
+	tran := receive()
+	require.Equal(t, 1, tran.Payload.Len())
+	assert.Equal(t, `{"name":"foo"}`, string(tran.Payload.Get(0).Get()))
+	assert.Equal(t, "/testing.Greeter/SayHello", tran.Payload.Get(0).Metadata().Get("grpc_server_method"))
+	assert.Equal(t, "bar", tran.Payload.Get(0).Metadata().Get("foo"))
+
+	resMsg := tran.Payload.Copy()
+	resMsg.Get(0).Set([]byte(`{"message":"hello foo"}`))
+	require.NoError(t, roundtrip.SetAsResponse(resMsg))
+	ack(tran, nil)
+
+	res := <-resChan
+	require.NoError(t, res.err)
+	assert.Equal(t, `{"message":"hello foo"}`, string(res.res))
+
+	// A unary call without a sync response.
+	resChan = call(func() ([]byte, error) {
+		return sayHello.Unary(context.Background(), 0, msg)
+	})
+	ack(receive(), nil)
+
+	res = <-resChan
+	require.NoError(t, res.err)
+	assert.Equal(t, `{}`, string(res.res))
+
+	// A unary call that fails to be delivered.
+	resChan = call(func() ([]byte, error) {
+		return sayHello.Unary(context.Background(), 0, msg)
+	})
+	ack(receive(), errors.New("nope"))
+
+	res = <-resChan
+	require.Error(t, res.err)
+	assert.Equal(t, codes.Unavailable, status.Code(res.err))
+
+	// A client streaming call consumed as a batch.
+	sayHellos := newClient("testing.Greeter/SayHellos")
+	resChan = call(func() ([]byte, error) {
+		return sayHellos.ClientStream(context.Background(), message.New([][]byte{
+			[]byte(`{"name":"foo"}`),
+			[]byte(`{"name":"bar"}`),
+		}))
+	})
+	tran = receive()
+	require.Equal(t, 2, tran.Payload.Len())
+	assert.Equal(t, `{"name":"foo"}`, string(tran.Payload.Get(0).Get()))
+	assert.Equal(t, `{"name":"bar"}`, string(tran.Payload.Get(1).Get()))
+	assert.Equal(t, "/testing.Greeter/SayHellos", tran.Payload.Get(1).Metadata().Get("grpc_server_method"))
+
+	resMsg = tran.Payload.Copy()
+	resMsg.Get(0).Set([]byte(`{"message":"hello everyone"}`))
+	require.NoError(t, roundtrip.SetAsResponse(resMsg))
+	ack(tran, nil)
+
+	res = <-resChan
+	require.NoError(t, res.err)
+	assert.Equal(t, `{"message":"hello everyone"}`, string(res.res))
+}
@@ -3,6 +3,7 @@ package input
 import (
 	"errors"
 
+	"github.com/Jeffail/benthos/v3/internal/codec"
 	"github.com/Jeffail/benthos/v3/internal/docs"
 	"github.com/Jeffail/benthos/v3/lib/input/reader"
 	"github.com/Jeffail/benthos/v3/lib/log"
@@ -17,7 +18,7 @@ func init() {
 		constructor: fromSimpleConstructor(NewHDFS),
 		Summary: `
 Reads files from a HDFS directory, where each discrete file will be consumed as
-a single message payload.`,
+a single message payload, or as multiple messages according to the chosen codec.`,
 		Description: `
 ### Metadata
 
@@ -37,6 +38,8 @@ You can access these metadata fields using
 			docs.FieldCommon("hosts", "A list of target host addresses to connect to."),
 			docs.FieldCommon("user", "A user ID to connect as."),
 			docs.FieldCommon("directory", "The directory to consume from."),
+			codec.ReaderDocs.AtVersion("3.41.0"),
+			docs.FieldAdvanced("max_buffer", "The largest token size expected when consuming delimited files.").AtVersion("3.41.0"),
 		},
 	}
 }
@@ -48,12 +51,14 @@ func NewHDFS(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type
 	if len(conf.HDFS.Directory) == 0 {
 		return nil, errors.New("invalid directory (cannot be empty)")
 	}
+	r, err := reader.NewHDFSAsync(conf.HDFS, log, stats)
+	if err != nil {
+		return nil, err
+	}
 	return NewAsyncReader(
 		TypeHDFS,
 		true,
-		reader.NewAsyncPreserver(
-			reader.NewHDFS(conf.HDFS, log, stats),
-		),
+		reader.NewAsyncPreserver(r),
 		log, stats,
 	)
 }
@@ -2,6 +2,7 @@ package input
 
 import (
 	"context"
+	"errors"
 	"io"
 	"net/http"
 	"sync"
@@ -35,6 +36,17 @@ then line feed (\n) is used.`).HasType("string"),
 		docs.FieldCommon(
 			"stream", "Allows you to set streaming mode, where requests are kept open and messages are processed line-by-line.",
 		).WithChildren(streamSpecs...),
+		docs.FieldAdvanced(
+			"pagination", "Allows you to walk paginated APIs, where each request is derived from the response of the previous request.",
+		).WithChildren(
+			docs.FieldCommon(
+				"mapping", "A [Bloblang mapping](/docs/guides/bloblang/about) executed against each response in order to determine the next request. The mapping should result in an object with an optional `url` field, which replaces the configured URL, and an optional `body` field, which replaces the configured payload. Deleting the root indicates that pagination is complete. When empty pagination is disabled.",
+				`root = if this.next_cursor != null { {"url": "https://api.example.com/tickets?cursor=" + this.next_cursor.escape_url_query()} } else { deleted() }`,
+				`root = if (meta("link") | "").contains("rel=\"next\"") { {"url": meta("link").re_find_object("<(?P<url>[^>]+)>; rel=\"next\"").url} } else { deleted() }`,
+			),
+			docs.FieldCommon("cache", "An optional [cache resource](/docs/components/caches/about) used to persist the next request once a page has been delivered, allowing pagination to resume where it left off after a restart."),
+			docs.FieldAdvanced("cache_key", "The key under which the next request is stored within the cache."),
+		).AtVersion("3.41.0"),
 	)
 	return specs
 }
@@ -53,7 +65,36 @@ interpolations described [here](/docs/configuration/interpolation#bloblang-queri
 If you enable streaming then Benthos will consume the body of the response as a
 line delimited feed of message parts. Each part is read as an individual message
 unless multipart is set to true, in which case an empty line indicates the end
-of a message.`,
+of a message.
+
+### Pagination
+
+Setting a ` + "`pagination.mapping`" + ` walks a paginated API by executing a [Bloblang mapping](/docs/guides/bloblang/about) against each response in order to determine the next request. The mapping is executed against the response body, with the response headers (lower cased), the status code ` + "`http_status_code`" + ` and the URL of the request ` + "`http_request_url`" + ` available as metadata.
+
+The mapping results in an object where the field ` + "`url`" + ` replaces the configured URL and the field ` + "`body`" + ` replaces the configured payload, either of which can be omitted in order to use the configured values. Once the mapping deletes the root pagination is complete and the input closes gracefully. Note that deleting only a field such as ` + "`root.url = deleted()`" + ` results in the configured URL being requested again. Since a missing metadata value results in a mapping error, optional headers should be given a fallback value, e.g. ` + "`meta(\"link\") | \"\"`" + `.
+
+If a ` + "`pagination.cache`" + ` is configured then the next request is persisted once a page and all pages before it have been delivered, and after a restart the input resumes from the persisted request rather than the first page. Since the last request is kept once pagination is complete, restarting after completion requests the final page again.
+
+` + "```yaml" + `
+input:
+  http_client:
+    url: https://api.example.com/tickets
+    verb: GET
+    pagination:
+      mapping: |
+        root = if this.next_cursor != null {
+          { "url": "https://api.example.com/tickets?cursor=" + this.next_cursor.escape_url_query() }
+        } else {
+          deleted()
+        }
+      cache: cursors
+
+resources:
+  caches:
+    cursors:
+      file:
+        directory: /var/lib/benthos/cursors
+` + "```" + ``,
 		FieldSpecs: httpClientSpecs(),
 		Categories: []Category{
 			CategoryNetwork,
@@ -76,9 +117,10 @@ type StreamConfig struct {
 // HTTPClientConfig contains configuration for the HTTPClient output type.
 type HTTPClientConfig struct {
 	client.Config   `json:",inline" yaml:",inline"`
-	Payload         string       `json:"payload" yaml:"payload"`
-	DropEmptyBodies bool         `json:"drop_empty_bodies" yaml:"drop_empty_bodies"`
-	Stream          StreamConfig `json:"stream" yaml:"stream"`
+	Payload         string                     `json:"payload" yaml:"payload"`
+	DropEmptyBodies bool                       `json:"drop_empty_bodies" yaml:"drop_empty_bodies"`
+	Stream          StreamConfig               `json:"stream" yaml:"stream"`
+	Pagination      HTTPClientPaginationConfig `json:"pagination" yaml:"pagination"`
 }
 
 // NewHTTPClientConfig creates a new HTTPClientConfig with default values.
@@ -97,6 +139,7 @@ func NewHTTPClientConfig() HTTPClientConfig {
 			MaxBuffer: 1000000,
 			Delim:     "",
 		},
+		Pagination: NewHTTPClientPaginationConfig(),
 	}
 }
 
@@ -125,6 +168,10 @@ func NewHTTPClient(conf Config, mgr types.Manager, log log.Modular, stats metric
 		h.payload = message.New([][]byte{[]byte(h.conf.HTTPClient.Payload)})
 	}
 
+	if h.conf.HTTPClient.Stream.Enabled && len(h.conf.HTTPClient.Pagination.Mapping) > 0 {
+		return nil, errors.New("pagination cannot be combined with streaming mode")
+	}
+
 	var err error
 	if h.client, err = client.New(
 		h.conf.HTTPClient.Config,
@@ -135,6 +182,17 @@ func NewHTTPClient(conf Config, mgr types.Manager, log log.Modular, stats metric
 		return nil, err
 	}
 
+	if len(h.conf.HTTPClient.Pagination.Mapping) > 0 {
+		hp, err := newHTTPClientPaginator(
+			h.conf.HTTPClient.Pagination, h.payload, h.client,
+			h.conf.HTTPClient.DropEmptyBodies, mgr, log,
+		)
+		if err != nil {
+			return nil, err
+		}
+		return NewAsyncReader(TypeHTTPClient, true, reader.NewAsyncPreserver(hp), log, stats)
+	}
+
 	if !h.conf.HTTPClient.Stream.Enabled {
 		hc, err := reader.NewHTTPClient(
 			h.payload, h.client,
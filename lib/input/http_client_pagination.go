@@ -0,0 +1,307 @@
+package input
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
+	"github.com/Jeffail/benthos/v3/internal/checkpoint"
+	"github.com/Jeffail/benthos/v3/lib/input/reader"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/http/client"
+)
+
+//------------------------------------------------------------------------------
+
+// HTTPClientPaginationConfig contains fields for walking paginated APIs by
+// deriving each request from the response of the previous one.
+type HTTPClientPaginationConfig struct {
+	Mapping  string `json:"mapping" yaml:"mapping"`
+	Cache    string `json:"cache" yaml:"cache"`
+	CacheKey string `json:"cache_key" yaml:"cache_key"`
+}
+
+// NewHTTPClientPaginationConfig creates a new HTTPClientPaginationConfig with
+// default values.
+func NewHTTPClientPaginationConfig() HTTPClientPaginationConfig {
+	return HTTPClientPaginationConfig{
+		Mapping:  "",
+		Cache:    "",
+		CacheKey: "http_client_pagination",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// paginationRequest describes the next request to make when walking a
+// paginated API. An empty URL indicates that the URL of the client config is
+// used, and a nil body indicates that the configured payload is used.
+type paginationRequest struct {
+	URL  string  `json:"url,omitempty"`
+	Body *string `json:"body,omitempty"`
+}
+
+type httpClientPaginator struct {
+	client          *client.Type
+	payload         types.Message
+	mapping         *mapping.Executor
+	dropEmptyBodies bool
+
+	cache    types.Cache
+	cacheKey string
+
+	// The next request to make, nil once pagination is complete.
+	next   *paginationRequest
+	loaded bool
+
+	// Tracks the requests derived from each page that is yet to be
+	// acknowledged, keyed by sequence number, so that a request is only
+	// persisted once all prior pages have been delivered.
+	cMut         sync.Mutex
+	checkpointer *checkpoint.Capped
+	nextSeq      int
+	pending      map[int]*paginationRequest
+
+	log log.Modular
+}
+
+func newHTTPClientPaginator(
+	conf HTTPClientPaginationConfig,
+	payload types.Message,
+	httpClient *client.Type,
+	dropEmptyBodies bool,
+	mgr types.Manager,
+	log log.Modular,
+) (*httpClientPaginator, error) {
+	h := &httpClientPaginator{
+		client:          httpClient,
+		payload:         payload,
+		dropEmptyBodies: dropEmptyBodies,
+		cacheKey:        conf.CacheKey,
+		next:            &paginationRequest{},
+		checkpointer:    checkpoint.NewCapped(1024),
+		nextSeq:         1,
+		pending:         map[int]*paginationRequest{},
+		log:             log,
+	}
+
+	var err error
+	if h.mapping, err = bloblang.NewMapping("", conf.Mapping); err != nil {
+		return nil, fmt.Errorf("failed to parse pagination mapping: %w", err)
+	}
+	if len(conf.Cache) > 0 {
+		if len(conf.CacheKey) == 0 {
+			return nil, errors.New("a pagination cache_key must be specified when a cache is used")
+		}
+		if h.cache, err = mgr.GetCache(conf.Cache); err != nil {
+			return nil, fmt.Errorf("failed to obtain pagination cache '%v': %v", conf.Cache, err)
+		}
+	} else {
+		h.loaded = true
+	}
+	return h, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ConnectWithContext loads the last persisted request from the cache, if any,
+// so that pagination resumes from where it left off.
+func (h *httpClientPaginator) ConnectWithContext(ctx context.Context) error {
+	if h.loaded {
+		return nil
+	}
+
+	reqBytes, err := h.cache.Get(h.cacheKey)
+	if err != nil {
+		if errors.Is(err, types.ErrKeyNotFound) {
+			h.loaded = true
+			return nil
+		}
+		return fmt.Errorf("failed to read pagination cursor: %w", err)
+	}
+
+	var req paginationRequest
+	if err := json.Unmarshal(reqBytes, &req); err != nil {
+		return fmt.Errorf("failed to parse pagination cursor: %w", err)
+	}
+	h.log.Infof("Resuming pagination from persisted request to '%v'\n", req.URL)
+
+	h.next = &req
+	h.loaded = true
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+func (h *httpClientPaginator) requestMessage(req *paginationRequest) types.Message {
+	if req.Body == nil {
+		return h.payload
+	}
+	return message.New([][]byte{[]byte(*req.Body)})
+}
+
+// nextRequest executes the pagination mapping against a response in order to
+// determine the next request, or returns nil if pagination is complete.
+func (h *httpClientPaginator) nextRequest(res *http.Response, msg types.Message) (*paginationRequest, error) {
+	var part types.Part = message.NewPart(nil)
+	if msg.Len() > 0 {
+		part = msg.Get(0).Copy()
+	}
+	meta := part.Metadata()
+	for k, values := range res.Header {
+		if len(values) > 0 {
+			meta.Set(strings.ToLower(k), values[0])
+		}
+	}
+	if res.Request != nil && res.Request.URL != nil {
+		meta.Set("http_request_url", res.Request.URL.String())
+	}
+
+	refMsg := message.New(nil)
+	refMsg.Append(part)
+
+	mapped, err := h.mapping.MapPart(0, refMsg)
+	if err != nil {
+		return nil, err
+	}
+	if mapped == nil {
+		return nil, nil
+	}
+
+	jObj, err := mapped.JSON()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse mapping result: %w", err)
+	}
+	obj, ok := jObj.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected mapping to result in an object, got %T", jObj)
+	}
+
+	var req paginationRequest
+	if v, exists := obj["url"]; exists {
+		if req.URL, ok = v.(string); !ok {
+			return nil, fmt.Errorf("expected url to be a string, got %T", v)
+		}
+	}
+	if v, exists := obj["body"]; exists {
+		var body string
+		if body, ok = v.(string); !ok {
+			bodyBytes, err := json.Marshal(v)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal body: %w", err)
+			}
+			body = string(bodyBytes)
+		}
+		req.Body = &body
+	}
+	return &req, nil
+}
+
+// commit resolves the page of a sequence number and, if the page and all
+// prior pages are delivered, persists the request that follows it.
+func (h *httpClientPaginator) commit(seq int) error {
+	h.cMut.Lock()
+	defer h.cMut.Unlock()
+
+	highest, err := h.checkpointer.Resolve(seq)
+	if err != nil {
+		return err
+	}
+
+	req, exists := h.pending[highest]
+	for s := range h.pending {
+		if s <= highest {
+			delete(h.pending, s)
+		}
+	}
+	if !exists || req == nil || h.cache == nil {
+		return nil
+	}
+
+	reqBytes, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	if err = h.cache.Set(h.cacheKey, reqBytes); err != nil {
+		h.log.Errorf("Failed to persist pagination cursor: %v\n", err)
+	}
+	return err
+}
+
+// ReadWithContext requests the next page of a paginated API.
+func (h *httpClientPaginator) ReadWithContext(ctx context.Context) (types.Message, reader.AsyncAckFn, error) {
+	if h.next == nil {
+		return nil, nil, types.ErrTypeClosed
+	}
+
+	var res *http.Response
+	var err error
+	if len(h.next.URL) > 0 {
+		res, err = h.client.DoURLWithContext(ctx, h.next.URL, h.requestMessage(h.next))
+	} else {
+		res, err = h.client.DoWithContext(ctx, h.requestMessage(h.next))
+	}
+	if err != nil {
+		if strings.Contains(err.Error(), "(Client.Timeout exceeded while awaiting headers)") {
+			err = types.ErrTimeout
+		}
+		return nil, nil, err
+	}
+
+	var msg types.Message
+	if msg, err = h.client.ParseResponse(res); err != nil {
+		return nil, nil, err
+	}
+
+	next, err := h.nextRequest(res, msg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("pagination mapping failed: %w", err)
+	}
+
+	h.cMut.Lock()
+	seq := h.nextSeq
+	h.nextSeq++
+	h.pending[seq] = next
+	h.cMut.Unlock()
+
+	if err = h.checkpointer.Track(ctx, seq); err != nil {
+		return nil, nil, err
+	}
+	h.next = next
+	if next == nil {
+		h.log.Infoln("Pagination complete")
+	}
+
+	if msg.Len() == 0 || (msg.Len() == 1 && msg.Get(0).IsEmpty() && h.dropEmptyBodies) {
+		if err = h.commit(seq); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, types.ErrTimeout
+	}
+
+	return msg, func(ctx context.Context, res types.Response) error {
+		if res.Error() != nil {
+			return nil
+		}
+		return h.commit(seq)
+	}, nil
+}
+
+// CloseAsync shuts down the paginator and stops processing requests.
+func (h *httpClientPaginator) CloseAsync() {
+	h.client.CloseAsync()
+}
+
+// WaitForClose blocks until the paginator has closed down.
+func (h *httpClientPaginator) WaitForClose(timeout time.Duration) error {
+	return nil
+}
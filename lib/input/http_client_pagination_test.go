@@ -0,0 +1,287 @@
+package input
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/cache"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/http/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type paginationCacheMgr struct {
+	types.Manager
+	caches map[string]types.Cache
+}
+
+func (m paginationCacheMgr) GetCache(name string) (types.Cache, error) {
+	if c, exists := m.caches[name]; exists {
+		return c, nil
+	}
+	return nil, types.ErrCacheNotFound
+}
+
+type paginatedServer struct {
+	*httptest.Server
+
+	mut  sync.Mutex
+	reqs []string
+}
+
+// newPaginatedServer serves pages of the form {"items":[...],"next":"..."}
+// where the cursor is the index of the next page.
+func newPaginatedServer(t *testing.T, pages [][]string) *paginatedServer {
+	t.Helper()
+
+	s := &paginatedServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mut.Lock()
+		s.reqs = append(s.reqs, r.URL.RequestURI())
+		s.mut.Unlock()
+
+		var page int
+		if c := r.URL.Query().Get("cursor"); len(c) > 0 {
+			fmt.Sscanf(c, "%d", &page)
+		}
+		next := "null"
+		if page+1 < len(pages) {
+			next = fmt.Sprintf(`"%v"`, page+1)
+		}
+		items := ""
+		for i, item := range pages[page] {
+			if i > 0 {
+				items += ","
+			}
+			items += fmt.Sprintf(`"%v"`, item)
+		}
+		w.Header().Set("X-Page", fmt.Sprintf("%v", page))
+		fmt.Fprintf(w, `{"items":[%v],"next":%v}`, items, next)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *paginatedServer) requests() []string {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return append([]string(nil), s.reqs...)
+}
+
+func readPage(t *testing.T, in Type, res types.Response) string {
+	t.Helper()
+
+	var tran types.Transaction
+	var open bool
+	select {
+	case tran, open = <-in.TransactionChan():
+		require.True(t, open)
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out")
+	}
+	select {
+	case tran.ResponseChan <- res:
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out")
+	}
+	return string(tran.Payload.Get(0).Get())
+}
+
+func paginationConf(url string) Config {
+	conf := NewConfig()
+	conf.Type = TypeHTTPClient
+	conf.HTTPClient.URL = url + "/items"
+	conf.HTTPClient.Retry = "1ms"
+	conf.HTTPClient.Pagination.Mapping = fmt.Sprintf(`
+root = if this.next != null {
+  { "url": "%v/items?cursor=" + this.next.escape_url_query() }
+} else {
+  deleted()
+}`, url)
+	return conf
+}
+
+func TestHTTPClientPagination(t *testing.T) {
+	s := newPaginatedServer(t, [][]string{{"a", "b"}, {"c"}, {"d"}})
+
+	in, err := New(paginationConf(s.URL), nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	assert.Equal(t, `{"items":["a","b"],"next":"1"}`, readPage(t, in, response.NewAck()))
+	assert.Equal(t, `{"items":["c"],"next":"2"}`, readPage(t, in, response.NewAck()))
+	assert.Equal(t, `{"items":["d"],"next":null}`, readPage(t, in, response.NewAck()))
+
+	// The input closes once pagination is complete.
+	select {
+	case _, open := <-in.TransactionChan():
+		assert.False(t, open)
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out")
+	}
+	require.NoError(t, in.WaitForClose(time.Second*5))
+
+	assert.Equal(t, []string{"/items", "/items?cursor=1", "/items?cursor=2"}, s.requests())
+}
+
+func TestHTTPClientPaginationHeadersAndBody(t *testing.T) {
+	var bodies []string
+	var bodiesMut sync.Mutex
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqBytes, _ := ioutil.ReadAll(r.Body)
+		bodiesMut.Lock()
+		bodies = append(bodies, string(reqBytes))
+		bodiesMut.Unlock()
+
+		if string(reqBytes) == `{"page":1}` {
+			w.Write([]byte("second"))
+			return
+		}
+		w.Header().Set("X-Next-Page", "1")
+		w.Write([]byte("first"))
+	}))
+	defer ts.Close()
+
+	conf := NewConfig()
+	conf.Type = TypeHTTPClient
+	conf.HTTPClient.URL = ts.URL
+	conf.HTTPClient.Verb = "POST"
+	conf.HTTPClient.Payload = `{"page":0}`
+	conf.HTTPClient.Retry = "1ms"
+	conf.HTTPClient.Pagination.Mapping = `
+root = if (meta("x-next-page") | "") != "" && meta("http_status_code") == "200" {
+  { "body": { "page": meta("x-next-page").number() } }
+} else {
+  deleted()
+}`
+
+	in, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		in.CloseAsync()
+		assert.NoError(t, in.WaitForClose(time.Second*5))
+	})
+
+	assert.Equal(t, "first", readPage(t, in, response.NewAck()))
+	assert.Equal(t, "second", readPage(t, in, response.NewAck()))
+
+	bodiesMut.Lock()
+	assert.Equal(t, []string{`{"page":0}`, `{"page":1}`}, bodies)
+	bodiesMut.Unlock()
+}
+
+func TestHTTPClientPaginationResume(t *testing.T) {
+	s := newPaginatedServer(t, [][]string{{"a"}, {"b"}, {"c"}})
+
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	mgr := paginationCacheMgr{
+		Manager: types.NoopMgr(),
+		caches:  map[string]types.Cache{"foo": memCache},
+	}
+
+	conf := paginationConf(s.URL)
+	conf.HTTPClient.Pagination.Cache = "foo"
+
+	in, err := New(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	assert.Equal(t, `{"items":["a"],"next":"1"}`, readPage(t, in, response.NewAck()))
+	assert.Eventually(t, func() bool {
+		v, err := memCache.Get("http_client_pagination")
+		return err == nil && string(v) == fmt.Sprintf(`{"url":"%v/items?cursor=1"}`, s.URL)
+	}, time.Second*5, time.Millisecond*10)
+
+	in.CloseAsync()
+	require.NoError(t, in.WaitForClose(time.Second*5))
+
+	in, err = New(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		in.CloseAsync()
+		assert.NoError(t, in.WaitForClose(time.Second*5))
+	})
+
+	assert.Equal(t, `{"items":["b"],"next":"2"}`, readPage(t, in, response.NewAck()))
+	assert.Equal(t, `{"items":["c"],"next":null}`, readPage(t, in, response.NewAck()))
+
+	// The first page is never requested again after a restart.
+	reqs := s.requests()
+	assert.Equal(t, "/items", reqs[0])
+	assert.NotContains(t, reqs[1:], "/items")
+}
+
+func TestHTTPClientPaginationCommitOrdering(t *testing.T) {
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	mgr := paginationCacheMgr{
+		Manager: types.NoopMgr(),
+		caches:  map[string]types.Cache{"foo": memCache},
+	}
+
+	s := newPaginatedServer(t, [][]string{{"a"}, {"b"}, {"c"}})
+	conf := paginationConf(s.URL)
+	conf.HTTPClient.Pagination.Cache = "foo"
+
+	httpClient, err := client.New(conf.HTTPClient.Config)
+	require.NoError(t, err)
+
+	p, err := newHTTPClientPaginator(conf.HTTPClient.Pagination, nil, httpClient, true, mgr, log.Noop())
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	require.NoError(t, p.ConnectWithContext(ctx))
+
+	_, ackFirst, err := p.ReadWithContext(ctx)
+	require.NoError(t, err)
+	_, ackSecond, err := p.ReadWithContext(ctx)
+	require.NoError(t, err)
+
+	cursor := func() string {
+		v, _ := memCache.Get("http_client_pagination")
+		return string(v)
+	}
+
+	require.NoError(t, ackSecond(ctx, response.NewAck()))
+	assert.Equal(t, "", cursor())
+
+	require.NoError(t, ackFirst(ctx, response.NewError(fmt.Errorf("nope"))))
+	assert.Equal(t, "", cursor())
+
+	require.NoError(t, ackFirst(ctx, response.NewAck()))
+	assert.Equal(t, fmt.Sprintf(`{"url":"%v/items?cursor=2"}`, s.URL), cursor())
+}
+
+func TestHTTPClientPaginationConfigErrors(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeHTTPClient
+	conf.HTTPClient.Pagination.Mapping = `root.url = "foo"`
+	conf.HTTPClient.Stream.Enabled = true
+	_, err := New(conf, nil, log.Noop(), metrics.Noop())
+	assert.EqualError(t, err, "failed to create input 'http_client': pagination cannot be combined with streaming mode")
+
+	conf = NewConfig()
+	conf.Type = TypeHTTPClient
+	conf.HTTPClient.Pagination.Mapping = `root.url = `
+	_, err = New(conf, nil, log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+
+	conf = NewConfig()
+	conf.Type = TypeHTTPClient
+	conf.HTTPClient.Pagination.Mapping = `root.url = "foo"`
+	conf.HTTPClient.Pagination.Cache = "nope"
+	_, err = New(conf, types.NoopMgr(), log.Noop(), metrics.Noop())
+	assert.EqualError(t, err, "failed to create input 'http_client': failed to obtain pagination cache 'nope': cache not found")
+}
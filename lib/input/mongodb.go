@@ -0,0 +1,423 @@
+package input
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/checkpoint"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/service/mongodb"
+	"github.com/Jeffail/benthos/v3/lib/input/reader"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeMongoDB] = TypeSpec{
+		constructor: fromSimpleConstructor(func(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+			r, err := newMongoDBReader(conf.MongoDB, mgr, log, stats)
+			if err != nil {
+				return nil, err
+			}
+			return NewAsyncReader(TypeMongoDB, false, reader.NewAsyncPreserver(r), log, stats)
+		}),
+		Status:  docs.StatusExperimental,
+		Version: "3.41.0",
+		Summary: `
+Streams the changes of a MongoDB collection or database by consuming a
+[change stream](https://docs.mongodb.com/manual/changeStreams/).`,
+		Description: `
+Each change event is emitted as a message containing the event document
+marshalled as
+[extended JSON](https://docs.mongodb.com/manual/reference/mongodb-extended-json/)
+according to ` + "`json_marshal_mode`" + `. When a ` + "`collection`" + ` is
+not specified the changes of all collections within the database are consumed.
+
+Change streams are only available on replica sets and sharded clusters.
+
+### Delivery Guarantees
+
+When a ` + "`cache`" + ` is configured the resume token of the stream is
+persisted to it under the key ` + "`cache_key`" + `, and is only advanced to an
+event once it, and all prior events, have been acknowledged by the output. When
+the input restarts it resumes after the persisted token, and therefore messages
+might be duplicated but are never lost. Without a cache the input starts from
+the current position of the stream each time it connects.
+
+The number of unacknowledged messages held at a given time is limited by
+` + "`checkpoint_limit`" + `.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "```text" + `
+- mongodb_operation_type
+- mongodb_database
+- mongodb_collection
+` + "```" + `
+
+You can access these metadata fields using
+[function interpolation](/docs/configuration/interpolation#metadata).`,
+		FieldSpecs: mongodb.ConfigDocs().Add(
+			docs.FieldCommon("collection", "An optional collection to consume the changes of. When empty the changes of all collections within the database are consumed."),
+			docs.FieldCommon("cache", "An optional [cache resource](/docs/components/caches/about) to persist the resume token of the stream with."),
+			docs.FieldAdvanced("cache_key", "The key under which the resume token is persisted within the cache."),
+			docs.FieldAdvanced("full_document", "Whether update events should include a copy of the full document as it is at the time the event is read.").HasOptions("default", "updateLookup"),
+			docs.FieldAdvanced("json_marshal_mode", "The [extended JSON](https://docs.mongodb.com/manual/reference/mongodb-extended-json/) mode used to marshal change events.").HasOptions(mongodb.MarshalModes...),
+			docs.FieldAdvanced("checkpoint_limit", "The maximum number of messages that can be processed at a given time. Increasing this limit enables parallel processing and batching at the output level. The resume token is never advanced beyond a message that has not been delivered in order to preserve at least once delivery guarantees."),
+		),
+		Categories: []Category{
+			CategoryServices,
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// MongoDBConfig contains configuration fields for the MongoDB input type.
+type MongoDBConfig struct {
+	mongodb.Config  `json:",inline" yaml:",inline"`
+	Collection      string `json:"collection" yaml:"collection"`
+	Cache           string `json:"cache" yaml:"cache"`
+	CacheKey        string `json:"cache_key" yaml:"cache_key"`
+	FullDocument    string `json:"full_document" yaml:"full_document"`
+	JSONMarshalMode string `json:"json_marshal_mode" yaml:"json_marshal_mode"`
+	CheckpointLimit int    `json:"checkpoint_limit" yaml:"checkpoint_limit"`
+}
+
+// NewMongoDBConfig creates a new MongoDBConfig with default values.
+func NewMongoDBConfig() MongoDBConfig {
+	return MongoDBConfig{
+		Config:          mongodb.NewConfig(),
+		Collection:      "",
+		Cache:           "",
+		CacheKey:        "mongodb_resume_token",
+		FullDocument:    "default",
+		JSONMarshalMode: "relaxed",
+		CheckpointLimit: 1024,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type mongoDBReader struct {
+	conf  MongoDBConfig
+	cache types.Cache
+
+	cMut          sync.Mutex
+	msgChan       chan asyncMessage
+	streamCloseFn context.CancelFunc
+	streamDoneCtx context.Context
+
+	log   log.Modular
+	stats metrics.Type
+
+	closeOnce  sync.Once
+	closedChan chan struct{}
+}
+
+func newMongoDBReader(conf MongoDBConfig, mgr types.Manager, log log.Modular, stats metrics.Type) (*mongoDBReader, error) {
+	if _, err := conf.Config.Client(); err != nil {
+		return nil, err
+	}
+	if conf.CheckpointLimit < 1 {
+		return nil, fmt.Errorf("checkpoint_limit must be greater than zero, got: %v", conf.CheckpointLimit)
+	}
+	if conf.FullDocument != "default" && conf.FullDocument != "updateLookup" {
+		return nil, fmt.Errorf("full_document option %v was not recognised", conf.FullDocument)
+	}
+	if conf.JSONMarshalMode != "canonical" && conf.JSONMarshalMode != "relaxed" {
+		return nil, fmt.Errorf("json_marshal_mode %v was not recognised", conf.JSONMarshalMode)
+	}
+
+	m := &mongoDBReader{
+		conf:       conf,
+		log:        log,
+		stats:      stats,
+		closedChan: make(chan struct{}),
+	}
+	if len(conf.Cache) > 0 {
+		if len(conf.CacheKey) == 0 {
+			return nil, errors.New("a cache_key must be specified when a cache is used")
+		}
+		var err error
+		if m.cache, err = mgr.GetCache(conf.Cache); err != nil {
+			return nil, fmt.Errorf("failed to obtain cache '%v': %v", conf.Cache, err)
+		}
+	}
+	return m, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ConnectWithContext connects to the deployment and opens a change stream,
+// resuming after the persisted resume token if there is one.
+func (m *mongoDBReader) ConnectWithContext(ctx context.Context) error {
+	m.cMut.Lock()
+	defer m.cMut.Unlock()
+
+	if m.msgChan != nil {
+		return nil
+	}
+
+	opts := options.ChangeStream().SetFullDocument(options.FullDocument(m.conf.FullDocument))
+	if m.cache != nil {
+		tokenBytes, err := m.cache.Get(m.conf.CacheKey)
+		if err == nil {
+			var token bson.D
+			if err = bson.UnmarshalExtJSON(tokenBytes, true, &token); err != nil {
+				return fmt.Errorf("failed to parse persisted resume token: %v", err)
+			}
+			opts.SetResumeAfter(token)
+		} else if err != types.ErrKeyNotFound {
+			return fmt.Errorf("failed to read persisted resume token: %v", err)
+		}
+	}
+
+	client, db, err := m.conf.Config.Connect(ctx)
+	if err != nil {
+		return err
+	}
+
+	var stream *mongo.ChangeStream
+	if len(m.conf.Collection) > 0 {
+		stream, err = db.Collection(m.conf.Collection).Watch(ctx, mongo.Pipeline{}, opts)
+	} else {
+		stream, err = db.Watch(ctx, mongo.Pipeline{}, opts)
+	}
+	if err != nil {
+		_ = client.Disconnect(context.Background())
+		return fmt.Errorf("failed to open change stream: %w", err)
+	}
+
+	changes := &mongoChangeStream{
+		checkpointer: checkpoint.NewCapped(m.conf.CheckpointLimit),
+		nextSeq:      1,
+		marshalMode:  m.conf.JSONMarshalMode,
+		cache:        m.cache,
+		cacheKey:     m.conf.CacheKey,
+		log:          m.log,
+	}
+
+	msgChan := make(chan asyncMessage)
+	streamCtx, doneFn := context.WithCancel(context.Background())
+	closeCtx, closeFn := context.WithCancel(context.Background())
+
+	go func() {
+		defer func() {
+			_ = stream.Close(context.Background())
+			_ = client.Disconnect(context.Background())
+			close(msgChan)
+			doneFn()
+			closeFn()
+
+			m.cMut.Lock()
+			if m.msgChan == msgChan {
+				m.msgChan = nil
+			}
+			m.cMut.Unlock()
+		}()
+
+		if err := changes.run(closeCtx, stream, msgChan); err != nil {
+			m.log.Errorf("Change stream failed: %v\n", err)
+		}
+	}()
+
+	m.msgChan = msgChan
+	m.streamCloseFn = closeFn
+	m.streamDoneCtx = streamCtx
+
+	m.log.Infof("Consuming changes from MongoDB database: %v\n", m.conf.Database)
+	return nil
+}
+
+// ReadWithContext attempts to read a new change event.
+func (m *mongoDBReader) ReadWithContext(ctx context.Context) (types.Message, reader.AsyncAckFn, error) {
+	m.cMut.Lock()
+	msgChan := m.msgChan
+	m.cMut.Unlock()
+
+	if msgChan == nil {
+		return nil, nil, types.ErrNotConnected
+	}
+
+	select {
+	case msg, open := <-msgChan:
+		if !open {
+			return nil, nil, types.ErrNotConnected
+		}
+		return msg.msg, msg.ackFn, nil
+	case <-ctx.Done():
+	}
+	return nil, nil, types.ErrTimeout
+}
+
+func (m *mongoDBReader) closeStream() {
+	m.cMut.Lock()
+	streamCloseFn := m.streamCloseFn
+	streamDoneCtx := m.streamDoneCtx
+	m.cMut.Unlock()
+
+	if streamCloseFn != nil {
+		streamCloseFn()
+		<-streamDoneCtx.Done()
+	}
+
+	m.closeOnce.Do(func() {
+		close(m.closedChan)
+	})
+}
+
+// CloseAsync shuts down the input and stops processing requests.
+func (m *mongoDBReader) CloseAsync() {
+	go m.closeStream()
+}
+
+// WaitForClose blocks until the input has closed down.
+func (m *mongoDBReader) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-m.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+type pendingResumeToken struct {
+	seq   int
+	token bson.Raw
+}
+
+// mongoChangeStream consumes the events of a change stream and persists the
+// resume token of the stream as the events are acknowledged.
+type mongoChangeStream struct {
+	marshalMode string
+
+	// Each event is tracked with a sequence number, and the resume token of an
+	// event is persisted once its sequence number is resolved.
+	checkpointer *checkpoint.Capped
+	nextSeq      int
+
+	tokensMut sync.Mutex
+	tokens    []pendingResumeToken
+
+	cache    types.Cache
+	cacheKey string
+
+	log log.Modular
+}
+
+func (s *mongoChangeStream) run(ctx context.Context, stream *mongo.ChangeStream, msgChan chan<- asyncMessage) error {
+	for stream.Next(ctx) {
+		msg, err := changeEventMessage(stream.Current, s.marshalMode)
+		if err != nil {
+			return err
+		}
+		seq, err := s.track(ctx, append(bson.Raw(nil), stream.ResumeToken()...))
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		select {
+		case msgChan <- asyncMessage{
+			msg: msg,
+			ackFn: func(context.Context, types.Response) error {
+				return s.resolve(seq)
+			},
+		}:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	return stream.Err()
+}
+
+func (s *mongoChangeStream) track(ctx context.Context, token bson.Raw) (int, error) {
+	if err := s.checkpointer.Track(ctx, s.nextSeq); err != nil {
+		return 0, err
+	}
+	seq := s.nextSeq
+	s.nextSeq++
+
+	s.tokensMut.Lock()
+	s.tokens = append(s.tokens, pendingResumeToken{seq: seq, token: token})
+	s.tokensMut.Unlock()
+	return seq, nil
+}
+
+// resolve a sequence number and persist the resume token of the highest event
+// that is now fully delivered.
+func (s *mongoChangeStream) resolve(seq int) error {
+	highest, err := s.checkpointer.Resolve(seq)
+	if err != nil {
+		return err
+	}
+
+	s.tokensMut.Lock()
+	defer s.tokensMut.Unlock()
+
+	var token bson.Raw
+	i := 0
+	for ; i < len(s.tokens) && s.tokens[i].seq <= highest; i++ {
+		token = s.tokens[i].token
+	}
+	s.tokens = s.tokens[i:]
+	if token == nil || s.cache == nil {
+		return nil
+	}
+
+	tokenBytes, err := bson.MarshalExtJSON(token, true, false)
+	if err != nil {
+		return fmt.Errorf("failed to marshal resume token: %v", err)
+	}
+	if err = s.cache.Set(s.cacheKey, tokenBytes); err != nil {
+		s.log.Errorf("Failed to persist resume token: %v\n", err)
+		return err
+	}
+	return nil
+}
+
+func changeEventMessage(event bson.Raw, marshalMode string) (types.Message, error) {
+	var header struct {
+		OperationType string `bson:"operationType"`
+		NS            struct {
+			DB   string `bson:"db"`
+			Coll string `bson:"coll"`
+		} `bson:"ns"`
+	}
+	if err := bson.Unmarshal(event, &header); err != nil {
+		return nil, fmt.Errorf("failed to decode change event: %v", err)
+	}
+
+	data, err := mongodb.MarshalJSON(event, marshalMode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal change event: %v", err)
+	}
+
+	msg := message.New([][]byte{data})
+	meta := msg.Get(0).Metadata()
+	meta.Set("mongodb_operation_type", header.OperationType)
+	meta.Set("mongodb_database", header.NS.DB)
+	meta.Set("mongodb_collection", header.NS.Coll)
+	return msg, nil
+}
+
+//------------------------------------------------------------------------------
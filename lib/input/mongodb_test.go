@@ -0,0 +1,134 @@
+package input
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/internal/checkpoint"
+	"github.com/Jeffail/benthos/v3/lib/cache"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestMongoDBConfigErrors(t *testing.T) {
+	tests := map[string]struct {
+		conf func(c *MongoDBConfig)
+		err  string
+	}{
+		"no database": {
+			conf: func(c *MongoDBConfig) {
+				c.Database = ""
+			},
+			err: "a database must be specified",
+		},
+		"bad checkpoint limit": {
+			conf: func(c *MongoDBConfig) {
+				c.CheckpointLimit = 0
+			},
+			err: "checkpoint_limit must be greater than zero, got: 0",
+		},
+		"bad full document": {
+			conf: func(c *MongoDBConfig) {
+				c.FullDocument = "nope"
+			},
+			err: "full_document option nope was not recognised",
+		},
+		"bad marshal mode": {
+			conf: func(c *MongoDBConfig) {
+				c.JSONMarshalMode = "nope"
+			},
+			err: "json_marshal_mode nope was not recognised",
+		},
+		"missing cache": {
+			conf: func(c *MongoDBConfig) {
+				c.Cache = "nope"
+			},
+			err: "failed to obtain cache 'nope': cache not found",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			conf := NewMongoDBConfig()
+			conf.Database = "foo"
+			test.conf(&conf)
+
+			_, err := newMongoDBReader(conf, types.NoopMgr(), log.Noop(), metrics.Noop())
+			require.EqualError(t, err, test.err)
+		})
+	}
+}
+
+func TestMongoDBChangeEventMessage(t *testing.T) {
+	event, err := bson.Marshal(bson.D{
+		{Key: "_id", Value: bson.D{{Key: "_data", Value: "token1"}}},
+		{Key: "operationType", Value: "insert"},
+		{Key: "ns", Value: bson.D{{Key: "db", Value: "foo"}, {Key: "coll", Value: "bar"}}},
+		{Key: "fullDocument", Value: bson.D{{Key: "a", Value: int64(5)}}},
+	})
+	require.NoError(t, err)
+
+	msg, err := changeEventMessage(event, "relaxed")
+	require.NoError(t, err)
+	assert.Equal(t, `{"_id":{"_data":"token1"},"operationType":"insert","ns":{"db":"foo","coll":"bar"},"fullDocument":{"a":5}}`, string(msg.Get(0).Get()))
+	assert.Equal(t, "insert", msg.Get(0).Metadata().Get("mongodb_operation_type"))
+	assert.Equal(t, "foo", msg.Get(0).Metadata().Get("mongodb_database"))
+	assert.Equal(t, "bar", msg.Get(0).Metadata().Get("mongodb_collection"))
+
+	msg, err = changeEventMessage(event, "canonical")
+	require.NoError(t, err)
+	assert.Contains(t, string(msg.Get(0).Get()), `"fullDocument":{"a":{"$numberLong":"5"}}`)
+}
+
+func TestMongoDBResumeTokens(t *testing.T) {
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	s := &mongoChangeStream{
+		checkpointer: checkpoint.NewCapped(10),
+		nextSeq:      1,
+		cache:        memCache,
+		cacheKey:     "foo",
+		log:          log.Noop(),
+	}
+
+	token := func(data string) bson.Raw {
+		b, err := bson.Marshal(bson.D{{Key: "_data", Value: data}})
+		require.NoError(t, err)
+		return b
+	}
+
+	ctx := context.Background()
+	var seqs []int
+	for _, data := range []string{"a", "b", "c"} {
+		seq, err := s.track(ctx, token(data))
+		require.NoError(t, err)
+		seqs = append(seqs, seq)
+	}
+
+	// Resolving a later event does not persist a token until all prior events
+	// are resolved.
+	require.NoError(t, s.resolve(seqs[1]))
+	_, err = memCache.Get("foo")
+	assert.Equal(t, types.ErrKeyNotFound, err)
+
+	require.NoError(t, s.resolve(seqs[0]))
+	tokenBytes, err := memCache.Get("foo")
+	require.NoError(t, err)
+	assert.Equal(t, `{"_data":"b"}`, string(tokenBytes))
+
+	var parsed bson.D
+	require.NoError(t, bson.UnmarshalExtJSON(tokenBytes, true, &parsed))
+	assert.Equal(t, bson.D{{Key: "_data", Value: "b"}}, parsed)
+
+	require.NoError(t, s.resolve(seqs[2]))
+	tokenBytes, err = memCache.Get("foo")
+	require.NoError(t, err)
+	assert.Equal(t, `{"_data":"c"}`, string(tokenBytes))
+	assert.Empty(t, s.tokens)
+}
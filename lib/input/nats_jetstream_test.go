@@ -0,0 +1,101 @@
+package input
+
+import (
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNATSJetStreamConfigErrors(t *testing.T) {
+	tests := map[string]struct {
+		conf func(c *NATSJetStreamConfig)
+		err  string
+	}{
+		"no subject": {
+			conf: func(c *NATSJetStreamConfig) {
+				c.Subject = ""
+			},
+			err: "a subject must be specified",
+		},
+		"queue without durable": {
+			conf: func(c *NATSJetStreamConfig) {
+				c.Queue = "foo"
+			},
+			err: "a durable name must be specified when consuming as a queue group",
+		},
+		"bad max ack pending": {
+			conf: func(c *NATSJetStreamConfig) {
+				c.MaxAckPending = 0
+			},
+			err: "max_ack_pending must be greater than zero, got: 0",
+		},
+		"bad deliver": {
+			conf: func(c *NATSJetStreamConfig) {
+				c.Deliver = "nope"
+			},
+			err: "deliver option nope was not recognised",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			conf := NewNATSJetStreamConfig()
+			conf.Subject = "foo.bar"
+			test.conf(&conf)
+
+			_, err := newNATSJetStreamReader(conf, log.Noop(), metrics.Noop())
+			require.EqualError(t, err, test.err)
+		})
+	}
+}
+
+func TestNATSJetStreamConfigDeliver(t *testing.T) {
+	for _, deliver := range []string{"all", "last", "new"} {
+		conf := NewNATSJetStreamConfig()
+		conf.Subject = "foo.bar"
+		conf.Durable = "baz"
+		conf.Queue = "buz"
+		conf.Deliver = deliver
+
+		r, err := newNATSJetStreamReader(conf, log.Noop(), metrics.Noop())
+		require.NoError(t, err, deliver)
+		assert.Len(t, r.subOpts, 5, deliver)
+	}
+}
+
+func TestNATSJetStreamMessage(t *testing.T) {
+	nmsg := &nats.Msg{
+		Subject: "foo.bar",
+		Reply:   "$JS.ACK.foostream.fooconsumer.2.10.4.1617000000000000000.7",
+		Header:  nats.Header{},
+		Data:    []byte("hello world"),
+		Sub:     &nats.Subscription{},
+	}
+	nmsg.Header.Set("Baz", "buz")
+
+	msg := natsJetStreamMessage(nmsg)
+	require.Equal(t, 1, msg.Len())
+	assert.Equal(t, "hello world", string(msg.Get(0).Get()))
+
+	meta := map[string]string{}
+	msg.Get(0).Metadata().Iter(func(k, v string) error {
+		meta[k] = v
+		return nil
+	})
+	assert.Equal(t, map[string]string{
+		"Baz":                      "buz",
+		"nats_subject":             "foo.bar",
+		"nats_stream":              "foostream",
+		"nats_consumer":            "fooconsumer",
+		"nats_sequence_stream":     "10",
+		"nats_sequence_consumer":   "4",
+		"nats_num_delivered":       "2",
+		"nats_num_pending":         "7",
+		"nats_timestamp_unix_nano": "1617000000000000000",
+	}, meta)
+}
@@ -0,0 +1,464 @@
+package input
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+//------------------------------------------------------------------------------
+
+// Operations of a row change.
+const (
+	cdcOpInsert   = "insert"
+	cdcOpUpdate   = "update"
+	cdcOpDelete   = "delete"
+	cdcOpTruncate = "truncate"
+)
+
+// cdcChange is a single row change decoded from a logical replication stream.
+type cdcChange struct {
+	Operation string                 `json:"operation"`
+	Schema    string                 `json:"schema"`
+	Table     string                 `json:"table"`
+	Before    map[string]interface{} `json:"before"`
+	After     map[string]interface{} `json:"after"`
+
+	lsn uint64
+	xid uint32
+}
+
+// cdcEvent is the result of decoding a WAL data message, which is either a
+// list of row changes or the commit of a transaction, in which case commitLSN
+// is the position to be confirmed once all prior changes are delivered.
+type cdcEvent struct {
+	changes   []cdcChange
+	commitLSN uint64
+}
+
+// cdcDecoder decodes the WAL data messages of a logical decoding output
+// plugin.
+type cdcDecoder interface {
+	// Plugin returns the name of the output plugin.
+	Plugin() string
+
+	// Options returns the plugin options to start replication with.
+	Options() string
+
+	// Decode a WAL data message starting at a given LSN.
+	Decode(walStart uint64, data []byte) (cdcEvent, error)
+}
+
+func newCDCDecoder(plugin, publication string) (cdcDecoder, error) {
+	switch plugin {
+	case "pgoutput":
+		if len(publication) == 0 {
+			return nil, errors.New("a publication must be specified when using the pgoutput plugin")
+		}
+		return newPGOutputDecoder(publication), nil
+	case "wal2json":
+		return &wal2JSONDecoder{}, nil
+	}
+	return nil, fmt.Errorf("plugin not recognised: %v", plugin)
+}
+
+// formatLSN returns the textual representation of a log sequence number.
+func formatLSN(lsn uint64) string {
+	return fmt.Sprintf("%X/%X", uint32(lsn>>32), uint32(lsn))
+}
+
+//------------------------------------------------------------------------------
+
+// walReader reads the fields of binary protocol messages.
+type walReader struct {
+	data []byte
+	err  error
+}
+
+func (r *walReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if len(r.data) < n {
+		r.err = errors.New("message is truncated")
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *walReader) uint8() uint8 {
+	if b := r.next(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *walReader) uint16() uint16 {
+	if b := r.next(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *walReader) uint32() uint32 {
+	if b := r.next(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *walReader) uint64() uint64 {
+	if b := r.next(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (r *walReader) string() string {
+	if r.err != nil {
+		return ""
+	}
+	i := bytes.IndexByte(r.data, 0)
+	if i < 0 {
+		r.err = errors.New("string is not terminated")
+		return ""
+	}
+	s := string(r.data[:i])
+	r.data = r.data[i+1:]
+	return s
+}
+
+//------------------------------------------------------------------------------
+
+type pgRelationColumn struct {
+	name  string
+	oid   uint32
+	isKey bool
+}
+
+type pgRelation struct {
+	schema  string
+	table   string
+	columns []pgRelationColumn
+}
+
+// pgOutputDecoder decodes version 1 of the protocol of the pgoutput plugin,
+// https://www.postgresql.org/docs/current/protocol-logicalrep-message-formats.html
+type pgOutputDecoder struct {
+	publication string
+	relations   map[uint32]pgRelation
+	xid         uint32
+}
+
+func newPGOutputDecoder(publication string) *pgOutputDecoder {
+	return &pgOutputDecoder{
+		publication: publication,
+		relations:   map[uint32]pgRelation{},
+	}
+}
+
+func (d *pgOutputDecoder) Plugin() string {
+	return "pgoutput"
+}
+
+func (d *pgOutputDecoder) Options() string {
+	return fmt.Sprintf("proto_version '1', publication_names %v", quoteLiteral(quoteIdent(d.publication)))
+}
+
+func (d *pgOutputDecoder) Decode(walStart uint64, data []byte) (cdcEvent, error) {
+	if len(data) == 0 {
+		return cdcEvent{}, errors.New("empty message")
+	}
+
+	r := &walReader{data: data[1:]}
+
+	var event cdcEvent
+	switch data[0] {
+	case 'B':
+		r.uint64() // Final LSN
+		r.uint64() // Commit timestamp
+		d.xid = r.uint32()
+	case 'C':
+		r.uint8()  // Flags
+		r.uint64() // Commit LSN
+		event.commitLSN = r.uint64()
+	case 'R':
+		id := r.uint32()
+		rel := pgRelation{
+			schema: r.string(),
+			table:  r.string(),
+		}
+		r.uint8() // Replica identity
+		n := int(r.uint16())
+		for i := 0; i < n && r.err == nil; i++ {
+			flags := r.uint8()
+			rel.columns = append(rel.columns, pgRelationColumn{
+				isKey: flags&1 == 1,
+				name:  r.string(),
+				oid:   r.uint32(),
+			})
+			r.uint32() // Type modifier
+		}
+		if r.err == nil {
+			d.relations[id] = rel
+		}
+	case 'I', 'U', 'D':
+		change, err := d.decodeChange(data[0], r)
+		if err != nil {
+			return cdcEvent{}, err
+		}
+		change.lsn = walStart
+		event.changes = append(event.changes, change)
+	case 'T':
+		n := int(r.uint32())
+		r.uint8() // Options
+		for i := 0; i < n && r.err == nil; i++ {
+			rel, err := d.relation(r.uint32())
+			if err != nil {
+				return cdcEvent{}, err
+			}
+			event.changes = append(event.changes, cdcChange{
+				Operation: cdcOpTruncate,
+				Schema:    rel.schema,
+				Table:     rel.table,
+				lsn:       walStart,
+				xid:       d.xid,
+			})
+		}
+	}
+	if r.err != nil {
+		return cdcEvent{}, fmt.Errorf("failed to decode message type '%c': %w", data[0], r.err)
+	}
+	return event, nil
+}
+
+func (d *pgOutputDecoder) relation(id uint32) (pgRelation, error) {
+	rel, exists := d.relations[id]
+	if !exists {
+		return rel, fmt.Errorf("relation %v was not described", id)
+	}
+	return rel, nil
+}
+
+func (d *pgOutputDecoder) decodeChange(kind byte, r *walReader) (cdcChange, error) {
+	rel, err := d.relation(r.uint32())
+	if err != nil {
+		return cdcChange{}, err
+	}
+
+	change := cdcChange{
+		Schema: rel.schema,
+		Table:  rel.table,
+		xid:    d.xid,
+	}
+	switch kind {
+	case 'I':
+		change.Operation = cdcOpInsert
+	case 'U':
+		change.Operation = cdcOpUpdate
+	case 'D':
+		change.Operation = cdcOpDelete
+	}
+
+	for r.err == nil && len(r.data) > 0 {
+		switch tupleType := r.uint8(); tupleType {
+		case 'K', 'O':
+			if change.Before, err = decodeTuple(rel, r, tupleType == 'K'); err != nil {
+				return cdcChange{}, err
+			}
+		case 'N':
+			if change.After, err = decodeTuple(rel, r, false); err != nil {
+				return cdcChange{}, err
+			}
+		default:
+			return cdcChange{}, fmt.Errorf("unexpected tuple type '%c'", tupleType)
+		}
+	}
+	return change, r.err
+}
+
+// decodeTuple decodes the columns of a tuple in text format, where unchanged
+// TOASTed values are omitted. When keyOnly is true only the columns that are
+// part of the replica identity are included.
+func decodeTuple(rel pgRelation, r *walReader, keyOnly bool) (map[string]interface{}, error) {
+	n := int(r.uint16())
+	if r.err == nil && n != len(rel.columns) {
+		return nil, fmt.Errorf("tuple of relation %v.%v has %v columns, expected %v", rel.schema, rel.table, n, len(rel.columns))
+	}
+
+	row := make(map[string]interface{}, n)
+	for i := 0; i < n && r.err == nil; i++ {
+		col := rel.columns[i]
+		kind := r.uint8()
+		if r.err != nil {
+			break
+		}
+		switch kind {
+		case 'n':
+			if !keyOnly || col.isKey {
+				row[col.name] = nil
+			}
+		case 'u':
+		case 't':
+			value := r.next(int(r.uint32()))
+			if r.err != nil {
+				break
+			}
+			if !keyOnly || col.isKey {
+				row[col.name] = decodeTextValue(col.oid, value)
+			}
+		default:
+			return nil, fmt.Errorf("unexpected column kind '%c'", kind)
+		}
+	}
+	return row, r.err
+}
+
+// Type OIDs of values that are not represented as strings.
+const (
+	pgOIDBool    = 16
+	pgOIDInt8    = 20
+	pgOIDInt2    = 21
+	pgOIDInt4    = 23
+	pgOIDOID     = 26
+	pgOIDJSON    = 114
+	pgOIDFloat4  = 700
+	pgOIDFloat8  = 701
+	pgOIDNumeric = 1700
+	pgOIDJSONB   = 3802
+)
+
+// decodeTextValue converts the text representation of a value into a JSON
+// equivalent according to its type, falling back to a string.
+func decodeTextValue(oid uint32, value []byte) interface{} {
+	switch oid {
+	case pgOIDBool:
+		return string(value) == "t"
+	case pgOIDInt2, pgOIDInt4, pgOIDInt8, pgOIDOID:
+		if _, err := strconv.ParseInt(string(value), 10, 64); err == nil {
+			return json.Number(value)
+		}
+	case pgOIDFloat4, pgOIDFloat8, pgOIDNumeric:
+		// Special values such as NaN and Infinity are kept as strings.
+		if f, err := strconv.ParseFloat(string(value), 64); err == nil && !math.IsNaN(f) && !math.IsInf(f, 0) {
+			return json.Number(value)
+		}
+	case pgOIDJSON, pgOIDJSONB:
+		if json.Valid(value) {
+			return json.RawMessage(value)
+		}
+	}
+	return string(value)
+}
+
+//------------------------------------------------------------------------------
+
+// wal2JSONDecoder decodes version 2 of the format of the wal2json plugin,
+// where each message is a JSON object describing a single action.
+type wal2JSONDecoder struct {
+	xid uint32
+}
+
+func (d *wal2JSONDecoder) Plugin() string {
+	return "wal2json"
+}
+
+func (d *wal2JSONDecoder) Options() string {
+	return `"format-version" '2', "include-xids" '1', "include-lsn" '1'`
+}
+
+type wal2JSONColumn struct {
+	Name  string          `json:"name"`
+	Value json.RawMessage `json:"value"`
+}
+
+type wal2JSONAction struct {
+	Action   string           `json:"action"`
+	XID      uint32           `json:"xid"`
+	NextLSN  string           `json:"nextlsn"`
+	Schema   string           `json:"schema"`
+	Table    string           `json:"table"`
+	Columns  []wal2JSONColumn `json:"columns"`
+	Identity []wal2JSONColumn `json:"identity"`
+}
+
+func wal2JSONRow(cols []wal2JSONColumn) map[string]interface{} {
+	if cols == nil {
+		return nil
+	}
+	row := make(map[string]interface{}, len(cols))
+	for _, c := range cols {
+		row[c.Name] = c.Value
+	}
+	return row
+}
+
+func (d *wal2JSONDecoder) Decode(walStart uint64, data []byte) (cdcEvent, error) {
+	var action wal2JSONAction
+	if err := json.Unmarshal(data, &action); err != nil {
+		return cdcEvent{}, fmt.Errorf("failed to parse action: %w", err)
+	}
+
+	change := cdcChange{
+		Schema: action.Schema,
+		Table:  action.Table,
+		lsn:    walStart,
+		xid:    d.xid,
+	}
+
+	var event cdcEvent
+	switch action.Action {
+	case "B":
+		d.xid = action.XID
+		return event, nil
+	case "C":
+		lsn, err := parseLSN(action.NextLSN)
+		if err != nil {
+			return cdcEvent{}, fmt.Errorf("failed to parse commit LSN: %w", err)
+		}
+		event.commitLSN = lsn
+		return event, nil
+	case "I":
+		change.Operation = cdcOpInsert
+		change.After = wal2JSONRow(action.Columns)
+	case "U":
+		change.Operation = cdcOpUpdate
+		change.Before = wal2JSONRow(action.Identity)
+		change.After = wal2JSONRow(action.Columns)
+	case "D":
+		change.Operation = cdcOpDelete
+		change.Before = wal2JSONRow(action.Identity)
+	case "T":
+		change.Operation = cdcOpTruncate
+	default:
+		return event, nil
+	}
+	event.changes = append(event.changes, change)
+	return event, nil
+}
+
+// parseLSN parses the textual representation of a log sequence number.
+func parseLSN(s string) (uint64, error) {
+	var upper, lower uint32
+	if _, err := fmt.Sscanf(s, "%X/%X", &upper, &lower); err != nil {
+		return 0, err
+	}
+	return uint64(upper)<<32 | uint64(lower), nil
+}
+
+//------------------------------------------------------------------------------
+
+func quoteIdent(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+func quoteLiteral(s string) string {
+	return `'` + strings.ReplaceAll(s, `'`, `''`) + `'`
+}
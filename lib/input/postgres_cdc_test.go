@@ -0,0 +1,233 @@
+package input
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/checkpoint"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type walBuilder []byte
+
+func (b walBuilder) u8(v uint8) walBuilder {
+	return append(b, v)
+}
+
+func (b walBuilder) u16(v uint16) walBuilder {
+	return append(b, byte(v>>8), byte(v))
+}
+
+func (b walBuilder) u32(v uint32) walBuilder {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+func (b walBuilder) u64(v uint64) walBuilder {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+func (b walBuilder) str(v string) walBuilder {
+	return append(append(b, v...), 0)
+}
+
+func (b walBuilder) text(v string) walBuilder {
+	return append(b.u8('t').u32(uint32(len(v))), v...)
+}
+
+func TestPGOutputDecoder(t *testing.T) {
+	d := newPGOutputDecoder("foo")
+	assert.Equal(t, `proto_version '1', publication_names '"foo"'`, d.Options())
+
+	event, err := d.Decode(1, walBuilder{'B'}.u64(10).u64(0).u32(5))
+	require.NoError(t, err)
+	assert.Equal(t, cdcEvent{}, event)
+
+	rel := walBuilder{'R'}.u32(1).str("public").str("users").u8('d').u16(4).
+		u8(1).str("id").u32(pgOIDInt4).u32(0).
+		u8(0).str("name").u32(25).u32(0).
+		u8(0).str("meta").u32(pgOIDJSONB).u32(0).
+		u8(0).str("active").u32(pgOIDBool).u32(0)
+	_, err = d.Decode(2, rel)
+	require.NoError(t, err)
+
+	event, err = d.Decode(3, walBuilder{'I'}.u32(1).u8('N').u16(4).
+		text("1").text("foo").text(`{"a":[1]}`).u8('n'))
+	require.NoError(t, err)
+	require.Len(t, event.changes, 1)
+	assert.Equal(t, cdcChange{
+		Operation: cdcOpInsert,
+		Schema:    "public",
+		Table:     "users",
+		After: map[string]interface{}{
+			"id":     json.Number("1"),
+			"name":   "foo",
+			"meta":   json.RawMessage(`{"a":[1]}`),
+			"active": nil,
+		},
+		lsn: 3,
+		xid: 5,
+	}, event.changes[0])
+
+	event, err = d.Decode(4, walBuilder{'U'}.u32(1).
+		u8('K').u16(4).text("1").u8('n').u8('n').u8('n').
+		u8('N').u16(4).text("2").text("bar").u8('u').text("t"))
+	require.NoError(t, err)
+	require.Len(t, event.changes, 1)
+	assert.Equal(t, cdcOpUpdate, event.changes[0].Operation)
+	assert.Equal(t, map[string]interface{}{"id": json.Number("1")}, event.changes[0].Before)
+	assert.Equal(t, map[string]interface{}{
+		"id":     json.Number("2"),
+		"name":   "bar",
+		"active": true,
+	}, event.changes[0].After)
+
+	event, err = d.Decode(5, walBuilder{'D'}.u32(1).
+		u8('O').u16(4).text("2").text("bar").u8('n').text("f"))
+	require.NoError(t, err)
+	require.Len(t, event.changes, 1)
+	assert.Equal(t, cdcOpDelete, event.changes[0].Operation)
+	assert.Equal(t, map[string]interface{}{
+		"id":     json.Number("2"),
+		"name":   "bar",
+		"meta":   nil,
+		"active": false,
+	}, event.changes[0].Before)
+	assert.Nil(t, event.changes[0].After)
+
+	event, err = d.Decode(6, walBuilder{'T'}.u32(1).u8(0).u32(1))
+	require.NoError(t, err)
+	require.Len(t, event.changes, 1)
+	assert.Equal(t, cdcOpTruncate, event.changes[0].Operation)
+
+	event, err = d.Decode(7, walBuilder{'C'}.u8(0).u64(10).u64(20).u64(0))
+	require.NoError(t, err)
+	assert.Equal(t, cdcEvent{commitLSN: 20}, event)
+
+	_, err = d.Decode(8, walBuilder{'I'}.u32(2).u8('N').u16(0))
+	assert.EqualError(t, err, "relation 2 was not described")
+
+	_, err = d.Decode(8, walBuilder{'I'}.u32(1).u8('N').u16(4).text("1"))
+	assert.EqualError(t, err, "message is truncated")
+}
+
+func TestDecodeTextValue(t *testing.T) {
+	tests := []struct {
+		oid      uint32
+		value    string
+		expected interface{}
+	}{
+		{oid: pgOIDInt8, value: "9007199254740993", expected: json.Number("9007199254740993")},
+		{oid: pgOIDNumeric, value: "1.50", expected: json.Number("1.50")},
+		{oid: pgOIDNumeric, value: "NaN", expected: "NaN"},
+		{oid: pgOIDFloat8, value: "-Infinity", expected: "-Infinity"},
+		{oid: pgOIDJSON, value: `nope`, expected: "nope"},
+		{oid: 1184, value: "2021-02-01 10:00:00+00", expected: "2021-02-01 10:00:00+00"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, decodeTextValue(test.oid, []byte(test.value)), test.value)
+	}
+}
+
+func TestWAL2JSONDecoder(t *testing.T) {
+	d := &wal2JSONDecoder{}
+
+	event, err := d.Decode(1, []byte(`{"action":"B","xid":7,"lsn":"0/16B2F10","nextlsn":"0/16B2F48"}`))
+	require.NoError(t, err)
+	assert.Equal(t, cdcEvent{}, event)
+
+	event, err = d.Decode(2, []byte(`{"action":"U","xid":7,"schema":"public","table":"users","columns":[{"name":"id","type":"integer","value":1},{"name":"name","type":"text","value":"bar"}],"identity":[{"name":"id","type":"integer","value":1}]}`))
+	require.NoError(t, err)
+	assert.Equal(t, cdcEvent{changes: []cdcChange{{
+		Operation: cdcOpUpdate,
+		Schema:    "public",
+		Table:     "users",
+		Before:    map[string]interface{}{"id": json.RawMessage(`1`)},
+		After:     map[string]interface{}{"id": json.RawMessage(`1`), "name": json.RawMessage(`"bar"`)},
+		lsn:       2,
+		xid:       7,
+	}}}, event)
+
+	event, err = d.Decode(3, []byte(`{"action":"C","xid":7,"lsn":"0/16B2F10","nextlsn":"1/16B2F48"}`))
+	require.NoError(t, err)
+	assert.Equal(t, cdcEvent{commitLSN: 0x1016B2F48}, event)
+
+	_, err = d.Decode(4, []byte(`nope`))
+	assert.Error(t, err)
+}
+
+func TestLSNFormat(t *testing.T) {
+	lsn, err := parseLSN("16/B374D848")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0x16B374D848), lsn)
+	assert.Equal(t, "16/B374D848", formatLSN(lsn))
+}
+
+func TestPostgresCDCConfigErrors(t *testing.T) {
+	conf := NewPostgresCDCConfig()
+	conf.Plugin = "nope"
+	_, err := newPostgresCDCReader(conf, log.Noop(), metrics.Noop())
+	assert.EqualError(t, err, "plugin not recognised: nope")
+
+	conf = NewPostgresCDCConfig()
+	conf.Publication = ""
+	_, err = newPostgresCDCReader(conf, log.Noop(), metrics.Noop())
+	assert.EqualError(t, err, "a publication must be specified when using the pgoutput plugin")
+
+	conf = NewPostgresCDCConfig()
+	conf.Slot = ""
+	_, err = newPostgresCDCReader(conf, log.Noop(), metrics.Noop())
+	assert.EqualError(t, err, "a slot name must be specified")
+}
+
+func TestPostgresCDCConfirmOrdering(t *testing.T) {
+	s := &pgReplicationStream{
+		checkpointer: checkpoint.NewCapped(10),
+		nextSeq:      1,
+		nextStatus:   time.Now().Add(time.Hour),
+		log:          log.Noop(),
+	}
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	msgChan := make(chan asyncMessage, 10)
+	change := cdcChange{Operation: cdcOpInsert, Schema: "public", Table: "foo", After: map[string]interface{}{"id": 1}, lsn: 0x100000010, xid: 3}
+
+	// Two transactions of two and one changes respectively.
+	require.NoError(t, s.handleEvent(ctx, msgChan, cdcEvent{changes: []cdcChange{change, change}}))
+	require.NoError(t, s.handleEvent(ctx, msgChan, cdcEvent{commitLSN: 100}))
+	require.NoError(t, s.handleEvent(ctx, msgChan, cdcEvent{changes: []cdcChange{change}}))
+	require.NoError(t, s.handleEvent(ctx, msgChan, cdcEvent{commitLSN: 200}))
+
+	require.Len(t, msgChan, 3)
+	msgs := []asyncMessage{<-msgChan, <-msgChan, <-msgChan}
+
+	assert.Equal(t, `{"operation":"insert","schema":"public","table":"foo","before":null,"after":{"id":1}}`, string(msgs[0].msg.Get(0).Get()))
+	assert.Equal(t, "1/10", msgs[0].msg.Get(0).Metadata().Get("postgres_lsn"))
+	assert.Equal(t, "3", msgs[0].msg.Get(0).Metadata().Get("postgres_xid"))
+	assert.Equal(t, "foo", msgs[0].msg.Get(0).Metadata().Get("postgres_table"))
+
+	require.NoError(t, msgs[2].ackFn(ctx, response.NewAck()))
+	assert.Equal(t, uint64(0), s.confirmedLSN())
+
+	require.NoError(t, msgs[1].ackFn(ctx, response.NewAck()))
+	assert.Equal(t, uint64(0), s.confirmedLSN())
+
+	require.NoError(t, msgs[0].ackFn(ctx, response.NewAck()))
+	assert.Equal(t, uint64(200), s.confirmedLSN())
+
+	// Empty transactions are confirmed immediately.
+	require.NoError(t, s.handleEvent(ctx, msgChan, cdcEvent{commitLSN: 300}))
+	assert.Equal(t, uint64(300), s.confirmedLSN())
+}
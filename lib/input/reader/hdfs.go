@@ -2,9 +2,13 @@ package reader
 
 import (
 	"context"
+	"errors"
+	"io"
 	"path/filepath"
+	"sync"
 	"time"
 
+	"github.com/Jeffail/benthos/v3/internal/codec"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
@@ -19,6 +23,8 @@ type HDFSConfig struct {
 	Hosts     []string `json:"hosts" yaml:"hosts"`
 	User      string   `json:"user" yaml:"user"`
 	Directory string   `json:"directory" yaml:"directory"`
+	Codec     string   `json:"codec" yaml:"codec"`
+	MaxBuffer int      `json:"max_buffer" yaml:"max_buffer"`
 }
 
 // NewHDFSConfig creates a new Config with default values.
@@ -27,72 +33,138 @@ func NewHDFSConfig() HDFSConfig {
 		Hosts:     []string{"localhost:9000"},
 		User:      "benthos_hdfs",
 		Directory: "",
+		Codec:     "all-bytes",
+		MaxBuffer: 1000000,
 	}
 }
 
 //------------------------------------------------------------------------------
 
-// HDFS is a benthos reader.Type implementation that reads messages from a
-// HDFS directory.
+// HDFS is a benthos reader.Type and reader.Async implementation that reads
+// messages from a HDFS directory.
 type HDFS struct {
 	conf HDFSConfig
 
+	scannerCtor codec.ReaderConstructor
+	codecErr    error
+
 	targets []string
+	client  *hdfs.Client
 
-	client *hdfs.Client
+	scannerMut  sync.Mutex
+	scanner     codec.Reader
+	currentName string
 
 	log   log.Modular
 	stats metrics.Type
 }
 
-// NewHDFS creates a new HDFS writer.Type.
+// NewHDFS creates a new HDFS reader.Type. An invalid codec is reported when
+// attempting to connect, use NewHDFSAsync in order to check it upfront.
 func NewHDFS(
 	conf HDFSConfig,
 	log log.Modular,
 	stats metrics.Type,
 ) *HDFS {
-	return &HDFS{
-		conf:  conf,
-		log:   log,
-		stats: stats,
+	h, err := NewHDFSAsync(conf, log, stats)
+	if err != nil {
+		return &HDFS{
+			conf:     conf,
+			codecErr: err,
+			log:      log,
+			stats:    stats,
+		}
 	}
+	return h
+}
+
+// NewHDFSAsync creates a new HDFS reader.Async, returning an error if the
+// configured codec is invalid.
+func NewHDFSAsync(
+	conf HDFSConfig,
+	log log.Modular,
+	stats metrics.Type,
+) (*HDFS, error) {
+	codecConf := codec.NewReaderConfig()
+	codecConf.MaxScanTokenSize = conf.MaxBuffer
+	ctor, err := codec.GetReader(conf.Codec, codecConf)
+	if err != nil {
+		return nil, err
+	}
+	return &HDFS{
+		conf:        conf,
+		scannerCtor: ctor,
+		log:         log,
+		stats:       stats,
+	}, nil
 }
 
 //------------------------------------------------------------------------------
 
-// Connect attempts to establish a connection to the target HDFS host.
+// Connect attempts to establish a connection to the target HDFS host and opens
+// the next file of the directory to be consumed.
 func (h *HDFS) Connect() error {
 	return h.ConnectWithContext(context.Background())
 }
 
 // ConnectWithContext attempts to establish a connection to the target HDFS
-// host.
+// host and opens the next file of the directory to be consumed.
 func (h *HDFS) ConnectWithContext(ctx context.Context) error {
-	if h.client != nil {
+	h.scannerMut.Lock()
+	defer h.scannerMut.Unlock()
+
+	if h.codecErr != nil {
+		return h.codecErr
+	}
+	if h.scanner != nil {
 		return nil
 	}
 
-	client, err := hdfs.NewClient(hdfs.ClientOptions{
-		Addresses: h.conf.Hosts,
-		User:      h.conf.User,
-	})
-	if err != nil {
-		return err
+	if h.client == nil {
+		client, err := hdfs.NewClient(hdfs.ClientOptions{
+			Addresses: h.conf.Hosts,
+			User:      h.conf.User,
+		})
+		if err != nil {
+			return err
+		}
+
+		targets, err := client.ReadDir(h.conf.Directory)
+		if err != nil {
+			client.Close()
+			return err
+		}
+
+		h.client = client
+		for _, info := range targets {
+			if !info.IsDir() {
+				h.targets = append(h.targets, info.Name())
+			}
+		}
+		h.log.Infof("Receiving files from HDFS directory: %v\n", h.conf.Directory)
+	}
+
+	if len(h.targets) == 0 {
+		return types.ErrTypeClosed
 	}
 
-	h.client = client
-	targets, err := client.ReadDir(h.conf.Directory)
+	fileName := h.targets[0]
+	filePath := filepath.Join(h.conf.Directory, fileName)
+
+	file, err := h.client.Open(filePath)
 	if err != nil {
 		return err
 	}
 
-	for _, info := range targets {
-		if !info.IsDir() {
-			h.targets = append(h.targets, info.Name())
-		}
+	if h.scanner, err = h.scannerCtor(filePath, file, func(ctx context.Context, err error) error {
+		return nil
+	}); err != nil {
+		file.Close()
+		return err
 	}
 
-	h.log.Infof("Receiving files from HDFS directory: %v\n", h.conf.Directory)
+	h.currentName = fileName
+	h.targets = h.targets[1:]
 	return nil
 }
 
@@ -100,32 +172,44 @@ func (h *HDFS) ConnectWithContext(ctx context.Context) error {
 
 // ReadWithContext reads a new HDFS message.
 func (h *HDFS) ReadWithContext(ctx context.Context) (types.Message, AsyncAckFn, error) {
-	msg, err := h.Read()
+	h.scannerMut.Lock()
+	defer h.scannerMut.Unlock()
+
+	if h.scanner == nil {
+		return nil, nil, types.ErrNotConnected
+	}
+
+	part, codecAckFn, err := h.scanner.Next(ctx)
 	if err != nil {
+		if errors.Is(err, context.Canceled) ||
+			errors.Is(err, context.DeadlineExceeded) {
+			err = types.ErrTimeout
+		}
+		if err != types.ErrTimeout {
+			h.scanner.Close(ctx)
+			h.scanner = nil
+		}
+		if errors.Is(err, io.EOF) {
+			err = types.ErrTimeout
+		}
 		return nil, nil, err
 	}
-	return msg, noopAsyncAckFn, nil
-}
 
-// Read a new HDFS message.
-func (h *HDFS) Read() (types.Message, error) {
-	if len(h.targets) == 0 {
-		return nil, types.ErrTypeClosed
-	}
+	part.Metadata().Set("hdfs_name", h.currentName)
+	part.Metadata().Set("hdfs_path", filepath.Join(h.conf.Directory, h.currentName))
 
-	fileName := h.targets[0]
-	h.targets = h.targets[1:]
+	msg := message.New(nil)
+	msg.Append(part)
 
-	filePath := filepath.Join(h.conf.Directory, fileName)
-	msgBytes, readerr := h.client.ReadFile(filePath)
-	if readerr != nil {
-		return nil, readerr
-	}
+	return msg, func(ctx context.Context, res types.Response) error {
+		return codecAckFn(ctx, res.Error())
+	}, nil
+}
 
-	msg := message.New([][]byte{msgBytes})
-	msg.Get(0).Metadata().Set("hdfs_name", fileName)
-	msg.Get(0).Metadata().Set("hdfs_path", filePath)
-	return msg, nil
+// Read a new HDFS message.
+func (h *HDFS) Read() (types.Message, error) {
+	msg, _, err := h.ReadWithContext(context.Background())
+	return msg, err
 }
 
 // Acknowledge instructs whether unacknowledged messages have been successfully
@@ -136,6 +220,19 @@ func (h *HDFS) Acknowledge(err error) error {
 
 // CloseAsync shuts down the HDFS input and stops processing requests.
 func (h *HDFS) CloseAsync() {
+	go func() {
+		h.scannerMut.Lock()
+		if h.scanner != nil {
+			h.scanner.Close(context.Background())
+			h.scanner = nil
+		}
+		h.targets = nil
+		if h.client != nil {
+			h.client.Close()
+			h.client = nil
+		}
+		h.scannerMut.Unlock()
+	}()
 }
 
 // WaitForClose blocks until the HDFS input has closed down.
@@ -0,0 +1,27 @@
+package reader
+
+import (
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ Type  = &HDFS{}
+	_ Async = &HDFS{}
+)
+
+func TestHDFSBadCodec(t *testing.T) {
+	conf := NewHDFSConfig()
+	conf.Directory = "/foo"
+	conf.Codec = "not-a-codec"
+
+	_, err := NewHDFSAsync(conf, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+
+	h := NewHDFS(conf, log.Noop(), metrics.Noop())
+	assert.Equal(t, err, h.Connect())
+}
@@ -436,7 +436,7 @@ func (k *Kafka) commit() error {
 
 	commitReq := sarama.OffsetCommitRequest{}
 	commitReq.ConsumerGroup = k.conf.ConsumerGroup
-	commitReq.AddBlock(k.conf.Topic, k.conf.Partition, k.offset, 0, "")
+	commitReq.AddBlock(k.conf.Topic, k.conf.Partition, k.offset, 0, 0, "")
 
 	commitRes, err := coordinator.CommitOffset(&commitReq)
 	if err == nil {
@@ -6,6 +6,10 @@
 package tracing
 
 import (
+	"net/http"
+	"strings"
+	"sync"
+
 	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/types"
 	"github.com/opentracing/opentracing-go"
@@ -13,6 +17,88 @@ import (
 
 //------------------------------------------------------------------------------
 
+var (
+	propagationKeys    []string
+	propagationKeysMut sync.RWMutex
+)
+
+// SetMetadataPropagation sets a list of metadata keys used for propagating
+// span contexts through messages. When set, spans initialised by inputs are
+// created as children of span contexts extracted from these metadata keys, and
+// outputs inject the span context of each message into them. An empty list
+// disables propagation, which is the default.
+//
+// Span contexts are injected and extracted with the global tracer using the
+// opentracing.HTTPHeaders format, where the keys are lowercase.
+func SetMetadataPropagation(keys ...string) {
+	lowerKeys := make([]string, len(keys))
+	for i, k := range keys {
+		lowerKeys[i] = strings.ToLower(k)
+	}
+	propagationKeysMut.Lock()
+	propagationKeys = lowerKeys
+	propagationKeysMut.Unlock()
+}
+
+func getPropagationKeys() []string {
+	propagationKeysMut.RLock()
+	keys := propagationKeys
+	propagationKeysMut.RUnlock()
+	return keys
+}
+
+// ExtractSpanContext attempts to extract a span context from the metadata of a
+// message part, returns nil if metadata propagation is disabled or the part
+// does not contain a span context.
+func ExtractSpanContext(p types.Part) opentracing.SpanContext {
+	keys := getPropagationKeys()
+	if len(keys) == 0 {
+		return nil
+	}
+	carrier := opentracing.HTTPHeadersCarrier(http.Header{})
+	meta := p.Metadata()
+	for _, k := range keys {
+		if v := meta.Get(k); len(v) > 0 {
+			carrier.Set(k, v)
+		}
+	}
+	spanCtx, err := opentracing.GlobalTracer().Extract(opentracing.HTTPHeaders, carrier)
+	if err != nil {
+		return nil
+	}
+	return spanCtx
+}
+
+// InjectSpans returns a copy of a message where the span context of each span
+// is written into the metadata of the corresponding message part when metadata
+// propagation is enabled, otherwise the message is returned unchanged. The
+// original message is never modified as it may be shared with other outputs.
+// The length of the spans slice is expected to match the message size.
+func InjectSpans(msg types.Message, spans []opentracing.Span) types.Message {
+	if len(getPropagationKeys()) == 0 {
+		return msg
+	}
+	msg = msg.Copy()
+	msg.Iter(func(i int, p types.Part) error {
+		if i >= len(spans) || spans[i] == nil {
+			return nil
+		}
+		carrier := opentracing.HTTPHeadersCarrier(http.Header{})
+		if err := opentracing.GlobalTracer().Inject(spans[i].Context(), opentracing.HTTPHeaders, carrier); err != nil {
+			return nil
+		}
+		for k, v := range carrier {
+			if len(v) > 0 {
+				p.Metadata().Set(strings.ToLower(k), v[0])
+			}
+		}
+		return nil
+	})
+	return msg
+}
+
+//------------------------------------------------------------------------------
+
 // GetSpan returns a span attached to a message part. Returns nil if the part
 // doesn't have a span attached.
 func GetSpan(p types.Part) opentracing.Span {
@@ -131,7 +217,8 @@ func IterateWithChildSpans(operationName string, msg types.Message, iter func(in
 }
 
 // InitSpans sets up OpenTracing spans on each message part if one does not
-// already exist.
+// already exist. When metadata propagation is enabled the spans are children of
+// any span context found within the metadata of each part.
 func InitSpans(operationName string, msg types.Message) {
 	tracedParts := make([]types.Part, msg.Len())
 	msg.Iter(func(i int, p types.Part) error {
@@ -139,7 +226,12 @@ func InitSpans(operationName string, msg types.Message) {
 			tracedParts[i] = p
 			return nil
 		}
-		span := opentracing.StartSpan(operationName)
+		var span opentracing.Span
+		if parent := ExtractSpanContext(p); parent != nil {
+			span = opentracing.StartSpan(operationName, opentracing.ChildOf(parent))
+		} else {
+			span = opentracing.StartSpan(operationName)
+		}
 		ctx := opentracing.ContextWithSpan(message.GetContext(p), span)
 		tracedParts[i] = message.WithContext(ctx, p)
 		return nil
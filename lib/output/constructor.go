@@ -131,6 +131,7 @@ const (
 	TypeBroker             = "broker"
 	TypeCache              = "cache"
 	TypeCassandra          = "cassandra"
+	TypeDeadLetter         = "dead_letter"
 	TypeDrop               = "drop"
 	TypeDropOn             = "drop_on"
 	TypeDropOnError        = "drop_on_error"
@@ -140,6 +141,7 @@ const (
 	TypeFile               = "file"
 	TypeFiles              = "files"
 	TypeGCPPubSub          = "gcp_pubsub"
+	TypeGRPCClient         = "grpc_client"
 	TypeHDFS               = "hdfs"
 	TypeHTTPClient         = "http_client"
 	TypeHTTPServer         = "http_server"
@@ -147,9 +149,11 @@ const (
 	TypeKafka              = "kafka"
 	TypeKinesis            = "kinesis"
 	TypeKinesisFirehose    = "kinesis_firehose"
+	TypeMongoDB            = "mongodb"
 	TypeMQTT               = "mqtt"
 	TypeNanomsg            = "nanomsg"
 	TypeNATS               = "nats"
+	TypeNATSJetStream      = "nats_jetstream"
 	TypeNATSStream         = "nats_stream"
 	TypeNSQ                = "nsq"
 	TypeRedisHash          = "redis_hash"
@@ -163,6 +167,7 @@ const (
 	TypeSFTP               = "sftp"
 	TypeSNS                = "sns"
 	TypeSQL                = "sql"
+	TypeSQLInsert          = "sql_insert"
 	TypeSQS                = "sqs"
 	TypeSTDOUT             = "stdout"
 	TypeSubprocess         = "subprocess"
@@ -198,6 +203,7 @@ type Config struct {
 	Broker             BrokerConfig                   `json:"broker" yaml:"broker"`
 	Cache              writer.CacheConfig             `json:"cache" yaml:"cache"`
 	Cassandra          CassandraConfig                `json:"cassandra" yaml:"cassandra"`
+	DeadLetter         DeadLetterConfig               `json:"dead_letter" yaml:"dead_letter"`
 	Drop               writer.DropConfig              `json:"drop" yaml:"drop"`
 	DropOn             DropOnConfig                   `json:"drop_on" yaml:"drop_on"`
 	DropOnError        DropOnErrorConfig              `json:"drop_on_error" yaml:"drop_on_error"`
@@ -207,6 +213,7 @@ type Config struct {
 	File               FileConfig                     `json:"file" yaml:"file"`
 	Files              writer.FilesConfig             `json:"files" yaml:"files"`
 	GCPPubSub          writer.GCPPubSubConfig         `json:"gcp_pubsub" yaml:"gcp_pubsub"`
+	GRPCClient         GRPCClientConfig               `json:"grpc_client" yaml:"grpc_client"`
 	HDFS               writer.HDFSConfig              `json:"hdfs" yaml:"hdfs"`
 	HTTPClient         writer.HTTPClientConfig        `json:"http_client" yaml:"http_client"`
 	HTTPServer         HTTPServerConfig               `json:"http_server" yaml:"http_server"`
@@ -214,9 +221,11 @@ type Config struct {
 	Kafka              writer.KafkaConfig             `json:"kafka" yaml:"kafka"`
 	Kinesis            writer.KinesisConfig           `json:"kinesis" yaml:"kinesis"`
 	KinesisFirehose    writer.KinesisFirehoseConfig   `json:"kinesis_firehose" yaml:"kinesis_firehose"`
+	MongoDB            MongoDBConfig                  `json:"mongodb" yaml:"mongodb"`
 	MQTT               writer.MQTTConfig              `json:"mqtt" yaml:"mqtt"`
 	Nanomsg            writer.NanomsgConfig           `json:"nanomsg" yaml:"nanomsg"`
 	NATS               writer.NATSConfig              `json:"nats" yaml:"nats"`
+	NATSJetStream      NATSJetStreamConfig            `json:"nats_jetstream" yaml:"nats_jetstream"`
 	NATSStream         writer.NATSStreamConfig        `json:"nats_stream" yaml:"nats_stream"`
 	NSQ                writer.NSQConfig               `json:"nsq" yaml:"nsq"`
 	Plugin             interface{}                    `json:"plugin,omitempty" yaml:"plugin,omitempty"`
@@ -231,6 +240,7 @@ type Config struct {
 	SFTP               SFTPConfig                     `json:"sftp" yaml:"sftp"`
 	SNS                writer.SNSConfig               `json:"sns" yaml:"sns"`
 	SQL                SQLConfig                      `json:"sql" yaml:"sql"`
+	SQLInsert          SQLInsertConfig                `json:"sql_insert" yaml:"sql_insert"`
 	SQS                writer.AmazonSQSConfig         `json:"sqs" yaml:"sqs"`
 	STDOUT             STDOUTConfig                   `json:"stdout" yaml:"stdout"`
 	Subprocess         SubprocessConfig               `json:"subprocess" yaml:"subprocess"`
@@ -266,6 +276,7 @@ func NewConfig() Config {
 		Broker:             NewBrokerConfig(),
 		Cache:              writer.NewCacheConfig(),
 		Cassandra:          NewCassandraConfig(),
+		DeadLetter:         NewDeadLetterConfig(),
 		Drop:               writer.NewDropConfig(),
 		DropOn:             NewDropOnConfig(),
 		DropOnError:        NewDropOnErrorConfig(),
@@ -275,6 +286,7 @@ func NewConfig() Config {
 		File:               NewFileConfig(),
 		Files:              writer.NewFilesConfig(),
 		GCPPubSub:          writer.NewGCPPubSubConfig(),
+		GRPCClient:         NewGRPCClientConfig(),
 		HDFS:               writer.NewHDFSConfig(),
 		HTTPClient:         writer.NewHTTPClientConfig(),
 		HTTPServer:         NewHTTPServerConfig(),
@@ -282,9 +294,11 @@ func NewConfig() Config {
 		Kafka:              writer.NewKafkaConfig(),
 		Kinesis:            writer.NewKinesisConfig(),
 		KinesisFirehose:    writer.NewKinesisFirehoseConfig(),
+		MongoDB:            NewMongoDBConfig(),
 		MQTT:               writer.NewMQTTConfig(),
 		Nanomsg:            writer.NewNanomsgConfig(),
 		NATS:               writer.NewNATSConfig(),
+		NATSJetStream:      NewNATSJetStreamConfig(),
 		NATSStream:         writer.NewNATSStreamConfig(),
 		NSQ:                writer.NewNSQConfig(),
 		Plugin:             nil,
@@ -299,6 +313,7 @@ func NewConfig() Config {
 		SFTP:               NewSFTPConfig(),
 		SNS:                writer.NewSNSConfig(),
 		SQL:                NewSQLConfig(),
+		SQLInsert:          NewSQLInsertConfig(),
 		SQS:                writer.NewAmazonSQSConfig(),
 		STDOUT:             NewSTDOUTConfig(),
 		Subprocess:         NewSubprocessConfig(),
@@ -0,0 +1,527 @@
+package output
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/batch"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/retries"
+	"github.com/cenkalti/backoff/v4"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeDeadLetter] = TypeSpec{
+		constructor: fromSimpleConstructor(func(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+			if conf.DeadLetter.Output == nil {
+				return nil, errors.New("cannot create a dead_letter output without a child output")
+			}
+			if conf.DeadLetter.DeadLetter == nil {
+				return nil, errors.New("cannot create a dead_letter output without a dead letter output")
+			}
+			wrapped, err := New(*conf.DeadLetter.Output, mgr, log, stats)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create output '%v': %v", conf.DeadLetter.Output.Type, err)
+			}
+			dlq, err := New(
+				*conf.DeadLetter.DeadLetter, mgr,
+				log.NewModule(".dead_letter"),
+				metrics.Namespaced(stats, "dead_letter.output"),
+			)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create dead letter output '%v': %v", conf.DeadLetter.DeadLetter.Type, err)
+			}
+			return newDeadLetter(conf.DeadLetter, conf.DeadLetter.Output.Type, wrapped, dlq, log, stats)
+		}),
+		Status:  docs.StatusExperimental,
+		Version: "3.41.0",
+		Summary: `
+Attempts to write messages to a child output and, once a number of retries has
+been exhausted or the write fails with a given class of error, sends the
+messages to a dead letter output instead.`,
+		Description: `
+Messages that fail to be written by the child output are retried with a backoff
+up to ` + "`max_retries`" + ` times. When the retries are exhausted, or
+immediately when the error matches any of the regular expressions of
+` + "`error_patterns`" + `, the messages are written to the
+` + "`dead_letter`" + ` output and are acknowledged once that write succeeds.
+If the dead letter write also fails the error is returned, and the messages are
+reattempted from the input as usual.
+
+When the child output reports which specific messages of a batch have failed
+only those messages are reattempted and sent to the dead letter output.
+
+### Metadata
+
+The following metadata fields are added to each message sent to the dead letter
+output:
+
+` + "```text" + `
+- dead_letter_error
+- dead_letter_attempts
+- dead_letter_timestamp
+- dead_letter_source
+` + "```" + `
+
+Where ` + "`dead_letter_source`" + ` is the type of the child output and
+` + "`dead_letter_timestamp`" + ` is an RFC 3339 timestamp of the final failed
+attempt.
+
+### Metrics
+
+The number of messages and batches sent to the dead letter output are exposed
+as the counters ` + "`dead_letter.sent`" + ` and
+` + "`dead_letter.batch.sent`" + `, and failed writes to the dead letter output
+as ` + "`dead_letter.error`" + `. Metrics of the dead letter output itself are
+prefixed with ` + "`dead_letter.output`" + `, e.g. the number of messages it
+has written is ` + "`dead_letter.output.sent`" + `.`,
+		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
+			confBytes, err := json.Marshal(conf.DeadLetter)
+			if err != nil {
+				return nil, err
+			}
+
+			confMap := map[string]interface{}{}
+			if err = json.Unmarshal(confBytes, &confMap); err != nil {
+				return nil, err
+			}
+
+			var outputSanit interface{} = struct{}{}
+			if conf.DeadLetter.Output != nil {
+				if outputSanit, err = SanitiseConfig(*conf.DeadLetter.Output); err != nil {
+					return nil, err
+				}
+			}
+			confMap["output"] = outputSanit
+
+			var dlqSanit interface{} = struct{}{}
+			if conf.DeadLetter.DeadLetter != nil {
+				if dlqSanit, err = SanitiseConfig(*conf.DeadLetter.DeadLetter); err != nil {
+					return nil, err
+				}
+			}
+			confMap["dead_letter"] = dlqSanit
+			return confMap, nil
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("max_retries", "The maximum number of times a failed write is retried before the messages are sent to the dead letter output. If zero then messages are sent to the dead letter output after the first failure."),
+			docs.FieldAdvanced("backoff", "Control time intervals between retry attempts.").WithChildren(
+				docs.FieldAdvanced("initial_interval", "The initial period to wait between retry attempts."),
+				docs.FieldAdvanced("max_interval", "The maximum period to wait between retry attempts."),
+				docs.FieldAdvanced("max_elapsed_time", "The maximum period to wait before retry attempts are abandoned and messages are sent to the dead letter output. If zero then no limit is used."),
+			),
+			docs.FieldCommon(
+				"error_patterns", "A list of regular expressions, where if the error of a failed write matches any of them the messages are sent to the dead letter output without being retried.",
+				[]string{`\(4\d\d\)`}, []string{"(?i)invalid", "too large"},
+			),
+			docs.FieldCommon("output", "A child output."),
+			docs.FieldCommon("dead_letter", "The output to send messages to once they have failed."),
+		},
+		Examples: []docs.AnnotatedExample{
+			{
+				Title: "Dead Lettering Rejected HTTP Requests",
+				Summary: `
+Messages are retried three times when an HTTP endpoint fails, unless the
+endpoint rejects them with a 4XX status code, in which case they are sent to a
+Kafka topic straight away:`,
+				Config: `
+output:
+  dead_letter:
+    max_retries: 3
+    error_patterns: [ '\(4\d\d\)' ]
+    output:
+      http_client:
+        url: http://example.com/foo/messages
+        verb: POST
+        retries: 0
+    dead_letter:
+      kafka:
+        addresses: [ localhost:9092 ]
+        topic: failed_messages
+`,
+			},
+		},
+		Categories: []Category{
+			CategoryUtility,
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// DeadLetterConfig contains configuration values for the DeadLetter output
+// type.
+type DeadLetterConfig struct {
+	MaxRetries    uint64          `json:"max_retries" yaml:"max_retries"`
+	Backoff       retries.Backoff `json:"backoff" yaml:"backoff"`
+	ErrorPatterns []string        `json:"error_patterns" yaml:"error_patterns"`
+	Output        *Config         `json:"output" yaml:"output"`
+	DeadLetter    *Config         `json:"dead_letter" yaml:"dead_letter"`
+}
+
+// NewDeadLetterConfig creates a new DeadLetterConfig with default values.
+func NewDeadLetterConfig() DeadLetterConfig {
+	return DeadLetterConfig{
+		MaxRetries: 3,
+		Backoff: retries.Backoff{
+			InitialInterval: "500ms",
+			MaxInterval:     "3s",
+			MaxElapsedTime:  "0s",
+		},
+		ErrorPatterns: []string{},
+		Output:        nil,
+		DeadLetter:    nil,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type dummyDeadLetterConfig struct {
+	MaxRetries    uint64          `json:"max_retries" yaml:"max_retries"`
+	Backoff       retries.Backoff `json:"backoff" yaml:"backoff"`
+	ErrorPatterns []string        `json:"error_patterns" yaml:"error_patterns"`
+	Output        interface{}     `json:"output" yaml:"output"`
+	DeadLetter    interface{}     `json:"dead_letter" yaml:"dead_letter"`
+}
+
+func (d DeadLetterConfig) dummy() dummyDeadLetterConfig {
+	dummy := dummyDeadLetterConfig{
+		MaxRetries:    d.MaxRetries,
+		Backoff:       d.Backoff,
+		ErrorPatterns: d.ErrorPatterns,
+		Output:        d.Output,
+		DeadLetter:    d.DeadLetter,
+	}
+	if d.Output == nil {
+		dummy.Output = struct{}{}
+	}
+	if d.DeadLetter == nil {
+		dummy.DeadLetter = struct{}{}
+	}
+	return dummy
+}
+
+// MarshalJSON prints an empty object instead of nil.
+func (d DeadLetterConfig) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.dummy())
+}
+
+// MarshalYAML prints an empty object instead of nil.
+func (d DeadLetterConfig) MarshalYAML() (interface{}, error) {
+	return d.dummy(), nil
+}
+
+//------------------------------------------------------------------------------
+
+// deadLetter attempts to forward messages to a child output, and sends the
+// messages that repeatedly fail to a dead letter output.
+type deadLetter struct {
+	stats metrics.Type
+	log   log.Modular
+
+	maxRetries    uint64
+	backoffCtor   func() backoff.BackOff
+	errorPatterns []*regexp.Regexp
+	source        string
+
+	wrapped Type
+	dlq     Type
+
+	mRetry     metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+	mErr       metrics.StatCounter
+
+	transactionsIn  <-chan types.Transaction
+	transactionsOut chan types.Transaction
+	dlqOut          chan types.Transaction
+
+	ctx        context.Context
+	done       func()
+	closedChan chan struct{}
+}
+
+func newDeadLetter(conf DeadLetterConfig, source string, wrapped, dlq Type, log log.Modular, stats metrics.Type) (*deadLetter, error) {
+	rConf := retries.NewConfig()
+	rConf.Backoff = conf.Backoff
+	backoffCtor, err := rConf.GetCtor()
+	if err != nil {
+		return nil, err
+	}
+
+	var patterns []*regexp.Regexp
+	for _, p := range conf.ErrorPatterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile error pattern '%v': %w", p, err)
+		}
+		patterns = append(patterns, re)
+	}
+
+	ctx, done := context.WithCancel(context.Background())
+	return &deadLetter{
+		log:   log,
+		stats: stats,
+
+		maxRetries:    conf.MaxRetries,
+		backoffCtor:   backoffCtor,
+		errorPatterns: patterns,
+		source:        source,
+
+		wrapped: wrapped,
+		dlq:     dlq,
+
+		mRetry:     stats.GetCounter("dead_letter.retry"),
+		mSent:      stats.GetCounter("dead_letter.sent"),
+		mBatchSent: stats.GetCounter("dead_letter.batch.sent"),
+		mErr:       stats.GetCounter("dead_letter.error"),
+
+		transactionsOut: make(chan types.Transaction),
+		dlqOut:          make(chan types.Transaction),
+
+		ctx:        ctx,
+		done:       done,
+		closedChan: make(chan struct{}),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// failedParts returns the messages of a batch that failed to be written along
+// with their individual errors.
+func failedParts(msg types.Message, err error) (types.Message, []error) {
+	var bErr *batch.Error
+	if !errors.As(err, &bErr) || bErr.IndexedErrors() == 0 {
+		errs := make([]error, msg.Len())
+		for i := range errs {
+			errs[i] = err
+		}
+		return msg, errs
+	}
+
+	failed := message.New(nil)
+	var errs []error
+	bErr.WalkParts(func(i int, _ types.Part, pErr error) bool {
+		if pErr != nil && i < msg.Len() {
+			failed.Append(msg.Get(i))
+			errs = append(errs, pErr)
+		}
+		return true
+	})
+	return failed, errs
+}
+
+func (d *deadLetter) matchesPattern(err error) bool {
+	for _, re := range d.errorPatterns {
+		if re.MatchString(err.Error()) {
+			return true
+		}
+	}
+	return false
+}
+
+// sendDeadLetter writes messages to the dead letter output along with metadata
+// describing why they failed.
+func (d *deadLetter) sendDeadLetter(msg types.Message, errs []error, attempts int) types.Response {
+	timestamp := time.Now().Format(time.RFC3339Nano)
+
+	dlqMsg := message.New(nil)
+	msg.Iter(func(i int, p types.Part) error {
+		part := p.Copy()
+		meta := part.Metadata()
+		meta.Set("dead_letter_error", errs[i].Error())
+		meta.Set("dead_letter_attempts", strconv.Itoa(attempts))
+		meta.Set("dead_letter_timestamp", timestamp)
+		meta.Set("dead_letter_source", d.source)
+		dlqMsg.Append(part)
+		return nil
+	})
+
+	resChan := make(chan types.Response)
+	select {
+	case d.dlqOut <- types.NewTransaction(dlqMsg, resChan):
+	case <-d.ctx.Done():
+		return response.NewError(types.ErrTypeClosed)
+	}
+
+	var res types.Response
+	select {
+	case res = <-resChan:
+	case <-d.ctx.Done():
+		return response.NewError(types.ErrTypeClosed)
+	}
+
+	if err := res.Error(); err != nil {
+		d.mErr.Incr(1)
+		d.log.Errorf("Failed to send messages to dead letter output: %v\n", err)
+		return response.NewError(fmt.Errorf("failed to send messages to dead letter output: %w", err))
+	}
+	d.mSent.Incr(int64(dlqMsg.Len()))
+	d.mBatchSent.Incr(1)
+	return response.NewAck()
+}
+
+// handle waits for the result of a transaction sent to the child output,
+// retrying it and sending it to the dead letter output as required.
+func (d *deadLetter) handle(ts types.Transaction, resChan chan types.Response) {
+	msg := ts.Payload
+	attempts := 1
+
+	var boff backoff.BackOff
+	var resOut types.Response
+
+attemptLoop:
+	for {
+		var res types.Response
+		select {
+		case res = <-resChan:
+		case <-d.ctx.Done():
+			return
+		}
+
+		err := res.Error()
+		if err == nil {
+			resOut = response.NewAck()
+			break
+		}
+
+		var errs []error
+		if msg, errs = failedParts(msg, err); msg.Len() == 0 {
+			resOut = response.NewAck()
+			break
+		}
+		d.log.Errorf("Failed to send message: %v\n", err)
+
+		if uint64(attempts) > d.maxRetries || d.matchesPattern(err) {
+			resOut = d.sendDeadLetter(msg, errs, attempts)
+			break
+		}
+
+		if boff == nil {
+			boff = d.backoffCtor()
+		}
+		nextBackoff := boff.NextBackOff()
+		if nextBackoff == backoff.Stop {
+			resOut = d.sendDeadLetter(msg, errs, attempts)
+			break
+		}
+		select {
+		case <-time.After(nextBackoff):
+		case <-d.ctx.Done():
+			return
+		}
+
+		d.mRetry.Incr(1)
+		attempts++
+		select {
+		case d.transactionsOut <- types.NewTransaction(msg, resChan):
+		case <-d.ctx.Done():
+			break attemptLoop
+		}
+	}
+	if resOut == nil {
+		return
+	}
+
+	select {
+	case ts.ResponseChan <- resOut:
+	case <-d.ctx.Done():
+	}
+}
+
+func (d *deadLetter) loop() {
+	wg := sync.WaitGroup{}
+
+	defer func() {
+		wg.Wait()
+		close(d.transactionsOut)
+		close(d.dlqOut)
+		d.wrapped.CloseAsync()
+		d.dlq.CloseAsync()
+		err := d.wrapped.WaitForClose(time.Second)
+		for ; err != nil; err = d.wrapped.WaitForClose(time.Second) {
+		}
+		err = d.dlq.WaitForClose(time.Second)
+		for ; err != nil; err = d.dlq.WaitForClose(time.Second) {
+		}
+		close(d.closedChan)
+	}()
+
+	for {
+		var ts types.Transaction
+		var open bool
+		select {
+		case ts, open = <-d.transactionsIn:
+			if !open {
+				return
+			}
+		case <-d.ctx.Done():
+			return
+		}
+
+		resChan := make(chan types.Response)
+		select {
+		case d.transactionsOut <- types.NewTransaction(ts.Payload, resChan):
+		case <-d.ctx.Done():
+			return
+		}
+
+		wg.Add(1)
+		go func(ts types.Transaction, resChan chan types.Response) {
+			defer wg.Done()
+			d.handle(ts, resChan)
+		}(ts, resChan)
+	}
+}
+
+// Consume assigns a messages channel for the output to read.
+func (d *deadLetter) Consume(ts <-chan types.Transaction) error {
+	if d.transactionsIn != nil {
+		return types.ErrAlreadyStarted
+	}
+	if err := d.wrapped.Consume(d.transactionsOut); err != nil {
+		return err
+	}
+	if err := d.dlq.Consume(d.dlqOut); err != nil {
+		return err
+	}
+	d.transactionsIn = ts
+	go d.loop()
+	return nil
+}
+
+// Connected returns a boolean indicating whether this output is currently
+// connected to its target.
+func (d *deadLetter) Connected() bool {
+	return d.wrapped.Connected()
+}
+
+// CloseAsync shuts down the DeadLetter output and stops processing requests.
+func (d *deadLetter) CloseAsync() {
+	d.done()
+}
+
+// WaitForClose blocks until the DeadLetter output has closed down.
+func (d *deadLetter) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-d.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,245 @@
+package output
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/batch"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeadLetterConfigErrs(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeDeadLetter
+
+	_, err := New(conf, nil, log.Noop(), metrics.Noop())
+	assert.EqualError(t, err, "failed to create output 'dead_letter': cannot create a dead_letter output without a child output")
+
+	childConf := NewConfig()
+	conf.DeadLetter.Output = &childConf
+	_, err = New(conf, nil, log.Noop(), metrics.Noop())
+	assert.EqualError(t, err, "failed to create output 'dead_letter': cannot create a dead_letter output without a dead letter output")
+
+	dlqConf := NewConfig()
+	conf.DeadLetter.DeadLetter = &dlqConf
+	conf.DeadLetter.ErrorPatterns = []string{"("}
+	_, err = New(conf, nil, log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+
+	conf.DeadLetter.ErrorPatterns = nil
+	_, err = New(conf, nil, log.Noop(), metrics.Noop())
+	assert.NoError(t, err)
+}
+
+type deadLetterHarness struct {
+	t       *testing.T
+	d       *deadLetter
+	out     *mockOutput
+	dlq     *mockOutput
+	tChan   chan types.Transaction
+	resChan chan types.Response
+	stats   metrics.Type
+}
+
+func newDeadLetterHarness(t *testing.T, conf DeadLetterConfig) *deadLetterHarness {
+	t.Helper()
+
+	conf.Backoff.InitialInterval = "1ms"
+	conf.Backoff.MaxInterval = "1ms"
+
+	h := &deadLetterHarness{
+		t:       t,
+		out:     &mockOutput{},
+		dlq:     &mockOutput{},
+		tChan:   make(chan types.Transaction),
+		resChan: make(chan types.Response),
+		stats:   metrics.NewLocal(),
+	}
+
+	var err error
+	h.d, err = newDeadLetter(conf, "foo", h.out, h.dlq, log.Noop(), h.stats)
+	require.NoError(t, err)
+	require.NoError(t, h.d.Consume(h.tChan))
+	t.Cleanup(func() {
+		h.d.CloseAsync()
+		assert.NoError(t, h.d.WaitForClose(time.Second*5))
+	})
+	return h
+}
+
+func (h *deadLetterHarness) send(msg types.Message) {
+	h.t.Helper()
+	select {
+	case h.tChan <- types.NewTransaction(msg, h.resChan):
+	case <-time.After(time.Second * 5):
+		h.t.Fatal("timed out")
+	}
+}
+
+func (h *deadLetterHarness) respond(ts <-chan types.Transaction, res types.Response) types.Message {
+	h.t.Helper()
+	var tran types.Transaction
+	select {
+	case tran = <-ts:
+	case <-time.After(time.Second * 5):
+		h.t.Fatal("timed out")
+	}
+	select {
+	case tran.ResponseChan <- res:
+	case <-time.After(time.Second * 5):
+		h.t.Fatal("timed out")
+	}
+	return tran.Payload
+}
+
+func (h *deadLetterHarness) result() error {
+	h.t.Helper()
+	select {
+	case res := <-h.resChan:
+		return res.Error()
+	case <-time.After(time.Second * 5):
+		h.t.Fatal("timed out")
+	}
+	return nil
+}
+
+func TestDeadLetterSuccess(t *testing.T) {
+	h := newDeadLetterHarness(t, NewDeadLetterConfig())
+
+	h.send(message.New([][]byte{[]byte("foo")}))
+	h.respond(h.out.ts, response.NewError(errors.New("nope")))
+	h.respond(h.out.ts, response.NewAck())
+	assert.NoError(t, h.result())
+
+	counters := h.stats.(*metrics.Local).GetCounters()
+	assert.Equal(t, int64(1), counters["dead_letter.retry"])
+	assert.Equal(t, int64(0), counters["dead_letter.sent"])
+}
+
+func TestDeadLetterRetriesExhausted(t *testing.T) {
+	conf := NewDeadLetterConfig()
+	conf.MaxRetries = 2
+	h := newDeadLetterHarness(t, conf)
+
+	h.send(message.New([][]byte{[]byte("foo"), []byte("bar")}))
+	for i := 0; i < 3; i++ {
+		h.respond(h.out.ts, response.NewError(errors.New("nope")))
+	}
+
+	msg := h.respond(h.dlq.ts, response.NewAck())
+	assert.NoError(t, h.result())
+
+	assert.Equal(t, [][]byte{[]byte("foo"), []byte("bar")}, message.GetAllBytes(msg))
+	meta := msg.Get(1).Metadata()
+	assert.Equal(t, "nope", meta.Get("dead_letter_error"))
+	assert.Equal(t, "3", meta.Get("dead_letter_attempts"))
+	assert.Equal(t, "foo", meta.Get("dead_letter_source"))
+	_, err := time.Parse(time.RFC3339Nano, meta.Get("dead_letter_timestamp"))
+	assert.NoError(t, err)
+
+	counters := h.stats.(*metrics.Local).GetCounters()
+	assert.Equal(t, int64(2), counters["dead_letter.retry"])
+	assert.Equal(t, int64(2), counters["dead_letter.sent"])
+	assert.Equal(t, int64(1), counters["dead_letter.batch.sent"])
+}
+
+func TestDeadLetterErrorPatterns(t *testing.T) {
+	conf := NewDeadLetterConfig()
+	conf.ErrorPatterns = []string{`\(4\d\d\)`}
+	h := newDeadLetterHarness(t, conf)
+
+	h.send(message.New([][]byte{[]byte("foo")}))
+	h.respond(h.out.ts, response.NewError(errors.New("request failed (400)")))
+
+	msg := h.respond(h.dlq.ts, response.NewAck())
+	assert.NoError(t, h.result())
+	assert.Equal(t, "request failed (400)", msg.Get(0).Metadata().Get("dead_letter_error"))
+	assert.Equal(t, "1", msg.Get(0).Metadata().Get("dead_letter_attempts"))
+}
+
+func TestDeadLetterBatchErrors(t *testing.T) {
+	conf := NewDeadLetterConfig()
+	conf.MaxRetries = 1
+	h := newDeadLetterHarness(t, conf)
+
+	input := message.New([][]byte{[]byte("foo"), []byte("bar"), []byte("baz")})
+	h.send(input)
+	h.respond(h.out.ts, response.NewError(
+		batch.NewError(input, errors.New("nope")).Failed(0, errors.New("foo failed")).Failed(2, errors.New("baz failed")),
+	))
+
+	retried := h.respond(h.out.ts, response.NewError(errors.New("still failing")))
+	assert.Equal(t, [][]byte{[]byte("foo"), []byte("baz")}, message.GetAllBytes(retried))
+
+	msg := h.respond(h.dlq.ts, response.NewAck())
+	assert.NoError(t, h.result())
+	assert.Equal(t, [][]byte{[]byte("foo"), []byte("baz")}, message.GetAllBytes(msg))
+	assert.Equal(t, "still failing", msg.Get(0).Metadata().Get("dead_letter_error"))
+}
+
+func TestDeadLetterOutputFails(t *testing.T) {
+	conf := NewDeadLetterConfig()
+	conf.MaxRetries = 0
+	h := newDeadLetterHarness(t, conf)
+
+	h.send(message.New([][]byte{[]byte("foo")}))
+	h.respond(h.out.ts, response.NewError(errors.New("nope")))
+	h.respond(h.dlq.ts, response.NewError(errors.New("dlq nope")))
+	assert.EqualError(t, h.result(), "failed to send messages to dead letter output: dlq nope")
+
+	counters := h.stats.(*metrics.Local).GetCounters()
+	assert.Equal(t, int64(1), counters["dead_letter.error"])
+}
+
+func TestDeadLetterMetrics(t *testing.T) {
+	childConf := NewConfig()
+	childConf.Type = TypeReject
+	childConf.Reject = "nope"
+
+	dlqConf := NewConfig()
+	dlqConf.Type = TypeDrop
+
+	conf := NewConfig()
+	conf.Type = TypeDeadLetter
+	conf.DeadLetter.MaxRetries = 0
+	conf.DeadLetter.Output = &childConf
+	conf.DeadLetter.DeadLetter = &dlqConf
+
+	stats := metrics.NewLocal()
+	out, err := New(conf, nil, log.Noop(), stats)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		out.CloseAsync()
+		assert.NoError(t, out.WaitForClose(time.Second*5))
+	})
+
+	tChan, resChan := make(chan types.Transaction), make(chan types.Response)
+	require.NoError(t, out.Consume(tChan))
+
+	select {
+	case tChan <- types.NewTransaction(message.New([][]byte{[]byte("foo")}), resChan):
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out")
+	}
+	select {
+	case res := <-resChan:
+		require.NoError(t, res.Error())
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out")
+	}
+
+	// Messages routed to the dead letter output are counted once by the
+	// wrapper, and separately by the dead letter output itself.
+	counters := stats.GetCounters()
+	assert.Equal(t, int64(1), counters["dead_letter.sent"])
+	assert.Equal(t, int64(1), counters["dead_letter.batch.sent"])
+	assert.Equal(t, int64(1), counters["dead_letter.output.sent"])
+	assert.Equal(t, int64(1), counters["dead_letter.output.batch.sent"])
+}
@@ -0,0 +1,137 @@
+package output
+
+import (
+	"context"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	bgrpc "github.com/Jeffail/benthos/v3/internal/service/grpc"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message/batch"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/output/writer"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeGRPCClient] = TypeSpec{
+		constructor: fromSimpleConstructor(func(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+			g, err := newGRPCClientWriter(conf.GRPCClient, log)
+			if err != nil {
+				return nil, err
+			}
+			w, err := NewAsyncWriter(TypeGRPCClient, conf.GRPCClient.MaxInFlight, g, log, stats)
+			if err != nil {
+				return nil, err
+			}
+			return newBatcherFromConf(conf.GRPCClient.Batching, w, mgr, log, stats)
+		}),
+		Status:  docs.StatusExperimental,
+		Batches: true,
+		Async:   true,
+		Version: "3.41.0",
+		Summary: `
+Calls a gRPC method with each message as the request.`,
+		Description: `
+Messages are converted into requests of the method following the
+[JSON mapping of protobuf messages](https://developers.google.com/protocol-buffers/docs/proto3#json),
+and responses are discarded.
+
+The definition of the method is either parsed from the .proto files found within
+` + "`import_paths`" + `, or when no import paths are specified it is obtained
+from the server using
+[server reflection](https://github.com/grpc/grpc/blob/master/doc/server-reflection.md).
+
+Unary methods are called once for each message. Client streaming methods are
+called once for each batch, where the messages of the batch are streamed in
+order. Server streaming and bidirectional streaming methods are not supported.`,
+		FieldSpecs: bgrpc.ClientConfigDocs().Add(
+			docs.FieldCommon("max_in_flight", "The maximum number of messages to have in flight at a given time. Increase this to improve throughput."),
+			batch.FieldSpec(),
+		),
+		Categories: []Category{
+			CategoryNetwork,
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// GRPCClientConfig contains configuration fields for the grpc_client output
+// type.
+type GRPCClientConfig struct {
+	bgrpc.ClientConfig `json:",inline" yaml:",inline"`
+	MaxInFlight        int                `json:"max_in_flight" yaml:"max_in_flight"`
+	Batching           batch.PolicyConfig `json:"batching" yaml:"batching"`
+}
+
+// NewGRPCClientConfig creates a new GRPCClientConfig with default values.
+func NewGRPCClientConfig() GRPCClientConfig {
+	return GRPCClientConfig{
+		ClientConfig: bgrpc.NewClientConfig(),
+		MaxInFlight:  1,
+		Batching:     batch.NewPolicyConfig(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type grpcClientWriter struct {
+	conf   GRPCClientConfig
+	client *bgrpc.Client
+	log    log.Modular
+}
+
+func newGRPCClientWriter(conf GRPCClientConfig, log log.Modular) (*grpcClientWriter, error) {
+	client, err := bgrpc.NewClient(conf.ClientConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &grpcClientWriter{
+		conf:   conf,
+		client: client,
+		log:    log,
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ConnectWithContext resolves the target method, which requires a connection
+// to the server when the method is obtained via reflection.
+func (g *grpcClientWriter) ConnectWithContext(ctx context.Context) error {
+	if _, err := g.client.Method(ctx); err != nil {
+		return err
+	}
+	g.log.Infof("Calling gRPC method '%v' at: %v\n", g.conf.Method, g.conf.Address)
+	return nil
+}
+
+// WriteWithContext calls the target method with the messages of a batch.
+func (g *grpcClientWriter) WriteWithContext(ctx context.Context, msg types.Message) error {
+	method, err := g.client.Method(ctx)
+	if err != nil {
+		return err
+	}
+	if method.IsClientStreaming() {
+		_, err = g.client.ClientStream(ctx, msg)
+		return err
+	}
+	return writer.IterateBatchedSend(msg, func(i int, _ types.Part) error {
+		_, err := g.client.Unary(ctx, i, msg)
+		return err
+	})
+}
+
+// CloseAsync shuts down the output and stops processing messages.
+func (g *grpcClientWriter) CloseAsync() {
+	go g.client.Close()
+}
+
+// WaitForClose blocks until the output has closed down.
+func (g *grpcClientWriter) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,77 @@
+package output
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+func TestGRPCClientConfigErrors(t *testing.T) {
+	conf := NewGRPCClientConfig()
+	conf.Method = "nope"
+
+	_, err := newGRPCClientWriter(conf, log.Noop())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "must be of the form package.Service/Method")
+}
+
+func TestGRPCClientReflection(t *testing.T) {
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	server := grpc.NewServer()
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	reflection.Register(server)
+	go func() {
+		_ = server.Serve(lis)
+	}()
+	t.Cleanup(server.Stop)
+
+	conf := NewGRPCClientConfig()
+	conf.Address = lis.Addr().String()
+	conf.Method = "grpc.health.v1.Health/Check"
+
+	w, err := newGRPCClientWriter(conf, log.Noop())
+	require.NoError(t, err)
+	t.Cleanup(w.CloseAsync)
+
+	ctx := context.Background()
+	require.NoError(t, w.ConnectWithContext(ctx))
+	require.NoError(t, w.WriteWithContext(ctx, message.New([][]byte{
+		[]byte(`{}`),
+		[]byte(`{"service":""}`),
+	})))
+
+	err = w.WriteWithContext(ctx, message.New([][]byte{
+		[]byte(`{}`),
+		[]byte(`{"service":"nope"}`),
+	}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown service")
+}
+
+func TestGRPCClientReflectionUnavailable(t *testing.T) {
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	addr := lis.Addr().String()
+	require.NoError(t, lis.Close())
+
+	conf := NewGRPCClientConfig()
+	conf.Address = addr
+	conf.Method = "grpc.health.v1.Health/Check"
+
+	w, err := newGRPCClientWriter(conf, log.Noop())
+	require.NoError(t, err)
+	t.Cleanup(w.CloseAsync)
+
+	require.Error(t, w.ConnectWithContext(context.Background()))
+}
@@ -30,7 +30,17 @@ When strict ordering is required for messages written to topic partitions it is
 
 You must also ensure that failed batches are never rerouted back to the same output. This can be done by setting the field ` + "`max_retries` to `0` and `backoff.max_elapsed_time`" + ` to empty, which will apply back pressure indefinitely until the batch is sent successfully.
 
-However, this also means that manual intervention will eventually be required in cases where the batch cannot be sent due to configuration problems such as an incorrect ` + "`max_msg_bytes`" + ` estimate. A less strict but automated alternative would be to route failed batches to a dead letter queue using a ` + "[`try` broker](/docs/components/outputs/try)" + `, but this would allow subsequent batches to be delivered in the meantime whilst those failed batches are dealt with.`,
+However, this also means that manual intervention will eventually be required in cases where the batch cannot be sent due to configuration problems such as an incorrect ` + "`max_msg_bytes`" + ` estimate. A less strict but automated alternative would be to route failed batches to a dead letter queue using a ` + "[`try` broker](/docs/components/outputs/try)" + `, but this would allow subsequent batches to be delivered in the meantime whilst those failed batches are dealt with.
+
+### Idempotent and Transactional Writes
+
+Setting ` + "`idempotent_write` to `true`" + ` enables the idempotent producer, where brokers discard duplicates of messages that are resent by the producer after an intermittent error. This requires a ` + "`target_version`" + ` of at least ` + "`0.11.0`" + ` and implies acknowledgement from all replicas regardless of ` + "`ack_replicas`" + `.
+
+Setting a ` + "`transaction.id`" + ` additionally writes each batch of messages within a Kafka transaction, where a batch is either written in its entirety or not at all, and on failure the whole batch is retried within a new transaction. The ID should be unique to each instance of a pipeline and stable across restarts, as it is used to fence off transactions left unresolved by previous instances. Transactional writes require ` + "`max_in_flight` to be `1`" + `.
+
+When consuming from a ` + "[`kafka` input](/docs/components/inputs/kafka)" + ` the field ` + "`transaction.consumer_group`" + ` can be set to the consumer group of that input, in which case the offsets of the consumed messages are committed to the group within the same transaction as the written messages, providing exactly-once delivery for read-process-write pipelines. The offsets are determined from the metadata fields ` + "`kafka_topic`, `kafka_partition` and `kafka_offset`" + ` of each message, and therefore these fields must be preserved by any processors of the pipeline.
+
+Consumers of the output topics must read with a ` + "`read_committed`" + ` isolation level in order to ignore messages of aborted transactions.`,
 		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
 			return sanitiseWithBatch(conf.Kafka, conf.Kafka.Batching)
 		},
@@ -53,6 +63,12 @@ However, this also means that manual intervention will eventually be required in
 			docs.FieldAdvanced("timeout", "The maximum period of time to wait for message sends before abandoning the request and retrying."),
 			docs.FieldAdvanced("target_version", "The version of the Kafka protocol to use."),
 			docs.FieldAdvanced("retry_as_batch", "When enabled forces an entire batch of messages to be retried if any individual message fails on a send, otherwise only the individual messages that failed are retried. Disabling this helps to reduce message duplicates during intermittent errors, but also makes it impossible to guarantee strict ordering of messages."),
+			docs.FieldAdvanced("idempotent_write", "Enable the idempotent producer, which prevents duplicate messages from being written when sends are retried by the producer.").AtVersion("3.41.0"),
+			docs.FieldAdvanced("transaction", "Write each batch of messages within a Kafka transaction.").WithChildren(
+				docs.FieldCommon("id", "A transactional ID that identifies this producer across restarts. When empty transactions are disabled."),
+				docs.FieldCommon("consumer_group", "An optional consumer group to commit the offsets of consumed messages to within each transaction, as determined by the metadata fields `kafka_topic`, `kafka_partition` and `kafka_offset`."),
+				docs.FieldAdvanced("timeout", "The maximum period of time a transaction can remain unresolved before it is aborted by the broker."),
+			).AtVersion("3.41.0"),
 			batch.FieldSpec(),
 		}, retries.FieldSpecs()...),
 		Categories: []Category{
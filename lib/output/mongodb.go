@@ -0,0 +1,262 @@
+package output
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/service/mongodb"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message/batch"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/output/writer"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeMongoDB] = TypeSpec{
+		constructor: fromSimpleConstructor(func(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+			m, err := newMongoDBWriter(conf.MongoDB, log)
+			if err != nil {
+				return nil, err
+			}
+			w, err := NewAsyncWriter(TypeMongoDB, conf.MongoDB.MaxInFlight, m, log, stats)
+			if err != nil {
+				return nil, err
+			}
+			return newBatcherFromConf(conf.MongoDB.Batching, w, mgr, log, stats)
+		}),
+		Status:  docs.StatusExperimental,
+		Batches: true,
+		Async:   true,
+		Version: "3.41.0",
+		Categories: []Category{
+			CategoryServices,
+		},
+		Summary: `
+Inserts, updates, replaces or deletes documents in a MongoDB collection for each
+message.`,
+		Description: `
+The documents and filters of each operation are built with
+[Bloblang mappings](/docs/guides/bloblang/about) executed against each message,
+where values can be expressed using
+[extended JSON](https://docs.mongodb.com/manual/reference/mongodb-extended-json/)
+such as ` + "`{\"$oid\":\"...\"}`" + `.
+
+The messages of a batch are written with a single unordered bulk write, and only
+the messages that failed to be written are retried.`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title: "Upsert Documents",
+				Summary: `
+The following example upserts documents into the collection users, keyed by the
+id field of each message:`,
+				Config: `
+output:
+  mongodb:
+    url: mongodb://localhost:27017
+    database: app
+    collection: users
+    operation: replace-one
+    upsert: true
+    filter_map: root.id = this.id
+    document_map: root = this
+    batching:
+      count: 100
+      period: 1s
+`,
+			},
+		},
+		FieldSpecs: mongodb.ConfigDocs().Add(
+			docs.FieldCommon("collection", "The name of the target collection."),
+			docs.FieldCommon("operation", "The operation to perform for each message.").HasOptions(
+				string(mongodb.OperationInsertOne),
+				string(mongodb.OperationDeleteOne),
+				string(mongodb.OperationDeleteMany),
+				string(mongodb.OperationReplaceOne),
+				string(mongodb.OperationUpdateOne),
+			),
+			mongodb.WriteConcernDocs(),
+		).Add(mongodb.MappingDocs()...).Add(
+			docs.FieldCommon("upsert", "Whether the `replace-one` and `update-one` operations should insert a new document when no document matches the filter."),
+			docs.FieldCommon("max_in_flight", "The maximum number of messages to have in flight at a given time. Increase this to improve throughput."),
+			batch.FieldSpec(),
+		),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// MongoDBConfig contains configuration fields for the mongodb output type.
+type MongoDBConfig struct {
+	mongodb.Config `json:",inline" yaml:",inline"`
+	Collection     string               `json:"collection" yaml:"collection"`
+	Operation      string               `json:"operation" yaml:"operation"`
+	WriteConcern   mongodb.WriteConcern `json:"write_concern" yaml:"write_concern"`
+	DocumentMap    string               `json:"document_map" yaml:"document_map"`
+	FilterMap      string               `json:"filter_map" yaml:"filter_map"`
+	HintMap        string               `json:"hint_map" yaml:"hint_map"`
+	Upsert         bool                 `json:"upsert" yaml:"upsert"`
+	MaxInFlight    int                  `json:"max_in_flight" yaml:"max_in_flight"`
+	Batching       batch.PolicyConfig   `json:"batching" yaml:"batching"`
+}
+
+// NewMongoDBConfig returns a MongoDBConfig with default values.
+func NewMongoDBConfig() MongoDBConfig {
+	return MongoDBConfig{
+		Config:       mongodb.NewConfig(),
+		Collection:   "",
+		Operation:    string(mongodb.OperationUpdateOne),
+		WriteConcern: mongodb.NewWriteConcern(),
+		DocumentMap:  "",
+		FilterMap:    "",
+		HintMap:      "",
+		Upsert:       false,
+		MaxInFlight:  1,
+		Batching:     batch.NewPolicyConfig(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type mongoDBWriter struct {
+	conf      MongoDBConfig
+	operation mongodb.Operation
+	mappings  mongodb.Mappings
+	collOpts  *options.CollectionOptions
+
+	connMut    sync.RWMutex
+	client     *mongo.Client
+	collection *mongo.Collection
+
+	log log.Modular
+}
+
+func newMongoDBWriter(conf MongoDBConfig, log log.Modular) (*mongoDBWriter, error) {
+	if len(conf.Collection) == 0 {
+		return nil, errors.New("a collection must be specified")
+	}
+	if _, err := conf.Config.Client(); err != nil {
+		return nil, err
+	}
+
+	m := &mongoDBWriter{
+		conf:     conf,
+		collOpts: options.Collection(),
+		log:      log,
+	}
+
+	var err error
+	if m.operation, err = mongodb.NewOperation(
+		conf.Operation,
+		mongodb.OperationInsertOne,
+		mongodb.OperationDeleteOne,
+		mongodb.OperationDeleteMany,
+		mongodb.OperationReplaceOne,
+		mongodb.OperationUpdateOne,
+	); err != nil {
+		return nil, err
+	}
+	if m.mappings, err = mongodb.NewMappings(m.operation, conf.DocumentMap, conf.FilterMap, conf.HintMap); err != nil {
+		return nil, err
+	}
+
+	wc, err := conf.WriteConcern.Get()
+	if err != nil {
+		return nil, err
+	}
+	if wc != nil {
+		m.collOpts.SetWriteConcern(wc)
+	}
+	return m, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ConnectWithContext attempts to establish a connection to the target
+// deployment.
+func (m *mongoDBWriter) ConnectWithContext(ctx context.Context) error {
+	m.connMut.Lock()
+	defer m.connMut.Unlock()
+
+	if m.client != nil {
+		return nil
+	}
+
+	client, db, err := m.conf.Config.Connect(ctx)
+	if err != nil {
+		return err
+	}
+
+	m.client = client
+	m.collection = db.Collection(m.conf.Collection, m.collOpts)
+
+	m.log.Infof("Writing messages to MongoDB collection: %v.%v\n", m.conf.Database, m.conf.Collection)
+	return nil
+}
+
+// WriteWithContext attempts to write a message batch to the collection.
+func (m *mongoDBWriter) WriteWithContext(ctx context.Context, msg types.Message) error {
+	m.connMut.RLock()
+	collection := m.collection
+	m.connMut.RUnlock()
+
+	if collection == nil {
+		return types.ErrNotConnected
+	}
+
+	errs := make([]error, msg.Len())
+	var models []mongo.WriteModel
+	var modelIndexes []int
+	_ = msg.Iter(func(i int, _ types.Part) error {
+		model, err := m.mappings.WriteModel(m.operation, m.conf.Upsert, i, msg)
+		if err != nil {
+			m.log.Errorf("Failed to build %v operation: %v\n", m.operation, err)
+			errs[i] = err
+			return nil
+		}
+		models = append(models, model)
+		modelIndexes = append(modelIndexes, i)
+		return nil
+	})
+
+	if len(models) > 0 {
+		_, err := collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+		for j, werr := range mongodb.BulkWriteErrors(err, len(models)) {
+			if werr != nil {
+				errs[modelIndexes[j]] = fmt.Errorf("%v operation failed: %w", m.operation, werr)
+			}
+		}
+	}
+
+	return writer.IterateBatchedSend(msg, func(i int, _ types.Part) error {
+		return errs[i]
+	})
+}
+
+// CloseAsync shuts down the output and stops processing messages.
+func (m *mongoDBWriter) CloseAsync() {
+	go func() {
+		m.connMut.Lock()
+		if m.client != nil {
+			_ = m.client.Disconnect(context.Background())
+			m.client = nil
+			m.collection = nil
+		}
+		m.connMut.Unlock()
+	}()
+}
+
+// WaitForClose blocks until the output has closed down.
+func (m *mongoDBWriter) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,79 @@
+package output
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMongoDBConfigErrors(t *testing.T) {
+	tests := map[string]struct {
+		conf func(c *MongoDBConfig)
+		err  string
+	}{
+		"no collection": {
+			conf: func(c *MongoDBConfig) {
+				c.Collection = ""
+			},
+			err: "a collection must be specified",
+		},
+		"no database": {
+			conf: func(c *MongoDBConfig) {
+				c.Database = ""
+			},
+			err: "a database must be specified",
+		},
+		"bad operation": {
+			conf: func(c *MongoDBConfig) {
+				c.Operation = "find-one"
+			},
+			err: "operation find-one was not recognised",
+		},
+		"missing filter": {
+			conf: func(c *MongoDBConfig) {
+				c.FilterMap = ""
+			},
+			err: "a filter_map is required for the update-one operation",
+		},
+		"bad write concern": {
+			conf: func(c *MongoDBConfig) {
+				c.WriteConcern.W = "nope"
+			},
+			err: "failed to parse write concern w 'nope': expected a number or majority",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			conf := NewMongoDBConfig()
+			conf.Database = "foo"
+			conf.Collection = "bar"
+			conf.DocumentMap = `root = {"$set": this}`
+			conf.FilterMap = `root.id = this.id`
+			test.conf(&conf)
+
+			_, err := newMongoDBWriter(conf, log.Noop())
+			require.EqualError(t, err, test.err)
+		})
+	}
+}
+
+func TestMongoDBNotConnected(t *testing.T) {
+	conf := NewMongoDBConfig()
+	conf.Database = "foo"
+	conf.Collection = "bar"
+	conf.Operation = "insert-one"
+	conf.DocumentMap = `root = this`
+
+	w, err := newMongoDBWriter(conf, log.Noop())
+	require.NoError(t, err)
+
+	err = w.WriteWithContext(context.Background(), message.New([][]byte{[]byte(`{"id":"1"}`)}))
+	assert.Equal(t, types.ErrNotConnected, err)
+}
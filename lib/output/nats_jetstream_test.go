@@ -0,0 +1,49 @@
+package output
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNATSJetStreamConfigErrors(t *testing.T) {
+	conf := NewNATSJetStreamConfig()
+	_, err := newNATSJetStreamWriter(conf, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "a subject must be specified")
+
+	conf.Subject = "foo.${! nope() }"
+	_, err = newNATSJetStreamWriter(conf, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to parse subject expression")
+
+	conf.Subject = "foo"
+	conf.MsgID = "${! nope() }"
+	_, err = newNATSJetStreamWriter(conf, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to parse msg_id expression")
+
+	conf.MsgID = ""
+	conf.Headers = map[string]string{"foo": "${! nope() }"}
+	_, err = newNATSJetStreamWriter(conf, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to parse header 'foo' expression")
+}
+
+func TestNATSJetStreamNotConnected(t *testing.T) {
+	conf := NewNATSJetStreamConfig()
+	conf.Subject = `foo.${! meta("bar") }`
+	conf.MsgID = `${! json("id") }`
+	conf.Headers = map[string]string{"baz": `${! meta("baz") }`}
+
+	w, err := newNATSJetStreamWriter(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	err = w.WriteWithContext(context.Background(), message.New([][]byte{[]byte(`{"id":"1"}`)}))
+	assert.Equal(t, types.ErrNotConnected, err)
+}
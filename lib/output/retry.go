@@ -37,7 +37,8 @@ we want to avoid reapplying to the same message more than once in the pipeline.
 
 Rather than retrying the same output you may wish to retry the send using a
 different output target (a dead letter queue). In which case you should instead
-use the ` + "[`try`](/docs/components/outputs/try)" + ` output type.`,
+use the ` + "[`try`](/docs/components/outputs/try)" + ` or
+` + "[`dead_letter`](/docs/components/outputs/dead_letter)" + ` output types.`,
 		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
 			confBytes, err := json.Marshal(conf.Retry)
 			if err != nil {
@@ -3,12 +3,15 @@ package output
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
 
 	"github.com/Jeffail/benthos/v3/internal/bloblang"
 	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/query"
 	"github.com/Jeffail/benthos/v3/internal/docs"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message/batch"
@@ -46,7 +49,9 @@ func init() {
 		Summary: `
 Runs an SQL prepared query against a target database for each message.`,
 		Description: `
-Query arguments are set using [interpolation functions](/docs/configuration/interpolation#bloblang-queries) in the ` + "`args`" + ` field.
+Query arguments are set using [interpolation functions](/docs/configuration/interpolation#bloblang-queries) in the ` + "`args`" + ` field, or alternatively with a single [Bloblang mapping](/docs/guides/bloblang/about) in the ` + "`args_mapping`" + ` field.
+
+This output executes the query once for each message of a batch within a single transaction. For bulk loading batches of rows into a table more efficiently use the ` + "[`sql_insert`](/docs/components/outputs/sql_insert)" + ` output instead.
 
 ## Drivers
 
@@ -57,6 +62,7 @@ The following is a list of supported drivers and their respective DSN formats:
 ` + "| `clickhouse` | [`tcp://[netloc][:port][?param1=value1&...&paramN=valueN]`](https://github.com/ClickHouse/clickhouse-go#dsn)" + `
 ` + "| `mysql` | `[username[:password]@][protocol[(address)]]/dbname[?param1=value1&...&paramN=valueN]` |" + `
 ` + "| `postgres` | `postgresql://[user[:password]@][netloc][:port][/dbname][?param1=value1&...]` |" + `
+` + "| `sqlite` | `file:/path/to/filename.db[?param1=value1&...]` |" + `
 
 Please note that the ` + "`postgres`" + ` driver enforces SSL by default, you can override this with the parameter ` + "`sslmode=disable`" + ` if required.`,
 		Examples: []docs.AnnotatedExample{
@@ -103,7 +109,7 @@ output:
 			docs.FieldCommon(
 				"driver",
 				"A database [driver](#drivers) to use.",
-			).HasOptions("mysql", "postgres", "clickhouse"),
+			).HasOptions("mysql", "postgres", "clickhouse", "sqlite"),
 			docs.FieldCommon(
 				"data_source_name", "A Data Source Name to identify the target database.",
 				"tcp://host1:9000?username=user&password=qwerty&database=clicks&read_timeout=10&write_timeout=20&alt_hosts=host2:9000,host3:9000",
@@ -119,6 +125,11 @@ output:
 				"args",
 				"A list of arguments for the query to be resolved for each message.",
 			).SupportsInterpolation(true),
+			docs.FieldAdvanced(
+				"args_mapping",
+				"An optional [Bloblang mapping](/docs/guides/bloblang/about) which should evaluate to an array of values matching in size to the number of placeholder arguments in the field `query`. This field cannot be used in combination with `args`.",
+				`root = [ this.document.foo, this.document.bar, meta("kafka_topic") ]`,
+			).AtVersion("3.41.0"),
 			docs.FieldCommon("max_in_flight", "The maximum number of messages to have in flight at a given time. Increase this to improve throughput."),
 			batch.FieldSpec(),
 		},
@@ -133,6 +144,7 @@ type SQLConfig struct {
 	DataSourceName string             `json:"data_source_name" yaml:"data_source_name"`
 	Query          string             `json:"query" yaml:"query"`
 	Args           []string           `json:"args" yaml:"args"`
+	ArgsMapping    string             `json:"args_mapping" yaml:"args_mapping"`
 	MaxInFlight    int                `json:"max_in_flight" yaml:"max_in_flight"`
 	Batching       batch.PolicyConfig `json:"batching" yaml:"batching"`
 }
@@ -144,6 +156,7 @@ func NewSQLConfig() SQLConfig {
 		DataSourceName: "",
 		Query:          "",
 		Args:           []string{},
+		ArgsMapping:    "",
 		MaxInFlight:    1,
 		Batching:       batch.NewPolicyConfig(),
 	}
@@ -158,15 +171,41 @@ func insertOnlyBatchDriver(driver string) bool {
 	return exists
 }
 
+// sqlArgsFromMapping executes an args mapping against a message of a batch and
+// returns the resulting array of query arguments.
+func sqlArgsFromMapping(argsMapping *mapping.Executor, index int, msg types.Message) ([]interface{}, error) {
+	v, err := argsMapping.Exec(query.FunctionContext{
+		Maps:     map[string]query.Function{},
+		Vars:     map[string]interface{}{},
+		Index:    index,
+		MsgBatch: msg,
+	}.WithValueFunc(func() *interface{} {
+		jObj, err := msg.Get(index).JSON()
+		if err != nil {
+			return nil
+		}
+		return &jObj
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("args mapping failed: %w", err)
+	}
+	args, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("args mapping returned non-array result: %T", v)
+	}
+	return args, nil
+}
+
 //------------------------------------------------------------------------------
 
 type sqlWriter struct {
 	log  log.Modular
 	conf SQLConfig
 
-	db    *sql.DB
-	dbMut sync.Mutex
-	args  []field.Expression
+	db          *sql.DB
+	dbMut       sync.Mutex
+	args        []field.Expression
+	argsMapping *mapping.Executor
 
 	query *sql.Stmt
 }
@@ -181,10 +220,22 @@ func newSQLWriter(conf SQLConfig, log log.Modular) (*sqlWriter, error) {
 		args = append(args, expr)
 	}
 
+	var argsMapping *mapping.Executor
+	if conf.ArgsMapping != "" {
+		if len(args) > 0 {
+			return nil, errors.New("cannot specify both args and an args_mapping")
+		}
+		var err error
+		if argsMapping, err = bloblang.NewMapping("", conf.ArgsMapping); err != nil {
+			return nil, fmt.Errorf("failed to parse args mapping: %w", err)
+		}
+	}
+
 	s := &sqlWriter{
-		log:  log,
-		conf: conf,
-		args: args,
+		log:         log,
+		conf:        conf,
+		args:        args,
+		argsMapping: argsMapping,
 	}
 
 	return s, nil
@@ -220,7 +271,7 @@ func (s *sqlWriter) ConnectWithContext(ctx context.Context) error {
 	return nil
 }
 
-func (s *sqlWriter) doExecute(argSets [][]interface{}) (errs []error) {
+func (s *sqlWriter) doExecute(argSets [][]interface{}, argErrs []error) (errs []error) {
 	s.dbMut.Lock()
 	db := s.db
 	stmt := s.query
@@ -260,6 +311,13 @@ func (s *sqlWriter) doExecute(argSets [][]interface{}) (errs []error) {
 	}
 
 	for i, args := range argSets {
+		if argErrs[i] != nil {
+			if len(errs) == 0 {
+				errs = make([]error, len(argSets))
+			}
+			errs[i] = argErrs[i]
+			continue
+		}
 		if _, serr := stmt.Exec(args...); serr != nil {
 			if len(errs) == 0 {
 				errs = make([]error, len(argSets))
@@ -275,7 +333,12 @@ func (s *sqlWriter) doExecute(argSets [][]interface{}) (errs []error) {
 // WriteWithContext attempts to write a message to the database.
 func (s *sqlWriter) WriteWithContext(ctx context.Context, msg types.Message) error {
 	argSets := make([][]interface{}, msg.Len())
+	argErrs := make([]error, msg.Len())
 	msg.Iter(func(index int, p types.Part) error {
+		if s.argsMapping != nil {
+			argSets[index], argErrs[index] = sqlArgsFromMapping(s.argsMapping, index, msg)
+			return nil
+		}
 		args := make([]interface{}, len(s.args))
 		for i, v := range s.args {
 			args[i] = v.String(index, msg)
@@ -284,7 +347,7 @@ func (s *sqlWriter) WriteWithContext(ctx context.Context, msg types.Message) err
 		return nil
 	})
 
-	errs := s.doExecute(argSets)
+	errs := s.doExecute(argSets, argErrs)
 	return writer.IterateBatchedSend(msg, func(i int, _ types.Part) error {
 		if len(errs) > i {
 			return errs[i]
@@ -6,4 +6,5 @@ package output
 import (
 	// SQL Drivers
 	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
 )
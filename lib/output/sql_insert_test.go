@@ -0,0 +1,394 @@
+package output
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/internal/batch"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSQLDriver is a database/sql driver that records executed statements and
+// transaction outcomes, and fails any statement with an argument "fail".
+type fakeSQLDriver struct {
+	mut sync.Mutex
+	log []fakeSQLEntry
+}
+
+type fakeSQLEntry struct {
+	query string
+	args  []driver.Value
+}
+
+var testSQLDriver = &fakeSQLDriver{}
+
+func init() {
+	sql.Register("benthos_test_sql", testSQLDriver)
+}
+
+func (d *fakeSQLDriver) reset() {
+	d.mut.Lock()
+	d.log = nil
+	d.mut.Unlock()
+}
+
+func (d *fakeSQLDriver) entries() []fakeSQLEntry {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+	return append([]fakeSQLEntry(nil), d.log...)
+}
+
+func (d *fakeSQLDriver) record(query string, args []driver.Value) {
+	d.mut.Lock()
+	d.log = append(d.log, fakeSQLEntry{query: query, args: args})
+	d.mut.Unlock()
+}
+
+func (d *fakeSQLDriver) Open(name string) (driver.Conn, error) {
+	return &fakeSQLConn{d: d}, nil
+}
+
+type fakeSQLConn struct {
+	d *fakeSQLDriver
+}
+
+func (c *fakeSQLConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeSQLStmt{d: c.d, query: query}, nil
+}
+
+func (c *fakeSQLConn) Close() error {
+	return nil
+}
+
+func (c *fakeSQLConn) Begin() (driver.Tx, error) {
+	c.d.record("BEGIN", nil)
+	return c, nil
+}
+
+func (c *fakeSQLConn) Commit() error {
+	c.d.record("COMMIT", nil)
+	return nil
+}
+
+func (c *fakeSQLConn) Rollback() error {
+	c.d.record("ROLLBACK", nil)
+	return nil
+}
+
+type fakeSQLStmt struct {
+	d     *fakeSQLDriver
+	query string
+}
+
+func (s *fakeSQLStmt) Close() error {
+	return nil
+}
+
+func (s *fakeSQLStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeSQLStmt) Exec(args []driver.Value) (driver.Result, error) {
+	for _, a := range args {
+		if a == "fail" {
+			return nil, errors.New("failed on purpose")
+		}
+	}
+	s.d.record(s.query, args)
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeSQLStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+//------------------------------------------------------------------------------
+
+func newTestSQLInsertWriter(t *testing.T, conf SQLInsertConfig) *sqlInsertWriter {
+	t.Helper()
+
+	w, err := newSQLInsertWriter(conf, log.Noop())
+	require.NoError(t, err)
+	require.NoError(t, w.ConnectWithContext(context.Background()))
+	t.Cleanup(w.CloseAsync)
+	return w
+}
+
+func TestSQLInsertBatch(t *testing.T) {
+	testSQLDriver.reset()
+
+	conf := NewSQLInsertConfig()
+	conf.Driver = "benthos_test_sql"
+	conf.Table = "footable"
+	conf.Columns = []string{"id", "content"}
+	conf.ArgsMapping = `root = [ this.id, this.content ]`
+	conf.Suffix = "ON CONFLICT (id) DO NOTHING"
+
+	w := newTestSQLInsertWriter(t, conf)
+
+	require.NoError(t, w.WriteWithContext(context.Background(), message.New([][]byte{
+		[]byte(`{"id":"a","content":"foo"}`),
+		[]byte(`{"id":"b","content":"bar"}`),
+		[]byte(`{"id":"c","content":"baz"}`),
+	})))
+
+	assert.Equal(t, []fakeSQLEntry{
+		{query: "BEGIN"},
+		{
+			query: "INSERT INTO footable (id, content) VALUES (?, ?), (?, ?), (?, ?) ON CONFLICT (id) DO NOTHING",
+			args:  []driver.Value{"a", "foo", "b", "bar", "c", "baz"},
+		},
+		{query: "COMMIT"},
+	}, testSQLDriver.entries())
+}
+
+func TestSQLInsertRollback(t *testing.T) {
+	testSQLDriver.reset()
+
+	conf := NewSQLInsertConfig()
+	conf.Driver = "benthos_test_sql"
+	conf.Table = "footable"
+	conf.Columns = []string{"id", "content"}
+	conf.ArgsMapping = `root = [ this.id, this.content ]`
+
+	w := newTestSQLInsertWriter(t, conf)
+
+	err := w.WriteWithContext(context.Background(), message.New([][]byte{
+		[]byte(`{"id":"a","content":"foo"}`),
+		[]byte(`{"id":"b","content":"fail"}`),
+	}))
+	require.EqualError(t, err, "failed on purpose")
+
+	assert.Equal(t, []fakeSQLEntry{
+		{query: "BEGIN"},
+		{query: "ROLLBACK"},
+	}, testSQLDriver.entries())
+}
+
+func TestSQLInsertChunks(t *testing.T) {
+	testSQLDriver.reset()
+
+	conf := NewSQLInsertConfig()
+	conf.Driver = "benthos_test_sql"
+	conf.Table = "footable"
+	conf.Columns = []string{"id", "content"}
+	conf.ArgsMapping = `root = [ this.id, "foo" ]`
+
+	w := newTestSQLInsertWriter(t, conf)
+
+	chunkSize := sqlMaxPlaceholders / 2
+	parts := make([][]byte, chunkSize+10)
+	for i := range parts {
+		parts[i] = []byte(fmt.Sprintf(`{"id":%v}`, i))
+	}
+	require.NoError(t, w.WriteWithContext(context.Background(), message.New(parts)))
+
+	entries := testSQLDriver.entries()
+	require.Len(t, entries, 4)
+	assert.Equal(t, "BEGIN", entries[0].query)
+	assert.Len(t, entries[1].args, chunkSize*2)
+	assert.Len(t, entries[2].args, 20)
+	assert.True(t, strings.HasPrefix(entries[2].query, "INSERT INTO footable (id, content) VALUES (?, ?), "))
+	assert.Equal(t, "COMMIT", entries[3].query)
+}
+
+func TestSQLInsertMappingErrors(t *testing.T) {
+	testSQLDriver.reset()
+
+	conf := NewSQLInsertConfig()
+	conf.Driver = "benthos_test_sql"
+	conf.Table = "footable"
+	conf.Columns = []string{"id", "content"}
+	conf.ArgsMapping = `root = this.values`
+
+	w := newTestSQLInsertWriter(t, conf)
+
+	err := w.WriteWithContext(context.Background(), message.New([][]byte{
+		[]byte(`{"values":["a","b"]}`),
+		[]byte(`{"values":["c"]}`),
+	}))
+	require.EqualError(t, err, "args mapping returned 1 values, expected 2 to match columns")
+
+	err = w.WriteWithContext(context.Background(), message.New([][]byte{
+		[]byte(`{"values":"nope"}`),
+	}))
+	require.EqualError(t, err, "args mapping returned non-array result: string")
+
+	assert.Empty(t, testSQLDriver.entries())
+}
+
+func TestSQLInsertStatements(t *testing.T) {
+	conf := NewSQLInsertConfig()
+	conf.Driver = "postgres"
+	conf.Table = "footable"
+	conf.Columns = []string{"id", "content"}
+	conf.ArgsMapping = `root = [ this.id, this.content ]`
+
+	w, err := newSQLInsertWriter(conf, log.Noop())
+	require.NoError(t, err)
+
+	assert.Equal(t, "INSERT INTO footable (id, content) VALUES ($1, $2), ($3, $4)", w.insertStatement(2))
+	assert.Equal(t, "COPY footable (id, content) FROM STDIN", w.copyStatement())
+	assert.False(t, w.insertEachRow())
+
+	conf.Suffix = "ON CONFLICT (id) DO UPDATE SET content = EXCLUDED.content"
+
+	w, err = newSQLInsertWriter(conf, log.Noop())
+	require.NoError(t, err)
+
+	assert.True(t, w.insertEachRow())
+
+	conf.Driver = "mysql"
+	conf.Prefix = "INSERT IGNORE"
+	conf.Suffix = "ON DUPLICATE KEY UPDATE content = VALUES(content)"
+
+	w, err = newSQLInsertWriter(conf, log.Noop())
+	require.NoError(t, err)
+
+	assert.Equal(t, "INSERT IGNORE INTO footable (id, content) VALUES (?, ?) ON DUPLICATE KEY UPDATE content = VALUES(content)", w.insertStatement(1))
+	assert.False(t, w.insertEachRow())
+}
+
+func TestSQLInsertConfigErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		fn     func(c *SQLInsertConfig)
+		errStr string
+	}{
+		{
+			name:   "no table",
+			fn:     func(c *SQLInsertConfig) { c.Table = "" },
+			errStr: "a table must be specified",
+		},
+		{
+			name:   "no columns",
+			fn:     func(c *SQLInsertConfig) { c.Columns = nil },
+			errStr: "at least one column must be specified",
+		},
+		{
+			name:   "no mapping",
+			fn:     func(c *SQLInsertConfig) { c.ArgsMapping = "" },
+			errStr: "an args_mapping must be specified",
+		},
+		{
+			name:   "copy without postgres",
+			fn:     func(c *SQLInsertConfig) { c.UseCopy = true },
+			errStr: "use_copy is not supported by the mysql driver",
+		},
+		{
+			name: "copy with suffix",
+			fn: func(c *SQLInsertConfig) {
+				c.Driver = "postgres"
+				c.UseCopy = true
+				c.Suffix = "ON CONFLICT DO NOTHING"
+			},
+			errStr: "use_copy cannot be combined with a prefix or suffix",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			conf := NewSQLInsertConfig()
+			conf.Table = "footable"
+			conf.Columns = []string{"id"}
+			conf.ArgsMapping = `root = [ this.id ]`
+			test.fn(&conf)
+
+			_, err := newSQLInsertWriter(conf, log.Noop())
+			require.EqualError(t, err, test.errStr)
+		})
+	}
+}
+
+func TestSQLArgsMapping(t *testing.T) {
+	testSQLDriver.reset()
+
+	conf := NewSQLConfig()
+	conf.Driver = "benthos_test_sql"
+	conf.Query = "INSERT INTO footable (id, content) VALUES (?, ?)"
+	conf.ArgsMapping = `root = [ this.id, this.content.uppercase() ]`
+
+	w, err := newSQLWriter(conf, log.Noop())
+	require.NoError(t, err)
+	require.NoError(t, w.ConnectWithContext(context.Background()))
+	t.Cleanup(w.CloseAsync)
+
+	err = w.WriteWithContext(context.Background(), message.New([][]byte{
+		[]byte(`{"id":"a","content":"foo"}`),
+		[]byte(`{"id":"b"}`),
+	}))
+	require.Error(t, err)
+
+	// Only the message that failed the mapping is reported as an error.
+	var bErr *batch.Error
+	require.True(t, errors.As(err, &bErr))
+	assert.Equal(t, 1, bErr.IndexedErrors())
+	bErr.WalkParts(func(i int, _ types.Part, err error) bool {
+		assert.Equal(t, i == 1, err != nil, "index %v", i)
+		return true
+	})
+
+	entries := testSQLDriver.entries()
+	require.Len(t, entries, 3)
+	assert.Equal(t, []driver.Value{"a", "FOO"}, entries[1].args)
+	assert.Equal(t, "COMMIT", entries[2].query)
+
+	conf.Args = []string{`${! json("id") }`}
+	_, err = newSQLWriter(conf, log.Noop())
+	require.EqualError(t, err, "cannot specify both args and an args_mapping")
+}
+
+func TestSQLInsertSQLite(t *testing.T) {
+	dsn := "file:" + filepath.Join(t.TempDir(), "foo.db")
+
+	db, err := sql.Open("sqlite", dsn)
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec("CREATE TABLE footable (id TEXT PRIMARY KEY, content TEXT)")
+	require.NoError(t, err)
+
+	conf := NewSQLInsertConfig()
+	conf.Driver = "sqlite"
+	conf.DataSourceName = dsn
+	conf.Table = "footable"
+	conf.Columns = []string{"id", "content"}
+	conf.ArgsMapping = `root = [ this.id, this.content ]`
+	conf.Suffix = "ON CONFLICT (id) DO UPDATE SET content = excluded.content"
+
+	w := newTestSQLInsertWriter(t, conf)
+
+	require.NoError(t, w.WriteWithContext(context.Background(), message.New([][]byte{
+		[]byte(`{"id":"a","content":"foo"}`),
+		[]byte(`{"id":"b","content":"bar"}`),
+	})))
+	require.NoError(t, w.WriteWithContext(context.Background(), message.New([][]byte{
+		[]byte(`{"id":"b","content":"baz"}`),
+		[]byte(`{"id":"c","content":"buz"}`),
+	})))
+
+	rows, err := db.Query("SELECT id, content FROM footable ORDER BY id")
+	require.NoError(t, err)
+	defer rows.Close()
+
+	var results []string
+	for rows.Next() {
+		var id, content string
+		require.NoError(t, rows.Scan(&id, &content))
+		results = append(results, id+":"+content)
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, []string{"a:foo", "b:baz", "c:buz"}, results)
+}
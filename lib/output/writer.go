@@ -126,7 +126,8 @@ func (w *Writer) loop() {
 
 		w.log.Tracef("Attempting to write %v messages to '%v'.\n", ts.Payload.Len(), w.typeStr)
 		spans := tracing.CreateChildSpans("output_"+w.typeStr, ts.Payload)
-		latency, err := w.latencyMeasuringWrite(ts.Payload)
+		payload := tracing.InjectSpans(ts.Payload, spans)
+		latency, err := w.latencyMeasuringWrite(payload)
 
 		// If our writer says it is not connected.
 		if errors.Is(err, types.ErrNotConnected) {
@@ -146,7 +147,7 @@ func (w *Writer) loop() {
 					if !throt.Retry() {
 						return
 					}
-				} else if latency, err = w.latencyMeasuringWrite(ts.Payload); !errors.Is(err, types.ErrNotConnected) {
+				} else if latency, err = w.latencyMeasuringWrite(payload); !errors.Is(err, types.ErrNotConnected) {
 					atomic.StoreInt32(&w.isConnected, 1)
 					mConn.Incr(1)
 					break
@@ -3,7 +3,10 @@ package writer
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -27,28 +30,48 @@ import (
 
 // KafkaConfig contains configuration fields for the Kafka output type.
 type KafkaConfig struct {
-	Addresses      []string    `json:"addresses" yaml:"addresses"`
-	ClientID       string      `json:"client_id" yaml:"client_id"`
-	Key            string      `json:"key" yaml:"key"`
-	Partitioner    string      `json:"partitioner" yaml:"partitioner"`
-	Topic          string      `json:"topic" yaml:"topic"`
-	Compression    string      `json:"compression" yaml:"compression"`
-	MaxMsgBytes    int         `json:"max_msg_bytes" yaml:"max_msg_bytes"`
-	Timeout        string      `json:"timeout" yaml:"timeout"`
-	AckReplicas    bool        `json:"ack_replicas" yaml:"ack_replicas"`
-	TargetVersion  string      `json:"target_version" yaml:"target_version"`
-	TLS            btls.Config `json:"tls" yaml:"tls"`
-	SASL           sasl.Config `json:"sasl" yaml:"sasl"`
-	MaxInFlight    int         `json:"max_in_flight" yaml:"max_in_flight"`
-	retries.Config `json:",inline" yaml:",inline"`
-	RetryAsBatch   bool               `json:"retry_as_batch" yaml:"retry_as_batch"`
-	Batching       batch.PolicyConfig `json:"batching" yaml:"batching"`
-	StaticHeaders  map[string]string  `json:"static_headers" yaml:"static_headers"`
+	Addresses       []string    `json:"addresses" yaml:"addresses"`
+	ClientID        string      `json:"client_id" yaml:"client_id"`
+	Key             string      `json:"key" yaml:"key"`
+	Partitioner     string      `json:"partitioner" yaml:"partitioner"`
+	Topic           string      `json:"topic" yaml:"topic"`
+	Compression     string      `json:"compression" yaml:"compression"`
+	MaxMsgBytes     int         `json:"max_msg_bytes" yaml:"max_msg_bytes"`
+	Timeout         string      `json:"timeout" yaml:"timeout"`
+	AckReplicas     bool        `json:"ack_replicas" yaml:"ack_replicas"`
+	TargetVersion   string      `json:"target_version" yaml:"target_version"`
+	TLS             btls.Config `json:"tls" yaml:"tls"`
+	SASL            sasl.Config `json:"sasl" yaml:"sasl"`
+	MaxInFlight     int         `json:"max_in_flight" yaml:"max_in_flight"`
+	retries.Config  `json:",inline" yaml:",inline"`
+	RetryAsBatch    bool                   `json:"retry_as_batch" yaml:"retry_as_batch"`
+	IdempotentWrite bool                   `json:"idempotent_write" yaml:"idempotent_write"`
+	Transaction     KafkaTransactionConfig `json:"transaction" yaml:"transaction"`
+	Batching        batch.PolicyConfig     `json:"batching" yaml:"batching"`
+	StaticHeaders   map[string]string      `json:"static_headers" yaml:"static_headers"`
 
 	// TODO: V4 remove this.
 	RoundRobinPartitions bool `json:"round_robin_partitions" yaml:"round_robin_partitions"`
 }
 
+// KafkaTransactionConfig contains configuration fields for writing batches
+// to Kafka within transactions.
+type KafkaTransactionConfig struct {
+	ID            string `json:"id" yaml:"id"`
+	ConsumerGroup string `json:"consumer_group" yaml:"consumer_group"`
+	Timeout       string `json:"timeout" yaml:"timeout"`
+}
+
+// NewKafkaTransactionConfig creates a new KafkaTransactionConfig with default
+// values.
+func NewKafkaTransactionConfig() KafkaTransactionConfig {
+	return KafkaTransactionConfig{
+		ID:            "",
+		ConsumerGroup: "",
+		Timeout:       "1m",
+	}
+}
+
 // NewKafkaConfig creates a new KafkaConfig with default values.
 func NewKafkaConfig() KafkaConfig {
 	rConf := retries.NewConfig()
@@ -74,6 +97,8 @@ func NewKafkaConfig() KafkaConfig {
 		MaxInFlight:          1,
 		Config:               rConf,
 		RetryAsBatch:         false,
+		IdempotentWrite:      false,
+		Transaction:          NewKafkaTransactionConfig(),
 		Batching:             batch.NewPolicyConfig(),
 	}
 }
@@ -88,8 +113,9 @@ type Kafka struct {
 
 	backoffCtor func() backoff.BackOff
 
-	tlsConf *tls.Config
-	timeout time.Duration
+	tlsConf    *tls.Config
+	timeout    time.Duration
+	txnTimeout time.Duration
 
 	addresses []string
 	version   sarama.KafkaVersion
@@ -163,6 +189,22 @@ func NewKafka(conf KafkaConfig, mgr types.Manager, log log.Modular, stats metric
 		return nil, err
 	}
 
+	if conf.IdempotentWrite || len(conf.Transaction.ID) > 0 {
+		if !k.version.IsAtLeast(sarama.V0_11_0_0) {
+			return nil, errors.New("idempotent and transactional writes require a target_version of at least 0.11.0")
+		}
+	}
+	if len(conf.Transaction.ID) > 0 {
+		if conf.MaxInFlight > 1 {
+			return nil, errors.New("transactional writes require a max_in_flight of 1")
+		}
+		if k.txnTimeout, err = time.ParseDuration(conf.Transaction.Timeout); err != nil {
+			return nil, fmt.Errorf("failed to parse transaction timeout string: %v", err)
+		}
+	} else if len(conf.Transaction.ConsumerGroup) > 0 {
+		return nil, errors.New("a transaction id must be specified in order to commit consumer group offsets")
+	}
+
 	for _, addr := range conf.Addresses {
 		for _, splitAddr := range strings.Split(addr, ",") {
 			if trimmed := strings.TrimSpace(splitAddr); len(trimmed) > 0 {
@@ -291,6 +333,16 @@ func (k *Kafka) Connect() error {
 		config.Producer.RequiredAcks = sarama.WaitForLocal
 	}
 
+	if k.conf.IdempotentWrite || len(k.conf.Transaction.ID) > 0 {
+		config.Producer.Idempotent = true
+		config.Producer.RequiredAcks = sarama.WaitForAll
+		config.Net.MaxOpenRequests = 1
+	}
+	if len(k.conf.Transaction.ID) > 0 {
+		config.Producer.Transaction.ID = k.conf.Transaction.ID
+		config.Producer.Transaction.Timeout = k.txnTimeout
+	}
+
 	var err error
 	k.producer, err = sarama.NewSyncProducer(k.addresses, config)
 
@@ -337,6 +389,10 @@ func (k *Kafka) WriteWithContext(ctx context.Context, msg types.Message) error {
 		return nil
 	})
 
+	if producer.IsTransactional() {
+		return k.writeTransaction(ctx, producer, msg, msgs, boff)
+	}
+
 	err := producer.SendMessages(msgs)
 	for err != nil {
 		if pErrs, ok := err.(sarama.ProducerErrors); !k.conf.RetryAsBatch && ok {
@@ -388,6 +444,134 @@ func (k *Kafka) WriteWithContext(ctx context.Context, msg types.Message) error {
 	return nil
 }
 
+// txnOffsets extracts the offsets to commit for a consumer group from the
+// kafka input metadata of a message batch. The offset of each topic partition
+// is one greater than the highest offset consumed from it.
+func txnOffsets(msg types.Message) map[string][]*sarama.PartitionOffsetMetadata {
+	type topicPartition struct {
+		topic     string
+		partition int32
+	}
+	highest := map[topicPartition]int64{}
+	msg.Iter(func(i int, p types.Part) error {
+		meta := p.Metadata()
+		topic := meta.Get("kafka_topic")
+		if len(topic) == 0 {
+			return nil
+		}
+		partition, err := strconv.ParseInt(meta.Get("kafka_partition"), 10, 32)
+		if err != nil {
+			return nil
+		}
+		offset, err := strconv.ParseInt(meta.Get("kafka_offset"), 10, 64)
+		if err != nil {
+			return nil
+		}
+		tp := topicPartition{topic: topic, partition: int32(partition)}
+		if current, exists := highest[tp]; !exists || offset > current {
+			highest[tp] = offset
+		}
+		return nil
+	})
+	if len(highest) == 0 {
+		return nil
+	}
+
+	offsets := map[string][]*sarama.PartitionOffsetMetadata{}
+	for tp, offset := range highest {
+		offsets[tp.topic] = append(offsets[tp.topic], &sarama.PartitionOffsetMetadata{
+			Partition: tp.partition,
+			Offset:    offset + 1,
+		})
+	}
+	for _, partitions := range offsets {
+		sort.Slice(partitions, func(i, j int) bool {
+			return partitions[i].Partition < partitions[j].Partition
+		})
+	}
+	return offsets
+}
+
+// sendTransaction writes a batch of messages, along with any consumed offsets,
+// within a single transaction. If any step fails the transaction is aborted.
+func (k *Kafka) sendTransaction(producer sarama.SyncProducer, msg types.Message, msgs []*sarama.ProducerMessage) error {
+	if err := producer.BeginTxn(); err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	err := producer.SendMessages(msgs)
+	if err == nil && len(k.conf.Transaction.ConsumerGroup) > 0 {
+		if offsets := txnOffsets(msg); len(offsets) > 0 {
+			if err = producer.AddOffsetsToTxn(offsets, k.conf.Transaction.ConsumerGroup); err != nil {
+				err = fmt.Errorf("failed to add offsets to transaction: %w", err)
+			}
+		}
+	}
+	if err == nil {
+		if err = producer.CommitTxn(); err == nil {
+			return nil
+		}
+		err = fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if producer.TxnStatus()&sarama.ProducerTxnFlagFatalError == 0 {
+		if aerr := producer.AbortTxn(); aerr != nil {
+			k.log.Errorf("Failed to abort transaction: %v\n", aerr)
+		}
+	}
+	return err
+}
+
+// writeTransaction attempts to write a batch of messages within a transaction,
+// retrying the entire batch in a new transaction until it succeeds or the
+// backoff is exhausted.
+func (k *Kafka) writeTransaction(
+	ctx context.Context,
+	producer sarama.SyncProducer,
+	msg types.Message,
+	msgs []*sarama.ProducerMessage,
+	boff backoff.BackOff,
+) error {
+	for {
+		err := k.sendTransaction(producer, msg, msgs)
+		if err == nil {
+			return nil
+		}
+		k.log.Errorf("Failed to send messages: %v\n", err)
+
+		if producer.TxnStatus()&sarama.ProducerTxnFlagFatalError != 0 {
+			// The producer cannot recover from a fatal error and must be
+			// recreated, which also fences any previous transactions.
+			k.connMut.Lock()
+			if k.producer == producer {
+				k.producer.Close()
+				k.producer = nil
+			}
+			k.connMut.Unlock()
+			return types.ErrNotConnected
+		}
+
+		tNext := boff.NextBackOff()
+		if tNext == backoff.Stop {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(tNext):
+		}
+
+		// Recheck connection is alive
+		k.connMut.RLock()
+		producer = k.producer
+		k.connMut.RUnlock()
+
+		if producer == nil {
+			return types.ErrNotConnected
+		}
+	}
+}
+
 // CloseAsync shuts down the Kafka writer and stops processing messages.
 func (k *Kafka) CloseAsync() {
 	go func() {
@@ -0,0 +1,233 @@
+package writer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockTxnProducer struct {
+	status   sarama.ProducerTxnStatusFlag
+	sendErrs []error
+
+	calls   []string
+	sent    [][]*sarama.ProducerMessage
+	offsets []map[string][]*sarama.PartitionOffsetMetadata
+	groups  []string
+	closed  bool
+}
+
+func (m *mockTxnProducer) SendMessage(msg *sarama.ProducerMessage) (int32, int64, error) {
+	return 0, 0, m.SendMessages([]*sarama.ProducerMessage{msg})
+}
+
+func (m *mockTxnProducer) SendMessages(msgs []*sarama.ProducerMessage) error {
+	m.calls = append(m.calls, "send")
+	m.sent = append(m.sent, msgs)
+	if len(m.sendErrs) > 0 {
+		err := m.sendErrs[0]
+		m.sendErrs = m.sendErrs[1:]
+		if err != nil {
+			m.status = sarama.ProducerTxnFlagInError | sarama.ProducerTxnFlagAbortableError
+		}
+		return err
+	}
+	return nil
+}
+
+func (m *mockTxnProducer) Close() error {
+	m.closed = true
+	return nil
+}
+
+func (m *mockTxnProducer) TxnStatus() sarama.ProducerTxnStatusFlag {
+	return m.status
+}
+
+func (m *mockTxnProducer) IsTransactional() bool {
+	return true
+}
+
+func (m *mockTxnProducer) BeginTxn() error {
+	m.calls = append(m.calls, "begin")
+	m.status = sarama.ProducerTxnFlagInTransaction
+	return nil
+}
+
+func (m *mockTxnProducer) CommitTxn() error {
+	m.calls = append(m.calls, "commit")
+	m.status = sarama.ProducerTxnFlagReady
+	return nil
+}
+
+func (m *mockTxnProducer) AbortTxn() error {
+	m.calls = append(m.calls, "abort")
+	m.status = sarama.ProducerTxnFlagReady
+	return nil
+}
+
+func (m *mockTxnProducer) AddOffsetsToTxn(offsets map[string][]*sarama.PartitionOffsetMetadata, groupID string) error {
+	m.calls = append(m.calls, "offsets")
+	m.offsets = append(m.offsets, offsets)
+	m.groups = append(m.groups, groupID)
+	return nil
+}
+
+func (m *mockTxnProducer) AddMessageToTxn(msg *sarama.ConsumerMessage, groupID string, metadata *string) error {
+	return errors.New("not implemented")
+}
+
+//------------------------------------------------------------------------------
+
+func newTxnTestWriter(t *testing.T, conf KafkaConfig, producer sarama.SyncProducer) *Kafka {
+	t.Helper()
+
+	conf.Backoff.InitialInterval = "1ms"
+	conf.Backoff.MaxInterval = "1ms"
+
+	k, err := NewKafka(conf, types.NoopMgr(), log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	k.producer = producer
+	return k
+}
+
+func kafkaInputMsg(content, topic, partition, offset string) types.Part {
+	p := message.NewPart([]byte(content))
+	p.Metadata().Set("kafka_topic", topic)
+	p.Metadata().Set("kafka_partition", partition)
+	p.Metadata().Set("kafka_offset", offset)
+	return p
+}
+
+func TestKafkaTransactionConfigErrors(t *testing.T) {
+	conf := NewKafkaConfig()
+	conf.TargetVersion = "0.10.2.0"
+	conf.IdempotentWrite = true
+	_, err := NewKafka(conf, types.NoopMgr(), log.Noop(), metrics.Noop())
+	assert.EqualError(t, err, "idempotent and transactional writes require a target_version of at least 0.11.0")
+
+	conf = NewKafkaConfig()
+	conf.Transaction.ID = "foo"
+	conf.MaxInFlight = 2
+	_, err = NewKafka(conf, types.NoopMgr(), log.Noop(), metrics.Noop())
+	assert.EqualError(t, err, "transactional writes require a max_in_flight of 1")
+
+	conf = NewKafkaConfig()
+	conf.Transaction.ConsumerGroup = "foo"
+	_, err = NewKafka(conf, types.NoopMgr(), log.Noop(), metrics.Noop())
+	assert.EqualError(t, err, "a transaction id must be specified in order to commit consumer group offsets")
+
+	conf = NewKafkaConfig()
+	conf.Transaction.ID = "foo"
+	conf.Transaction.Timeout = "nope"
+	_, err = NewKafka(conf, types.NoopMgr(), log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+}
+
+func TestKafkaTxnOffsets(t *testing.T) {
+	msg := message.New(nil)
+	msg.Append(kafkaInputMsg("a", "foo", "1", "10"))
+	msg.Append(kafkaInputMsg("b", "foo", "0", "5"))
+	msg.Append(kafkaInputMsg("c", "foo", "1", "12"))
+	msg.Append(kafkaInputMsg("d", "foo", "1", "11"))
+	msg.Append(kafkaInputMsg("e", "bar", "0", "3"))
+	msg.Append(kafkaInputMsg("f", "bar", "nope", "4"))
+	msg.Append(message.NewPart([]byte("g")))
+
+	assert.Equal(t, map[string][]*sarama.PartitionOffsetMetadata{
+		"foo": {
+			{Partition: 0, Offset: 6},
+			{Partition: 1, Offset: 13},
+		},
+		"bar": {
+			{Partition: 0, Offset: 4},
+		},
+	}, txnOffsets(msg))
+
+	assert.Nil(t, txnOffsets(message.New([][]byte{[]byte("foo")})))
+}
+
+func TestKafkaTransactionWrite(t *testing.T) {
+	conf := NewKafkaConfig()
+	conf.Topic = "out"
+	conf.Transaction.ID = "foo"
+	conf.Transaction.ConsumerGroup = "bar"
+
+	producer := &mockTxnProducer{}
+	k := newTxnTestWriter(t, conf, producer)
+
+	msg := message.New(nil)
+	msg.Append(kafkaInputMsg("a", "in", "0", "10"))
+	msg.Append(kafkaInputMsg("b", "in", "0", "11"))
+
+	require.NoError(t, k.WriteWithContext(context.Background(), msg))
+
+	assert.Equal(t, []string{"begin", "send", "offsets", "commit"}, producer.calls)
+	require.Len(t, producer.sent, 1)
+	assert.Len(t, producer.sent[0], 2)
+	assert.Equal(t, "out", producer.sent[0][0].Topic)
+	assert.Equal(t, []string{"bar"}, producer.groups)
+	assert.Equal(t, []map[string][]*sarama.PartitionOffsetMetadata{
+		{"in": {{Partition: 0, Offset: 12}}},
+	}, producer.offsets)
+}
+
+func TestKafkaTransactionWriteRetry(t *testing.T) {
+	conf := NewKafkaConfig()
+	conf.Transaction.ID = "foo"
+	conf.Transaction.ConsumerGroup = "bar"
+
+	producer := &mockTxnProducer{
+		sendErrs: []error{errors.New("nope"), nil},
+	}
+	k := newTxnTestWriter(t, conf, producer)
+
+	msg := message.New(nil)
+	msg.Append(kafkaInputMsg("a", "in", "0", "10"))
+
+	require.NoError(t, k.WriteWithContext(context.Background(), msg))
+
+	assert.Equal(t, []string{
+		"begin", "send", "abort",
+		"begin", "send", "offsets", "commit",
+	}, producer.calls)
+	require.Len(t, producer.sent, 2)
+	assert.Equal(t, producer.sent[0], producer.sent[1])
+}
+
+func TestKafkaTransactionWriteFatal(t *testing.T) {
+	conf := NewKafkaConfig()
+	conf.Transaction.ID = "foo"
+
+	// Simulate a fatal error that cannot be aborted.
+	producer := &mockTxnProducer{
+		sendErrs: []error{errors.New("nope")},
+	}
+	k := newTxnTestWriter(t, conf, &fatalTxnProducer{mockTxnProducer: producer})
+
+	err := k.WriteWithContext(context.Background(), message.New([][]byte{[]byte("foo")}))
+	assert.Equal(t, types.ErrNotConnected, err)
+
+	assert.Equal(t, []string{"begin", "send"}, producer.calls)
+	assert.True(t, producer.closed)
+	assert.Nil(t, k.producer)
+}
+
+type fatalTxnProducer struct {
+	*mockTxnProducer
+}
+
+func (f *fatalTxnProducer) SendMessages(msgs []*sarama.ProducerMessage) error {
+	err := f.mockTxnProducer.SendMessages(msgs)
+	f.status = sarama.ProducerTxnFlagInError | sarama.ProducerTxnFlagFatalError
+	return err
+}
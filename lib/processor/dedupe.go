@@ -10,11 +10,13 @@ import (
 	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
 	"github.com/Jeffail/benthos/v3/internal/docs"
 	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/message/tracing"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/response"
 	"github.com/Jeffail/benthos/v3/lib/types"
 	"github.com/OneOfOne/xxhash"
+	"github.com/opentracing/opentracing-go"
 	olog "github.com/opentracing/opentracing-go/log"
 )
 
@@ -30,9 +32,11 @@ func init() {
 Deduplicates message batches by caching selected (and optionally hashed)
 messages, dropping batches that are already cached.`,
 		Description: `
-This processor acts across an entire batch, in order to deduplicate individual
-messages within a batch use this processor with the
-` + "[`for_each`](/docs/components/processors/for_each)" + ` processor.
+By default this processor acts across an entire batch. In order to deduplicate
+individual messages within a batch set the field ` + "`per_message`" + ` to
+` + "`true`" + `, in which case duplicates within the same batch are removed
+before the cache is consulted, and the field ` + "`keep`" + ` determines whether
+the first or last message of a group of duplicates within a batch is retained.
 
 Optionally, the ` + "`key`" + ` field can be populated in order to hash on a
 function interpolated string rather than the full contents of messages. This
@@ -60,6 +64,40 @@ always wrap the output within a ` + "[`retry`](/docs/components/outputs/retry)"
 block. This ensures that during outages your messages aren't reprocessed after
 failures, which would result in messages being dropped.
 
+## Deduplication Windows
+
+The field ` + "`ttl`" + ` can be used in order to define a precise window of time
+within which messages are considered duplicates, after which the key expires
+from the cache. This requires a cache type that supports per-key TTLs, such as
+` + "`redis`" + ` or ` + "`ristretto`" + `.
+
+## Marking Duplicates
+
+Rather than dropping duplicates it's possible to keep them and mark them with a
+metadata field by setting ` + "`duplicate_metadata`" + `, which allows them to be
+routed elsewhere. For example, the following config routes duplicate webhook
+deliveries seen within a 24 hour window to a separate output:
+
+` + "```yaml" + `
+pipeline:
+  processors:
+    - dedupe:
+        cache: foocache
+        key: ${! json("delivery_id") }
+        ttl: 24h
+        per_message: true
+        duplicate_metadata: is_duplicate
+
+output:
+  switch:
+    cases:
+      - check: meta("is_duplicate") == "true"
+        output:
+          resource: duplicates_output
+      - output:
+          resource: main_output
+` + "```" + `
+
 ## Delivery Guarantees
 
 Performing deduplication on a stream using a distributed cache voids any
@@ -77,6 +115,10 @@ service restarts increase the chances of duplicates passing undetected.`,
 			docs.FieldCommon("hash", "The hash type to used.").HasOptions("none", "xxhash"),
 			docs.FieldCommon("key", "An optional key to use for deduplication (instead of the entire message contents).").SupportsInterpolation(true),
 			docs.FieldCommon("drop_on_err", "Whether messages should be dropped when the cache returns an error."),
+			docs.FieldCommon("ttl", "An optional duration defining the deduplication window, after which keys expire from the cache. Requires a cache type that supports per-key TTLs.", "24h", "60s").AtVersion("3.41.0"),
+			docs.FieldCommon("per_message", "Whether to deduplicate each message of a batch individually rather than the batch as a whole. When enabled the field `parts` is ignored.").AtVersion("3.41.0"),
+			docs.FieldAdvanced("keep", "When `per_message` is enabled this determines which message of a group of duplicates within the same batch is retained.").HasOptions("first", "last").AtVersion("3.41.0"),
+			docs.FieldCommon("duplicate_metadata", "An optional metadata key that, when set, causes duplicates to be retained and marked by setting this key to `true` rather than being dropped.", "is_duplicate").AtVersion("3.41.0"),
 			docs.FieldAdvanced("parts", "An array of message indexes within the batch to deduplicate based on. If left empty all messages included. This field is only applicable when batching messages [at the input level](/docs/configuration/batching)."),
 		},
 	}
@@ -91,6 +133,10 @@ type DedupeConfig struct {
 	Parts          []int  `json:"parts" yaml:"parts"` // message parts to hash
 	Key            string `json:"key" yaml:"key"`
 	DropOnCacheErr bool   `json:"drop_on_err" yaml:"drop_on_err"`
+	TTL            string `json:"ttl" yaml:"ttl"`
+	PerMessage     bool   `json:"per_message" yaml:"per_message"`
+	Keep           string `json:"keep" yaml:"keep"`
+	DuplicateMeta  string `json:"duplicate_metadata" yaml:"duplicate_metadata"`
 }
 
 // NewDedupeConfig returns a DedupeConfig with default values.
@@ -101,6 +147,10 @@ func NewDedupeConfig() DedupeConfig {
 		Parts:          []int{0}, // only consider the 1st part
 		Key:            "",
 		DropOnCacheErr: true,
+		TTL:            "",
+		PerMessage:     false,
+		Keep:           "first",
+		DuplicateMeta:  "",
 	}
 }
 
@@ -157,6 +207,8 @@ type Dedupe struct {
 	key field.Expression
 
 	cache      types.Cache
+	ttl        *time.Duration
+	keepLast   bool
 	hasherFunc hasherFunc
 
 	mCount     metrics.StatCounter
@@ -187,6 +239,27 @@ func NewDedupe(
 		return nil, fmt.Errorf("failed to parse key expression: %v", err)
 	}
 
+	var ttl *time.Duration
+	if conf.Dedupe.TTL != "" {
+		if _, ok := c.(types.CacheWithTTL); !ok {
+			return nil, fmt.Errorf("this cache type does not support per-key ttl")
+		}
+		td, err := time.ParseDuration(conf.Dedupe.TTL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ttl: %v", err)
+		}
+		ttl = &td
+	}
+
+	var keepLast bool
+	switch conf.Dedupe.Keep {
+	case "first":
+	case "last":
+		keepLast = true
+	default:
+		return nil, fmt.Errorf("keep option not recognised: %v", conf.Dedupe.Keep)
+	}
+
 	return &Dedupe{
 		conf:  conf,
 		log:   log,
@@ -195,6 +268,8 @@ func NewDedupe(
 		key: key,
 
 		cache:      c,
+		ttl:        ttl,
+		keepLast:   keepLast,
 		hasherFunc: hFunc,
 
 		mCount:     stats.GetCounter("count"),
@@ -209,14 +284,24 @@ func NewDedupe(
 
 //------------------------------------------------------------------------------
 
+// addKey attempts to add a key to the cache, returning
+// types.ErrKeyAlreadyExists if the key is a duplicate.
+func (d *Dedupe) addKey(key []byte) error {
+	if cttl, ok := d.cache.(types.CacheWithTTL); ok {
+		return cttl.AddWithTTL(string(key), []byte{'t'}, d.ttl)
+	}
+	return d.cache.Add(string(key), []byte{'t'})
+}
+
+func (d *Dedupe) markDuplicate(part types.Part) {
+	part.Metadata().Set(d.conf.Dedupe.DuplicateMeta, "true")
+}
+
 // ProcessMessage applies the processor to a message, either creating >0
 // resulting messages or a response to be sent back to the message source.
 func (d *Dedupe) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
 	d.mCount.Incr(1)
 
-	extractedHash := false
-	hasher := d.hasherFunc()
-
 	spans := tracing.CreateChildSpans(TypeDedupe, msg)
 	defer func() {
 		for _, s := range spans {
@@ -224,6 +309,13 @@ func (d *Dedupe) ProcessMessage(msg types.Message) ([]types.Message, types.Respo
 		}
 	}()
 
+	if d.conf.Dedupe.PerMessage {
+		return d.processPerMessage(msg, spans)
+	}
+
+	extractedHash := false
+	hasher := d.hasherFunc()
+
 	key := d.key.Bytes(0, msg)
 	if len(key) > 0 {
 		hasher.Write(key)
@@ -249,7 +341,7 @@ func (d *Dedupe) ProcessMessage(msg types.Message) ([]types.Message, types.Respo
 			d.mDropped.Incr(1)
 			return nil, response.NewAck()
 		}
-	} else if err := d.cache.Add(string(hasher.Bytes()), []byte{'t'}); err != nil {
+	} else if err := d.addKey(hasher.Bytes()); err != nil {
 		if err != types.ErrKeyAlreadyExists {
 			d.mErrCache.Incr(1)
 			d.mErr.Incr(1)
@@ -264,6 +356,12 @@ func (d *Dedupe) ProcessMessage(msg types.Message) ([]types.Message, types.Respo
 				d.mDropped.Incr(1)
 				return nil, response.NewAck()
 			}
+		} else if d.conf.Dedupe.DuplicateMeta != "" {
+			msg = msg.Copy()
+			msg.Iter(func(i int, p types.Part) error {
+				d.markDuplicate(p)
+				return nil
+			})
 		} else {
 			for _, s := range spans {
 				s.LogFields(
@@ -282,6 +380,100 @@ func (d *Dedupe) ProcessMessage(msg types.Message) ([]types.Message, types.Respo
 	return msgs[:], nil
 }
 
+func (d *Dedupe) processPerMessage(msg types.Message, spans []opentracing.Span) ([]types.Message, types.Response) {
+	// Extract the hash of each message, and detect duplicates within the batch
+	// before consulting the cache.
+	hashes := make([][]byte, msg.Len())
+	duplicate := make([]bool, msg.Len())
+	retain := make([]bool, msg.Len())
+	seen := map[string]int{}
+
+	msg.Iter(func(i int, p types.Part) error {
+		hasher := d.hasherFunc()
+		if key := d.key.Bytes(i, msg); len(key) > 0 {
+			hasher.Write(key)
+		} else if partBytes := p.Get(); partBytes != nil {
+			if _, err := hasher.Write(partBytes); err != nil {
+				d.mErrHash.Incr(1)
+				d.mErr.Incr(1)
+				d.log.Errorf("Hash error: %v\n", err)
+				retain[i] = !d.conf.Dedupe.DropOnCacheErr
+				return nil
+			}
+		} else {
+			retain[i] = !d.conf.Dedupe.DropOnCacheErr
+			return nil
+		}
+
+		hashes[i] = hasher.Bytes()
+		if j, exists := seen[string(hashes[i])]; exists {
+			if d.keepLast {
+				duplicate[j], hashes[j] = true, nil
+				seen[string(hashes[i])] = i
+			} else {
+				duplicate[i], hashes[i] = true, nil
+			}
+		} else {
+			seen[string(hashes[i])] = i
+		}
+		return nil
+	})
+
+	for i, hash := range hashes {
+		if hash == nil {
+			continue
+		}
+		err := d.addKey(hash)
+		if err == nil {
+			retain[i] = true
+			continue
+		}
+		if err == types.ErrKeyAlreadyExists {
+			duplicate[i] = true
+			continue
+		}
+		d.mErrCache.Incr(1)
+		d.mErr.Incr(1)
+		d.log.Errorf("Cache error: %v\n", err)
+		spans[i].LogFields(
+			olog.String("event", "error"),
+			olog.String("type", err.Error()),
+		)
+		retain[i] = !d.conf.Dedupe.DropOnCacheErr
+	}
+
+	newMsg := message.New(nil)
+	msg.Iter(func(i int, p types.Part) error {
+		if duplicate[i] {
+			if d.conf.Dedupe.DuplicateMeta != "" {
+				p = p.Copy()
+				d.markDuplicate(p)
+				newMsg.Append(p)
+				return nil
+			}
+			spans[i].LogFields(
+				olog.String("event", "dropped"),
+				olog.String("type", "deduplicated"),
+			)
+		}
+		if retain[i] && !duplicate[i] {
+			newMsg.Append(p)
+			return nil
+		}
+		d.mDropped.Incr(1)
+		return nil
+	})
+
+	if newMsg.Len() == 0 {
+		return nil, response.NewAck()
+	}
+
+	d.mBatchSent.Incr(1)
+	d.mSent.Incr(int64(newMsg.Len()))
+	msgs := [1]types.Message{newMsg}
+	return msgs[:], nil
+}
+
 // CloseAsync shuts down the processor and stops processing requests.
 func (d *Dedupe) CloseAsync() {
 }
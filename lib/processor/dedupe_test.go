@@ -15,6 +15,8 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/response"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func init() {
@@ -462,3 +464,239 @@ func randStringRunes(n int) string {
 	}
 	return string(b)
 }
+
+type ttlRecordingCache struct {
+	types.Cache
+	ttls map[string]*time.Duration
+}
+
+func (c *ttlRecordingCache) SetWithTTL(key string, value []byte, ttl *time.Duration) error {
+	c.ttls[key] = ttl
+	return c.Set(key, value)
+}
+
+func (c *ttlRecordingCache) SetMultiWithTTL(items map[string]types.CacheTTLItem) error {
+	for k, v := range items {
+		if err := c.SetWithTTL(k, v.Value, v.TTL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *ttlRecordingCache) AddWithTTL(key string, value []byte, ttl *time.Duration) error {
+	if err := c.Add(key, value); err != nil {
+		return err
+	}
+	c.ttls[key] = ttl
+	return nil
+}
+
+func newDedupeTestMgr(t *testing.T) (*fakeMgr, *ttlRecordingCache) {
+	t.Helper()
+
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	ttlCache := &ttlRecordingCache{Cache: memCache, ttls: map[string]*time.Duration{}}
+	return &fakeMgr{
+		caches: map[string]types.Cache{
+			"memcache": memCache,
+			"ttlcache": ttlCache,
+		},
+	}, ttlCache
+}
+
+func dedupeBatchContents(msgs []types.Message, metaKey string) []string {
+	var contents []string
+	for _, m := range msgs {
+		m.Iter(func(i int, p types.Part) error {
+			str := string(p.Get())
+			if metaKey != "" && p.Metadata().Get(metaKey) != "" {
+				str += " (" + p.Metadata().Get(metaKey) + ")"
+			}
+			contents = append(contents, str)
+			return nil
+		})
+	}
+	return contents
+}
+
+func TestDedupeTTL(t *testing.T) {
+	mgr, ttlCache := newDedupeTestMgr(t)
+
+	conf := NewConfig()
+	conf.Dedupe.Cache = "ttlcache"
+	conf.Dedupe.TTL = "24h"
+
+	proc, err := NewDedupe(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte("foo")}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+
+	msgs, res = proc.ProcessMessage(message.New([][]byte{[]byte("foo")}))
+	require.Len(t, msgs, 0)
+	require.Equal(t, response.NewAck(), res)
+
+	require.Contains(t, ttlCache.ttls, "foo")
+	require.NotNil(t, ttlCache.ttls["foo"])
+	assert.Equal(t, time.Hour*24, *ttlCache.ttls["foo"])
+
+	conf.Dedupe.Cache = "memcache"
+	_, err = NewDedupe(conf, mgr, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "this cache type does not support per-key ttl")
+
+	conf.Dedupe.Cache = "ttlcache"
+	conf.Dedupe.TTL = "not a duration"
+	_, err = NewDedupe(conf, mgr, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+}
+
+func TestDedupePerMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		keep    string
+		metaKey string
+		batches [][]string
+		outputs [][]string
+	}{
+		{
+			name: "keep first",
+			keep: "first",
+			batches: [][]string{
+				{`{"id":"a","v":1}`, `{"id":"b","v":2}`, `{"id":"a","v":3}`},
+				{`{"id":"b","v":4}`, `{"id":"c","v":5}`},
+			},
+			outputs: [][]string{
+				{`{"id":"a","v":1}`, `{"id":"b","v":2}`},
+				{`{"id":"c","v":5}`},
+			},
+		},
+		{
+			name: "keep last",
+			keep: "last",
+			batches: [][]string{
+				{`{"id":"a","v":1}`, `{"id":"b","v":2}`, `{"id":"a","v":3}`},
+				{`{"id":"a","v":4}`, `{"id":"c","v":5}`, `{"id":"c","v":6}`},
+			},
+			outputs: [][]string{
+				{`{"id":"b","v":2}`, `{"id":"a","v":3}`},
+				{`{"id":"c","v":6}`},
+			},
+		},
+		{
+			name:    "mark duplicates",
+			keep:    "first",
+			metaKey: "is_duplicate",
+			batches: [][]string{
+				{`{"id":"a","v":1}`, `{"id":"a","v":2}`},
+				{`{"id":"a","v":3}`, `{"id":"b","v":4}`},
+			},
+			outputs: [][]string{
+				{`{"id":"a","v":1}`, `{"id":"a","v":2} (true)`},
+				{`{"id":"a","v":3} (true)`, `{"id":"b","v":4}`},
+			},
+		},
+		{
+			name: "all duplicates",
+			keep: "first",
+			batches: [][]string{
+				{`{"id":"a","v":1}`},
+				{`{"id":"a","v":2}`, `{"id":"a","v":3}`},
+			},
+			outputs: [][]string{
+				{`{"id":"a","v":1}`},
+				nil,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			mgr, _ := newDedupeTestMgr(t)
+
+			conf := NewConfig()
+			conf.Dedupe.Cache = "memcache"
+			conf.Dedupe.Key = `${! json("id") }`
+			conf.Dedupe.PerMessage = true
+			conf.Dedupe.Keep = test.keep
+			conf.Dedupe.DuplicateMeta = test.metaKey
+
+			proc, err := NewDedupe(conf, mgr, log.Noop(), metrics.Noop())
+			require.NoError(t, err)
+
+			for i, batch := range test.batches {
+				var parts [][]byte
+				for _, p := range batch {
+					parts = append(parts, []byte(p))
+				}
+				msgs, res := proc.ProcessMessage(message.New(parts))
+				if test.outputs[i] == nil {
+					assert.Len(t, msgs, 0)
+					assert.Equal(t, response.NewAck(), res)
+					continue
+				}
+				require.Nil(t, res)
+				assert.Equal(t, test.outputs[i], dedupeBatchContents(msgs, test.metaKey), "batch %v", i)
+			}
+		})
+	}
+}
+
+func TestDedupeMarkBatch(t *testing.T) {
+	mgr, _ := newDedupeTestMgr(t)
+
+	conf := NewConfig()
+	conf.Dedupe.Cache = "memcache"
+	conf.Dedupe.DuplicateMeta = "is_duplicate"
+
+	proc, err := NewDedupe(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	input := message.New([][]byte{[]byte("foo"), []byte("bar")})
+
+	msgs, res := proc.ProcessMessage(input)
+	require.Nil(t, res)
+	assert.Equal(t, []string{"foo", "bar"}, dedupeBatchContents(msgs, "is_duplicate"))
+
+	msgs, res = proc.ProcessMessage(input)
+	require.Nil(t, res)
+	assert.Equal(t, []string{"foo (true)", "bar (true)"}, dedupeBatchContents(msgs, "is_duplicate"))
+
+	// The original message must not be modified.
+	assert.Equal(t, "", input.Get(0).Metadata().Get("is_duplicate"))
+}
+
+func TestDedupePerMessageCacheErrors(t *testing.T) {
+	conf := NewConfig()
+	conf.Dedupe.Cache = "foocache"
+	conf.Dedupe.PerMessage = true
+
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": errCache{},
+		},
+	}
+
+	proc, err := NewDedupe(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte("foo"), []byte("bar")}))
+	assert.Len(t, msgs, 0)
+	assert.Equal(t, response.NewAck(), res)
+
+	conf.Dedupe.DropOnCacheErr = false
+	proc, err = NewDedupe(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res = proc.ProcessMessage(message.New([][]byte{[]byte("foo"), []byte("bar"), []byte("foo")}))
+	require.Nil(t, res)
+	assert.Equal(t, []string{"foo", "bar"}, dedupeBatchContents(msgs, ""))
+
+	conf.Dedupe.Keep = "nope"
+	_, err = NewDedupe(conf, mgr, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "keep option not recognised: nope")
+}
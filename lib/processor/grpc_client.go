@@ -0,0 +1,198 @@
+package processor
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	bgrpc "github.com/Jeffail/benthos/v3/internal/service/grpc"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/opentracing/opentracing-go"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeGRPCClient] = TypeSpec{
+		constructor: NewGRPCClient,
+		Categories: []Category{
+			CategoryIntegration,
+		},
+		Status:  docs.StatusExperimental,
+		Version: "3.41.0",
+		Summary: `
+Calls a gRPC method with each message as the request and replaces the message
+with the response.`,
+		Description: `
+Messages are converted into requests of the method, and responses are converted
+back into JSON documents, following the
+[JSON mapping of protobuf messages](https://developers.google.com/protocol-buffers/docs/proto3#json).
+
+The definition of the method is either parsed from the .proto files found within
+` + "`import_paths`" + `, or when no import paths are specified it is obtained
+from the server using
+[server reflection](https://github.com/grpc/grpc/blob/master/doc/server-reflection.md).
+
+Unary methods are called once for each message, and the contents of each
+message are replaced with its response. Client streaming methods are called once
+for each batch, where the messages of the batch are streamed in order and the
+batch is replaced with a single message containing the response. Server
+streaming and bidirectional streaming methods are not supported.
+
+When a call fails the messages are left unchanged and flagged as having failed,
+which can be handled with
+[error handling patterns](/docs/configuration/error_handling).`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title: "Enrich From a Service",
+				Summary: `
+The following example uses a [` + "`branch`" + ` processor](/docs/components/processors/branch)
+to look up the profile of the user of each message and adds it to the field
+` + "`profile`" + `:`,
+				Config: `
+pipeline:
+  processors:
+    - branch:
+        request_map: root.id = this.user_id
+        processors:
+          - grpc_client:
+              address: localhost:50051
+              method: users.Profiles/GetProfile
+        result_map: root.profile = this
+`,
+			},
+		},
+		FieldSpecs: bgrpc.ClientConfigDocs(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// GRPCClientConfig contains configuration fields for the GRPCClient processor.
+type GRPCClientConfig struct {
+	bgrpc.ClientConfig `json:",inline" yaml:",inline"`
+}
+
+// NewGRPCClientConfig returns a GRPCClientConfig with default values.
+func NewGRPCClientConfig() GRPCClientConfig {
+	return GRPCClientConfig{
+		ClientConfig: bgrpc.NewClientConfig(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// GRPCClient is a processor that calls a gRPC method for each message.
+type GRPCClient struct {
+	client *bgrpc.Client
+
+	log   log.Modular
+	stats metrics.Type
+
+	closeOnce  sync.Once
+	closedChan chan struct{}
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewGRPCClient returns a GRPCClient processor.
+func NewGRPCClient(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	client, err := bgrpc.NewClient(conf.GRPCClient.ClientConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &GRPCClient{
+		client:     client,
+		log:        log,
+		stats:      stats,
+		closedChan: make(chan struct{}),
+
+		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (g *GRPCClient) clientStream(msg types.Message) types.Message {
+	res, err := g.client.ClientStream(context.Background(), msg)
+	if err != nil {
+		g.mErr.Incr(1)
+		g.log.Debugf("gRPC call failed: %v\n", err)
+
+		newMsg := msg.Copy()
+		newMsg.Iter(func(i int, p types.Part) error {
+			FlagErr(p, err)
+			return nil
+		})
+		return newMsg
+	}
+
+	part := msg.Get(0).Copy()
+	part.Set(res)
+
+	newMsg := message.New(nil)
+	newMsg.Append(part)
+	return newMsg
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (g *GRPCClient) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	g.mCount.Incr(1)
+
+	var newMsg types.Message
+	method, err := g.client.Method(context.Background())
+	if err == nil && method.IsClientStreaming() {
+		newMsg = g.clientStream(msg)
+	} else {
+		newMsg = msg.Copy()
+		IteratePartsWithSpan(TypeGRPCClient, nil, newMsg, func(index int, span opentracing.Span, part types.Part) error {
+			res, err := g.client.Unary(context.Background(), index, msg)
+			if err != nil {
+				g.mErr.Incr(1)
+				g.log.Debugf("gRPC call failed: %v\n", err)
+				return err
+			}
+			part.Set(res)
+			return nil
+		})
+	}
+
+	g.mBatchSent.Incr(1)
+	g.mSent.Incr(int64(newMsg.Len()))
+	return []types.Message{newMsg}, nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (g *GRPCClient) CloseAsync() {
+	g.closeOnce.Do(func() {
+		go func() {
+			_ = g.client.Close()
+			close(g.closedChan)
+		}()
+	})
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (g *GRPCClient) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	case <-g.closedChan:
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
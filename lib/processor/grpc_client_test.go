@@ -0,0 +1,66 @@
+package processor
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+func TestGRPCClientConfigErrors(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeGRPCClient
+	conf.GRPCClient.Method = "nope"
+
+	_, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "must be of the form package.Service/Method")
+}
+
+func TestGRPCClientReflection(t *testing.T) {
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	server := grpc.NewServer()
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	reflection.Register(server)
+	go func() {
+		_ = server.Serve(lis)
+	}()
+	t.Cleanup(server.Stop)
+
+	conf := NewConfig()
+	conf.Type = TypeGRPCClient
+	conf.GRPCClient.Address = lis.Addr().String()
+	conf.GRPCClient.Method = "grpc.health.v1.Health/Check"
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		proc.CloseAsync()
+		assert.NoError(t, proc.WaitForClose(time.Second*5))
+	})
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{}`),
+		[]byte(`{"service":"nope"}`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	require.Equal(t, 2, msgs[0].Len())
+
+	assert.Equal(t, `{"status":"SERVING"}`, string(msgs[0].Get(0).Get()))
+	assert.Empty(t, GetFail(msgs[0].Get(0)))
+
+	assert.Equal(t, `{"service":"nope"}`, string(msgs[0].Get(1).Get()))
+	assert.Contains(t, GetFail(msgs[0].Get(1)), "unknown service")
+}
@@ -0,0 +1,308 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/service/mongodb"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/opentracing/opentracing-go"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeMongoDB] = TypeSpec{
+		constructor: NewMongoDB,
+		Categories: []Category{
+			CategoryIntegration,
+		},
+		Status:  docs.StatusExperimental,
+		Version: "3.41.0",
+		Summary: `
+Performs operations against a MongoDB collection for each message, allowing
+documents to be looked up or written as part of a pipeline.`,
+		Description: `
+The documents and filters of each operation are built with
+[Bloblang mappings](/docs/guides/bloblang/about) executed against each message,
+where values can be expressed using
+[extended JSON](https://docs.mongodb.com/manual/reference/mongodb-extended-json/).
+
+The ` + "`find-one`" + ` operation replaces the contents of each message with the
+first document matching the filter, marshalled as extended JSON according to
+` + "`json_marshal_mode`" + `. When no document matches the message is flagged
+as having failed, which can be handled with
+[error handling patterns](/docs/configuration/error_handling).
+
+All other operations leave the contents of messages unchanged, and the messages
+of a batch are written with a single unordered bulk write. Messages that fail to
+be written are flagged as having failed.`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title: "Enrich From a Collection",
+				Summary: `
+The following example uses a [` + "`branch`" + ` processor](/docs/components/processors/branch)
+to look up the user of each message and adds it to the field ` + "`user`" + `:`,
+				Config: `
+pipeline:
+  processors:
+    - branch:
+        processors:
+          - mongodb:
+              url: mongodb://localhost:27017
+              database: app
+              collection: users
+              operation: find-one
+              filter_map: root._id = this.user_id
+        result_map: root.user = this
+`,
+			},
+		},
+		FieldSpecs: mongodb.ConfigDocs().Add(
+			docs.FieldCommon("collection", "The name of the target collection."),
+			docs.FieldCommon("operation", "The operation to perform for each message.").HasOptions(
+				string(mongodb.OperationInsertOne),
+				string(mongodb.OperationDeleteOne),
+				string(mongodb.OperationDeleteMany),
+				string(mongodb.OperationReplaceOne),
+				string(mongodb.OperationUpdateOne),
+				string(mongodb.OperationFindOne),
+			),
+			mongodb.WriteConcernDocs(),
+		).Add(mongodb.MappingDocs()...).Add(
+			docs.FieldCommon("upsert", "Whether the `replace-one` and `update-one` operations should insert a new document when no document matches the filter."),
+			docs.FieldAdvanced("json_marshal_mode", "The [extended JSON](https://docs.mongodb.com/manual/reference/mongodb-extended-json/) mode used to marshal the documents of the `find-one` operation.").HasOptions(mongodb.MarshalModes...),
+		),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// MongoDBConfig contains configuration fields for the MongoDB processor.
+type MongoDBConfig struct {
+	mongodb.Config  `json:",inline" yaml:",inline"`
+	Collection      string               `json:"collection" yaml:"collection"`
+	Operation       string               `json:"operation" yaml:"operation"`
+	WriteConcern    mongodb.WriteConcern `json:"write_concern" yaml:"write_concern"`
+	DocumentMap     string               `json:"document_map" yaml:"document_map"`
+	FilterMap       string               `json:"filter_map" yaml:"filter_map"`
+	HintMap         string               `json:"hint_map" yaml:"hint_map"`
+	Upsert          bool                 `json:"upsert" yaml:"upsert"`
+	JSONMarshalMode string               `json:"json_marshal_mode" yaml:"json_marshal_mode"`
+}
+
+// NewMongoDBConfig returns a MongoDBConfig with default values.
+func NewMongoDBConfig() MongoDBConfig {
+	return MongoDBConfig{
+		Config:          mongodb.NewConfig(),
+		Collection:      "",
+		Operation:       string(mongodb.OperationFindOne),
+		WriteConcern:    mongodb.NewWriteConcern(),
+		DocumentMap:     "",
+		FilterMap:       "",
+		HintMap:         "",
+		Upsert:          false,
+		JSONMarshalMode: "relaxed",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// MongoDB is a processor that performs operations against a MongoDB
+// collection.
+type MongoDB struct {
+	conf      MongoDBConfig
+	operation mongodb.Operation
+	mappings  mongodb.Mappings
+
+	client     *mongo.Client
+	collection *mongo.Collection
+
+	log log.Modular
+
+	closeOnce  sync.Once
+	closedChan chan struct{}
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewMongoDB returns a MongoDB processor.
+func NewMongoDB(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	if len(conf.MongoDB.Collection) == 0 {
+		return nil, errors.New("a collection must be specified")
+	}
+	if conf.MongoDB.JSONMarshalMode != "canonical" && conf.MongoDB.JSONMarshalMode != "relaxed" {
+		return nil, fmt.Errorf("json_marshal_mode %v was not recognised", conf.MongoDB.JSONMarshalMode)
+	}
+
+	m := &MongoDB{
+		conf:       conf.MongoDB,
+		log:        log,
+		closedChan: make(chan struct{}),
+
+		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}
+
+	var err error
+	if m.operation, err = mongodb.NewOperation(
+		conf.MongoDB.Operation,
+		mongodb.OperationInsertOne,
+		mongodb.OperationDeleteOne,
+		mongodb.OperationDeleteMany,
+		mongodb.OperationReplaceOne,
+		mongodb.OperationUpdateOne,
+		mongodb.OperationFindOne,
+	); err != nil {
+		return nil, err
+	}
+	if m.mappings, err = mongodb.NewMappings(m.operation, conf.MongoDB.DocumentMap, conf.MongoDB.FilterMap, conf.MongoDB.HintMap); err != nil {
+		return nil, err
+	}
+
+	collOpts := options.Collection()
+	wc, err := conf.MongoDB.WriteConcern.Get()
+	if err != nil {
+		return nil, err
+	}
+	if wc != nil {
+		collOpts.SetWriteConcern(wc)
+	}
+
+	// Connecting does not block on the deployment being reachable, operations
+	// fail until it is.
+	if m.client, err = conf.MongoDB.Config.Client(); err != nil {
+		return nil, err
+	}
+	if err = m.client.Connect(context.Background()); err != nil {
+		return nil, err
+	}
+	m.collection = m.client.Database(conf.MongoDB.Database).Collection(conf.MongoDB.Collection, collOpts)
+	return m, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (m *MongoDB) findOne(index int, msg types.Message, part types.Part) error {
+	filter, err := mongodb.MapDocument(m.mappings.Filter, index, msg)
+	if err != nil {
+		return fmt.Errorf("filter_map: %w", err)
+	}
+
+	opts := options.FindOne()
+	if m.mappings.Hint != nil {
+		hint, err := mongodb.MapDocument(m.mappings.Hint, index, msg)
+		if err != nil {
+			return fmt.Errorf("hint_map: %w", err)
+		}
+		opts.SetHint(hint)
+	}
+
+	var doc bson.Raw
+	if doc, err = m.collection.FindOne(context.Background(), filter, opts).DecodeBytes(); err != nil {
+		return err
+	}
+
+	data, err := mongodb.MarshalJSON(doc, m.conf.JSONMarshalMode)
+	if err != nil {
+		return fmt.Errorf("failed to marshal document: %v", err)
+	}
+	part.Set(data)
+	return nil
+}
+
+func (m *MongoDB) write(msg types.Message) []error {
+	errs := make([]error, msg.Len())
+
+	var models []mongo.WriteModel
+	var modelIndexes []int
+	_ = msg.Iter(func(i int, _ types.Part) error {
+		model, err := m.mappings.WriteModel(m.operation, m.conf.Upsert, i, msg)
+		if err != nil {
+			errs[i] = err
+			return nil
+		}
+		models = append(models, model)
+		modelIndexes = append(modelIndexes, i)
+		return nil
+	})
+	if len(models) == 0 {
+		return errs
+	}
+
+	_, err := m.collection.BulkWrite(context.Background(), models, options.BulkWrite().SetOrdered(false))
+	for j, werr := range mongodb.BulkWriteErrors(err, len(models)) {
+		if werr != nil {
+			errs[modelIndexes[j]] = fmt.Errorf("%v operation failed: %w", m.operation, werr)
+		}
+	}
+	return errs
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (m *MongoDB) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	m.mCount.Incr(1)
+	newMsg := msg.Copy()
+
+	if m.operation == mongodb.OperationFindOne {
+		IteratePartsWithSpan(TypeMongoDB, nil, newMsg, func(index int, span opentracing.Span, part types.Part) error {
+			if err := m.findOne(index, msg, part); err != nil {
+				m.mErr.Incr(1)
+				m.log.Debugf("MongoDB find-one error: %v\n", err)
+				return err
+			}
+			return nil
+		})
+	} else {
+		for i, err := range m.write(msg) {
+			if err != nil {
+				m.mErr.Incr(1)
+				m.log.Debugf("MongoDB error: %v\n", err)
+				FlagErr(newMsg.Get(i), err)
+			}
+		}
+	}
+
+	m.mBatchSent.Incr(1)
+	m.mSent.Incr(int64(newMsg.Len()))
+	return []types.Message{newMsg}, nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (m *MongoDB) CloseAsync() {
+	m.closeOnce.Do(func() {
+		go func() {
+			_ = m.client.Disconnect(context.Background())
+			close(m.closedChan)
+		}()
+	})
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (m *MongoDB) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	case <-m.closedChan:
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,88 @@
+package processor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMongoDBConfigErrors(t *testing.T) {
+	tests := map[string]struct {
+		conf func(c *MongoDBConfig)
+		err  string
+	}{
+		"no collection": {
+			conf: func(c *MongoDBConfig) {
+				c.Collection = ""
+			},
+			err: "a collection must be specified",
+		},
+		"bad operation": {
+			conf: func(c *MongoDBConfig) {
+				c.Operation = "nope"
+			},
+			err: "operation nope was not recognised",
+		},
+		"missing filter": {
+			conf: func(c *MongoDBConfig) {
+				c.FilterMap = ""
+			},
+			err: "a filter_map is required for the find-one operation",
+		},
+		"missing document": {
+			conf: func(c *MongoDBConfig) {
+				c.Operation = "insert-one"
+			},
+			err: "a document_map is required for the insert-one operation",
+		},
+		"bad marshal mode": {
+			conf: func(c *MongoDBConfig) {
+				c.JSONMarshalMode = "nope"
+			},
+			err: "json_marshal_mode nope was not recognised",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			conf := NewConfig()
+			conf.Type = TypeMongoDB
+			conf.MongoDB.Database = "foo"
+			conf.MongoDB.Collection = "bar"
+			conf.MongoDB.FilterMap = `root.id = this.id`
+			test.conf(&conf.MongoDB)
+
+			_, err := NewMongoDB(conf, nil, log.Noop(), metrics.Noop())
+			require.EqualError(t, err, test.err)
+		})
+	}
+}
+
+func TestMongoDBMappingErrors(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeMongoDB
+	conf.MongoDB.Database = "foo"
+	conf.MongoDB.Collection = "bar"
+	conf.MongoDB.Operation = "insert-one"
+	conf.MongoDB.DocumentMap = `root = this.doc`
+
+	proc, err := NewMongoDB(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		proc.CloseAsync()
+		assert.NoError(t, proc.WaitForClose(time.Second*5))
+	})
+
+	// Messages that fail to map are flagged without reaching the collection.
+	msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte(`{"doc":"not an object"}`)}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, `{"doc":"not an object"}`, string(msgs[0].Get(0).Get()))
+	assert.Contains(t, GetFail(msgs[0].Get(0)), "document_map: failed to parse mapping result as a document")
+}
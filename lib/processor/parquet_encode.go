@@ -0,0 +1,243 @@
+package processor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/batch"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/parquet"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message/tracing"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	olog "github.com/opentracing/opentracing-go/log"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeParquetEncode] = TypeSpec{
+		constructor: NewParquetEncode,
+		Categories: []Category{
+			CategoryParsing,
+		},
+		Status:  docs.StatusExperimental,
+		Version: "3.41.0",
+		Summary: `
+Encodes a [batch](/docs/configuration/batching) of JSON documents into a single
+[Apache Parquet](https://parquet.apache.org/) file.`,
+		Description: `
+Each message of the batch is a row of the resulting file, and the batch is
+replaced with a single message containing the file. The metadata of the file
+message is copied from the first message of the batch.
+
+If a ` + "`schema`" + ` is not specified then it is inferred from the
+documents of each batch, where all fields are optional, numbers are encoded as
+` + "`INT64`" + ` columns when all values of a field are integers and as
+` + "`DOUBLE`" + ` columns otherwise, arrays are encoded as repeated fields,
+and fields that only ever contain ` + "`null`" + ` values are omitted. Since the
+schema of each file then depends on its contents it is recommended to specify a
+schema when files are consumed by other systems.
+
+If any document of a batch does not conform to the schema then no file is
+created and all messages of the batch are flagged as having failed, allowing
+you to [error handle them](/docs/configuration/error_handling).
+
+### Schema
+
+Each field of a schema has a ` + "`name`" + ` and either a ` + "`type`" + `
+or a list of child ` + "`fields`" + `, in which case it is a group. A field can
+also be marked as ` + "`optional`" + `, allowing it to be missing or
+` + "`null`" + `, or ` + "`repeated`" + `, where the field is an array of
+values. Names must begin with a letter and can only contain alphanumeric
+characters and underscores. Document values that are not within the schema are
+ignored.
+
+The supported types are ` + "`BOOLEAN`, `INT32`, `INT64`, `FLOAT`, `DOUBLE`, `BYTE_ARRAY` and `UTF8`" + `.`,
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon(
+				"schema", "A list of fields that describe the schema of encoded files. If empty the schema is inferred from the documents of each batch.",
+				[]interface{}{
+					map[string]interface{}{"name": "id", "type": "INT64"},
+					map[string]interface{}{"name": "name", "type": "UTF8", "optional": true},
+					map[string]interface{}{"name": "tags", "type": "UTF8", "repeated": true},
+				},
+			).HasType(docs.FieldArray),
+			docs.FieldCommon("compression", "The compression codec to use for the columns of encoded files.").HasOptions(parquet.CompressionCodecs...),
+		},
+		Examples: []docs.AnnotatedExample{
+			{
+				Title: "Writing Parquet Files to S3",
+				Summary: `
+Batches of documents can be encoded into Parquet files and uploaded to a data
+lake:`,
+				Config: `
+output:
+  aws_s3:
+    bucket: TODO
+    path: 'events/${! timestamp_unix() }-${! uuid_v4() }.parquet'
+    batching:
+      count: 1000
+      period: 1m
+      processors:
+        - parquet_encode:
+            schema:
+              - name: id
+                type: INT64
+              - name: user
+                fields:
+                  - name: name
+                    type: UTF8
+                  - name: email
+                    type: UTF8
+                    optional: true
+              - name: tags
+                type: UTF8
+                repeated: true
+`,
+			},
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ParquetSchemaFieldConfig describes a field of a parquet schema.
+type ParquetSchemaFieldConfig struct {
+	Name     string                     `json:"name" yaml:"name"`
+	Type     string                     `json:"type,omitempty" yaml:"type,omitempty"`
+	Repeated bool                       `json:"repeated,omitempty" yaml:"repeated,omitempty"`
+	Optional bool                       `json:"optional,omitempty" yaml:"optional,omitempty"`
+	Fields   []ParquetSchemaFieldConfig `json:"fields,omitempty" yaml:"fields,omitempty"`
+}
+
+func (p ParquetSchemaFieldConfig) toField() parquet.Field {
+	f := parquet.Field{
+		Name:     p.Name,
+		Type:     p.Type,
+		Repeated: p.Repeated,
+		Optional: p.Optional,
+	}
+	for _, c := range p.Fields {
+		f.Fields = append(f.Fields, c.toField())
+	}
+	return f
+}
+
+// ParquetEncodeConfig contains configuration fields for the ParquetEncode
+// processor.
+type ParquetEncodeConfig struct {
+	Schema      []ParquetSchemaFieldConfig `json:"schema" yaml:"schema"`
+	Compression string                     `json:"compression" yaml:"compression"`
+}
+
+// NewParquetEncodeConfig returns a ParquetEncodeConfig with default values.
+func NewParquetEncodeConfig() ParquetEncodeConfig {
+	return ParquetEncodeConfig{
+		Schema:      []ParquetSchemaFieldConfig{},
+		Compression: "snappy",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ParquetEncode is a processor that encodes batches of JSON documents into
+// parquet files.
+type ParquetEncode struct {
+	encoder *parquet.Encoder
+
+	log log.Modular
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mSucc      metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewParquetEncode returns a ParquetEncode processor.
+func NewParquetEncode(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	var fields []parquet.Field
+	for _, f := range conf.ParquetEncode.Schema {
+		fields = append(fields, f.toField())
+	}
+	encoder, err := parquet.NewEncoder(fields, conf.ParquetEncode.Compression)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create encoder: %w", err)
+	}
+	return &ParquetEncode{
+		encoder: encoder,
+		log:     log,
+
+		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
+		mSucc:      stats.GetCounter("success"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (p *ParquetEncode) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	p.mCount.Incr(1)
+
+	if msg.Len() == 0 {
+		return nil, response.NewAck()
+	}
+
+	p.mSent.Incr(1)
+	p.mBatchSent.Incr(1)
+
+	newMsg := msg.Copy()
+
+	rows := make([][]byte, 0, msg.Len())
+	msg.Iter(func(i int, part types.Part) error {
+		rows = append(rows, part.Get())
+		return nil
+	})
+
+	spans := tracing.CreateChildSpans(TypeParquetEncode, newMsg)
+	data, err := p.encoder.Encode(rows)
+	if err != nil {
+		newMsg.Iter(func(i int, part types.Part) error {
+			FlagErr(part, err)
+			spans[i].LogFields(
+				olog.String("event", "error"),
+				olog.String("type", err.Error()),
+			)
+			return nil
+		})
+		p.log.Errorf("Failed to encode parquet file: %v\n", err)
+		p.mErr.Incr(1)
+	} else {
+		p.mSucc.Incr(1)
+		newPart := newMsg.Get(0).Copy()
+		newPart.Set(data)
+		newPart = batch.WithCollapsedCount(newPart, msg.Len())
+		newMsg.SetAll([]types.Part{newPart})
+	}
+	for _, s := range spans {
+		s.Finish()
+	}
+
+	msgs := [1]types.Message{newMsg}
+	return msgs[:], nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (p *ParquetEncode) CloseAsync() {
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (p *ParquetEncode) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,125 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newParquetProcs(t *testing.T, schema []ParquetSchemaFieldConfig) (Type, Type) {
+	t.Helper()
+
+	encConf := NewConfig()
+	encConf.Type = TypeParquetEncode
+	encConf.ParquetEncode.Schema = schema
+
+	enc, err := New(encConf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	decConf := NewConfig()
+	decConf.Type = TypeParquetDecode
+
+	dec, err := New(decConf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	return enc, dec
+}
+
+func TestParquetEncodeConfigErrors(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeParquetEncode
+	conf.ParquetEncode.Compression = "nope"
+	_, err := New(conf, nil, log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+
+	conf = NewConfig()
+	conf.Type = TypeParquetEncode
+	conf.ParquetEncode.Schema = []ParquetSchemaFieldConfig{
+		{Name: "foo", Fields: []ParquetSchemaFieldConfig{
+			{Name: "bar", Type: "nope"},
+		}},
+	}
+	_, err = New(conf, nil, log.Noop(), metrics.Noop())
+	assert.EqualError(t, err, "failed to create encoder: field 'foo.bar' has unrecognised type 'nope'")
+}
+
+func TestParquetEncodeDecodeSchema(t *testing.T) {
+	enc, dec := newParquetProcs(t, []ParquetSchemaFieldConfig{
+		{Name: "id", Type: "INT64"},
+		{Name: "user", Fields: []ParquetSchemaFieldConfig{
+			{Name: "name", Type: "UTF8"},
+			{Name: "email", Type: "UTF8", Optional: true},
+		}},
+		{Name: "tags", Type: "UTF8", Repeated: true},
+	})
+
+	input := message.New([][]byte{
+		[]byte(`{"id":1,"user":{"name":"foo","email":"foo@example.com"},"tags":["a"]}`),
+		[]byte(`{"id":2,"user":{"name":"bar"}}`),
+	})
+	input.Get(0).Metadata().Set("foo", "bar")
+
+	encoded, res := enc.ProcessMessage(input)
+	require.Nil(t, res)
+	require.Len(t, encoded, 1)
+	require.Equal(t, 1, encoded[0].Len())
+	require.False(t, HasFailed(encoded[0].Get(0)), GetFail(encoded[0].Get(0)))
+	assert.Equal(t, "PAR1", string(encoded[0].Get(0).Get()[:4]))
+	assert.Equal(t, "bar", encoded[0].Get(0).Metadata().Get("foo"))
+
+	decoded, res := dec.ProcessMessage(encoded[0])
+	require.Nil(t, res)
+	require.Len(t, decoded, 1)
+	assert.Equal(t, [][]byte{
+		[]byte(`{"id":1,"tags":["a"],"user":{"email":"foo@example.com","name":"foo"}}`),
+		[]byte(`{"id":2,"tags":[],"user":{"email":null,"name":"bar"}}`),
+	}, message.GetAllBytes(decoded[0]))
+	assert.Equal(t, "bar", decoded[0].Get(1).Metadata().Get("foo"))
+}
+
+func TestParquetEncodeInferred(t *testing.T) {
+	enc, dec := newParquetProcs(t, nil)
+
+	encoded, _ := enc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"a":1,"b":"foo"}`),
+		[]byte(`{"a":2.5,"c":[true]}`),
+	}))
+	require.False(t, HasFailed(encoded[0].Get(0)), GetFail(encoded[0].Get(0)))
+
+	decoded, _ := dec.ProcessMessage(encoded[0])
+	assert.Equal(t, [][]byte{
+		[]byte(`{"a":1,"b":"foo","c":[]}`),
+		[]byte(`{"a":2.5,"b":null,"c":[true]}`),
+	}, message.GetAllBytes(decoded[0]))
+}
+
+func TestParquetEncodeErrors(t *testing.T) {
+	enc, _ := newParquetProcs(t, []ParquetSchemaFieldConfig{
+		{Name: "id", Type: "INT64"},
+	})
+
+	encoded, _ := enc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"id":1}`),
+		[]byte(`{"id":"nope"}`),
+	}))
+	require.Len(t, encoded, 1)
+	require.Equal(t, 2, encoded[0].Len())
+	assert.Equal(t, `{"id":1}`, string(encoded[0].Get(0).Get()))
+	assert.Equal(t, "document 1: field 'id' of type INT64 cannot be set to string value", GetFail(encoded[0].Get(0)))
+	assert.True(t, HasFailed(encoded[0].Get(1)))
+}
+
+func TestParquetDecodeErrors(t *testing.T) {
+	_, dec := newParquetProcs(t, nil)
+
+	decoded, _ := dec.ProcessMessage(message.New([][]byte{
+		[]byte(`not a parquet file`),
+	}))
+	require.Len(t, decoded, 1)
+	assert.Equal(t, `not a parquet file`, string(decoded[0].Get(0).Get()))
+	assert.True(t, HasFailed(decoded[0].Get(0)))
+}
@@ -3,17 +3,15 @@ package processor
 import (
 	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
 	"time"
 
 	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/service/grpc"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
 	"github.com/golang/protobuf/proto"
 	"github.com/jhump/protoreflect/desc"
-	"github.com/jhump/protoreflect/desc/protoparse"
 	"github.com/jhump/protoreflect/dynamic"
 	"github.com/opentracing/opentracing-go"
 )
@@ -233,38 +231,12 @@ func loadDescriptor(message string, importPaths []string) (*desc.MessageDescript
 		return nil, errors.New("message field must not be empty")
 	}
 
-	var parser protoparse.Parser
-	if len(importPaths) == 0 {
-		importPaths = []string{"."}
-	} else {
-		parser.ImportPaths = importPaths
-	}
-
-	var files []string
-	for _, importPath := range importPaths {
-		if err := filepath.Walk(importPath, func(path string, info os.FileInfo, ferr error) error {
-			if ferr != nil || info.IsDir() {
-				return ferr
-			}
-			if filepath.Ext(info.Name()) == ".proto" {
-				rPath, ferr := filepath.Rel(importPath, path)
-				if ferr != nil {
-					return fmt.Errorf("failed to get relative path: %v", ferr)
-				}
-				files = append(files, rPath)
-			}
-			return nil
-		}); err != nil {
-			return nil, err
-		}
-	}
-
-	fds, err := parser.ParseFiles(files...)
+	fds, err := grpc.ParseProtoFiles(importPaths)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse .proto file: %v", err)
+		return nil, err
 	}
-	if len(fds) == 0 {
-		return nil, fmt.Errorf("no .proto files were found in the paths '%v'", importPaths)
+	if len(importPaths) == 0 {
+		importPaths = []string{"."}
 	}
 
 	var msg *desc.MessageDescriptor
@@ -0,0 +1,389 @@
+package processor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/util/http/auth"
+	btls "github.com/Jeffail/benthos/v3/lib/util/tls"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/linkedin/goavro/v2"
+	jsonschema "github.com/xeipuuv/gojsonschema"
+)
+
+//------------------------------------------------------------------------------
+
+func schemaRegistryFieldSpecs() docs.FieldSpecs {
+	return docs.FieldSpecs{
+		docs.FieldCommon("url", "The base URL of the schema registry service."),
+		auth.BasicAuthFieldSpec(),
+		btls.FieldSpec(),
+	}
+}
+
+const schemaRegistryWireFormatDesc = `
+### Wire Format
+
+Messages are expected to be in the
+[Confluent wire format](https://docs.confluent.io/platform/current/schema-registry/serdes-develop/index.html#wire-format),
+where the payload is prefixed with a zero magic byte followed by the four byte
+big-endian ID of the schema within the registry. Protobuf payloads are
+additionally prefixed with the indexes of the message type within the schema.
+
+### Schema Types
+
+- Avro schemas are converted to and from the [Avro JSON encoding](https://avro.apache.org/docs/current/spec.html#json_encoding), where union values are wrapped in an object keyed by their type.
+- Protobuf schemas are converted to and from the [canonical JSON mapping](https://developers.google.com/protocol-buffers/docs/proto3#json). Schemas that import other schemas via references are supported.
+- JSON schemas are used to validate documents, which are otherwise left unchanged.`
+
+//------------------------------------------------------------------------------
+
+// schemaRegistryWireMagic is the first byte of all messages in the wire format.
+const schemaRegistryWireMagic = 0
+
+type schemaRegistryReference struct {
+	Name    string `json:"name"`
+	Subject string `json:"subject"`
+	Version int    `json:"version"`
+}
+
+// schemaRegistryInfo is the response of the registry for a schema lookup.
+type schemaRegistryInfo struct {
+	ID         int                       `json:"id"`
+	Version    int                       `json:"version"`
+	Schema     string                    `json:"schema"`
+	SchemaType string                    `json:"schemaType"`
+	References []schemaRegistryReference `json:"references"`
+}
+
+// schemaRegistryCodec converts between JSON documents and the payloads of
+// messages encoded with a registered schema, excluding the magic byte and
+// schema ID.
+type schemaRegistryCodec struct {
+	id     int
+	decode func(payload []byte) ([]byte, error)
+	encode func(doc []byte) ([]byte, error)
+}
+
+type schemaRegistryClient struct {
+	url       *url.URL
+	client    *http.Client
+	basicAuth auth.BasicAuthConfig
+}
+
+func newSchemaRegistryClient(
+	urlStr string, basicAuth auth.BasicAuthConfig, tlsConf btls.Config,
+) (*schemaRegistryClient, error) {
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse url: %w", err)
+	}
+	if len(u.Host) == 0 {
+		return nil, fmt.Errorf("url '%v' must include a host", urlStr)
+	}
+
+	client := &http.Client{Timeout: time.Second * 5}
+	if tlsConf.Enabled {
+		tlsClientConf, err := tlsConf.Get()
+		if err != nil {
+			return nil, err
+		}
+		client.Transport = &http.Transport{
+			TLSClientConfig: tlsClientConf,
+		}
+	}
+
+	return &schemaRegistryClient{
+		url:       u,
+		client:    client,
+		basicAuth: basicAuth,
+	}, nil
+}
+
+func (c *schemaRegistryClient) get(pathSegments ...string) (*schemaRegistryInfo, error) {
+	reqURL := *c.url
+	escaped := make([]string, len(pathSegments))
+	for i, s := range pathSegments {
+		escaped[i] = url.PathEscape(s)
+	}
+	reqURL.Path = path.Join(append([]string{"/", c.url.Path}, pathSegments...)...)
+	reqURL.RawPath = path.Join(append([]string{"/", c.url.EscapedPath()}, escaped...)...)
+
+	req, err := http.NewRequest("GET", reqURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.schemaregistry.v1+json")
+	if err = c.basicAuth.Sign(req); err != nil {
+		return nil, err
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("request to '%v' returned status %v: %s", reqURL.Path, res.StatusCode, bytes.TrimSpace(body))
+	}
+
+	var info schemaRegistryInfo
+	if err = json.Unmarshal(body, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse registry response: %w", err)
+	}
+	return &info, nil
+}
+
+// getByID obtains a schema by its unique ID.
+func (c *schemaRegistryClient) getByID(id int) (*schemaRegistryInfo, error) {
+	info, err := c.get("schemas", "ids", strconv.Itoa(id))
+	if err != nil {
+		return nil, err
+	}
+	info.ID = id
+	return info, nil
+}
+
+// getBySubject obtains a schema by its subject and version, where the version
+// can be `latest`.
+func (c *schemaRegistryClient) getBySubject(subject, version string) (*schemaRegistryInfo, error) {
+	return c.get("subjects", subject, "versions", version)
+}
+
+// compile creates a codec from a schema obtained from the registry. Protobuf
+// messages are encoded as the message type protoMessageType, or the first
+// message type of the schema when empty.
+func (c *schemaRegistryClient) compile(info *schemaRegistryInfo, protoMessageType string) (*schemaRegistryCodec, error) {
+	switch info.SchemaType {
+	case "", "AVRO":
+		if len(info.References) > 0 {
+			return nil, errors.New("schema references are not supported for Avro schemas")
+		}
+		return newSchemaRegistryAvroCodec(info)
+	case "PROTOBUF":
+		return c.newProtobufCodec(info, protoMessageType)
+	case "JSON":
+		if len(info.References) > 0 {
+			return nil, errors.New("schema references are not supported for JSON schemas")
+		}
+		return newSchemaRegistryJSONCodec(info)
+	}
+	return nil, fmt.Errorf("schema type '%v' not recognised", info.SchemaType)
+}
+
+//------------------------------------------------------------------------------
+
+func newSchemaRegistryAvroCodec(info *schemaRegistryInfo) (*schemaRegistryCodec, error) {
+	codec, err := goavro.NewCodec(info.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Avro schema %v: %w", info.ID, err)
+	}
+	return &schemaRegistryCodec{
+		id: info.ID,
+		decode: func(payload []byte) ([]byte, error) {
+			native, _, err := codec.NativeFromBinary(payload)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode Avro payload: %w", err)
+			}
+			return codec.TextualFromNative(nil, native)
+		},
+		encode: func(doc []byte) ([]byte, error) {
+			native, _, err := codec.NativeFromTextual(doc)
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert document to Avro schema: %w", err)
+			}
+			return codec.BinaryFromNative(nil, native)
+		},
+	}, nil
+}
+
+func newSchemaRegistryJSONCodec(info *schemaRegistryInfo) (*schemaRegistryCodec, error) {
+	schema, err := jsonschema.NewSchema(jsonschema.NewStringLoader(info.Schema))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JSON schema %v: %w", info.ID, err)
+	}
+	validate := func(doc []byte) ([]byte, error) {
+		result, err := schema.Validate(jsonschema.NewBytesLoader(doc))
+		if err != nil {
+			return nil, fmt.Errorf("failed to validate document: %w", err)
+		}
+		if !result.Valid() {
+			var errStr string
+			for i, desc := range result.Errors() {
+				if i > 0 {
+					errStr = errStr + "\n"
+				}
+				errStr = errStr + desc.String()
+			}
+			return nil, errors.New(errStr)
+		}
+		return doc, nil
+	}
+	return &schemaRegistryCodec{
+		id:     info.ID,
+		decode: validate,
+		encode: validate,
+	}, nil
+}
+
+// collectProtobufReferences walks the references of a protobuf schema and adds
+// the contents of each imported file to a map.
+func (c *schemaRegistryClient) collectProtobufReferences(refs []schemaRegistryReference, files map[string]string) error {
+	for _, ref := range refs {
+		if _, exists := files[ref.Name]; exists {
+			continue
+		}
+		info, err := c.getBySubject(ref.Subject, strconv.Itoa(ref.Version))
+		if err != nil {
+			return fmt.Errorf("failed to obtain reference '%v': %w", ref.Name, err)
+		}
+		files[ref.Name] = info.Schema
+		if err = c.collectProtobufReferences(info.References, files); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *schemaRegistryClient) newProtobufCodec(info *schemaRegistryInfo, messageType string) (*schemaRegistryCodec, error) {
+	const rootFile = "schema_registry_root.proto"
+	files := map[string]string{rootFile: info.Schema}
+	if err := c.collectProtobufReferences(info.References, files); err != nil {
+		return nil, err
+	}
+
+	parser := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(files),
+	}
+	fds, err := parser.ParseFiles(rootFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse protobuf schema %v: %w", info.ID, err)
+	}
+	messages := fds[0].GetMessageTypes()
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("protobuf schema %v does not contain any message types", info.ID)
+	}
+
+	encodeDesc, encodeIndexes := messages[0], []int{0}
+	if messageType != "" {
+		if encodeDesc, encodeIndexes = findProtobufMessageType(messages, messageType); encodeDesc == nil {
+			return nil, fmt.Errorf("protobuf schema %v does not contain message type '%v'", info.ID, messageType)
+		}
+	}
+	indexesPrefix := writeProtobufMessageIndexes(encodeIndexes)
+
+	return &schemaRegistryCodec{
+		id: info.ID,
+		decode: func(payload []byte) ([]byte, error) {
+			msgDesc, remaining, err := readProtobufMessageIndexes(messages, payload)
+			if err != nil {
+				return nil, err
+			}
+			msg := dynamic.NewMessage(msgDesc)
+			if err := msg.Unmarshal(remaining); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal protobuf message: %w", err)
+			}
+			return msg.MarshalJSON()
+		},
+		encode: func(doc []byte) ([]byte, error) {
+			msg := dynamic.NewMessage(encodeDesc)
+			if err := msg.UnmarshalJSON(doc); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal JSON message: %w", err)
+			}
+			data, err := msg.Marshal()
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal protobuf message: %w", err)
+			}
+			return append(append([]byte{}, indexesPrefix...), data...), nil
+		},
+	}, nil
+}
+
+// findProtobufMessageType searches a tree of message types for a type by its
+// fully qualified name and returns it along with its indexes within the tree.
+func findProtobufMessageType(messages []*desc.MessageDescriptor, name string) (*desc.MessageDescriptor, []int) {
+	for i, msgDesc := range messages {
+		if msgDesc.GetFullyQualifiedName() == name {
+			return msgDesc, []int{i}
+		}
+		if nested, indexes := findProtobufMessageType(msgDesc.GetNestedMessageTypes(), name); nested != nil {
+			return nested, append([]int{i}, indexes...)
+		}
+	}
+	return nil, nil
+}
+
+// writeProtobufMessageIndexes returns the prefix of a protobuf payload that
+// identifies its message type by indexes.
+func writeProtobufMessageIndexes(indexes []int) []byte {
+	// The message indexes of the first message type are optimised into a
+	// single zero byte.
+	if len(indexes) == 1 && indexes[0] == 0 {
+		return []byte{0}
+	}
+	buf := make([]byte, binary.MaxVarintLen64*(len(indexes)+1))
+	n := binary.PutVarint(buf, int64(len(indexes)))
+	for _, index := range indexes {
+		n += binary.PutVarint(buf[n:], int64(index))
+	}
+	return buf[:n]
+}
+
+// readProtobufMessageIndexes reads the message indexes that prefix a protobuf
+// payload and returns the message type they identify along with the remaining
+// payload.
+func readProtobufMessageIndexes(messages []*desc.MessageDescriptor, payload []byte) (*desc.MessageDescriptor, []byte, error) {
+	count, n := binary.Varint(payload)
+	if n <= 0 {
+		return nil, nil, errors.New("failed to read protobuf message indexes")
+	}
+	payload = payload[n:]
+
+	// Each index occupies at least one byte, which bounds a valid count by the
+	// remaining payload.
+	if count < 0 || count > int64(len(payload)) {
+		return nil, nil, fmt.Errorf("invalid protobuf message index count: %v", count)
+	}
+
+	indexes := []int64{0}
+	if count > 0 {
+		indexes = make([]int64, 0, count)
+		for i := int64(0); i < count; i++ {
+			index, n := binary.Varint(payload)
+			if n <= 0 {
+				return nil, nil, errors.New("failed to read protobuf message indexes")
+			}
+			indexes = append(indexes, index)
+			payload = payload[n:]
+		}
+	}
+
+	var msgDesc *desc.MessageDescriptor
+	for _, index := range indexes {
+		if index < 0 || int(index) >= len(messages) {
+			return nil, nil, fmt.Errorf("protobuf message index %v is out of bounds", index)
+		}
+		msgDesc = messages[index]
+		messages = msgDesc.GetNestedMessageTypes()
+	}
+	return msgDesc, payload, nil
+}
+
+//------------------------------------------------------------------------------
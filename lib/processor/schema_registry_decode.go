@@ -0,0 +1,208 @@
+package processor
+
+import (
+	"encoding/binary"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/http/auth"
+	btls "github.com/Jeffail/benthos/v3/lib/util/tls"
+	"github.com/opentracing/opentracing-go"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeSchemaRegistryDecode] = TypeSpec{
+		constructor: NewSchemaRegistryDecode,
+		Categories: []Category{
+			CategoryParsing, CategoryIntegration,
+		},
+		Status:  docs.StatusExperimental,
+		Version: "3.41.0",
+		Summary: `
+Decodes messages automatically from a schema stored within a
+[Confluent Schema Registry service](https://docs.confluent.io/platform/current/schema-registry/index.html)
+into JSON documents.`,
+		Description: `
+The schema ID of each message is extracted from its header, and the schema is
+obtained from the registry the first time it is seen. Schemas are immutable and
+therefore are cached indefinitely. Since each message is decoded with the schema
+it was written with, messages produced with different versions of a schema can
+be consumed within the same stream.
+
+The ID of the schema used to decode each message is stored within the metadata
+field ` + "`schema_id`" + `.
+` + schemaRegistryWireFormatDesc,
+		FieldSpecs: schemaRegistryFieldSpecs(),
+		Examples: []docs.AnnotatedExample{
+			{
+				Title: "Kafka Topics",
+				Summary: `
+Messages consumed from Kafka topics written by Confluent serializers can be
+decoded into JSON with:`,
+				Config: `
+input:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topics: [ foo ]
+    consumer_group: benthos_consumer
+
+pipeline:
+  processors:
+    - schema_registry_decode:
+        url: http://localhost:8081
+`,
+			},
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// SchemaRegistryDecodeConfig contains configuration fields for the
+// SchemaRegistryDecode processor.
+type SchemaRegistryDecodeConfig struct {
+	URL       string               `json:"url" yaml:"url"`
+	BasicAuth auth.BasicAuthConfig `json:"basic_auth" yaml:"basic_auth"`
+	TLS       btls.Config          `json:"tls" yaml:"tls"`
+}
+
+// NewSchemaRegistryDecodeConfig returns a SchemaRegistryDecodeConfig with
+// default values.
+func NewSchemaRegistryDecodeConfig() SchemaRegistryDecodeConfig {
+	return SchemaRegistryDecodeConfig{
+		URL:       "http://localhost:8081",
+		BasicAuth: auth.NewBasicAuthConfig(),
+		TLS:       btls.NewConfig(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// SchemaRegistryDecode is a processor that decodes messages using schemas
+// obtained from a schema registry.
+type SchemaRegistryDecode struct {
+	client *schemaRegistryClient
+
+	codecs   map[int]*schemaRegistryCodec
+	codecMut sync.RWMutex
+
+	log log.Modular
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewSchemaRegistryDecode returns a SchemaRegistryDecode processor.
+func NewSchemaRegistryDecode(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	client, err := newSchemaRegistryClient(
+		conf.SchemaRegistryDecode.URL,
+		conf.SchemaRegistryDecode.BasicAuth,
+		conf.SchemaRegistryDecode.TLS,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &SchemaRegistryDecode{
+		client: client,
+		codecs: map[int]*schemaRegistryCodec{},
+		log:    log,
+
+		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+var errSchemaRegistryWireFormat = errors.New("message is not in the schema registry wire format")
+
+func (s *SchemaRegistryDecode) getCodec(id int) (*schemaRegistryCodec, error) {
+	s.codecMut.RLock()
+	codec, exists := s.codecs[id]
+	s.codecMut.RUnlock()
+	if exists {
+		return codec, nil
+	}
+
+	s.codecMut.Lock()
+	defer s.codecMut.Unlock()
+
+	if codec, exists = s.codecs[id]; exists {
+		return codec, nil
+	}
+
+	info, err := s.client.getByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if codec, err = s.client.compile(info, ""); err != nil {
+		return nil, err
+	}
+	s.codecs[id] = codec
+	return codec, nil
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (s *SchemaRegistryDecode) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	s.mCount.Incr(1)
+	newMsg := msg.Copy()
+
+	proc := func(index int, span opentracing.Span, part types.Part) error {
+		b := part.Get()
+		if len(b) < 5 || b[0] != schemaRegistryWireMagic {
+			s.mErr.Incr(1)
+			return errSchemaRegistryWireFormat
+		}
+		id := int(binary.BigEndian.Uint32(b[1:5]))
+
+		codec, err := s.getCodec(id)
+		if err != nil {
+			s.mErr.Incr(1)
+			s.log.Errorf("Failed to obtain schema %v: %v\n", id, err)
+			return err
+		}
+
+		doc, err := codec.decode(b[5:])
+		if err != nil {
+			s.mErr.Incr(1)
+			s.log.Debugf("Failed to decode message: %v\n", err)
+			return err
+		}
+
+		part.Set(doc)
+		part.Metadata().Set("schema_id", strconv.Itoa(id))
+		return nil
+	}
+
+	IteratePartsWithSpan(TypeSchemaRegistryDecode, nil, newMsg, proc)
+
+	s.mBatchSent.Incr(1)
+	s.mSent.Incr(int64(newMsg.Len()))
+	return []types.Message{newMsg}, nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (s *SchemaRegistryDecode) CloseAsync() {
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (s *SchemaRegistryDecode) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,311 @@
+package processor
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/http/auth"
+	btls "github.com/Jeffail/benthos/v3/lib/util/tls"
+	"github.com/opentracing/opentracing-go"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeSchemaRegistryEncode] = TypeSpec{
+		constructor: NewSchemaRegistryEncode,
+		Categories: []Category{
+			CategoryParsing, CategoryIntegration,
+		},
+		Status:  docs.StatusExperimental,
+		Version: "3.41.0",
+		Summary: `
+Encodes JSON documents using a schema within a
+[Confluent Schema Registry service](https://docs.confluent.io/platform/current/schema-registry/index.html).`,
+		Description: `
+By default the latest version of the schema of each subject is obtained from the
+registry the first time it is used, and is refreshed periodically according to
+the ` + "`refresh_period`" + `. This allows the schema of a subject to evolve
+without restarting the pipeline, and messages are always encoded with the ID of
+the schema version used so that consumers can decode them with the same schema.
+
+A schema can instead be pinned with either a specific ` + "`version`" + ` of the
+subject or a ` + "`schema_id`" + `, in which case it is obtained once and never
+refreshed.
+
+Protobuf messages are encoded as the message type ` + "`message_type`" + `, or
+the first message type defined within the schema when it is empty.
+` + schemaRegistryWireFormatDesc,
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("url", "The base URL of the schema registry service."),
+			docs.FieldCommon(
+				"subject", "The schema subject to derive schemas from.",
+				"foo", `${! meta("kafka_topic") }-value`,
+			).SupportsInterpolation(true),
+			docs.FieldAdvanced(
+				"version", "The version of the subject schema to use, which can be `latest` or a specific version number.",
+				"latest", "3",
+			),
+			docs.FieldAdvanced("schema_id", "The ID of a schema to use instead of a `subject`, where `0` means no ID is set."),
+			docs.FieldAdvanced(
+				"message_type", "The fully qualified name of the message type to encode protobuf messages as, which defaults to the first message type of the schema when empty.",
+				"foo.Person", "foo.Person.Address",
+			),
+			docs.FieldCommon("refresh_period", "The period after which the latest schema of a subject is refreshed."),
+			auth.BasicAuthFieldSpec(),
+			btls.FieldSpec(),
+		},
+		Examples: []docs.AnnotatedExample{
+			{
+				Title: "Kafka Topics",
+				Summary: `
+JSON documents can be encoded for consumers using Confluent deserializers,
+where the subject of each message is derived from the topic it is written to:`,
+				Config: `
+pipeline:
+  processors:
+    - schema_registry_encode:
+        url: http://localhost:8081
+        subject: ${! meta("topic") }-value
+
+output:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topic: ${! meta("topic") }
+`,
+			},
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// SchemaRegistryEncodeConfig contains configuration fields for the
+// SchemaRegistryEncode processor.
+type SchemaRegistryEncodeConfig struct {
+	URL           string               `json:"url" yaml:"url"`
+	Subject       string               `json:"subject" yaml:"subject"`
+	Version       string               `json:"version" yaml:"version"`
+	SchemaID      int                  `json:"schema_id" yaml:"schema_id"`
+	MessageType   string               `json:"message_type" yaml:"message_type"`
+	RefreshPeriod string               `json:"refresh_period" yaml:"refresh_period"`
+	BasicAuth     auth.BasicAuthConfig `json:"basic_auth" yaml:"basic_auth"`
+	TLS           btls.Config          `json:"tls" yaml:"tls"`
+}
+
+// NewSchemaRegistryEncodeConfig returns a SchemaRegistryEncodeConfig with
+// default values.
+func NewSchemaRegistryEncodeConfig() SchemaRegistryEncodeConfig {
+	return SchemaRegistryEncodeConfig{
+		URL:           "http://localhost:8081",
+		Subject:       "",
+		Version:       "latest",
+		SchemaID:      0,
+		MessageType:   "",
+		RefreshPeriod: "10m",
+		BasicAuth:     auth.NewBasicAuthConfig(),
+		TLS:           btls.NewConfig(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type schemaRegistrySubject struct {
+	codec     *schemaRegistryCodec
+	refreshed time.Time
+}
+
+// SchemaRegistryEncode is a processor that encodes messages using schemas
+// obtained from a schema registry.
+type SchemaRegistryEncode struct {
+	client        *schemaRegistryClient
+	subject       field.Expression
+	version       string
+	schemaID      int
+	messageType   string
+	refreshPeriod time.Duration
+
+	subjects   map[string]*schemaRegistrySubject
+	subjectMut sync.Mutex
+	nowFn      func() time.Time
+
+	log log.Modular
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewSchemaRegistryEncode returns a SchemaRegistryEncode processor.
+func NewSchemaRegistryEncode(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	sConf := conf.SchemaRegistryEncode
+	if sConf.SchemaID < 0 {
+		return nil, fmt.Errorf("schema_id must not be negative")
+	}
+	if len(sConf.Subject) == 0 && sConf.SchemaID == 0 {
+		return nil, fmt.Errorf("either a subject or a schema_id must be specified")
+	}
+	if len(sConf.Subject) > 0 && sConf.SchemaID > 0 {
+		return nil, fmt.Errorf("cannot specify both a subject and a schema_id")
+	}
+
+	var subject field.Expression
+	var err error
+	if len(sConf.Subject) > 0 {
+		if subject, err = bloblang.NewField(sConf.Subject); err != nil {
+			return nil, fmt.Errorf("failed to parse subject expression: %v", err)
+		}
+		if sConf.Version != "latest" {
+			if v, err := strconv.Atoi(sConf.Version); err != nil || v <= 0 {
+				return nil, fmt.Errorf("version must be latest or a positive integer, got: %v", sConf.Version)
+			}
+		}
+	}
+
+	refreshPeriod, err := time.ParseDuration(conf.SchemaRegistryEncode.RefreshPeriod)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse refresh period: %v", err)
+	}
+
+	client, err := newSchemaRegistryClient(
+		conf.SchemaRegistryEncode.URL,
+		conf.SchemaRegistryEncode.BasicAuth,
+		conf.SchemaRegistryEncode.TLS,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SchemaRegistryEncode{
+		client:        client,
+		subject:       subject,
+		version:       sConf.Version,
+		schemaID:      sConf.SchemaID,
+		messageType:   sConf.MessageType,
+		refreshPeriod: refreshPeriod,
+		subjects:      map[string]*schemaRegistrySubject{},
+		nowFn:         time.Now,
+		log:           log,
+
+		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// pinned returns whether the schema used is fixed by its ID or subject
+// version, in which case it never needs refreshing.
+func (s *SchemaRegistryEncode) pinned() bool {
+	return s.schemaID > 0 || s.version != "latest"
+}
+
+// getCodec returns the codec of the schema of a subject, refreshing it if the
+// latest version is used and the refresh period has passed. When a refresh
+// fails the previous schema is used until the next refresh period.
+func (s *SchemaRegistryEncode) getCodec(subject string) (*schemaRegistryCodec, error) {
+	s.subjectMut.Lock()
+	defer s.subjectMut.Unlock()
+
+	now := s.nowFn()
+	cached, exists := s.subjects[subject]
+	if exists && (s.pinned() || now.Sub(cached.refreshed) < s.refreshPeriod) {
+		return cached.codec, nil
+	}
+
+	var info *schemaRegistryInfo
+	var err error
+	if s.schemaID > 0 {
+		info, err = s.client.getByID(s.schemaID)
+	} else {
+		info, err = s.client.getBySubject(subject, s.version)
+	}
+	if err == nil && exists && info.ID == cached.codec.id {
+		cached.refreshed = now
+		return cached.codec, nil
+	}
+
+	var codec *schemaRegistryCodec
+	if err == nil {
+		codec, err = s.client.compile(info, s.messageType)
+	}
+	if err != nil {
+		if !exists {
+			return nil, err
+		}
+		s.log.Errorf("Failed to refresh schema of subject '%v': %v\n", subject, err)
+		cached.refreshed = now
+		return cached.codec, nil
+	}
+
+	s.subjects[subject] = &schemaRegistrySubject{
+		codec:     codec,
+		refreshed: now,
+	}
+	return codec, nil
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (s *SchemaRegistryEncode) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	s.mCount.Incr(1)
+	newMsg := msg.Copy()
+
+	proc := func(index int, span opentracing.Span, part types.Part) error {
+		var subject string
+		if s.subject != nil {
+			subject = s.subject.String(index, msg)
+		}
+
+		codec, err := s.getCodec(subject)
+		if err != nil {
+			s.mErr.Incr(1)
+			s.log.Errorf("Failed to obtain schema of subject '%v': %v\n", subject, err)
+			return err
+		}
+
+		payload, err := codec.encode(part.Get())
+		if err != nil {
+			s.mErr.Incr(1)
+			s.log.Debugf("Failed to encode message: %v\n", err)
+			return err
+		}
+
+		encoded := make([]byte, 5, len(payload)+5)
+		encoded[0] = schemaRegistryWireMagic
+		binary.BigEndian.PutUint32(encoded[1:], uint32(codec.id))
+		part.Set(append(encoded, payload...))
+		return nil
+	}
+
+	IteratePartsWithSpan(TypeSchemaRegistryEncode, nil, newMsg, proc)
+
+	s.mBatchSent.Incr(1)
+	s.mSent.Incr(int64(newMsg.Len()))
+	return []types.Message{newMsg}, nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (s *SchemaRegistryEncode) CloseAsync() {
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (s *SchemaRegistryEncode) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,464 @@
+package processor
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSchemaRegistry struct {
+	mut      sync.Mutex
+	schemas  map[int]schemaRegistryInfo
+	subjects map[string][]int
+	requests int
+}
+
+func newFakeSchemaRegistry(t *testing.T) (*fakeSchemaRegistry, *httptest.Server) {
+	t.Helper()
+
+	reg := &fakeSchemaRegistry{
+		schemas:  map[int]schemaRegistryInfo{},
+		subjects: map[string][]int{},
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reg.mut.Lock()
+		defer reg.mut.Unlock()
+		reg.requests++
+
+		var info schemaRegistryInfo
+		var exists bool
+
+		path := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+		if len(path) == 3 && path[0] == "schemas" && path[1] == "ids" {
+			if id, err := strconv.Atoi(path[2]); err == nil {
+				info, exists = reg.schemas[id]
+			}
+		} else if len(path) == 4 && path[0] == "subjects" && path[2] == "versions" {
+			ids := reg.subjects[path[1]]
+			if path[3] == "latest" && len(ids) > 0 {
+				info, exists = reg.schemas[ids[len(ids)-1]]
+			} else if v, err := strconv.Atoi(path[3]); err == nil && v > 0 && v <= len(ids) {
+				info, exists = reg.schemas[ids[v-1]]
+			}
+		}
+		if !exists {
+			http.Error(w, `{"error_code":40403,"message":"Schema not found"}`, http.StatusNotFound)
+			return
+		}
+		b, _ := json.Marshal(info)
+		w.Write(b)
+	}))
+	return reg, ts
+}
+
+func (f *fakeSchemaRegistry) register(subject string, info schemaRegistryInfo) int {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	info.ID = len(f.schemas) + 1
+	f.subjects[subject] = append(f.subjects[subject], info.ID)
+	info.Version = len(f.subjects[subject])
+	f.schemas[info.ID] = info
+	return info.ID
+}
+
+//------------------------------------------------------------------------------
+
+const testAvroSchemaV1 = `{
+  "type": "record",
+  "name": "person",
+  "fields": [
+    {"name": "name", "type": "string"}
+  ]
+}`
+
+const testAvroSchemaV2 = `{
+  "type": "record",
+  "name": "person",
+  "fields": [
+    {"name": "name", "type": "string"},
+    {"name": "age", "type": ["null", "int"], "default": null}
+  ]
+}`
+
+func newSchemaRegistryProcs(t *testing.T, url, subject string) (Type, Type) {
+	t.Helper()
+
+	encConf := NewConfig()
+	encConf.Type = TypeSchemaRegistryEncode
+	encConf.SchemaRegistryEncode.URL = url
+	encConf.SchemaRegistryEncode.Subject = subject
+
+	enc, err := New(encConf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	decConf := NewConfig()
+	decConf.Type = TypeSchemaRegistryDecode
+	decConf.SchemaRegistryDecode.URL = url
+
+	dec, err := New(decConf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	return enc, dec
+}
+
+func TestSchemaRegistryConfigErrors(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeSchemaRegistryEncode
+	_, err := New(conf, nil, log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+
+	conf.SchemaRegistryEncode.Subject = "foo"
+	conf.SchemaRegistryEncode.RefreshPeriod = "nope"
+	_, err = New(conf, nil, log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+
+	conf.SchemaRegistryEncode.RefreshPeriod = "10m"
+	conf.SchemaRegistryEncode.Version = "0"
+	_, err = New(conf, nil, log.Noop(), metrics.Noop())
+	assert.EqualError(t, err, "version must be latest or a positive integer, got: 0")
+
+	conf.SchemaRegistryEncode.Version = "latest"
+	conf.SchemaRegistryEncode.SchemaID = 5
+	_, err = New(conf, nil, log.Noop(), metrics.Noop())
+	assert.EqualError(t, err, "cannot specify both a subject and a schema_id")
+
+	conf = NewConfig()
+	conf.Type = TypeSchemaRegistryDecode
+	conf.SchemaRegistryDecode.URL = "not a url"
+	_, err = New(conf, nil, log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+}
+
+func TestSchemaRegistryAvroEvolution(t *testing.T) {
+	reg, ts := newFakeSchemaRegistry(t)
+	defer ts.Close()
+
+	idV1 := reg.register("people-value", schemaRegistryInfo{Schema: testAvroSchemaV1})
+
+	enc, dec := newSchemaRegistryProcs(t, ts.URL, `${! meta("topic") }-value`)
+	encoder := enc.(*SchemaRegistryEncode)
+
+	now := time.Now()
+	encoder.nowFn = func() time.Time { return now }
+
+	input := message.New([][]byte{[]byte(`{"name":"foo"}`)})
+	input.Get(0).Metadata().Set("topic", "people")
+
+	encoded, res := enc.ProcessMessage(input)
+	require.Nil(t, res)
+	require.Len(t, encoded, 1)
+	require.False(t, HasFailed(encoded[0].Get(0)), GetFail(encoded[0].Get(0)))
+
+	b := encoded[0].Get(0).Get()
+	assert.Equal(t, []byte{0, 0, 0, 0, byte(idV1)}, b[:5])
+
+	// A new version of the schema is only used once the refresh period passes.
+	idV2 := reg.register("people-value", schemaRegistryInfo{Schema: testAvroSchemaV2})
+
+	input = message.New([][]byte{[]byte(`{"name":"bar","age":{"int":30}}`)})
+	input.Get(0).Metadata().Set("topic", "people")
+
+	// The age field is unknown to the first version of the schema.
+	encoded2, _ := enc.ProcessMessage(input)
+	assert.True(t, HasFailed(encoded2[0].Get(0)))
+
+	now = now.Add(time.Hour)
+	encoded2, _ = enc.ProcessMessage(input)
+	require.False(t, HasFailed(encoded2[0].Get(0)), GetFail(encoded2[0].Get(0)))
+	assert.Equal(t, byte(idV2), encoded2[0].Get(0).Get()[4])
+
+	// Both versions can be decoded by the same processor.
+	decoded, res := dec.ProcessMessage(encoded[0])
+	require.Nil(t, res)
+	assert.Equal(t, `{"name":"foo"}`, string(decoded[0].Get(0).Get()))
+	assert.Equal(t, "1", decoded[0].Get(0).Metadata().Get("schema_id"))
+
+	decoded, _ = dec.ProcessMessage(encoded2[0])
+	assert.JSONEq(t, `{"name":"bar","age":{"int":30}}`, string(decoded[0].Get(0).Get()))
+	assert.Equal(t, "2", decoded[0].Get(0).Metadata().Get("schema_id"))
+
+	// Schemas are cached by ID.
+	reg.mut.Lock()
+	requests := reg.requests
+	reg.mut.Unlock()
+
+	_, _ = dec.ProcessMessage(encoded[0])
+
+	reg.mut.Lock()
+	assert.Equal(t, requests, reg.requests)
+	reg.mut.Unlock()
+}
+
+func TestSchemaRegistryDecodeErrors(t *testing.T) {
+	_, ts := newFakeSchemaRegistry(t)
+	defer ts.Close()
+
+	_, dec := newSchemaRegistryProcs(t, ts.URL, "foo")
+
+	msgs, _ := dec.ProcessMessage(message.New([][]byte{
+		[]byte(`{"name":"foo"}`),
+		{0, 0, 0, 0, 5, 1, 2, 3},
+	}))
+	require.Len(t, msgs, 1)
+	assert.Equal(t, errSchemaRegistryWireFormat.Error(), GetFail(msgs[0].Get(0)))
+	assert.Contains(t, GetFail(msgs[0].Get(1)), "returned status 404")
+}
+
+func TestSchemaRegistryJSONSchema(t *testing.T) {
+	reg, ts := newFakeSchemaRegistry(t)
+	defer ts.Close()
+
+	reg.register("foo", schemaRegistryInfo{
+		SchemaType: "JSON",
+		Schema:     `{"type":"object","properties":{"name":{"type":"string"}}}`,
+	})
+
+	enc, dec := newSchemaRegistryProcs(t, ts.URL, "foo")
+
+	encoded, _ := enc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"name":"foo"}`),
+		[]byte(`{"name":5}`),
+	}))
+	require.Len(t, encoded, 1)
+	assert.Equal(t, append([]byte{0, 0, 0, 0, 1}, `{"name":"foo"}`...), encoded[0].Get(0).Get())
+	assert.True(t, HasFailed(encoded[0].Get(1)))
+
+	decoded, _ := dec.ProcessMessage(encoded[0])
+	assert.Equal(t, `{"name":"foo"}`, string(decoded[0].Get(0).Get()))
+	assert.False(t, HasFailed(decoded[0].Get(0)))
+}
+
+func TestSchemaRegistryProtobufReferences(t *testing.T) {
+	reg, ts := newFakeSchemaRegistry(t)
+	defer ts.Close()
+
+	reg.register("address", schemaRegistryInfo{
+		SchemaType: "PROTOBUF",
+		Schema: `syntax = "proto3";
+package testing;
+
+message Address {
+  string city = 1;
+}`,
+	})
+	reg.register("person", schemaRegistryInfo{
+		SchemaType: "PROTOBUF",
+		Schema: `syntax = "proto3";
+package testing;
+
+import "address.proto";
+
+message Person {
+  string name = 1;
+  Address address = 2;
+
+  message Pet {
+    string name = 1;
+  }
+}`,
+		References: []schemaRegistryReference{
+			{Name: "address.proto", Subject: "address", Version: 1},
+		},
+	})
+
+	enc, dec := newSchemaRegistryProcs(t, ts.URL, "person")
+
+	encoded, _ := enc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"name":"foo","address":{"city":"bar"}}`),
+	}))
+	require.False(t, HasFailed(encoded[0].Get(0)), GetFail(encoded[0].Get(0)))
+	assert.Equal(t, []byte{0, 0, 0, 0, 2, 0}, encoded[0].Get(0).Get()[:6])
+
+	decoded, _ := dec.ProcessMessage(encoded[0])
+	require.False(t, HasFailed(decoded[0].Get(0)), GetFail(decoded[0].Get(0)))
+	assert.JSONEq(t, `{"name":"foo","address":{"city":"bar"}}`, string(decoded[0].Get(0).Get()))
+
+	// A nested message type identified by the indexes [0, 0], where the name
+	// field has the tag 1.
+	nested := []byte{0, 0, 0, 0, 2, 4, 0, 0, 0x0a, 3, 'b', 'a', 'z'}
+	decoded, _ = dec.ProcessMessage(message.New([][]byte{nested}))
+	require.False(t, HasFailed(decoded[0].Get(0)), GetFail(decoded[0].Get(0)))
+	assert.Equal(t, `{"name":"baz"}`, string(decoded[0].Get(0).Get()))
+}
+
+func TestSchemaRegistryProtobufMalformedIndexes(t *testing.T) {
+	varint := func(v int64) []byte {
+		b := make([]byte, binary.MaxVarintLen64)
+		return b[:binary.PutVarint(b, v)]
+	}
+
+	tests := map[string]struct {
+		payload []byte
+		errStr  string
+	}{
+		"empty": {
+			payload: nil,
+			errStr:  "failed to read protobuf message indexes",
+		},
+		"huge count": {
+			payload: varint(1 << 60),
+			errStr:  "invalid protobuf message index count: 1152921504606846976",
+		},
+		"negative count": {
+			payload: append(varint(-5), 0x0a),
+			errStr:  "invalid protobuf message index count: -5",
+		},
+		"count exceeds payload": {
+			payload: append(varint(3), 0),
+			errStr:  "invalid protobuf message index count: 3",
+		},
+		"truncated index": {
+			payload: append(varint(1), 0x80),
+			errStr:  "failed to read protobuf message indexes",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			_, _, err := readProtobufMessageIndexes(nil, test.payload)
+			require.EqualError(t, err, test.errStr)
+		})
+	}
+
+	reg, ts := newFakeSchemaRegistry(t)
+	defer ts.Close()
+
+	reg.register("foo", schemaRegistryInfo{
+		SchemaType: "PROTOBUF",
+		Schema: `syntax = "proto3";
+package testing;
+
+message Foo {
+  string name = 1;
+}`,
+	})
+
+	_, dec := newSchemaRegistryProcs(t, ts.URL, "foo")
+
+	decoded, _ := dec.ProcessMessage(message.New([][]byte{
+		append([]byte{0, 0, 0, 0, 1}, varint(1<<60)...),
+	}))
+	require.Len(t, decoded, 1)
+	assert.Contains(t, GetFail(decoded[0].Get(0)), "invalid protobuf message index count")
+}
+
+func TestSchemaRegistryEncodePinned(t *testing.T) {
+	reg, ts := newFakeSchemaRegistry(t)
+	defer ts.Close()
+
+	idV1 := reg.register("people", schemaRegistryInfo{Schema: testAvroSchemaV1})
+	idV2 := reg.register("people", schemaRegistryInfo{Schema: testAvroSchemaV2})
+
+	versionConf := NewConfig()
+	versionConf.Type = TypeSchemaRegistryEncode
+	versionConf.SchemaRegistryEncode.URL = ts.URL
+	versionConf.SchemaRegistryEncode.Subject = "people"
+	versionConf.SchemaRegistryEncode.Version = "1"
+
+	idConf := NewConfig()
+	idConf.Type = TypeSchemaRegistryEncode
+	idConf.SchemaRegistryEncode.URL = ts.URL
+	idConf.SchemaRegistryEncode.SchemaID = idV2
+
+	for _, test := range []struct {
+		name string
+		conf Config
+		id   int
+	}{
+		{name: "version", conf: versionConf, id: idV1},
+		{name: "schema id", conf: idConf, id: idV2},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			enc, err := New(test.conf, nil, log.Noop(), metrics.Noop())
+			require.NoError(t, err)
+
+			encoder := enc.(*SchemaRegistryEncode)
+			now := time.Now()
+			encoder.nowFn = func() time.Time { return now }
+
+			for i := 0; i < 2; i++ {
+				encoded, _ := enc.ProcessMessage(message.New([][]byte{[]byte(`{"name":"foo"}`)}))
+				require.False(t, HasFailed(encoded[0].Get(0)), GetFail(encoded[0].Get(0)))
+				assert.Equal(t, []byte{0, 0, 0, 0, byte(test.id)}, encoded[0].Get(0).Get()[:5])
+
+				reg.mut.Lock()
+				requests := reg.requests
+				reg.mut.Unlock()
+
+				// Pinned schemas are never refreshed.
+				now = now.Add(time.Hour)
+				_, _ = enc.ProcessMessage(message.New([][]byte{[]byte(`{"name":"foo"}`)}))
+
+				reg.mut.Lock()
+				assert.Equal(t, requests, reg.requests)
+				reg.mut.Unlock()
+			}
+		})
+	}
+}
+
+func TestSchemaRegistryProtobufMessageType(t *testing.T) {
+	reg, ts := newFakeSchemaRegistry(t)
+	defer ts.Close()
+
+	reg.register("person", schemaRegistryInfo{
+		SchemaType: "PROTOBUF",
+		Schema: `syntax = "proto3";
+package testing;
+
+message Person {
+  string name = 1;
+}
+
+message Pet {
+  string name = 1;
+
+  message Toy {
+    string colour = 1;
+  }
+}`,
+	})
+
+	encConf := NewConfig()
+	encConf.Type = TypeSchemaRegistryEncode
+	encConf.SchemaRegistryEncode.URL = ts.URL
+	encConf.SchemaRegistryEncode.Subject = "person"
+	encConf.SchemaRegistryEncode.MessageType = "testing.Pet.Toy"
+
+	enc, err := New(encConf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	_, dec := newSchemaRegistryProcs(t, ts.URL, "person")
+
+	encoded, _ := enc.ProcessMessage(message.New([][]byte{[]byte(`{"colour":"red"}`)}))
+	require.False(t, HasFailed(encoded[0].Get(0)), GetFail(encoded[0].Get(0)))
+
+	// The message indexes [1, 0] are prefixed by their count.
+	assert.Equal(t, []byte{0, 0, 0, 0, 1, 4, 2, 0}, encoded[0].Get(0).Get()[:8])
+
+	decoded, _ := dec.ProcessMessage(encoded[0])
+	require.False(t, HasFailed(decoded[0].Get(0)), GetFail(decoded[0].Get(0)))
+	assert.Equal(t, `{"colour":"red"}`, string(decoded[0].Get(0).Get()))
+
+	encConf.SchemaRegistryEncode.MessageType = "testing.Nope"
+	enc, err = New(encConf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	encoded, _ = enc.ProcessMessage(message.New([][]byte{[]byte(`{"colour":"red"}`)}))
+	assert.Contains(t, GetFail(encoded[0].Get(0)), "does not contain message type 'testing.Nope'")
+}
@@ -6,4 +6,5 @@ package processor
 import (
 	// SQL Drivers
 	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
 )
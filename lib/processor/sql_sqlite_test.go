@@ -0,0 +1,73 @@
+package processor
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLSQLite(t *testing.T) {
+	dsn := "file:" + filepath.Join(t.TempDir(), "foo.db")
+
+	conf := NewConfig()
+	conf.Type = TypeSQL
+	conf.SQL.Driver = "sqlite"
+	conf.SQL.DataSourceName = dsn
+	conf.SQL.Query = "CREATE TABLE footable (foo TEXT PRIMARY KEY, bar TEXT, baz TEXT);"
+
+	s, err := NewSQL(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	resMsgs, response := s.ProcessMessage(message.New([][]byte{[]byte(`{}`)}))
+	require.Nil(t, response)
+	require.Len(t, resMsgs, 1)
+	s.CloseAsync()
+
+	conf.SQL.Query = "INSERT INTO footable (foo, bar, baz) VALUES (?, ?, ?);"
+	conf.SQL.Args = []string{
+		"${! json(\"foo\") }",
+		"${! json(\"bar\") }",
+		"${! json(\"baz\") }",
+	}
+
+	s, err = NewSQL(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	parts := [][]byte{
+		[]byte(`{"foo":"foo1","bar":"bar1","baz":"baz1"}`),
+		[]byte(`{"foo":"foo2","bar":"bar2","baz":"baz2"}`),
+	}
+
+	resMsgs, response = s.ProcessMessage(message.New(parts))
+	require.Nil(t, response)
+	require.Len(t, resMsgs, 1)
+	assert.Equal(t, parts, message.GetAllBytes(resMsgs[0]))
+	s.CloseAsync()
+
+	conf.SQL.Query = "SELECT * FROM footable WHERE foo = ?;"
+	conf.SQL.Args = []string{
+		"${! json(\"foo\") }",
+	}
+	conf.SQL.ResultCodec = "json_array"
+
+	s, err = NewSQL(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	defer s.CloseAsync()
+
+	resMsgs, response = s.ProcessMessage(message.New([][]byte{
+		[]byte(`{"foo":"foo1"}`),
+		[]byte(`{"foo":"foo2"}`),
+	}))
+	require.Nil(t, response)
+	require.Len(t, resMsgs, 1)
+
+	assert.Equal(t, [][]byte{
+		[]byte(`[{"bar":"bar1","baz":"baz1","foo":"foo1"}]`),
+		[]byte(`[{"bar":"bar2","baz":"baz2","foo":"foo2"}]`),
+	}, message.GetAllBytes(resMsgs[0]))
+}
@@ -0,0 +1,723 @@
+package processor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/parser"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/query"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/message/tracing"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeWindow] = TypeSpec{
+		constructor: NewWindow,
+		Categories: []Category{
+			CategoryComposition,
+		},
+		Status:  docs.StatusExperimental,
+		Version: "3.41.0",
+		Summary: `
+Assigns messages to tumbling, sliding or session windows based on an event
+timestamp, and emits a batch for each window once it closes.`,
+		Description: `
+The event time of each message is obtained by executing the
+` + "`timestamp_mapping`" + `, which must result in either a unix timestamp
+(in seconds, fractions are allowed) or an RFC3339 formatted string. Messages
+are then optionally grouped by the interpolated ` + "`group_by`" + ` key, where
+each key has windows of its own.
+
+Messages that are added to a window are removed from the stream, and the
+contents of open windows are stored within a
+[cache resource](/docs/components/caches/about) until the window closes. Using
+a persisted cache such as ` + "`redis`" + ` therefore allows windows to survive
+a restart of the service.
+
+### Window Types
+
+- ` + "`tumbling`" + `: Fixed sized, non-overlapping windows of ` + "`size`" + ` aligned to the unix epoch.
+- ` + "`sliding`" + `: Fixed sized windows of ` + "`size`" + ` that begin every ` + "`slide`" + `, where a message can belong to multiple windows.
+- ` + "`session`" + `: Windows that are extended each time a message arrives within ` + "`gap`" + ` of another, and close once no messages have arrived for the duration of the gap.
+
+### Watermarks
+
+The watermark of the processor is the largest event time seen so far minus the
+` + "`allowed_lateness`" + `. Windows are closed once their end time is at or
+before the watermark, at which point the messages of the window are emitted as
+a batch. Since the watermark is only advanced by new messages windows are not
+closed during periods where no messages are consumed.
+
+Messages that arrive after all of the windows they belong to have closed are
+flagged as failed and passed through unchanged, allowing you to handle them
+with [standard error handling patterns](/docs/configuration/error_handling).
+
+### Reducer
+
+When a ` + "`reducer`" + ` is specified the messages of a closed window are
+reduced into a single message by executing the mapping against an array of all
+the documents of the window. Without a reducer the window is emitted as a batch
+of the original messages.
+
+Emitted messages have the metadata fields ` + "`window_start` and `window_end`" + `
+set to RFC3339 timestamps of the window bounds, and ` + "`window_key`" + ` set
+to the group key when ` + "`group_by`" + ` is used.
+
+### Delivery Guarantees
+
+Messages are acknowledged once they are stored within the cache, and therefore
+delivery guarantees of windowed data are only as strong as the persistence of
+the cache. The state of windows is not safe to share across multiple instances
+of this processor, and therefore each processor should use a unique
+` + "`key_prefix`" + `.`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title: "Counting Per Minute",
+				Summary: `
+Given a stream of JSON documents each with a ` + "`timestamp`" + ` field and a
+` + "`type`" + `, we can emit a count of each type of event per minute:`,
+				Config: `
+pipeline:
+  processors:
+    - window:
+        cache: windows
+        timestamp_mapping: root = this.timestamp
+        group_by: ${! json("type") }
+        type: tumbling
+        size: 1m
+        allowed_lateness: 10s
+        reducer: |
+          root.type = meta("window_key")
+          root.start = meta("window_start")
+          root.count = this.length()
+
+resources:
+  caches:
+    windows:
+      redis:
+        url: tcp://localhost:6379
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("cache", "The [`cache` resource](/docs/components/caches/about) to store the state of windows within."),
+			docs.FieldCommon("timestamp_mapping", "A [Bloblang mapping](/docs/guides/bloblang/about) that returns the event time of a message as either a unix timestamp or an RFC3339 string.", `root = this.timestamp`, `root = meta("kafka_timestamp_unix").number()`),
+			docs.FieldCommon("group_by", "An optional key to group messages by, where each group has windows of its own.", `${! json("user_id") }`).SupportsInterpolation(true),
+			docs.FieldCommon("type", "The type of window to assign messages to.").HasOptions("tumbling", "sliding", "session"),
+			docs.FieldCommon("size", "The size of each window, used by `tumbling` and `sliding` windows."),
+			docs.FieldCommon("slide", "The period between the start of each window, used by `sliding` windows."),
+			docs.FieldCommon("gap", "The period of inactivity after which a window is closed, used by `session` windows."),
+			docs.FieldCommon("allowed_lateness", "The period of time after the largest event time seen before a window is closed, allowing out of order messages to be added to their windows."),
+			docs.FieldCommon("reducer", "An optional [Bloblang mapping](/docs/guides/bloblang/about) executed on an array of the documents of a closed window, where the result replaces the window with a single message.", `root.count = this.length()`),
+			docs.FieldAdvanced("key_prefix", "A prefix added to all keys written to the cache, which allows multiple window processors to share the same cache."),
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// WindowConfig contains configuration fields for the Window processor.
+type WindowConfig struct {
+	Cache            string `json:"cache" yaml:"cache"`
+	TimestampMapping string `json:"timestamp_mapping" yaml:"timestamp_mapping"`
+	GroupBy          string `json:"group_by" yaml:"group_by"`
+	Type             string `json:"type" yaml:"type"`
+	Size             string `json:"size" yaml:"size"`
+	Slide            string `json:"slide" yaml:"slide"`
+	Gap              string `json:"gap" yaml:"gap"`
+	AllowedLateness  string `json:"allowed_lateness" yaml:"allowed_lateness"`
+	Reducer          string `json:"reducer" yaml:"reducer"`
+	KeyPrefix        string `json:"key_prefix" yaml:"key_prefix"`
+}
+
+// NewWindowConfig returns a WindowConfig with default values.
+func NewWindowConfig() WindowConfig {
+	return WindowConfig{
+		Cache:            "",
+		TimestampMapping: "root = now()",
+		GroupBy:          "",
+		Type:             "tumbling",
+		Size:             "1m",
+		Slide:            "",
+		Gap:              "",
+		AllowedLateness:  "0s",
+		Reducer:          "",
+		KeyPrefix:        "benthos_window",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+var errWindowLate = errors.New("message arrived after all of its windows were closed")
+
+// windowState describes an open window, the documents of which are stored
+// within the cache as a sequence of chunks, one for each batch that added to
+// the window.
+type windowState struct {
+	ID     string  `json:"id"`
+	Key    string  `json:"key"`
+	Start  int64   `json:"start"`
+	End    int64   `json:"end"`
+	Chunks []int64 `json:"chunks,omitempty"`
+}
+
+// windowIndex is the state of all open windows of a processor.
+type windowIndex struct {
+	MaxEventTime int64          `json:"max_event_time"`
+	NextChunk    int64          `json:"next_chunk"`
+	Windows      []*windowState `json:"windows"`
+}
+
+// windowDoc is a stored message of a window.
+type windowDoc struct {
+	Content  []byte            `json:"content"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+//------------------------------------------------------------------------------
+
+// Window is a processor that groups messages into windows of event time and
+// emits each window as a batch once it closes.
+type Window struct {
+	log log.Modular
+
+	cache     types.Cache
+	keyPrefix string
+
+	timestamp *mapping.Executor
+	groupBy   field.Expression
+	reducer   *mapping.Executor
+
+	windowType string
+	size       time.Duration
+	slide      time.Duration
+	gap        time.Duration
+	lateness   time.Duration
+
+	mut sync.Mutex
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mLate      metrics.StatCounter
+	mClosed    metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewWindow returns a Window processor.
+func NewWindow(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	w := &Window{
+		log:        log,
+		keyPrefix:  conf.Window.KeyPrefix,
+		windowType: conf.Window.Type,
+
+		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
+		mLate:      stats.GetCounter("late"),
+		mClosed:    stats.GetCounter("window.closed"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}
+
+	parseDuration := func(name, str string) (time.Duration, error) {
+		d, err := time.ParseDuration(str)
+		if err != nil {
+			return 0, fmt.Errorf("failed to parse %v: %v", name, err)
+		}
+		if d <= 0 {
+			return 0, fmt.Errorf("%v must be larger than zero", name)
+		}
+		return d, nil
+	}
+
+	var err error
+	switch w.windowType {
+	case "tumbling":
+		if w.size, err = parseDuration("size", conf.Window.Size); err != nil {
+			return nil, err
+		}
+	case "sliding":
+		if w.size, err = parseDuration("size", conf.Window.Size); err != nil {
+			return nil, err
+		}
+		if w.slide, err = parseDuration("slide", conf.Window.Slide); err != nil {
+			return nil, err
+		}
+	case "session":
+		if w.gap, err = parseDuration("gap", conf.Window.Gap); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("window type not recognised: %v", w.windowType)
+	}
+
+	if len(conf.Window.AllowedLateness) > 0 {
+		if w.lateness, err = time.ParseDuration(conf.Window.AllowedLateness); err != nil {
+			return nil, fmt.Errorf("failed to parse allowed_lateness: %v", err)
+		}
+	}
+
+	if w.timestamp, err = newWindowMapping("timestamp_mapping", conf.Window.TimestampMapping); err != nil {
+		return nil, err
+	}
+	if len(conf.Window.Reducer) > 0 {
+		if w.reducer, err = newWindowMapping("reducer", conf.Window.Reducer); err != nil {
+			return nil, err
+		}
+	}
+	if len(conf.Window.GroupBy) > 0 {
+		if w.groupBy, err = bloblang.NewField(conf.Window.GroupBy); err != nil {
+			return nil, fmt.Errorf("failed to parse group_by expression: %v", err)
+		}
+	}
+
+	if w.cache, err = mgr.GetCache(conf.Window.Cache); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func newWindowMapping(name, m string) (*mapping.Executor, error) {
+	exec, err := bloblang.NewMapping("", m)
+	if err != nil {
+		if perr, ok := err.(*parser.Error); ok {
+			return nil, fmt.Errorf("failed to parse %v: %v", name, perr.ErrorAtPosition([]rune(m)))
+		}
+		return nil, fmt.Errorf("failed to parse %v: %v", name, err)
+	}
+	return exec, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (w *Window) indexKey() string {
+	return w.keyPrefix + ":index"
+}
+
+func (w *Window) chunkKey(n int64) string {
+	return w.keyPrefix + ":chunk:" + strconv.FormatInt(n, 10)
+}
+
+func (w *Window) loadIndex() (*windowIndex, error) {
+	idx := &windowIndex{}
+	b, err := w.cache.Get(w.indexKey())
+	if err == types.ErrKeyNotFound {
+		return idx, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(b, idx); err != nil {
+		return nil, fmt.Errorf("failed to parse window index: %v", err)
+	}
+	return idx, nil
+}
+
+func (w *Window) saveIndex(idx *windowIndex) error {
+	b, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+	return w.cache.Set(w.indexKey(), b)
+}
+
+func (w *Window) loadDocs(s *windowState) ([]windowDoc, error) {
+	var docs []windowDoc
+	for _, n := range s.Chunks {
+		b, err := w.cache.Get(w.chunkKey(n))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read window contents: %v", err)
+		}
+		var chunk []windowDoc
+		if err = json.Unmarshal(b, &chunk); err != nil {
+			return nil, fmt.Errorf("failed to parse window contents: %v", err)
+		}
+		docs = append(docs, chunk...)
+	}
+	return docs, nil
+}
+
+func (w *Window) saveChunk(n int64, docs []windowDoc) error {
+	b, err := json.Marshal(docs)
+	if err != nil {
+		return err
+	}
+	return w.cache.Set(w.chunkKey(n), b)
+}
+
+//------------------------------------------------------------------------------
+
+func (w *Window) eventTime(index int, msg types.Message) (time.Time, error) {
+	v, err := w.timestamp.Exec(query.FunctionContext{
+		Maps:     w.timestamp.Maps(),
+		Vars:     map[string]interface{}{},
+		Index:    index,
+		MsgBatch: msg,
+	}.WithValueFunc(func() *interface{} {
+		jObj, err := msg.Get(index).JSON()
+		if err != nil {
+			return nil
+		}
+		return &jObj
+	}))
+	if err != nil {
+		return time.Time{}, err
+	}
+	return query.IGetTimestamp(v)
+}
+
+// windowAssigner tracks changes to the open windows of an index whilst
+// messages of a batch are assigned to them.
+type windowAssigner struct {
+	w   *Window
+	idx *windowIndex
+
+	appends map[string][]windowDoc
+}
+
+func (a *windowAssigner) watermark() int64 {
+	return a.idx.MaxEventTime - int64(a.w.lateness)
+}
+
+func (a *windowAssigner) getOrCreate(key string, start, end int64) *windowState {
+	for _, s := range a.idx.Windows {
+		if s.Key == key && s.Start == start && s.End == end {
+			return s
+		}
+	}
+	s := &windowState{
+		ID:    fmt.Sprintf("%v:%v", key, start),
+		Key:   key,
+		Start: start,
+		End:   end,
+	}
+	a.idx.Windows = append(a.idx.Windows, s)
+	return s
+}
+
+func floorDiv(t, d int64) int64 {
+	r := t % d
+	if r < 0 {
+		r += d
+	}
+	return t - r
+}
+
+// assign returns the windows that an event time belongs to, creating or
+// extending windows where necessary. An empty result means all windows that
+// the message belongs to have already closed.
+func (a *windowAssigner) assign(key string, t int64) []*windowState {
+	watermark := a.watermark()
+	switch a.w.windowType {
+	case "tumbling":
+		start := floorDiv(t, int64(a.w.size))
+		end := start + int64(a.w.size)
+		if end <= watermark {
+			return nil
+		}
+		return []*windowState{a.getOrCreate(key, start, end)}
+	case "sliding":
+		var windows []*windowState
+		for start := floorDiv(t, int64(a.w.slide)); start+int64(a.w.size) > t; start -= int64(a.w.slide) {
+			end := start + int64(a.w.size)
+			if end <= watermark {
+				break
+			}
+			windows = append(windows, a.getOrCreate(key, start, end))
+		}
+		return windows
+	}
+
+	start, end := t, t+int64(a.w.gap)
+	if end <= watermark {
+		return nil
+	}
+
+	var target *windowState
+	remaining := a.idx.Windows[:0]
+	for _, s := range a.idx.Windows {
+		if s.Key != key || t < s.Start-int64(a.w.gap) || t >= s.End {
+			remaining = append(remaining, s)
+			continue
+		}
+		if target == nil {
+			target = s
+			remaining = append(remaining, s)
+			continue
+		}
+		// The message bridges two sessions, and so they're merged.
+		if s.Start < target.Start {
+			target.Start = s.Start
+		}
+		if s.End > target.End {
+			target.End = s.End
+		}
+		target.Chunks = append(target.Chunks, s.Chunks...)
+		a.appends[target.ID] = append(a.appends[target.ID], a.appends[s.ID]...)
+		delete(a.appends, s.ID)
+	}
+	a.idx.Windows = remaining
+
+	if target == nil {
+		target = &windowState{
+			ID:    fmt.Sprintf("%v:%v", key, start),
+			Key:   key,
+			Start: start,
+			End:   end,
+		}
+		a.idx.Windows = append(a.idx.Windows, target)
+		return []*windowState{target}
+	}
+	if start < target.Start {
+		target.Start = start
+	}
+	if end > target.End {
+		target.End = end
+	}
+	return []*windowState{target}
+}
+
+// flush writes the documents appended to each window as new chunks. Chunks are
+// only referenced once the index is saved, and since their numbers are
+// allocated from the index a batch that is retried after a failure overwrites
+// the chunks of its previous attempt rather than duplicating them.
+func (a *windowAssigner) flush() error {
+	for _, s := range a.idx.Windows {
+		appends := a.appends[s.ID]
+		if len(appends) == 0 {
+			continue
+		}
+		n := a.idx.NextChunk
+		a.idx.NextChunk++
+		if err := a.w.saveChunk(n, appends); err != nil {
+			return err
+		}
+		s.Chunks = append(s.Chunks, n)
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+func (w *Window) emit(s *windowState, docs []windowDoc) types.Message {
+	start := time.Unix(0, s.Start).UTC().Format(time.RFC3339Nano)
+	end := time.Unix(0, s.End).UTC().Format(time.RFC3339Nano)
+	setMeta := func(p types.Part) {
+		p.Metadata().Set("window_start", start)
+		p.Metadata().Set("window_end", end)
+		if w.groupBy != nil {
+			p.Metadata().Set("window_key", s.Key)
+		}
+	}
+
+	if w.reducer == nil {
+		msg := message.New(nil)
+		for _, d := range docs {
+			p := message.NewPart(d.Content)
+			for k, v := range d.Metadata {
+				p.Metadata().Set(k, v)
+			}
+			setMeta(p)
+			msg.Append(p)
+		}
+		return msg
+	}
+
+	values := make([]interface{}, 0, len(docs))
+	for _, d := range docs {
+		var v interface{}
+		if err := json.Unmarshal(d.Content, &v); err != nil {
+			v = string(d.Content)
+		}
+		values = append(values, v)
+	}
+
+	part := message.NewPart(nil)
+	if err := part.SetJSON(values); err != nil {
+		w.mErr.Incr(1)
+		FlagErr(part, err)
+	}
+	setMeta(part)
+
+	reduceMsg := message.New(nil)
+	reduceMsg.Append(part)
+
+	reduced, err := w.reducer.MapPart(0, reduceMsg)
+	if err != nil {
+		w.mErr.Incr(1)
+		w.log.Errorf("Failed to reduce window: %v\n", err)
+		FlagErr(part, err)
+		reduced = part
+	}
+	if reduced == nil {
+		return nil
+	}
+	out := message.New(nil)
+	out.Append(reduced)
+	return out
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (w *Window) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	w.mCount.Incr(1)
+
+	spans := tracing.CreateChildSpans(TypeWindow, msg)
+	defer func() {
+		for _, s := range spans {
+			s.Finish()
+		}
+	}()
+
+	w.mut.Lock()
+	defer w.mut.Unlock()
+
+	failBatch := func(err error) ([]types.Message, types.Response) {
+		w.mErr.Incr(1)
+		w.log.Errorf("Failed to update windows: %v\n", err)
+		newMsg := msg.Copy()
+		newMsg.Iter(func(i int, p types.Part) error {
+			FlagErr(p, err)
+			return nil
+		})
+		return []types.Message{newMsg}, nil
+	}
+
+	idx, err := w.loadIndex()
+	if err != nil {
+		return failBatch(err)
+	}
+
+	assigner := &windowAssigner{
+		w:       w,
+		idx:     idx,
+		appends: map[string][]windowDoc{},
+	}
+
+	failed := message.New(nil)
+	msg.Iter(func(i int, p types.Part) error {
+		t, err := w.eventTime(i, msg)
+		if err != nil {
+			w.mErr.Incr(1)
+			w.log.Debugf("Failed to obtain event time: %v\n", err)
+			failed.Append(p.Copy())
+			FlagErr(failed.Get(-1), fmt.Errorf("failed to obtain event time: %w", err))
+			return nil
+		}
+
+		var key string
+		if w.groupBy != nil {
+			key = w.groupBy.String(i, msg)
+		}
+
+		windows := assigner.assign(key, t.UnixNano())
+		if len(windows) == 0 {
+			w.mLate.Incr(1)
+			failed.Append(p.Copy())
+			FlagErr(failed.Get(-1), errWindowLate)
+			return nil
+		}
+
+		doc := windowDoc{Content: p.Get()}
+		p.Metadata().Iter(func(k, v string) error {
+			if doc.Metadata == nil {
+				doc.Metadata = map[string]string{}
+			}
+			doc.Metadata[k] = v
+			return nil
+		})
+		for _, s := range windows {
+			assigner.appends[s.ID] = append(assigner.appends[s.ID], doc)
+		}
+		if t.UnixNano() > idx.MaxEventTime {
+			idx.MaxEventTime = t.UnixNano()
+		}
+		return nil
+	})
+
+	if err = assigner.flush(); err != nil {
+		return failBatch(err)
+	}
+
+	watermark := assigner.watermark()
+	var closed, open []*windowState
+	for _, s := range idx.Windows {
+		if s.End <= watermark {
+			closed = append(closed, s)
+		} else {
+			open = append(open, s)
+		}
+	}
+	sort.SliceStable(closed, func(i, j int) bool {
+		if closed[i].End == closed[j].End {
+			return closed[i].Start < closed[j].Start
+		}
+		return closed[i].End < closed[j].End
+	})
+
+	var msgs []types.Message
+	for _, s := range closed {
+		docs, err := w.loadDocs(s)
+		if err != nil {
+			return failBatch(err)
+		}
+		w.mClosed.Incr(1)
+		if out := w.emit(s, docs); out != nil && out.Len() > 0 {
+			msgs = append(msgs, out)
+		}
+	}
+
+	idx.Windows = open
+	if err = w.saveIndex(idx); err != nil {
+		return failBatch(err)
+	}
+	for _, s := range closed {
+		for _, n := range s.Chunks {
+			if err = w.cache.Delete(w.chunkKey(n)); err != nil {
+				w.mErr.Incr(1)
+				w.log.Errorf("Failed to delete closed window: %v\n", err)
+			}
+		}
+	}
+
+	if failed.Len() > 0 {
+		msgs = append(msgs, failed)
+	}
+	if len(msgs) == 0 {
+		return nil, response.NewAck()
+	}
+
+	w.mBatchSent.Incr(int64(len(msgs)))
+	for _, m := range msgs {
+		w.mSent.Incr(int64(m.Len()))
+	}
+	return msgs, nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (w *Window) CloseAsync() {
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (w *Window) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
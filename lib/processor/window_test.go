@@ -0,0 +1,279 @@
+package processor
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/cache"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newWindowTestMgr(t *testing.T) *fakeMgr {
+	t.Helper()
+
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	return &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": memCache,
+		},
+	}
+}
+
+func windowBatchContents(msgs []types.Message) [][]string {
+	var batches [][]string
+	for _, m := range msgs {
+		var batch []string
+		m.Iter(func(i int, p types.Part) error {
+			batch = append(batch, string(p.Get()))
+			return nil
+		})
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+func TestWindowConfigErrors(t *testing.T) {
+	mgr := newWindowTestMgr(t)
+
+	tests := map[string]func(c *WindowConfig){
+		"bad type":      func(c *WindowConfig) { c.Type = "nope" },
+		"bad size":      func(c *WindowConfig) { c.Size = "nope" },
+		"missing slide": func(c *WindowConfig) { c.Type = "sliding" },
+		"missing gap":   func(c *WindowConfig) { c.Type = "session" },
+		"bad lateness":  func(c *WindowConfig) { c.AllowedLateness = "nope" },
+		"bad mapping":   func(c *WindowConfig) { c.TimestampMapping = "root = " },
+		"bad reducer":   func(c *WindowConfig) { c.Reducer = "root = " },
+		"bad cache":     func(c *WindowConfig) { c.Cache = "nope" },
+	}
+
+	for name, test := range tests {
+		conf := NewConfig()
+		conf.Type = TypeWindow
+		conf.Window.Cache = "foocache"
+		test(&conf.Window)
+
+		_, err := New(conf, mgr, log.Noop(), metrics.Noop())
+		assert.Error(t, err, name)
+	}
+}
+
+func TestWindowTumbling(t *testing.T) {
+	mgr := newWindowTestMgr(t)
+
+	conf := NewConfig()
+	conf.Type = TypeWindow
+	conf.Window.Cache = "foocache"
+	conf.Window.TimestampMapping = "root = this.ts"
+	conf.Window.Size = "10s"
+	conf.Window.AllowedLateness = "5s"
+
+	proc, err := New(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"ts":101,"id":"a"}`),
+		[]byte(`{"ts":108,"id":"b"}`),
+		[]byte(`{"ts":112,"id":"c"}`),
+	}))
+	assert.Empty(t, msgs)
+	require.NotNil(t, res)
+	assert.NoError(t, res.Error())
+
+	// Out of order but within the allowed lateness.
+	msgs, _ = proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"ts":109,"id":"d"}`),
+	}))
+	assert.Empty(t, msgs)
+
+	// Advances the watermark to 110, closing the first window.
+	msgs, _ = proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"ts":115,"id":"e"}`),
+	}))
+	assert.Equal(t, [][]string{
+		{`{"ts":101,"id":"a"}`, `{"ts":108,"id":"b"}`, `{"ts":109,"id":"d"}`},
+	}, windowBatchContents(msgs))
+	assert.Equal(t, "1970-01-01T00:01:40Z", msgs[0].Get(0).Metadata().Get("window_start"))
+	assert.Equal(t, "1970-01-01T00:01:50Z", msgs[0].Get(0).Metadata().Get("window_end"))
+
+	// Too late for its window, which has already closed.
+	msgs, _ = proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"ts":105,"id":"f"}`),
+	}))
+	require.Len(t, msgs, 1)
+	assert.Equal(t, `{"ts":105,"id":"f"}`, string(msgs[0].Get(0).Get()))
+	assert.Equal(t, errWindowLate.Error(), GetFail(msgs[0].Get(0)))
+
+	// Timestamp errors are flagged.
+	msgs, _ = proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"id":"g"}`),
+	}))
+	require.Len(t, msgs, 1)
+	assert.True(t, HasFailed(msgs[0].Get(0)))
+}
+
+func TestWindowReducerGroupBy(t *testing.T) {
+	mgr := newWindowTestMgr(t)
+
+	conf := NewConfig()
+	conf.Type = TypeWindow
+	conf.Window.Cache = "foocache"
+	conf.Window.TimestampMapping = "root = this.ts"
+	conf.Window.GroupBy = `${! json("type") }`
+	conf.Window.Size = "1m"
+	conf.Window.Reducer = `
+root.type = meta("window_key")
+root.start = meta("window_start")
+root.count = this.length()
+`
+
+	proc, err := New(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"ts":"2021-02-01T10:00:05Z","type":"foo"}`),
+		[]byte(`{"ts":"2021-02-01T10:00:10Z","type":"bar"}`),
+		[]byte(`{"ts":"2021-02-01T10:00:20Z","type":"foo"}`),
+	}))
+	assert.Empty(t, msgs)
+	assert.NoError(t, res.Error())
+
+	msgs, _ = proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"ts":"2021-02-01T10:01:00Z","type":"foo"}`),
+	}))
+	assert.Equal(t, [][]string{
+		{`{"count":2,"start":"2021-02-01T10:00:00Z","type":"foo"}`},
+		{`{"count":1,"start":"2021-02-01T10:00:00Z","type":"bar"}`},
+	}, windowBatchContents(msgs))
+}
+
+func TestWindowSliding(t *testing.T) {
+	mgr := newWindowTestMgr(t)
+
+	conf := NewConfig()
+	conf.Type = TypeWindow
+	conf.Window.Cache = "foocache"
+	conf.Window.TimestampMapping = "root = this.ts"
+	conf.Window.Type = "sliding"
+	conf.Window.Size = "10s"
+	conf.Window.Slide = "5s"
+	conf.Window.Reducer = `root = this.map_each(this.id).join(",")`
+
+	proc, err := New(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, _ := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"ts":101,"id":"a"}`),
+		[]byte(`{"ts":106,"id":"b"}`),
+		[]byte(`{"ts":111,"id":"c"}`),
+	}))
+	assert.Equal(t, [][]string{
+		{`a`},
+		{`a,b`},
+	}, windowBatchContents(msgs))
+
+	msgs, _ = proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"ts":125,"id":"d"}`),
+	}))
+	assert.Equal(t, [][]string{
+		{`b,c`},
+		{`c`},
+	}, windowBatchContents(msgs))
+}
+
+func TestWindowSession(t *testing.T) {
+	mgr := newWindowTestMgr(t)
+
+	conf := NewConfig()
+	conf.Type = TypeWindow
+	conf.Window.Cache = "foocache"
+	conf.Window.TimestampMapping = "root = this.ts"
+	conf.Window.GroupBy = `${! json("user") }`
+	conf.Window.Type = "session"
+	conf.Window.Gap = "10s"
+	conf.Window.AllowedLateness = "30s"
+	conf.Window.Reducer = `root = this.map_each(this.id).sort().join(",")`
+
+	proc, err := New(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, _ := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"ts":100,"user":"foo","id":"a"}`),
+		[]byte(`{"ts":115,"user":"foo","id":"b"}`),
+		[]byte(`{"ts":105,"user":"bar","id":"c"}`),
+	}))
+	assert.Empty(t, msgs)
+
+	// Bridges the two sessions of foo, which are merged.
+	msgs, _ = proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"ts":107,"user":"foo","id":"d"}`),
+	}))
+	assert.Empty(t, msgs)
+
+	msgs, _ = proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"ts":200,"user":"baz","id":"e"}`),
+	}))
+	assert.Equal(t, [][]string{
+		{`c`},
+		{`a,b,d`},
+	}, windowBatchContents(msgs))
+}
+
+type windowFailingCache struct {
+	types.Cache
+	failKey string
+}
+
+func (c *windowFailingCache) Set(key string, value []byte) error {
+	if key == c.failKey {
+		c.failKey = ""
+		return errors.New("nope")
+	}
+	return c.Cache.Set(key, value)
+}
+
+func TestWindowRetryAfterFailure(t *testing.T) {
+	mgr := newWindowTestMgr(t)
+	failCache := &windowFailingCache{Cache: mgr.caches["foocache"]}
+	mgr.caches["foocache"] = failCache
+
+	conf := NewConfig()
+	conf.Type = TypeWindow
+	conf.Window.Cache = "foocache"
+	conf.Window.TimestampMapping = "root = this.ts"
+	conf.Window.Size = "10s"
+
+	proc, err := New(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, _ := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"ts":101,"id":"a"}`),
+	}))
+	assert.Empty(t, msgs)
+
+	// The contents of the batch are written but the index is not, and so the
+	// batch is retried.
+	failCache.failKey = conf.Window.KeyPrefix + ":index"
+	batch := message.New([][]byte{
+		[]byte(`{"ts":102,"id":"b"}`),
+	})
+	msgs, _ = proc.ProcessMessage(batch)
+	require.Len(t, msgs, 1)
+	assert.True(t, HasFailed(msgs[0].Get(0)))
+
+	msgs, _ = proc.ProcessMessage(batch)
+	assert.Empty(t, msgs)
+
+	msgs, _ = proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"ts":115,"id":"c"}`),
+	}))
+	assert.Equal(t, [][]string{
+		{`{"ts":101,"id":"a"}`, `{"ts":102,"id":"b"}`},
+	}, windowBatchContents(msgs))
+}
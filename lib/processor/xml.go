@@ -66,9 +66,48 @@ The resulting JSON structure would look like this:
     ]
   }
 }
+` + "```" + `
+
+### ` + "`from_json`" + `
+
+Converts a JSON document into XML, following the reverse of the rules used by
+` + "`to_json`" + `:
+
+- Keys prefixed with a hyphen, ` + "`-`" + `, are encoded as attributes of the
+  parent element.
+- The key ` + "`#text`" + ` is encoded as the text content of the parent element,
+  and the key ` + "`#cdata`" + ` is encoded as a CDATA section.
+- Arrays are encoded as repeated elements of the same name.
+- Child elements are written in alphabetical order of their keys.
+
+If the JSON document is an object with a single key then that key is used as
+the root element, otherwise the document is wrapped within a root element
+` + "`doc`" + `. Namespaces can be expressed with prefixed keys such as
+` + "`soap:Envelope`" + ` and declared with attributes such as
+` + "`-xmlns:soap`" + `. For more control over the resulting document, such as
+setting the root element explicitly, use the
+[` + "`format_xml`" + ` Bloblang method](/docs/guides/bloblang/methods#format_xml)
+within a [` + "`bloblang`" + ` processor](/docs/components/processors/bloblang).
+
+For example, given the following JSON:
+
+` + "```json" + `
+{
+  "root":{
+    "-id":"1",
+    "title":"This is a title",
+    "content":{"#cdata":"<p>This is some content</p>"}
+  }
+}
+` + "```" + `
+
+The resulting XML document would look like this:
+
+` + "```xml" + `
+<root id="1"><content><![CDATA[<p>This is some content</p>]]></content><title>This is a title</title></root>
 ` + "```" + ``,
 		FieldSpecs: docs.FieldSpecs{
-			docs.FieldCommon("operator", "An XML [operation](#operators) to apply to messages.").HasOptions("to_json"),
+			docs.FieldCommon("operator", "An XML [operation](#operators) to apply to messages.").HasOptions("to_json", "from_json"),
 			partsFieldSpec,
 		},
 	}
@@ -94,7 +133,8 @@ func NewXMLConfig() XMLConfig {
 
 // XML is a processor that performs an operation on a XML payload.
 type XML struct {
-	parts []int
+	parts    []int
+	operator func(part types.Part) error
 
 	conf  Config
 	log   log.Modular
@@ -110,10 +150,6 @@ type XML struct {
 func NewXML(
 	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
 ) (Type, error) {
-	if conf.XML.Operator != "to_json" {
-		return nil, fmt.Errorf("operator not recognised: %v", conf.XML.Operator)
-	}
-
 	j := &XML{
 		parts: conf.XML.Parts,
 		conf:  conf,
@@ -125,11 +161,50 @@ func NewXML(
 		mSent:      stats.GetCounter("sent"),
 		mBatchSent: stats.GetCounter("batch.sent"),
 	}
+
+	switch conf.XML.Operator {
+	case "to_json":
+		j.operator = j.toJSON
+	case "from_json":
+		j.operator = j.fromJSON
+	default:
+		return nil, fmt.Errorf("operator not recognised: %v", conf.XML.Operator)
+	}
 	return j, nil
 }
 
 //------------------------------------------------------------------------------
 
+func (p *XML) toJSON(part types.Part) error {
+	root, err := xml.ToMap(part.Get())
+	if err != nil {
+		p.log.Debugf("Failed to parse part as XML: %v\n", err)
+		return err
+	}
+	if err = part.SetJSON(root); err != nil {
+		p.log.Debugf("Failed to marshal XML as JSON: %v\n", err)
+		return err
+	}
+	return nil
+}
+
+func (p *XML) fromJSON(part types.Part) error {
+	root, err := part.JSON()
+	if err != nil {
+		p.log.Debugf("Failed to parse part as JSON: %v\n", err)
+		return err
+	}
+	xmlBytes, err := xml.FromMap(root, "", "")
+	if err != nil {
+		p.log.Debugf("Failed to marshal JSON as XML: %v\n", err)
+		return err
+	}
+	part.Set(xmlBytes)
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
 // ProcessMessage applies the processor to a message, either creating >0
 // resulting messages or a response to be sent back to the message source.
 func (p *XML) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
@@ -137,15 +212,8 @@ func (p *XML) ProcessMessage(msg types.Message) ([]types.Message, types.Response
 	newMsg := msg.Copy()
 
 	proc := func(index int, span opentracing.Span, part types.Part) error {
-		root, err := xml.ToMap(part.Get())
-		if err != nil {
-			p.mErr.Incr(1)
-			p.log.Debugf("Failed to parse part as XML: %v\n", err)
-			return err
-		}
-		if err = part.SetJSON(root); err != nil {
+		if err := p.operator(part); err != nil {
 			p.mErr.Incr(1)
-			p.log.Debugf("Failed to marshal XML as JSON: %v\n", err)
 			return err
 		}
 		return nil
@@ -107,3 +107,94 @@ func TestXMLCases(t *testing.T) {
 		})
 	}
 }
+
+func TestXMLFromJSONCases(t *testing.T) {
+	type testCase struct {
+		name   string
+		input  string
+		output string
+	}
+	tests := []testCase{
+		{
+			name:   "basic 1",
+			input:  `{"root":{"next":"foo1"}}`,
+			output: `<root><next>foo1</next></root>`,
+		},
+		{
+			name:   "contains escapes 1",
+			input:  `{"root":{"next":"foo&bar"}}`,
+			output: `<root><next>foo&amp;bar</next></root>`,
+		},
+		{
+			name:   "with array 1",
+			input:  `{"root":{"next":["foo1","foo2","foo3"]}}`,
+			output: `<root><next>foo1</next><next>foo2</next><next>foo3</next></root>`,
+		},
+		{
+			name:   "with attributes 1",
+			input:  `{"root":{"-isRooted":"true","inner":{"thing":{"#text":"10","-someAttr":"is boring"}},"next":{"#text":"foo1","-withinRoot":"yes"}}}`,
+			output: `<root isRooted="true"><inner><thing someAttr="is boring">10</thing></inner><next withinRoot="yes">foo1</next></root>`,
+		},
+		{
+			name:   "with cdata 1",
+			input:  `{"root":{"content":{"#cdata":"<p>foo</p>"}}}`,
+			output: `<root><content><![CDATA[<p>foo</p>]]></content></root>`,
+		},
+		{
+			name:   "multiple root keys",
+			input:  `{"title":"foo","count":10}`,
+			output: `<doc><count>10</count><title>foo</title></doc>`,
+		},
+	}
+
+	conf := NewConfig()
+	conf.XML.Operator = "from_json"
+	proc, err := NewXML(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(tt *testing.T) {
+			msgsOut, res := proc.ProcessMessage(message.New([][]byte{[]byte(test.input)}))
+			if res != nil {
+				tt.Fatal(res.Error())
+			}
+			if len(msgsOut) != 1 {
+				tt.Fatalf("Wrong count of result messages: %v != 1", len(msgsOut))
+			}
+			if exp, act := test.output, string(msgsOut[0].Get(0).Get()); exp != act {
+				tt.Errorf("Wrong result: %v != %v", act, exp)
+			}
+			if errStr := GetFail(msgsOut[0].Get(0)); len(errStr) > 0 {
+				tt.Error(errStr)
+			}
+		})
+	}
+}
+
+func TestXMLFromJSONErrors(t *testing.T) {
+	conf := NewConfig()
+	conf.XML.Operator = "from_json"
+	proc, err := NewXML(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, input := range []string{`not json`, `"a string"`, `{"root":{"-id":[1,2]}}`} {
+		msgsOut, res := proc.ProcessMessage(message.New([][]byte{[]byte(input)}))
+		if res != nil {
+			t.Fatal(res.Error())
+		}
+		if exp, act := input, string(msgsOut[0].Get(0).Get()); exp != act {
+			t.Errorf("Wrong result: %v != %v", act, exp)
+		}
+		if !HasFailed(msgsOut[0].Get(0)) {
+			t.Errorf("Expected part to be flagged as failed for input: %v", input)
+		}
+	}
+
+	conf.XML.Operator = "nope"
+	if _, err = NewXML(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from unrecognised operator")
+	}
+}
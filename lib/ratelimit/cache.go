@@ -0,0 +1,254 @@
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeCache] = TypeSpec{
+		constructor: NewCache,
+		Status:      docs.StatusExperimental,
+		Summary: `
+A rate limit that coordinates a shared budget across any number of Benthos
+instances by storing counters within a [cache resource](/docs/components/caches/about).`,
+		Description: `
+Time is divided into windows of the configured ` + "`interval`" + `, and each
+access claims a slot within the current window by adding a key to the cache
+that must not already exist. Once all ` + "`count`" + ` slots of a window are
+claimed, by any instance, further accesses are rejected until the next window.
+This relies on the cache supporting atomic add operations, which is the case
+for caches such as ` + "`redis`" + ` and ` + "`memcached`" + `.
+
+Windows are derived from the wall clock, and therefore instances sharing a
+rate limit should have reasonably synchronised clocks.
+
+### Algorithms
+
+- ` + "`fixed_window`" + `: Allows up to ` + "`count`" + ` accesses within each window, which can result in bursts of up to twice the count across a window boundary.
+- ` + "`sliding_window`" + `: Approximates a sliding window by weighting the number of accesses of the previous window by how much of it overlaps the last interval, which smooths out bursts at window boundaries.
+
+### Key Expiry
+
+If the cache supports TTLs then keys are added with a TTL of twice the
+interval. Otherwise keys are not expired by the rate limit, and the cache should
+be configured with a default TTL of its own.`,
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("resource", "The [`cache` resource](/docs/components/caches/about) to store counters within."),
+			docs.FieldCommon("count", "The maximum number of requests to allow for a given period of time."),
+			docs.FieldCommon("interval", "The time window to limit requests by."),
+			docs.FieldCommon("algorithm", "The algorithm used to count requests within the time window.").HasOptions(
+				"fixed_window", "sliding_window",
+			),
+			docs.FieldAdvanced("key_prefix", "A prefix added to all keys written to the cache, which allows multiple rate limits to share the same cache."),
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// CacheConfig is a config struct containing rate limit fields for a cache
+// backed rate limit.
+type CacheConfig struct {
+	Resource  string `json:"resource" yaml:"resource"`
+	Count     int    `json:"count" yaml:"count"`
+	Interval  string `json:"interval" yaml:"interval"`
+	Algorithm string `json:"algorithm" yaml:"algorithm"`
+	KeyPrefix string `json:"key_prefix" yaml:"key_prefix"`
+}
+
+// NewCacheConfig returns a cache rate limit configuration struct with default
+// values.
+func NewCacheConfig() CacheConfig {
+	return CacheConfig{
+		Resource:  "",
+		Count:     1000,
+		Interval:  "1s",
+		Algorithm: "fixed_window",
+		KeyPrefix: "benthos_rate_limit",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Cache is a rate limit that tracks accesses within a cache resource, allowing
+// it to be shared across multiple running instances of Benthos.
+type Cache struct {
+	cache    types.Cache
+	ttlCache types.CacheWithTTL
+
+	size    int
+	period  time.Duration
+	sliding bool
+	prefix  string
+
+	mut       sync.Mutex
+	window    time.Time
+	nextSlot  int
+	prevCount int
+
+	nowFn func() time.Time
+
+	mLimited metrics.StatCounter
+	mErr     metrics.StatCounter
+}
+
+// NewCache creates a cache backed rate limit from a configuration struct. This
+// type is safe to share and call from parallel goroutines.
+func NewCache(
+	conf Config,
+	mgr types.Manager,
+	logger log.Modular,
+	stats metrics.Type,
+) (types.RateLimit, error) {
+	if conf.Cache.Count <= 0 {
+		return nil, errors.New("count must be larger than zero")
+	}
+	period, err := time.ParseDuration(conf.Cache.Interval)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse interval: %v", err)
+	}
+	if period <= 0 {
+		return nil, errors.New("interval must be larger than zero")
+	}
+
+	var sliding bool
+	switch conf.Cache.Algorithm {
+	case "fixed_window":
+	case "sliding_window":
+		sliding = true
+	default:
+		return nil, fmt.Errorf("algorithm not recognised: %v", conf.Cache.Algorithm)
+	}
+
+	c, err := mgr.GetCache(conf.Cache.Resource)
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain cache resource '%v': %v", conf.Cache.Resource, err)
+	}
+
+	r := &Cache{
+		cache:    c,
+		size:     conf.Cache.Count,
+		period:   period,
+		sliding:  sliding,
+		prefix:   conf.Cache.KeyPrefix,
+		nowFn:    time.Now,
+		mLimited: stats.GetCounter("limited"),
+		mErr:     stats.GetCounter("error"),
+	}
+	if ttlCache, ok := c.(types.CacheWithTTL); ok {
+		r.ttlCache = ttlCache
+	}
+	return r, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (r *Cache) slotKey(window time.Time, slot int) string {
+	return fmt.Sprintf("%v:%v:%v", r.prefix, window.UnixNano(), slot)
+}
+
+func (r *Cache) claimSlot(window time.Time, slot int) error {
+	key := r.slotKey(window, slot)
+	if r.ttlCache != nil {
+		ttl := r.period * 2
+		return r.ttlCache.AddWithTTL(key, []byte("1"), &ttl)
+	}
+	return r.cache.Add(key, []byte("1"))
+}
+
+// countSlots returns the number of slots claimed within a window. Slots are
+// always claimed in ascending order, and so this is done with a binary search
+// for the first slot that doesn't exist.
+func (r *Cache) countSlots(window time.Time) (int, error) {
+	low, high := 0, r.size
+	for low < high {
+		mid := (low + high) / 2
+		_, err := r.cache.Get(r.slotKey(window, mid))
+		if err == nil {
+			low = mid + 1
+		} else if err == types.ErrKeyNotFound {
+			high = mid
+		} else {
+			return 0, err
+		}
+	}
+	return low, nil
+}
+
+// Access the rate limited resource. Returns a duration or an error if the rate
+// limit check fails. The returned duration is either zero (meaning the resource
+// can be accessed) or a reasonable length of time to wait before requesting
+// again.
+func (r *Cache) Access() (time.Duration, error) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	now := r.nowFn()
+	window := now.Truncate(r.period)
+	if !window.Equal(r.window) {
+		prevCount := 0
+		if r.sliding {
+			var err error
+			if prevCount, err = r.countSlots(window.Add(-r.period)); err != nil {
+				r.mErr.Incr(1)
+				return 0, fmt.Errorf("failed to count previous window: %v", err)
+			}
+		}
+		r.window = window
+		r.nextSlot = 0
+		r.prevCount = prevCount
+	}
+
+	allowed := r.size
+	if r.sliding && r.prevCount > 0 {
+		overlap := 1 - float64(now.Sub(window))/float64(r.period)
+		allowed -= int(float64(r.prevCount) * overlap)
+	}
+
+	// Each slot we find to be taken was claimed by another accessor, and so
+	// there's no need to try it again within this window.
+	for r.nextSlot < allowed {
+		err := r.claimSlot(window, r.nextSlot)
+		if err == types.ErrKeyAlreadyExists {
+			r.nextSlot++
+			continue
+		}
+		if err != nil {
+			r.mErr.Incr(1)
+			return 0, err
+		}
+		r.nextSlot++
+		return 0, nil
+	}
+
+	r.mLimited.Incr(1)
+	remaining := window.Add(r.period).Sub(now)
+	if allowed < r.size {
+		// The allowance grows as the previous window slides out of view.
+		if step := r.period / time.Duration(r.prevCount); step < remaining {
+			remaining = step
+		}
+	}
+	return remaining, nil
+}
+
+// CloseAsync shuts down the rate limit.
+func (r *Cache) CloseAsync() {
+}
+
+// WaitForClose blocks until the rate limit has closed down.
+func (r *Cache) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
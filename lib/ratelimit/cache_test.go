@@ -0,0 +1,192 @@
+package ratelimit
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/cache"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//------------------------------------------------------------------------------
+
+type fakeMgr struct {
+	caches map[string]types.Cache
+}
+
+func (f *fakeMgr) RegisterEndpoint(path, desc string, h http.HandlerFunc) {
+}
+func (f *fakeMgr) GetCache(name string) (types.Cache, error) {
+	if c, exists := f.caches[name]; exists {
+		return c, nil
+	}
+	return nil, types.ErrCacheNotFound
+}
+func (f *fakeMgr) GetCondition(name string) (types.Condition, error) {
+	return nil, types.ErrConditionNotFound
+}
+func (f *fakeMgr) GetRateLimit(name string) (types.RateLimit, error) {
+	return nil, types.ErrRateLimitNotFound
+}
+func (f *fakeMgr) GetPlugin(name string) (interface{}, error) {
+	return nil, types.ErrPluginNotFound
+}
+func (f *fakeMgr) GetPipe(name string) (<-chan types.Transaction, error) {
+	return nil, types.ErrPipeNotFound
+}
+func (f *fakeMgr) SetPipe(name string, prod <-chan types.Transaction)   {}
+func (f *fakeMgr) UnsetPipe(name string, prod <-chan types.Transaction) {}
+
+func newMemCacheMgr(t *testing.T) *fakeMgr {
+	t.Helper()
+
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	return &fakeMgr{
+		caches: map[string]types.Cache{
+			"foo": memCache,
+		},
+	}
+}
+
+func newTestCacheRateLimit(t *testing.T, mgr types.Manager, algorithm string, now *time.Time) *Cache {
+	t.Helper()
+
+	conf := NewConfig()
+	conf.Type = TypeCache
+	conf.Cache.Resource = "foo"
+	conf.Cache.Count = 10
+	conf.Cache.Interval = "1s"
+	conf.Cache.Algorithm = algorithm
+
+	rl, err := New(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	crl := rl.(*Cache)
+	crl.nowFn = func() time.Time {
+		return *now
+	}
+	return crl
+}
+
+//------------------------------------------------------------------------------
+
+func TestCacheRateLimitConfErrors(t *testing.T) {
+	mgr := newMemCacheMgr(t)
+
+	conf := NewConfig()
+	conf.Type = TypeCache
+	conf.Cache.Resource = "foo"
+	conf.Cache.Count = -1
+	_, err := New(conf, mgr, log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+
+	conf = NewConfig()
+	conf.Type = TypeCache
+	conf.Cache.Resource = "foo"
+	conf.Cache.Interval = "nope"
+	_, err = New(conf, mgr, log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+
+	conf = NewConfig()
+	conf.Type = TypeCache
+	conf.Cache.Resource = "foo"
+	conf.Cache.Algorithm = "nope"
+	_, err = New(conf, mgr, log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+
+	conf = NewConfig()
+	conf.Type = TypeCache
+	conf.Cache.Resource = "bar"
+	_, err = New(conf, mgr, log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+}
+
+func TestCacheRateLimitFixedWindow(t *testing.T) {
+	mgr := newMemCacheMgr(t)
+
+	now := time.Unix(1000, 0)
+	rlOne := newTestCacheRateLimit(t, mgr, "fixed_window", &now)
+	rlTwo := newTestCacheRateLimit(t, mgr, "fixed_window", &now)
+
+	// Both rate limits share the same budget.
+	for i := 0; i < 5; i++ {
+		period, err := rlOne.Access()
+		require.NoError(t, err)
+		assert.Equal(t, time.Duration(0), period)
+
+		period, err = rlTwo.Access()
+		require.NoError(t, err)
+		assert.Equal(t, time.Duration(0), period)
+	}
+
+	now = now.Add(time.Millisecond * 300)
+
+	period, err := rlOne.Access()
+	require.NoError(t, err)
+	assert.Equal(t, time.Millisecond*700, period)
+
+	period, err = rlTwo.Access()
+	require.NoError(t, err)
+	assert.Equal(t, time.Millisecond*700, period)
+
+	now = now.Add(time.Millisecond * 700)
+
+	for i := 0; i < 10; i++ {
+		period, err := rlTwo.Access()
+		require.NoError(t, err)
+		assert.Equal(t, time.Duration(0), period)
+	}
+
+	period, err = rlOne.Access()
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, period)
+}
+
+func TestCacheRateLimitSlidingWindow(t *testing.T) {
+	mgr := newMemCacheMgr(t)
+
+	now := time.Unix(1000, 0)
+	rlOne := newTestCacheRateLimit(t, mgr, "sliding_window", &now)
+	rlTwo := newTestCacheRateLimit(t, mgr, "sliding_window", &now)
+
+	for i := 0; i < 10; i++ {
+		period, err := rlOne.Access()
+		require.NoError(t, err)
+		assert.Equal(t, time.Duration(0), period)
+	}
+
+	// A quarter of the way into the next window three quarters of the previous
+	// window are still in view, and so only a quarter of the budget remains.
+	now = now.Add(time.Millisecond * 1250)
+
+	for i := 0; i < 3; i++ {
+		period, err := rlTwo.Access()
+		require.NoError(t, err)
+		assert.Equal(t, time.Duration(0), period)
+	}
+
+	period, err := rlOne.Access()
+	require.NoError(t, err)
+	assert.Equal(t, time.Millisecond*100, period)
+
+	now = now.Add(time.Millisecond * 500)
+
+	for i := 0; i < 5; i++ {
+		period, err := rlOne.Access()
+		require.NoError(t, err)
+		assert.Equal(t, time.Duration(0), period)
+	}
+
+	period, err = rlTwo.Access()
+	require.NoError(t, err)
+	assert.Equal(t, time.Millisecond*100, period)
+}
+
+//------------------------------------------------------------------------------
@@ -37,6 +37,7 @@ var Constructors = map[string]TypeSpec{}
 
 // String constants representing each ratelimit type.
 const (
+	TypeCache = "cache"
 	TypeLocal = "local"
 )
 
@@ -45,6 +46,7 @@ const (
 // Config is the all encompassing configuration struct for all cache types.
 type Config struct {
 	Type   string      `json:"type" yaml:"type"`
+	Cache  CacheConfig `json:"cache" yaml:"cache"`
 	Local  LocalConfig `json:"local" yaml:"local"`
 	Plugin interface{} `json:"plugin,omitempty" yaml:"plugin,omitempty"`
 }
@@ -53,6 +55,7 @@ type Config struct {
 func NewConfig() Config {
 	return Config{
 		Type:   "local",
+		Cache:  NewCacheConfig(),
 		Local:  NewLocalConfig(),
 		Plugin: nil,
 	}
@@ -30,6 +30,10 @@ func CliCommand() *cli.Command {
 
    echo '{"foo":"bar"}' | benthos blobl -f ./mapping.blobl
 
+   Mappings can also be written interactively within a web page with:
+
+   benthos blobl server
+
    Find out more about Bloblang at: https://benthos.dev/docs/guides/bloblang/about`[4:],
 		Flags: []cli.Flag{
 			&cli.IntFlag{
@@ -55,6 +59,9 @@ func CliCommand() *cli.Command {
 			},
 		},
 		Action: run,
+		Subcommands: []*cli.Command{
+			serverCliCommand(),
+		},
 	}
 }
 
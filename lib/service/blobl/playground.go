@@ -0,0 +1,217 @@
+package blobl
+
+// playgroundPage is the template of the single page playground app, which is
+// executed with the initial playgroundState of the editors.
+const playgroundPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Bloblang Playground</title>
+<style>
+  * { box-sizing: border-box; }
+  html, body { height: 100%; margin: 0; }
+  body {
+    display: flex;
+    flex-direction: column;
+    background-color: #1e1e1e;
+    color: #d4d4d4;
+    font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, sans-serif;
+  }
+  header {
+    padding: 0.5rem 1rem;
+    background-color: #252526;
+    border-bottom: 1px solid #333;
+  }
+  header h1 { margin: 0; font-size: 1.1rem; font-weight: normal; }
+  header a { color: #9cdcfe; }
+  main {
+    flex: 1;
+    display: grid;
+    grid-template-columns: 1fr 1fr;
+    grid-template-rows: 2fr 1fr 2fr;
+    gap: 1px;
+    background-color: #333;
+    min-height: 0;
+  }
+  section {
+    display: flex;
+    flex-direction: column;
+    background-color: #1e1e1e;
+    min-height: 0;
+  }
+  section h2 {
+    margin: 0;
+    padding: 0.3rem 0.6rem;
+    font-size: 0.8rem;
+    font-weight: normal;
+    text-transform: uppercase;
+    color: #858585;
+  }
+  #mapping-section { grid-column: 2; grid-row: 1 / 4; }
+  .editor { flex: 1; display: flex; min-height: 0; }
+  .gutter {
+    padding: 0.5rem 0.4rem;
+    overflow: hidden;
+    text-align: right;
+    color: #858585;
+    background-color: #1e1e1e;
+    user-select: none;
+  }
+  .gutter .error { color: #f48771; font-weight: bold; }
+  textarea, pre {
+    flex: 1;
+    margin: 0;
+    padding: 0.5rem;
+    border: none;
+    resize: none;
+    outline: none;
+    overflow: auto;
+    background-color: #1e1e1e;
+    color: inherit;
+  }
+  textarea, pre, .gutter {
+    font-family: Menlo, Consolas, "DejaVu Sans Mono", monospace;
+    font-size: 0.85rem;
+    line-height: 1.3rem;
+    white-space: pre;
+  }
+  pre.error { color: #f48771; white-space: pre-wrap; }
+  pre.deleted { color: #858585; font-style: italic; }
+</style>
+</head>
+<body>
+<header>
+  <h1>Bloblang Playground &middot; <a href="https://benthos.dev/docs/guides/bloblang/about" target="_blank">docs</a></h1>
+</header>
+<main>
+  <section>
+    <h2>Input</h2>
+    <div class="editor"><textarea id="input" spellcheck="false"></textarea></div>
+  </section>
+  <section id="mapping-section">
+    <h2>Mapping</h2>
+    <div class="editor">
+      <div class="gutter" id="mapping-gutter"></div>
+      <textarea id="mapping" spellcheck="false"></textarea>
+    </div>
+  </section>
+  <section>
+    <h2>Input Metadata</h2>
+    <div class="editor"><textarea id="metadata" spellcheck="false"></textarea></div>
+  </section>
+  <section>
+    <h2>Output</h2>
+    <div class="editor"><pre id="output"></pre></div>
+  </section>
+</main>
+<script>
+(function() {
+  const initial = {{.}};
+
+  const input = document.getElementById("input");
+  const metadata = document.getElementById("metadata");
+  const mapping = document.getElementById("mapping");
+  const gutter = document.getElementById("mapping-gutter");
+  const output = document.getElementById("output");
+
+  input.value = initial.input;
+  metadata.value = initial.metadata;
+  mapping.value = initial.mapping;
+
+  let errorLine = 0;
+
+  function renderGutter() {
+    const lines = mapping.value.split("\n").length;
+    gutter.innerHTML = "";
+    for (let i = 1; i <= lines; i++) {
+      const line = document.createElement("div");
+      line.textContent = i;
+      if (i === errorLine) {
+        line.className = "error";
+      }
+      gutter.appendChild(line);
+    }
+    gutter.scrollTop = mapping.scrollTop;
+  }
+
+  function renderResult(res) {
+    errorLine = 0;
+    output.className = "";
+    if (res.parse_error) {
+      errorLine = res.parse_error.line;
+      output.className = "error";
+      output.textContent = "failed to parse mapping: " + res.parse_error.message;
+    } else if (res.mapping_error) {
+      output.className = "error";
+      output.textContent = res.mapping_error;
+    } else if (res.deleted) {
+      output.className = "deleted";
+      output.textContent = "message deleted";
+    } else {
+      let result = res.result;
+      try {
+        result = JSON.stringify(JSON.parse(result), null, 2);
+      } catch (e) {}
+      const meta = res.result_metadata || {};
+      const metaKeys = Object.keys(meta).sort();
+      if (metaKeys.length > 0) {
+        result += "\n\n# Metadata\n";
+        metaKeys.forEach(function(k) {
+          result += k + ": " + meta[k] + "\n";
+        });
+      }
+      output.textContent = result;
+    }
+    renderGutter();
+  }
+
+  let pending = null;
+  function execute() {
+    if (pending !== null) {
+      clearTimeout(pending);
+    }
+    pending = setTimeout(function() {
+      pending = null;
+      fetch("/execute", {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({
+          input: input.value,
+          metadata: metadata.value,
+          mapping: mapping.value,
+        }),
+      }).then(function(res) {
+        if (!res.ok) {
+          return res.text().then(function(text) { throw new Error(text); });
+        }
+        return res.json();
+      }).then(renderResult).catch(function(err) {
+        output.className = "error";
+        output.textContent = "failed to execute mapping: " + err.message;
+      });
+    }, 200);
+  }
+
+  [input, metadata, mapping].forEach(function(el) {
+    el.addEventListener("input", execute);
+  });
+  mapping.addEventListener("input", renderGutter);
+  mapping.addEventListener("scroll", function() {
+    gutter.scrollTop = mapping.scrollTop;
+  });
+  mapping.addEventListener("keydown", function(e) {
+    if (e.key === "Tab") {
+      e.preventDefault();
+      const start = mapping.selectionStart;
+      mapping.setRangeText("  ", start, mapping.selectionEnd, "end");
+      execute();
+    }
+  });
+
+  renderGutter();
+  execute();
+})();
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,345 @@
+package blobl
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/parser"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/urfave/cli/v2"
+)
+
+func serverCliCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "server",
+		Usage: "Run a web server that hosts a Bloblang playground",
+		Description: `
+   Serves a web page where an input document, its metadata and a Bloblang
+   mapping can be edited, with the result of the mapping updated live:
+
+   benthos blobl server --mapping-file ./mapping.blobl --write`[4:],
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "host",
+				Value: "localhost",
+				Usage: "the host to bind to.",
+			},
+			&cli.StringFlag{
+				Name:    "port",
+				Aliases: []string{"p"},
+				Value:   "4195",
+				Usage:   "the port to bind to.",
+			},
+			&cli.BoolFlag{
+				Name:    "no-open",
+				Aliases: []string{"n"},
+				Usage:   "do not open the app in the browser automatically.",
+			},
+			&cli.StringFlag{
+				Name:    "mapping-file",
+				Aliases: []string{"m"},
+				Usage:   "an optional path to a mapping file to load as the initial mapping within the app.",
+			},
+			&cli.StringFlag{
+				Name:    "input-file",
+				Aliases: []string{"i"},
+				Usage:   "an optional path to an input file to load as the initial input to the mapping within the app.",
+			},
+			&cli.BoolFlag{
+				Name:    "write",
+				Aliases: []string{"w"},
+				Usage:   "when editing a mapping loaded from a file, write changes made to the mapping back to the file.",
+			},
+		},
+		Action: runServer,
+	}
+}
+
+func openBrowserAt(url string) {
+	switch runtime.GOOS {
+	case "linux":
+		_ = exec.Command("xdg-open", url).Start()
+	case "windows":
+		_ = exec.Command("rundll32", "url.dll,FileProtocolHandler", url).Start()
+	case "darwin":
+		_ = exec.Command("open", url).Start()
+	}
+}
+
+func runServer(c *cli.Context) error {
+	mappingFile := c.String("mapping-file")
+	if c.Bool("write") && len(mappingFile) == 0 {
+		fmt.Fprintln(os.Stderr, red("invalid flags, the write flag requires a mapping file"))
+		os.Exit(1)
+	}
+
+	initial := playgroundState{
+		Input:    `{"message":"hello world"}`,
+		Metadata: `{}`,
+		Mapping:  "root = this\nroot.message = this.message.uppercase()",
+	}
+	if len(mappingFile) > 0 {
+		mappingBytes, err := ioutil.ReadFile(mappingFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, red("failed to read mapping file: %v\n"), err)
+			os.Exit(1)
+		}
+		initial.Mapping = string(mappingBytes)
+	}
+	if inputFile := c.String("input-file"); len(inputFile) > 0 {
+		inputBytes, err := ioutil.ReadFile(inputFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, red("failed to read input file: %v\n"), err)
+			os.Exit(1)
+		}
+		initial.Input = string(inputBytes)
+	}
+
+	srv := newPlaygroundServer(c.String("host"), initial)
+	if c.Bool("write") {
+		srv.writeMapping = func(mapping string) error {
+			return ioutil.WriteFile(mappingFile, []byte(mapping), 0644)
+		}
+	}
+
+	url := "http://" + net.JoinHostPort(c.String("host"), c.String("port"))
+	fmt.Printf("Serving Bloblang playground at: %v\n", url)
+	if !c.Bool("no-open") {
+		openBrowserAt(url)
+	}
+
+	if err := http.ListenAndServe(net.JoinHostPort(c.String("host"), c.String("port")), srv.handler()); err != nil {
+		fmt.Fprintf(os.Stderr, red("failed to run server: %v\n"), err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// playgroundState is the content of the editors of the playground.
+type playgroundState struct {
+	Input    string `json:"input"`
+	Metadata string `json:"metadata"`
+	Mapping  string `json:"mapping"`
+}
+
+// playgroundParseError is a mapping parser error along with its position.
+type playgroundParseError struct {
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
+}
+
+// playgroundResult is the result of executing a mapping.
+type playgroundResult struct {
+	Result         *string               `json:"result,omitempty"`
+	ResultMetadata map[string]string     `json:"result_metadata,omitempty"`
+	Deleted        bool                  `json:"deleted,omitempty"`
+	ParseError     *playgroundParseError `json:"parse_error,omitempty"`
+	MappingError   string                `json:"mapping_error,omitempty"`
+}
+
+type playgroundServer struct {
+	host    string
+	initial playgroundState
+	page    *template.Template
+
+	writeMapping func(mapping string) error
+	lastWritten  string
+	writeMut     sync.Mutex
+}
+
+func newPlaygroundServer(host string, initial playgroundState) *playgroundServer {
+	return &playgroundServer{
+		host:        host,
+		initial:     initial,
+		page:        template.Must(template.New("playground").Parse(playgroundPage)),
+		lastWritten: initial.Mapping,
+	}
+}
+
+func (p *playgroundServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", p.handlePage)
+	mux.HandleFunc("/execute", p.handleExecute)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !p.allowedHost(r) {
+			http.Error(w, "host not allowed", http.StatusForbidden)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// allowedHost returns whether a request addresses the server by an IP
+// address, a loopback name or the host it was bound to. Other names may have
+// been rebound by an attacker to resolve to the server, in which case the
+// browser considers their pages to be of the same origin as the playground.
+func (p *playgroundServer) allowedHost(r *http.Request) bool {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.Trim(host, "[]"), ".")
+	if net.ParseIP(host) != nil {
+		return true
+	}
+	host = strings.ToLower(host)
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	return host != "" && strings.EqualFold(host, p.host)
+}
+
+func (p *playgroundServer) handlePage(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := p.page.Execute(w, p.initial); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (p *playgroundServer) handleExecute(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Executing a mapping may write it to disk, and therefore requests are
+	// restricted to those that a cross-origin page cannot make without a CORS
+	// preflight, which this server never permits.
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+		http.Error(w, "content type must be application/json", http.StatusUnsupportedMediaType)
+		return
+	}
+	if !sameOrigin(r) {
+		http.Error(w, "cross-origin requests are not allowed", http.StatusForbidden)
+		return
+	}
+
+	var state playgroundState
+	if err := json.NewDecoder(r.Body).Decode(&state); err != nil {
+		http.Error(w, fmt.Sprintf("failed to parse request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	res := execute(state)
+	if res.ParseError == nil {
+		p.write(state.Mapping)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+// sameOrigin returns whether the origin of a request, when specified, matches
+// the host that it was sent to.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return u.Host == r.Host
+}
+
+// write stores a mapping that parsed successfully to the mapping file when
+// enabled and the mapping has changed.
+func (p *playgroundServer) write(mapping string) {
+	if p.writeMapping == nil {
+		return
+	}
+
+	p.writeMut.Lock()
+	defer p.writeMut.Unlock()
+
+	if mapping == p.lastWritten {
+		return
+	}
+	if err := p.writeMapping(mapping); err != nil {
+		fmt.Fprintf(os.Stderr, red("failed to write mapping file: %v\n"), err)
+		return
+	}
+	p.lastWritten = mapping
+}
+
+// execute parses and executes the mapping of a playground state against its
+// input document and metadata.
+func execute(state playgroundState) playgroundResult {
+	exec, err := bloblang.NewMapping("", state.Mapping)
+	if err != nil {
+		if perr, ok := err.(*parser.Error); ok {
+			line, column := parser.LineAndColOf([]rune(state.Mapping), perr.Input)
+			return playgroundResult{
+				ParseError: &playgroundParseError{
+					Line:    line,
+					Column:  column,
+					Message: perr.ErrorAtPosition([]rune(state.Mapping)),
+				},
+			}
+		}
+		return playgroundResult{
+			ParseError: &playgroundParseError{
+				Line:    1,
+				Column:  1,
+				Message: err.Error(),
+			},
+		}
+	}
+
+	msg := message.New([][]byte{[]byte(state.Input)})
+	if len(state.Metadata) > 0 {
+		var meta map[string]string
+		if err := json.Unmarshal([]byte(state.Metadata), &meta); err != nil {
+			return playgroundResult{
+				MappingError: fmt.Sprintf("failed to parse metadata as a JSON object of strings: %v", err),
+			}
+		}
+		for k, v := range meta {
+			msg.Get(0).Metadata().Set(k, v)
+		}
+	}
+
+	part, err := exec.MapPart(0, msg)
+	if err != nil {
+		return playgroundResult{
+			MappingError: err.Error(),
+		}
+	}
+	if part == nil {
+		return playgroundResult{
+			Deleted: true,
+		}
+	}
+
+	result := string(part.Get())
+	resultMeta := map[string]string{}
+	part.Metadata().Iter(func(k, v string) error {
+		resultMeta[k] = v
+		return nil
+	})
+	return playgroundResult{
+		Result:         &result,
+		ResultMetadata: resultMeta,
+	}
+}
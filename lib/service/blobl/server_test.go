@@ -0,0 +1,249 @@
+package blobl
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func executeRequest(t *testing.T, srv *playgroundServer, state playgroundState) playgroundResult {
+	t.Helper()
+
+	body, err := json.Marshal(state)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest("POST", "/execute", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	rec := httptest.NewRecorder()
+	srv.handler().ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var res playgroundResult
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	return res
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func TestPlaygroundExecute(t *testing.T) {
+	srv := newPlaygroundServer("example.com", playgroundState{})
+
+	tests := map[string]struct {
+		state    playgroundState
+		expected playgroundResult
+	}{
+		"json result": {
+			state: playgroundState{
+				Input:   `{"foo":"bar"}`,
+				Mapping: `root.foo = this.foo.uppercase()`,
+			},
+			expected: playgroundResult{
+				Result: strPtr(`{"foo":"BAR"}`),
+			},
+		},
+		"metadata": {
+			state: playgroundState{
+				Input:    `hello`,
+				Metadata: `{"topic":"foo"}`,
+				Mapping:  "root = content().string() + \" \" + meta(\"topic\")\nmeta bar = \"baz\"",
+			},
+			expected: playgroundResult{
+				Result: strPtr(`hello foo`),
+				ResultMetadata: map[string]string{
+					"topic": "foo",
+					"bar":   "baz",
+				},
+			},
+		},
+		"deleted": {
+			state: playgroundState{
+				Input:   `{}`,
+				Mapping: `root = deleted()`,
+			},
+			expected: playgroundResult{
+				Deleted: true,
+			},
+		},
+		"parse error": {
+			state: playgroundState{
+				Input:   `{}`,
+				Mapping: "root.foo = this.foo\nroot.bar = this.bar.(",
+			},
+			expected: playgroundResult{
+				ParseError: &playgroundParseError{
+					Line:    2,
+					Column:  22,
+					Message: "line 2 char 22: required: expected query",
+				},
+			},
+		},
+		"mapping error": {
+			state: playgroundState{
+				Input:   `not json`,
+				Mapping: `root.foo = this.foo`,
+			},
+			expected: playgroundResult{
+				MappingError: "failed to execute mapping query at line 1: failed to parse message as JSON: invalid character 'o' in literal null (expecting 'u')",
+			},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expected, executeRequest(t, srv, test.state))
+		})
+	}
+
+	res := executeRequest(t, srv, playgroundState{
+		Input:    `{}`,
+		Metadata: `{"foo":5}`,
+		Mapping:  `root = this`,
+	})
+	assert.Contains(t, res.MappingError, "failed to parse metadata as a JSON object of strings")
+}
+
+func TestPlaygroundWriteMapping(t *testing.T) {
+	srv := newPlaygroundServer("example.com", playgroundState{Mapping: "root = this"})
+
+	var written []string
+	srv.writeMapping = func(mapping string) error {
+		written = append(written, mapping)
+		return nil
+	}
+
+	for _, m := range []string{
+		"root = this",
+		"root = this.foo",
+		"root = this.(",
+		"root = this.foo",
+		"root = this.bar",
+	} {
+		executeRequest(t, srv, playgroundState{Input: `{}`, Mapping: m})
+	}
+
+	// Only mappings that have changed and are valid are written.
+	assert.Equal(t, []string{"root = this.foo", "root = this.bar"}, written)
+}
+
+func TestPlaygroundPage(t *testing.T) {
+	srv := newPlaygroundServer("example.com", playgroundState{
+		Input:   `{"foo":"</script>"}`,
+		Mapping: `root = this`,
+	})
+
+	rec := httptest.NewRecorder()
+	srv.handler().ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+
+	body := rec.Body.String()
+	assert.Contains(t, body, "<title>Bloblang Playground</title>")
+	assert.Contains(t, body, `"mapping":"root = this"`)
+	assert.Equal(t, 1, strings.Count(body, "</script>"), "initial state must be escaped")
+
+	rec = httptest.NewRecorder()
+	srv.handler().ServeHTTP(rec, httptest.NewRequest("GET", "/nope", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	srv.handler().ServeHTTP(rec, httptest.NewRequest("GET", "/execute", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestPlaygroundExecuteCrossOrigin(t *testing.T) {
+	srv := newPlaygroundServer("example.com", playgroundState{Mapping: "root = this"})
+
+	var written []string
+	srv.writeMapping = func(mapping string) error {
+		written = append(written, mapping)
+		return nil
+	}
+
+	body := `{"input":"{}","mapping":"root = this.foo"}`
+
+	tests := []struct {
+		name        string
+		contentType string
+		origin      string
+		code        int
+	}{
+		{name: "text plain", contentType: "text/plain", code: http.StatusUnsupportedMediaType},
+		{name: "form", contentType: "application/x-www-form-urlencoded", code: http.StatusUnsupportedMediaType},
+		{name: "no content type", contentType: "", code: http.StatusUnsupportedMediaType},
+		{name: "other origin", contentType: "application/json", origin: "http://evil.example.com", code: http.StatusForbidden},
+		{name: "other port", contentType: "application/json", origin: "http://example.com:8080", code: http.StatusForbidden},
+		{name: "same origin", contentType: "application/json; charset=utf-8", origin: "http://example.com", code: http.StatusOK},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest("POST", "/execute", strings.NewReader(body))
+		if test.contentType != "" {
+			req.Header.Set("Content-Type", test.contentType)
+		}
+		if test.origin != "" {
+			req.Header.Set("Origin", test.origin)
+		}
+
+		rec := httptest.NewRecorder()
+		srv.handler().ServeHTTP(rec, req)
+		assert.Equal(t, test.code, rec.Code, test.name)
+	}
+
+	// Only the same origin request is executed and written.
+	assert.Equal(t, []string{"root = this.foo"}, written)
+}
+
+func TestPlaygroundHost(t *testing.T) {
+	srv := newPlaygroundServer("example.com", playgroundState{Mapping: "root = this"})
+
+	var written []string
+	srv.writeMapping = func(mapping string) error {
+		written = append(written, mapping)
+		return nil
+	}
+
+	tests := []struct {
+		host string
+		code int
+	}{
+		{host: "example.com", code: http.StatusOK},
+		{host: "EXAMPLE.COM:4195", code: http.StatusOK},
+		{host: "localhost:4195", code: http.StatusOK},
+		{host: "127.0.0.1:4195", code: http.StatusOK},
+		{host: "[::1]:4195", code: http.StatusOK},
+		{host: "192.168.0.10", code: http.StatusOK},
+		{host: "rebind.evil.example.org:4195", code: http.StatusForbidden},
+		{host: "example.com.evil.org", code: http.StatusForbidden},
+	}
+
+	for _, test := range tests {
+		// A page on a rebound domain sends matching Origin and Host headers.
+		req := httptest.NewRequest("POST", "/execute", strings.NewReader(`{"input":"{}","mapping":"root = this.foo"}`))
+		req.Host = test.host
+		req.Header.Set("Origin", "http://"+test.host)
+		req.Header.Set("Content-Type", "application/json")
+
+		rec := httptest.NewRecorder()
+		srv.handler().ServeHTTP(rec, req)
+		assert.Equal(t, test.code, rec.Code, test.host)
+
+		req = httptest.NewRequest("GET", "/", nil)
+		req.Host = test.host
+
+		rec = httptest.NewRecorder()
+		srv.handler().ServeHTTP(rec, req)
+		assert.Equal(t, test.code, rec.Code, test.host)
+	}
+
+	assert.Len(t, written, 1)
+}
@@ -180,6 +180,12 @@ func Run() {
 			Value: false,
 			Usage: "continue to execute a config containing linter errors",
 		},
+		&cli.BoolFlag{
+			Name:    "watch",
+			Aliases: []string{"w"},
+			Value:   false,
+			Usage:   "watch the config and resource files for changes and apply them without restarting, unchanged inputs and outputs remain connected",
+		},
 	}
 	if len(customFlags) > 0 {
 		flags = append(flags, customFlags...)
@@ -194,7 +200,8 @@ func Run() {
    benthos list inputs
    benthos create kafka//file > ./config.yaml
    benthos -c ./config.yaml
-   benthos -r "./production/*.yaml" -c ./config.yaml`[4:],
+   benthos -r "./production/*.yaml" -c ./config.yaml
+   benthos -w -c ./config.yaml`[4:],
 		Flags: flags,
 		Action: func(c *cli.Context) error {
 			if c.Bool("version") {
@@ -210,8 +217,10 @@ func Run() {
 				c.StringSlice("resources"),
 				c.String("log.level"),
 				!c.Bool("chilled"),
+				c.Bool("watch"),
 				false,
 				nil,
+				streamsPersistConfig{},
 			))
 			return nil
 		},
@@ -258,16 +267,39 @@ func Run() {
    pipeline, output) will be ignored. Other fields will be shared across all
    loaded streams (resources, metrics, etc).
 
+   Streams created, updated or deleted via the REST API can be persisted so
+   that they survive restarts with either --persist-dir or --persist-cache:
+
+   benthos streams --persist-dir ./data/streams ./path/to/stream/configs
+   benthos -c ./root_config.yaml streams --persist-cache foo
+
    For more information check out the docs at:
    https://benthos.dev/docs/guides/streams_mode/about`[4:],
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "persist-dir",
+						Value: "",
+						Usage: "A directory to persist streams created via the REST API within.",
+					},
+					&cli.StringFlag{
+						Name:  "persist-cache",
+						Value: "",
+						Usage: "The name of a cache resource to persist streams created via the REST API within.",
+					},
+				},
 				Action: func(c *cli.Context) error {
 					os.Exit(cmdService(
 						c.String("config"),
 						c.StringSlice("resources"),
 						c.String("log.level"),
 						!c.Bool("chilled"),
+						c.Bool("watch"),
 						true,
 						c.Args().Slice(),
+						streamsPersistConfig{
+							dir:   c.String("persist-dir"),
+							cache: c.String("persist-cache"),
+						},
 					))
 					return nil
 				},
@@ -359,7 +391,7 @@ func Run() {
 		}
 
 		deprecatedExecute(*configPath, testSuffix)
-		os.Exit(cmdService(*configPath, nil, "", false, false, nil))
+		os.Exit(cmdService(*configPath, nil, "", false, false, false, nil, streamsPersistConfig{}))
 		return nil
 	}
 
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -30,17 +31,38 @@ import (
 var conf = config.New()
 var testSuffix = "_benthos_test"
 
+// Overrides of config default values, which are retained so that they can be
+// applied to configs that are read again when reloading.
+var confDefaultOverrides []func(c *config.Type)
+
+func overrideConfigDefaults(fn func(c *config.Type)) {
+	fn(&conf)
+	confDefaultOverrides = append(confDefaultOverrides, fn)
+}
+
+// newDefaultConfig returns a config with default values, including any that
+// have been overridden.
+func newDefaultConfig() config.Type {
+	c := config.New()
+	for _, fn := range confDefaultOverrides {
+		fn(&c)
+	}
+	return c
+}
+
 // OptSetServiceName creates an opt func that allows the default service name
 // config fields such as metrics and logging prefixes to be overridden.
 func OptSetServiceName(name string) func() {
 	return func() {
 		testSuffix = fmt.Sprintf("_%v_test", name)
-		conf.HTTP.RootPath = "/" + name
-		conf.Logger.Prefix = name
-		conf.Logger.StaticFields["@service"] = name
-		conf.Metrics.HTTP.Prefix = name
-		conf.Metrics.Prometheus.Prefix = name
-		conf.Metrics.Statsd.Prefix = name
+		overrideConfigDefaults(func(c *config.Type) {
+			c.HTTP.RootPath = "/" + name
+			c.Logger.Prefix = name
+			c.Logger.StaticFields["@service"] = name
+			c.Metrics.HTTP.Prefix = name
+			c.Metrics.Prometheus.Prefix = name
+			c.Metrics.Statsd.Prefix = name
+		})
 	}
 }
 
@@ -48,7 +70,7 @@ func OptSetServiceName(name string) func() {
 // to override config struct default values before the user config is parsed.
 func OptOverrideConfigDefaults(fn func(c *config.Type)) func() {
 	return func() {
-		fn(&conf)
+		overrideConfigDefaults(fn)
 	}
 }
 
@@ -92,40 +114,50 @@ func OptOnManagerInit(fn ManagerInitFunc) func() {
 
 //------------------------------------------------------------------------------
 
-func readConfig(path string, resourcesPaths []string) (lints []string) {
-	// A list of default config paths to check for if not explicitly defined
+// findConfigPath returns the first of a list of default config paths that
+// exists, or an empty string if none exist.
+func findConfigPath() string {
 	defaultPaths := []string{
 		"/benthos.yaml",
 		"/etc/benthos/config.yaml",
 		"/etc/benthos.yaml",
 	}
-
-	if len(path) > 0 {
-		var err error
-		if lints, err = config.Read(path, true, &conf); err != nil {
-			fmt.Fprintf(os.Stderr, "Configuration file read error: %v\n", err)
-			os.Exit(1)
+	for _, path := range defaultPaths {
+		if _, err := os.Stat(path); err == nil {
+			return path
 		}
-	} else {
+	}
+	return ""
+}
+
+func readConfig(path string, resourcesPaths []string) (lints []string) {
+	if len(path) == 0 {
 		// Iterate default config paths
-		for _, path := range defaultPaths {
-			if _, err := os.Stat(path); err == nil {
-				fmt.Fprintf(os.Stderr, "Config file not specified, reading from %v\n", path)
-
-				if lints, err = config.Read(path, true, &conf); err != nil {
-					fmt.Fprintf(os.Stderr, "Configuration file read error: %v\n", err)
-					os.Exit(1)
-				}
-				break
-			}
+		if path = findConfigPath(); len(path) > 0 {
+			fmt.Fprintf(os.Stderr, "Config file not specified, reading from %v\n", path)
+		}
+	}
+
+	var err error
+	if lints, err = readConfigInto(path, resourcesPaths, &conf); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	return
+}
+
+// readConfigInto parses a config file and resource files into a config.
+func readConfigInto(path string, resourcesPaths []string, target *config.Type) (lints []string, err error) {
+	if len(path) > 0 {
+		if lints, err = config.Read(path, true, target); err != nil {
+			return nil, fmt.Errorf("Configuration file read error: %v", err)
 		}
 	}
 
 	for _, rPath := range resourcesPaths {
 		resourceBytes, err := config.ReadWithJSONPointers(rPath, true)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Resource configuration file read error: %v\n", err)
-			os.Exit(1)
+			return nil, fmt.Errorf("Resource configuration file read error: %v", err)
 		}
 		extraMgrWrapper := struct {
 			Manager manager.Config `yaml:"resources"`
@@ -133,12 +165,10 @@ func readConfig(path string, resourcesPaths []string) (lints []string) {
 			Manager: manager.NewConfig(),
 		}
 		if err = yaml.Unmarshal(resourceBytes, &extraMgrWrapper); err != nil {
-			fmt.Fprintf(os.Stderr, "Resource configuration file read error: %v\n", err)
-			os.Exit(1)
+			return nil, fmt.Errorf("Resource configuration file read error: %v", err)
 		}
-		if err = conf.Manager.AddFrom(&extraMgrWrapper.Manager); err != nil {
-			fmt.Fprintf(os.Stderr, "Resource configuration file read error: %v\n", err)
-			os.Exit(1)
+		if err = target.Manager.AddFrom(&extraMgrWrapper.Manager); err != nil {
+			return nil, fmt.Errorf("Resource configuration file read error: %v", err)
 		}
 	}
 
@@ -147,13 +177,39 @@ func readConfig(path string, resourcesPaths []string) (lints []string) {
 
 //------------------------------------------------------------------------------
 
+// streamsPersistConfig describes an optional backend for persisting streams
+// created, updated or deleted via the HTTP API in streams mode.
+type streamsPersistConfig struct {
+	dir   string
+	cache string
+}
+
+func (s streamsPersistConfig) newStore(mgr types.Manager) (strmmgr.Store, error) {
+	if len(s.dir) > 0 && len(s.cache) > 0 {
+		return nil, errors.New("cannot persist streams to both a directory and a cache")
+	}
+	if len(s.dir) > 0 {
+		return strmmgr.NewDirectoryStore(s.dir)
+	}
+	if len(s.cache) > 0 {
+		cache, err := mgr.GetCache(s.cache)
+		if err != nil {
+			return nil, fmt.Errorf("failed to obtain cache '%v': %v", s.cache, err)
+		}
+		return strmmgr.NewCacheStore(cache, "benthos/streams/"), nil
+	}
+	return nil, nil
+}
+
 func cmdService(
 	confPath string,
 	resourcesPaths []string,
 	overrideLogLevel string,
 	strict bool,
+	watch bool,
 	streamsMode bool,
 	streamsConfigs []string,
+	streamsPersist streamsPersistConfig,
 ) int {
 	var err error
 	if resourcesPaths, err = filepath.Globs(resourcesPaths); err != nil {
@@ -244,11 +300,20 @@ func cmdService(
 
 	// Create data streams.
 	if streamsMode {
+		if watch {
+			logger.Warnln("Watching config files for changes is not supported in streams mode.")
+		}
+		store, err := streamsPersist.newStore(manager)
+		if err != nil {
+			logger.Errorf("Failed to create streams store: %v\n", err)
+			return 1
+		}
 		streamMgr := strmmgr.New(
 			strmmgr.OptSetAPITimeout(time.Second*5),
 			strmmgr.OptSetLogger(logger),
 			strmmgr.OptSetManager(manager),
 			strmmgr.OptSetStats(stats),
+			strmmgr.OptSetStore(store),
 		)
 		streamConfs := map[string]stream.Config{}
 		var streamLints []string
@@ -275,6 +340,18 @@ func cmdService(
 		}
 
 		dataStream = streamMgr
+
+		persisted, err := streamMgr.LoadFromStore(time.Second * 5)
+		if err != nil {
+			logger.Errorf("Failed to restore persisted streams: %v\n", err)
+			return 1
+		}
+		for _, id := range persisted {
+			if _, exists := streamConfs[id]; exists {
+				logger.Warnf("Stream '%v' config file is being ignored in favour of its persisted version\n", id)
+				delete(streamConfs, id)
+			}
+		}
 		for id, conf := range streamConfs {
 			if err = streamMgr.Create(id, conf); err != nil {
 				logger.Errorf("Failed to create stream (%v): %v\n", id, err)
@@ -283,15 +360,18 @@ func cmdService(
 		}
 		logger.Infoln("Launching benthos in streams mode, use CTRL+C to close.")
 	} else {
-		if dataStream, err = stream.New(
-			conf.Config,
+		strmOpts := []func(*stream.Type){
 			stream.OptSetLogger(logger),
 			stream.OptSetStats(stats),
 			stream.OptSetManager(manager),
 			stream.OptOnClose(func() {
 				close(dataStreamClosedChan)
 			}),
-		); err != nil {
+		}
+		if watch {
+			strmOpts = append(strmOpts, stream.OptEnableReload())
+		}
+		if dataStream, err = stream.New(conf.Config, strmOpts...); err != nil {
 			logger.Errorf("Service closing due to: %v\n", err)
 			return 1
 		}
@@ -321,8 +401,52 @@ func cmdService(
 		}
 	}
 
+	var reloader *streamReloader
+	var watcher *configWatcher
+	if strm, ok := dataStream.(*stream.Type); ok && watch {
+		watchPath := confPath
+		if len(watchPath) == 0 {
+			watchPath = findConfigPath()
+		}
+		reloader = &streamReloader{
+			confPath:         watchPath,
+			resourcesPaths:   resourcesPaths,
+			overrideLogLevel: overrideLogLevel,
+			strict:           strict,
+			timeout:          exitTimeout,
+			apiReg:           httpServer,
+			logger:           logger,
+			stats:            stats,
+			strm:             strm,
+			mgr:              manager,
+		}
+		if reloader.conf, _, err = reloader.readConfig(); err != nil {
+			logger.Errorf("Failed to read config for watching: %v\n", err)
+			return 1
+		}
+		watchPaths := resourcesPaths
+		if len(watchPath) > 0 {
+			watchPaths = append([]string{watchPath}, resourcesPaths...)
+		}
+		if watcher, err = newConfigWatcher(watchPaths, logger, func() {
+			logger.Infoln("Config file changes detected, attempting to reload.")
+			if err := reloader.Reload(); err != nil {
+				logger.Errorf("Failed to reload config, continuing with the previous config: %v\n", err)
+			}
+		}); err != nil {
+			logger.Errorf("Failed to watch config files: %v\n", err)
+			return 1
+		}
+		logger.Infof("Watching config files for changes: %v\n", strings.Join(watchPaths, ", "))
+	}
+
 	// Defer clean up.
 	defer func() {
+		if watcher != nil {
+			watcher.Close()
+			manager = reloader.Manager()
+		}
+
 		go func() {
 			httpServer.Shutdown(context.Background())
 			select {
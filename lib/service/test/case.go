@@ -1,6 +1,7 @@
 package test
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/Jeffail/benthos/v3/lib/message"
@@ -52,9 +53,13 @@ type Case struct {
 	Name             string            `yaml:"name"`
 	Environment      map[string]string `yaml:"environment"`
 	TargetProcessors string            `yaml:"target_processors"`
+	Mocks            Mocks             `yaml:"mocks,omitempty"`
 	InputBatch       []InputPart       `yaml:"input_batch"`
 	OutputBatches    [][]ConditionsMap `yaml:"output_batches"`
 
+	InputBatches [][]InputPart                `yaml:"input_batches,omitempty"`
+	Outputs      map[string][][]ConditionsMap `yaml:"outputs,omitempty"`
+
 	line int
 }
 
@@ -119,10 +124,52 @@ type ProcProvider interface {
 	Provide(jsonPtr string, environment map[string]string) ([]types.Processor, error)
 }
 
+// MockedProcProvider returns compiled processors extracted from a Benthos
+// config using a JSON Pointer, after replacing parts of the config with mocks.
+type MockedProcProvider interface {
+	ProvideMocked(jsonPtr string, environment map[string]string, mocks Mocks) ([]types.Processor, error)
+}
+
+// isStream returns whether the case targets the full stream of a config rather
+// than its processors.
+func (c *Case) isStream() bool {
+	return len(c.InputBatches) > 0
+}
+
+func newInputMessage(batch []InputPart) types.Message {
+	parts := make([]types.Part, len(batch))
+	for i, v := range batch {
+		part := message.NewPart([]byte(v.Content))
+		part.SetMetadata(metadata.New(v.Metadata))
+		parts[i] = part
+	}
+
+	msg := message.New(nil)
+	msg.SetAll(parts)
+	return msg
+}
+
 // Execute attempts to execute a test case against a Benthos configuration.
 func (c *Case) Execute(provider ProcProvider) (failures []CaseFailure, err error) {
+	if c.isStream() {
+		streamProvider, ok := provider.(StreamProvider)
+		if !ok {
+			return nil, errors.New("failed to initialise stream: provider does not support streams")
+		}
+		return c.executeStream(streamProvider)
+	}
+
 	var procSet []types.Processor
-	if procSet, err = provider.Provide(c.TargetProcessors, c.Environment); err != nil {
+	if len(c.Mocks) > 0 {
+		mockedProvider, ok := provider.(MockedProcProvider)
+		if !ok {
+			return nil, fmt.Errorf("failed to initialise processors '%v': provider does not support mocks", c.TargetProcessors)
+		}
+		procSet, err = mockedProvider.ProvideMocked(c.TargetProcessors, c.Environment, c.Mocks)
+	} else {
+		procSet, err = provider.Provide(c.TargetProcessors, c.Environment)
+	}
+	if err != nil {
 		return nil, fmt.Errorf("failed to initialise processors '%v': %v", c.TargetProcessors, err)
 	}
 
@@ -134,16 +181,7 @@ func (c *Case) Execute(provider ProcProvider) (failures []CaseFailure, err error
 		})
 	}
 
-	parts := make([]types.Part, len(c.InputBatch))
-	for i, v := range c.InputBatch {
-		part := message.NewPart([]byte(v.Content))
-		part.SetMetadata(metadata.New(v.Metadata))
-		parts[i] = part
-	}
-
-	inputMsg := message.New(nil)
-	inputMsg.SetAll(parts)
-	outputBatches, result := processor.ExecuteAll(procSet, inputMsg)
+	outputBatches, result := processor.ExecuteAll(procSet, newInputMessage(c.InputBatch))
 	if result != nil {
 		if len(c.OutputBatches) == 0 {
 			return
@@ -156,16 +194,23 @@ func (c *Case) Execute(provider ProcProvider) (failures []CaseFailure, err error
 		return
 	}
 
-	if lExp, lAct := len(c.OutputBatches), len(outputBatches); lAct < lExp {
+	checkOutputBatches(c.OutputBatches, outputBatches, reportFailure)
+	return
+}
+
+// checkOutputBatches compares batches of messages against the conditions of
+// expected batches, and reports any differences.
+func checkOutputBatches(expected [][]ConditionsMap, actual []types.Message, reportFailure func(reason string)) {
+	if lExp, lAct := len(expected), len(actual); lAct < lExp {
 		reportFailure(fmt.Sprintf("wrong batch count, expected %v, got %v", lExp, lAct))
 	}
 
-	for i, v := range outputBatches {
-		if len(c.OutputBatches) <= i {
+	for i, v := range actual {
+		if len(expected) <= i {
 			reportFailure(fmt.Sprintf("unexpected batch: %s", message.GetAllBytes(v)))
 			continue
 		}
-		expectedBatch := c.OutputBatches[i]
+		expectedBatch := expected[i]
 		if lExp, lAct := len(expectedBatch), v.Len(); lExp != lAct {
 			reportFailure(fmt.Sprintf("mismatch of output batch %v message counts, expected %v, got %v", i, lExp, lAct))
 		}
@@ -184,7 +229,6 @@ func (c *Case) Execute(provider ProcProvider) (failures []CaseFailure, err error
 			return nil
 		})
 	}
-	return
 }
 
 //------------------------------------------------------------------------------
@@ -46,7 +46,13 @@ func (d Definition) execute(filepath string, resourcesPaths []string, logger log
 	if d.Parallel {
 		// Warm the cache of processor configs.
 		for _, c := range d.Cases {
-			if _, err := procsProvider.getConfs(c.TargetProcessors, c.Environment); err != nil {
+			var err error
+			if c.isStream() {
+				_, err = procsProvider.getStreamConf(c.Environment, c.Mocks)
+			} else {
+				_, err = procsProvider.getConfs(c.TargetProcessors, c.Environment, c.Mocks)
+			}
+			if err != nil {
 				return nil, err
 			}
 		}
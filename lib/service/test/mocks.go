@@ -0,0 +1,176 @@
+package test
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Jeffail/benthos/v3/lib/cache"
+	"github.com/Jeffail/benthos/v3/lib/condition"
+	"github.com/Jeffail/benthos/v3/lib/input"
+	"github.com/Jeffail/benthos/v3/lib/manager"
+	"github.com/Jeffail/benthos/v3/lib/output"
+	"github.com/Jeffail/benthos/v3/lib/processor"
+	"github.com/Jeffail/benthos/v3/lib/ratelimit"
+	yaml "gopkg.in/yaml.v3"
+)
+
+//------------------------------------------------------------------------------
+
+// Mocks is a map of config replacements applied to a Benthos config before the
+// processors of a test case are constructed. Keys beginning with a forward
+// slash are treated as JSON pointers to any part of the target config,
+// otherwise the key is the name of a resource to replace.
+type Mocks map[string]yaml.Node
+
+func (m Mocks) id() string {
+	if len(m) == 0 {
+		return ""
+	}
+	b, err := yaml.Marshal(map[string]yaml.Node(m))
+	if err != nil {
+		return fmt.Sprintf("%v", map[string]yaml.Node(m))
+	}
+	return string(b)
+}
+
+// applyPointers replaces the parts of a generic config structure targeted by
+// JSON pointer mocks.
+func (m Mocks) applyPointers(root interface{}) error {
+	for k, v := range m {
+		if !strings.HasPrefix(k, "/") {
+			continue
+		}
+		var value interface{}
+		if err := v.Decode(&value); err != nil {
+			return fmt.Errorf("mock '%v': line %v: %v", k, v.Line, err)
+		}
+		if err := setJSONPointer(k, root, value); err != nil {
+			return fmt.Errorf("mock '%v': %v", k, err)
+		}
+	}
+	return nil
+}
+
+// applyResources replaces the resources of a manager config targeted by named
+// mocks.
+func (m Mocks) applyResources(conf *manager.Config) error {
+	for k, v := range m {
+		if strings.HasPrefix(k, "/") {
+			continue
+		}
+		v := v
+		var matches []func() error
+		if _, exists := conf.Inputs[k]; exists {
+			matches = append(matches, func() error {
+				c := input.NewConfig()
+				err := v.Decode(&c)
+				conf.Inputs[k] = c
+				return err
+			})
+		}
+		if _, exists := conf.Conditions[k]; exists {
+			matches = append(matches, func() error {
+				c := condition.NewConfig()
+				err := v.Decode(&c)
+				conf.Conditions[k] = c
+				return err
+			})
+		}
+		if _, exists := conf.Processors[k]; exists {
+			matches = append(matches, func() error {
+				c := processor.NewConfig()
+				err := v.Decode(&c)
+				conf.Processors[k] = c
+				return err
+			})
+		}
+		if _, exists := conf.Outputs[k]; exists {
+			matches = append(matches, func() error {
+				c := output.NewConfig()
+				err := v.Decode(&c)
+				conf.Outputs[k] = c
+				return err
+			})
+		}
+		if _, exists := conf.Caches[k]; exists {
+			matches = append(matches, func() error {
+				c := cache.NewConfig()
+				err := v.Decode(&c)
+				conf.Caches[k] = c
+				return err
+			})
+		}
+		if _, exists := conf.RateLimits[k]; exists {
+			matches = append(matches, func() error {
+				c := ratelimit.NewConfig()
+				err := v.Decode(&c)
+				conf.RateLimits[k] = c
+				return err
+			})
+		}
+		switch len(matches) {
+		case 0:
+			return fmt.Errorf("mock '%v': resource was not found", k)
+		case 1:
+			if err := matches[0](); err != nil {
+				return fmt.Errorf("mock '%v': line %v: %v", k, v.Line, err)
+			}
+		default:
+			return fmt.Errorf("mock '%v': name matches multiple resources, target it with a JSON pointer instead", k)
+		}
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// setJSONPointer parses a JSON pointer path (https://tools.ietf.org/html/rfc6901)
+// and replaces the value it references within a generic structure. Objects may
+// have new keys added, but array indexes must already exist.
+func setJSONPointer(path string, root, value interface{}) error {
+	if len(path) < 2 || path[0] != '/' {
+		return errors.New("failed to set JSON pointer: path must begin with '/' and not target the root of the config")
+	}
+	hierarchy := strings.Split(path, "/")[1:]
+	for i, v := range hierarchy {
+		v = strings.Replace(v, "~1", "/", -1)
+		v = strings.Replace(v, "~0", "~", -1)
+		hierarchy[i] = v
+	}
+
+	object := root
+	for target, pathSeg := range hierarchy {
+		last := target == len(hierarchy)-1
+		switch t := object.(type) {
+		case map[string]interface{}:
+			if last {
+				t[pathSeg] = value
+				return nil
+			}
+			var ok bool
+			if object, ok = t[pathSeg]; !ok {
+				return fmt.Errorf("failed to set JSON pointer: index '%v' value '%v' was not found", target, pathSeg)
+			}
+		case []interface{}:
+			index, err := strconv.Atoi(pathSeg)
+			if err != nil {
+				return fmt.Errorf("failed to set JSON pointer: could not parse index '%v' value '%v' into array index: %v", target, pathSeg, err)
+			}
+			if index < 0 || len(t) <= index {
+				return fmt.Errorf("failed to set JSON pointer: index '%v' value '%v' exceeded target array size of '%v'", target, pathSeg, len(t))
+			}
+			if last {
+				t[index] = value
+				return nil
+			}
+			object = t[index]
+		default:
+			return fmt.Errorf("failed to set JSON pointer: index '%v' field '%v' was not found", target, pathSeg)
+		}
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,206 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/manager"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/processor"
+	"github.com/fatih/color"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	yaml "gopkg.in/yaml.v3"
+)
+
+func TestSetJSONPointer(t *testing.T) {
+	var root interface{}
+	require.NoError(t, yaml.Unmarshal([]byte(`
+a:
+  b:
+    - c: 1
+    - d: 2
+e/f: 3
+`), &root))
+
+	require.NoError(t, setJSONPointer("/a/b/1", root, "foo"))
+	require.NoError(t, setJSONPointer("/a/b/0/c", root, 10))
+	require.NoError(t, setJSONPointer("/a/g", root, true))
+	require.NoError(t, setJSONPointer("/e~1f", root, 4))
+
+	assert.Equal(t, map[string]interface{}{
+		"a": map[string]interface{}{
+			"b": []interface{}{
+				map[string]interface{}{"c": 10},
+				"foo",
+			},
+			"g": true,
+		},
+		"e/f": 4,
+	}, root)
+
+	for _, path := range []string{"", "/", "a/b", "/a/nope/c", "/a/b/2", "/a/b/nope", "/a/g/h"} {
+		assert.Error(t, setJSONPointer(path, root, "bar"), path)
+	}
+}
+
+func parseMocks(t *testing.T, mocksStr string) Mocks {
+	t.Helper()
+
+	var mocks Mocks
+	require.NoError(t, yaml.Unmarshal([]byte(mocksStr), &mocks))
+	return mocks
+}
+
+func TestMocksApplyResources(t *testing.T) {
+	var conf manager.Config
+	require.NoError(t, yaml.Unmarshal([]byte(`
+caches:
+  foo:
+    redis:
+      url: tcp://localhost:6379
+  both:
+    memory: {}
+processors:
+  bar:
+    http:
+      url: http://localhost:4195
+  both:
+    noop: {}
+`), &conf))
+
+	require.NoError(t, parseMocks(t, `
+foo:
+  memory:
+    init_values:
+      hello: world
+bar:
+  bloblang: 'root = "mocked"'
+/not/a/resource: {}
+`).applyResources(&conf))
+
+	assert.Equal(t, "memory", conf.Caches["foo"].Type)
+	assert.Equal(t, map[string]string{"hello": "world"}, conf.Caches["foo"].Memory.InitValues)
+	assert.Equal(t, "bloblang", conf.Processors["bar"].Type)
+	assert.Equal(t, "noop", conf.Processors["both"].Type)
+
+	err := parseMocks(t, `nope: { noop: {} }`).applyResources(&conf)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "resource was not found")
+
+	err = parseMocks(t, `both: { noop: {} }`).applyResources(&conf)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "name matches multiple resources")
+}
+
+func TestProcessorsProviderMocks(t *testing.T) {
+	testDir, err := initTestFiles(map[string]string{
+		"config1.yaml": `
+pipeline:
+  processors:
+    - branch:
+        request_map: 'root = content()'
+        processors:
+          - http:
+              url: http://localhost:1/nope
+        result_map: 'root.result = content()'
+    - cache:
+        resource: foocache
+        operator: get
+        key: '${! json("result") }'
+`,
+		"resources.yaml": `
+resources:
+  caches:
+    foocache:
+      redis:
+        url: tcp://localhost:1
+`,
+	})
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	provider := NewProcessorsProvider(
+		filepath.Join(testDir, "config1.yaml"),
+		OptAddResourcesPaths([]string{filepath.Join(testDir, "resources.yaml")}),
+	)
+
+	mocks := parseMocks(t, `
+/pipeline/processors/0/branch/processors/0:
+  bloblang: 'root = content().uppercase()'
+foocache:
+  memory:
+    init_values:
+      HELLO: cached value
+`)
+
+	procs, err := provider.ProvideMocked("/pipeline/processors", nil, mocks)
+	require.NoError(t, err)
+
+	msgs, res := processor.ExecuteAll(procs, message.New([][]byte{[]byte("hello")}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "cached value", string(msgs[0].Get(0).Get()))
+
+	// The original config is left unchanged.
+	confs, err := provider.getConfs("/pipeline/processors", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "http", confs.procs[0].Branch.Processors[0].Type)
+	assert.Equal(t, "redis", confs.mgr.Caches["foocache"].Type)
+
+	_, err = provider.ProvideMocked("/pipeline/processors", nil, parseMocks(t, `/pipeline/processors/5: { noop: {} }`))
+	assert.Error(t, err)
+}
+
+func TestDefinitionMocks(t *testing.T) {
+	color.NoColor = true
+
+	testDir, err := initTestFiles(map[string]string{
+		"config1.yaml": `
+resources:
+  processors:
+    enrich:
+      http:
+        url: http://localhost:1/nope
+
+pipeline:
+  processors:
+    - resource: enrich
+    - bloblang: 'root = content().uppercase()'
+`,
+		"config1_benthos_test.yaml": `
+tests:
+  - name: mocked
+    target_processors: /pipeline/processors
+    mocks:
+      enrich:
+        bloblang: 'root = content() + " enriched"'
+    input_batch:
+      - content: foo
+    output_batches:
+      - - content_equals: FOO ENRICHED
+  - name: mocked differently
+    target_processors: /pipeline/processors
+    mocks:
+      enrich:
+        bloblang: 'root = content() + " other"'
+    input_batch:
+      - content: bar
+    output_batches:
+      - - content_equals: BAR OTHER
+`,
+	})
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	def, err := getDefinition(filepath.Join(testDir, "config1.yaml"), filepath.Join(testDir, "config1_benthos_test.yaml"))
+	require.NoError(t, err)
+
+	for _, parallel := range []bool{false, true} {
+		def.Parallel = parallel
+		failures, err := def.Execute(filepath.Join(testDir, "config1.yaml"))
+		require.NoError(t, err)
+		assert.Empty(t, failures)
+	}
+}
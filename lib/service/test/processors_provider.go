@@ -28,6 +28,7 @@ type ProcessorsProvider struct {
 	targetPath     string
 	resourcesPaths []string
 	cachedConfigs  map[string]cachedConfig
+	cachedStreams  map[string][]byte
 
 	logger log.Modular
 }
@@ -37,6 +38,7 @@ func NewProcessorsProvider(targetPath string, opts ...func(*ProcessorsProvider))
 	p := &ProcessorsProvider{
 		targetPath:    targetPath,
 		cachedConfigs: map[string]cachedConfig{},
+		cachedStreams: map[string][]byte{},
 		logger:        log.Noop(),
 	}
 	for _, opt := range opts {
@@ -67,13 +69,36 @@ func OptProcessorsProviderSetLogger(logger log.Modular) func(*ProcessorsProvider
 // the JSON Pointer targets a single processor config it will be constructed and
 // returned as an array of one element.
 func (p *ProcessorsProvider) Provide(jsonPtr string, environment map[string]string) ([]types.Processor, error) {
-	confs, err := p.getConfs(jsonPtr, environment)
+	return p.ProvideMocked(jsonPtr, environment, nil)
+}
+
+// ProvideMocked attempts to extract an array of processors from a Benthos
+// config after applying mocks to it. If the JSON Pointer targets a single
+// processor config it will be constructed and returned as an array of one
+// element.
+func (p *ProcessorsProvider) ProvideMocked(jsonPtr string, environment map[string]string, mocks Mocks) ([]types.Processor, error) {
+	confs, err := p.getConfs(jsonPtr, environment, mocks)
 	if err != nil {
 		return nil, err
 	}
 	return p.initProcs(confs)
 }
 
+// ProvideStream attempts to extract the full config of a Benthos stream and its
+// resources from a Benthos config after applying mocks to it, along with a
+// logger for its components.
+func (p *ProcessorsProvider) ProvideStream(environment map[string]string, mocks Mocks) (config.Type, log.Modular, error) {
+	conf := config.New()
+	confBytes, err := p.getStreamConf(environment, mocks)
+	if err != nil {
+		return conf, nil, err
+	}
+	if err = yaml.Unmarshal(confBytes, &conf); err != nil {
+		return conf, nil, fmt.Errorf("failed to parse config file '%v': %v", p.targetPath, err)
+	}
+	return conf, p.logger, nil
+}
+
 //------------------------------------------------------------------------------
 
 func (p *ProcessorsProvider) initProcs(confs cachedConfig) ([]types.Processor, error) {
@@ -91,8 +116,8 @@ func (p *ProcessorsProvider) initProcs(confs cachedConfig) ([]types.Processor, e
 	return procs, nil
 }
 
-func confTargetID(jsonPtr string, environment map[string]string) string {
-	return fmt.Sprintf("%v-%v", jsonPtr, environment)
+func confTargetID(jsonPtr string, environment map[string]string, mocks Mocks) string {
+	return fmt.Sprintf("%v-%v-%v", jsonPtr, environment, mocks.id())
 }
 
 func setEnvironment(vars map[string]string) func() {
@@ -144,35 +169,23 @@ func resolveProcessorsPointer(targetFile string, jsonPtr string) (filePath, proc
 	return
 }
 
-func (p *ProcessorsProvider) getConfs(jsonPtr string, environment map[string]string) (cachedConfig, error) {
-	cacheKey := confTargetID(jsonPtr, environment)
-
-	confs, exists := p.cachedConfigs[cacheKey]
-	if exists {
-		return confs, nil
+// readMocked reads a target config file and any resource files, and applies
+// mocks to them. The target config is returned both as a generic structure and
+// serialised, along with the merged resources, where resources within the
+// serialised config have not had mocks applied to them.
+func (p *ProcessorsProvider) readMocked(targetPath string, mocks Mocks) (root interface{}, configBytes []byte, mgrConf manager.Config, err error) {
+	if configBytes, err = config.ReadWithJSONPointers(targetPath, true); err != nil {
+		return nil, nil, mgrConf, fmt.Errorf("failed to parse config file '%v': %v", targetPath, err)
 	}
 
-	targetPath, procPath, err := resolveProcessorsPointer(p.targetPath, jsonPtr)
-	if err != nil {
-		return confs, err
-	}
-	if len(targetPath) == 0 {
-		targetPath = p.targetPath
+	if err = yaml.Unmarshal(configBytes, &root); err != nil {
+		return nil, nil, mgrConf, fmt.Errorf("failed to parse config file '%v': %v", targetPath, err)
 	}
-
-	// Set custom environment vars.
-	ogEnvVars := map[string]string{}
-	for k, v := range environment {
-		ogEnvVars[k] = os.Getenv(k)
-		os.Setenv(k, v)
+	if err = mocks.applyPointers(root); err != nil {
+		return nil, nil, mgrConf, fmt.Errorf("failed to apply mocks to config file '%v': %v", targetPath, err)
 	}
-
-	cleanupEnv := setEnvironment(environment)
-	defer cleanupEnv()
-
-	configBytes, err := config.ReadWithJSONPointers(targetPath, true)
-	if err != nil {
-		return confs, fmt.Errorf("failed to parse config file '%v': %v", targetPath, err)
+	if configBytes, err = yaml.Marshal(root); err != nil {
+		return nil, nil, mgrConf, fmt.Errorf("failed to apply mocks to config file '%v': %v", targetPath, err)
 	}
 
 	mgrWrapper := struct {
@@ -181,13 +194,13 @@ func (p *ProcessorsProvider) getConfs(jsonPtr string, environment map[string]str
 		Manager: manager.NewConfig(),
 	}
 	if err = yaml.Unmarshal(configBytes, &mgrWrapper); err != nil {
-		return confs, fmt.Errorf("failed to parse config file '%v': %v", targetPath, err)
+		return nil, nil, mgrConf, fmt.Errorf("failed to parse config file '%v': %v", targetPath, err)
 	}
 
 	for _, path := range p.resourcesPaths {
 		resourceBytes, err := config.ReadWithJSONPointers(path, true)
 		if err != nil {
-			return confs, fmt.Errorf("failed to parse resources config file '%v': %v", path, err)
+			return nil, nil, mgrConf, fmt.Errorf("failed to parse resources config file '%v': %v", path, err)
 		}
 		extraMgrWrapper := struct {
 			Manager manager.Config `yaml:"resources"`
@@ -195,19 +208,80 @@ func (p *ProcessorsProvider) getConfs(jsonPtr string, environment map[string]str
 			Manager: manager.NewConfig(),
 		}
 		if err = yaml.Unmarshal(resourceBytes, &extraMgrWrapper); err != nil {
-			return confs, fmt.Errorf("failed to parse resources config file '%v': %v", path, err)
+			return nil, nil, mgrConf, fmt.Errorf("failed to parse resources config file '%v': %v", path, err)
 		}
 		if err = mgrWrapper.Manager.AddFrom(&extraMgrWrapper.Manager); err != nil {
-			return confs, fmt.Errorf("failed to merge resources from '%v': %v", path, err)
+			return nil, nil, mgrConf, fmt.Errorf("failed to merge resources from '%v': %v", path, err)
 		}
 	}
 
-	confs.mgr = mgrWrapper.Manager
+	if err = mocks.applyResources(&mgrWrapper.Manager); err != nil {
+		return nil, nil, mgrConf, fmt.Errorf("failed to apply mocks to resources: %v", err)
+	}
+	return root, configBytes, mgrWrapper.Manager, nil
+}
 
-	var root interface{}
-	if err = yaml.Unmarshal(configBytes, &root); err != nil {
-		return confs, fmt.Errorf("failed to parse config file '%v': %v", targetPath, err)
+// getStreamConf returns the serialised config of the target file with mocks and
+// resources files applied, as configs cannot be reused across test cases.
+func (p *ProcessorsProvider) getStreamConf(environment map[string]string, mocks Mocks) ([]byte, error) {
+	cacheKey := confTargetID("", environment, mocks)
+	if confBytes, exists := p.cachedStreams[cacheKey]; exists {
+		return confBytes, nil
+	}
+
+	cleanupEnv := setEnvironment(environment)
+	defer cleanupEnv()
+
+	_, configBytes, mgrConf, err := p.readMocked(p.targetPath, mocks)
+	if err != nil {
+		return nil, err
+	}
+
+	conf := config.New()
+	if err = yaml.Unmarshal(configBytes, &conf); err != nil {
+		return nil, fmt.Errorf("failed to parse config file '%v': %v", p.targetPath, err)
+	}
+	conf.Manager = mgrConf
+
+	var confBytes []byte
+	if confBytes, err = yaml.Marshal(conf); err != nil {
+		return nil, fmt.Errorf("failed to serialise config file '%v': %v", p.targetPath, err)
+	}
+	p.cachedStreams[cacheKey] = confBytes
+	return confBytes, nil
+}
+
+func (p *ProcessorsProvider) getConfs(jsonPtr string, environment map[string]string, mocks Mocks) (cachedConfig, error) {
+	cacheKey := confTargetID(jsonPtr, environment, mocks)
+
+	confs, exists := p.cachedConfigs[cacheKey]
+	if exists {
+		return confs, nil
+	}
+
+	targetPath, procPath, err := resolveProcessorsPointer(p.targetPath, jsonPtr)
+	if err != nil {
+		return confs, err
+	}
+	if len(targetPath) == 0 {
+		targetPath = p.targetPath
+	}
+
+	// Set custom environment vars.
+	ogEnvVars := map[string]string{}
+	for k, v := range environment {
+		ogEnvVars[k] = os.Getenv(k)
+		os.Setenv(k, v)
+	}
+
+	cleanupEnv := setEnvironment(environment)
+	defer cleanupEnv()
+
+	root, _, mgrConf, err := p.readMocked(targetPath, mocks)
+	if err != nil {
+		return confs, err
 	}
+	confs.mgr = mgrConf
 
 	var procs interface{}
 	if procs, err = config.JSONPointer(procPath, root); err != nil {
@@ -0,0 +1,467 @@
+package test
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/buffer"
+	"github.com/Jeffail/benthos/v3/lib/config"
+	"github.com/Jeffail/benthos/v3/lib/input"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/manager"
+	"github.com/Jeffail/benthos/v3/lib/message/batch"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/output"
+	"github.com/Jeffail/benthos/v3/lib/pipeline"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	yaml "gopkg.in/yaml.v3"
+)
+
+//------------------------------------------------------------------------------
+
+// StreamProvider returns the config of a Benthos stream and its resources,
+// after replacing parts of the config with mocks, along with a logger for its
+// components.
+type StreamProvider interface {
+	ProvideStream(environment map[string]string, mocks Mocks) (config.Type, log.Modular, error)
+}
+
+// The amount of time a stream test is given to process its input batches and
+// shut down.
+var streamTestTimeout = time.Second * 10
+
+//------------------------------------------------------------------------------
+
+// componentBatching extracts the batching policy from the type specific config
+// of a component config, if it has one.
+func componentBatching(conf interface{}, typeStr string) (batch.PolicyConfig, error) {
+	policy := batch.NewPolicyConfig()
+
+	confBytes, err := yaml.Marshal(conf)
+	if err != nil {
+		return policy, err
+	}
+	var fields map[string]yaml.Node
+	if err = yaml.Unmarshal(confBytes, &fields); err != nil {
+		return policy, err
+	}
+	typeNode, exists := fields[typeStr]
+	if !exists {
+		return policy, nil
+	}
+	var typeFields map[string]yaml.Node
+	if err = typeNode.Decode(&typeFields); err != nil {
+		// The type specific config isn't an object, and therefore has no
+		// batching policy.
+		return policy, nil
+	}
+	if batchNode, exists := typeFields["batching"]; exists {
+		err = batchNode.Decode(&policy)
+	}
+	return policy, err
+}
+
+// captureOutputs replaces all outputs of an output config that write messages
+// somewhere with inproc outputs, which are keyed by their JSON Pointer. Outputs
+// that route messages to other outputs are left in place, and outputs that are
+// replaced keep their processors and batching policy.
+func captureOutputs(path string, conf *output.Config, sinks map[string]string) error {
+	switch conf.Type {
+	case output.TypeBroker:
+		for i := range conf.Broker.Outputs {
+			if err := captureOutputs(fmt.Sprintf("%v/broker/outputs/%v", path, i), &conf.Broker.Outputs[i], sinks); err != nil {
+				return err
+			}
+		}
+		return nil
+	case output.TypeDeadLetter:
+		if conf.DeadLetter.Output != nil {
+			if err := captureOutputs(path+"/dead_letter/output", conf.DeadLetter.Output, sinks); err != nil {
+				return err
+			}
+		}
+		if conf.DeadLetter.DeadLetter != nil {
+			return captureOutputs(path+"/dead_letter/dead_letter", conf.DeadLetter.DeadLetter, sinks)
+		}
+		return nil
+	case output.TypeDropOn:
+		if conf.DropOn.Output != nil {
+			return captureOutputs(path+"/drop_on/output", conf.DropOn.Output, sinks)
+		}
+		return nil
+	case output.TypeDropOnError:
+		if conf.DropOnError.Config != nil {
+			return captureOutputs(path+"/drop_on_error", conf.DropOnError.Config, sinks)
+		}
+		return nil
+	case output.TypeDynamic:
+		for k, v := range conf.Dynamic.Outputs {
+			v := v
+			if err := captureOutputs(fmt.Sprintf("%v/dynamic/outputs/%v", path, k), &v, sinks); err != nil {
+				return err
+			}
+			conf.Dynamic.Outputs[k] = v
+		}
+		return nil
+	case output.TypeRetry:
+		if conf.Retry.Output != nil {
+			return captureOutputs(path+"/retry/output", conf.Retry.Output, sinks)
+		}
+		return nil
+	case output.TypeSwitch:
+		for i := range conf.Switch.Cases {
+			if err := captureOutputs(fmt.Sprintf("%v/switch/cases/%v/output", path, i), &conf.Switch.Cases[i].Output, sinks); err != nil {
+				return err
+			}
+		}
+		for i := range conf.Switch.Outputs {
+			if err := captureOutputs(fmt.Sprintf("%v/switch/outputs/%v/output", path, i), &conf.Switch.Outputs[i].Output, sinks); err != nil {
+				return err
+			}
+		}
+		return nil
+	case output.TypeTry:
+		for i := range conf.Try {
+			if err := captureOutputs(fmt.Sprintf("%v/try/%v", path, i), &conf.Try[i], sinks); err != nil {
+				return err
+			}
+		}
+		return nil
+	case output.TypeReject, output.TypeResource:
+		// Rejections are part of the routing behaviour of a config, and
+		// resources are captured separately.
+		return nil
+	}
+
+	policy, err := componentBatching(*conf, conf.Type)
+	if err != nil {
+		return fmt.Errorf("failed to read batching policy of output '%v': %v", path, err)
+	}
+
+	pipe := "benthos_test_output:" + path
+	sinks[path] = pipe
+
+	sinkConf := output.NewConfig()
+	sinkConf.Type = output.TypeInproc
+	sinkConf.Inproc = output.InprocConfig(pipe)
+
+	newConf := output.NewConfig()
+	newConf.Processors = conf.Processors
+	if policy.IsNoop() {
+		newConf.Type = output.TypeInproc
+		newConf.Inproc = sinkConf.Inproc
+	} else {
+		newConf.Type = output.TypeBroker
+		newConf.Broker.Outputs = append(newConf.Broker.Outputs, sinkConf)
+		newConf.Broker.Batching = policy
+	}
+	*conf = newConf
+	return nil
+}
+
+// feedInput is an input that emits transactions from a channel, and closes once
+// that channel is closed, allowing the stream to shut down gracefully.
+type feedInput struct {
+	tranChan chan types.Transaction
+}
+
+func (f *feedInput) TransactionChan() <-chan types.Transaction {
+	return f.tranChan
+}
+
+func (f *feedInput) Connected() bool {
+	return true
+}
+
+func (f *feedInput) CloseAsync() {
+}
+
+func (f *feedInput) WaitForClose(time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// capturedBatches reads the batches written to the inproc pipes of captured
+// outputs.
+type capturedBatches struct {
+	mut     sync.Mutex
+	batches map[string][]types.Message
+
+	wg       sync.WaitGroup
+	doneChan chan struct{}
+}
+
+func captureBatches(mgr types.Manager, sinks map[string]string) *capturedBatches {
+	c := &capturedBatches{
+		batches:  map[string][]types.Message{},
+		doneChan: make(chan struct{}),
+	}
+	for path, pipe := range sinks {
+		c.wg.Add(1)
+		go c.loop(mgr, path, pipe)
+	}
+	return c
+}
+
+func (c *capturedBatches) loop(mgr types.Manager, path, pipe string) {
+	defer c.wg.Done()
+
+	// Inproc outputs only register their pipe once they are running, and if a
+	// stream stops without ever writing to an output we might never see it.
+	var tranChan <-chan types.Transaction
+	for tranChan == nil {
+		var err error
+		if tranChan, err = mgr.GetPipe(pipe); err != nil {
+			select {
+			case <-time.After(time.Millisecond * 10):
+			case <-c.doneChan:
+				return
+			}
+		}
+	}
+
+	for {
+		var tran types.Transaction
+		var open bool
+		select {
+		case tran, open = <-tranChan:
+			if !open {
+				return
+			}
+		case <-c.doneChan:
+			return
+		}
+		c.mut.Lock()
+		c.batches[path] = append(c.batches[path], tran.Payload.Copy())
+		c.mut.Unlock()
+		select {
+		case tran.ResponseChan <- response.NewAck():
+		case <-c.doneChan:
+			return
+		}
+	}
+}
+
+// Close stops reading from captured pipes and returns the batches that were
+// read.
+func (c *capturedBatches) Close() map[string][]types.Message {
+	close(c.doneChan)
+	c.wg.Wait()
+	return c.batches
+}
+
+//------------------------------------------------------------------------------
+
+// closableLayer is a component of a stream that can be shut down.
+type closableLayer interface {
+	CloseAsync()
+	WaitForClose(timeout time.Duration) error
+}
+
+// buildStream constructs the layers of a stream around an input, the same way
+// a stream would, and returns the output layer followed by all layers.
+func buildStream(conf config.Type, in input.Type, mgr types.Manager, logger log.Modular) (output.Type, []closableLayer, error) {
+	stats := metrics.Noop()
+
+	layers := []closableLayer{in}
+	closeAll := func() {
+		for _, l := range layers {
+			l.CloseAsync()
+		}
+	}
+
+	policy, err := componentBatching(conf.Input, conf.Input.Type)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read batching policy of input: %v", err)
+	}
+	if !policy.IsNoop() {
+		batcher, err := batch.NewPolicy(policy, mgr, logger.NewModule(".input.batching"), stats)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to construct input batch policy: %v", err)
+		}
+		in = input.NewBatcher(batcher, in, logger.NewModule(".input"), stats)
+		layers = append(layers, in)
+	}
+	nextTranChan := in.TransactionChan()
+
+	type consumer interface {
+		closableLayer
+		Consume(<-chan types.Transaction) error
+		TransactionChan() <-chan types.Transaction
+	}
+	chain := func(c consumer) error {
+		layers = append(layers, c)
+		if err := c.Consume(nextTranChan); err != nil {
+			return err
+		}
+		nextTranChan = c.TransactionChan()
+		return nil
+	}
+
+	if len(conf.Input.Processors) > 0 {
+		pipeConf := pipeline.NewConfig()
+		pipeConf.Processors = conf.Input.Processors
+		pipe, err := pipeline.New(pipeConf, mgr, logger.NewModule(".input"), stats)
+		if err != nil {
+			closeAll()
+			return nil, nil, fmt.Errorf("failed to create input processors: %v", err)
+		}
+		if err = chain(pipe); err != nil {
+			closeAll()
+			return nil, nil, err
+		}
+	}
+
+	if conf.Buffer.Type != buffer.TypeNone {
+		buf, err := buffer.New(conf.Buffer, mgr, logger.NewModule(".buffer"), stats)
+		if err != nil {
+			closeAll()
+			return nil, nil, fmt.Errorf("failed to create buffer: %v", err)
+		}
+		if err = chain(buf); err != nil {
+			closeAll()
+			return nil, nil, err
+		}
+	}
+
+	if len(conf.Pipeline.Processors) > 0 {
+		pipe, err := pipeline.New(conf.Pipeline, mgr, logger.NewModule(".pipeline"), stats)
+		if err != nil {
+			closeAll()
+			return nil, nil, fmt.Errorf("failed to create pipeline: %v", err)
+		}
+		if err = chain(pipe); err != nil {
+			closeAll()
+			return nil, nil, err
+		}
+	}
+
+	out, err := output.New(conf.Output, mgr, logger.NewModule(".output"), stats)
+	if err != nil {
+		closeAll()
+		return nil, nil, fmt.Errorf("failed to create output: %v", err)
+	}
+	layers = append(layers, out)
+	if err = out.Consume(nextTranChan); err != nil {
+		closeAll()
+		return nil, nil, err
+	}
+	return out, layers, nil
+}
+
+func (c *Case) executeStream(provider StreamProvider) (failures []CaseFailure, err error) {
+	conf, logger, err := provider.ProvideStream(c.Environment, c.Mocks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialise stream: %v", err)
+	}
+
+	reportFailure := func(reason string) {
+		failures = append(failures, CaseFailure{
+			Name:     c.Name,
+			TestLine: c.line,
+			Reason:   reason,
+		})
+	}
+
+	sinks := map[string]string{}
+	if err = captureOutputs("/output", &conf.Output, sinks); err != nil {
+		return nil, fmt.Errorf("failed to initialise stream: %v", err)
+	}
+	for k, v := range conf.Manager.Outputs {
+		v := v
+		if err = captureOutputs("/resources/outputs/"+k, &v, sinks); err != nil {
+			return nil, fmt.Errorf("failed to initialise stream: %v", err)
+		}
+		conf.Manager.Outputs[k] = v
+	}
+
+	sinkPaths := make([]string, 0, len(sinks))
+	for k := range sinks {
+		sinkPaths = append(sinkPaths, k)
+	}
+	sort.Strings(sinkPaths)
+
+	for k := range c.Outputs {
+		if _, exists := sinks[k]; !exists {
+			reportFailure(fmt.Sprintf("output '%v' was not found, outputs that can be targeted are: %v", k, strings.Join(sinkPaths, ", ")))
+		}
+	}
+	if len(failures) > 0 {
+		return
+	}
+
+	mgr, err := manager.New(conf.Manager, types.NoopMgr(), logger, metrics.Noop())
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialise resources: %v", err)
+	}
+	defer func() {
+		mgr.CloseAsync()
+		if err := mgr.WaitForClose(streamTestTimeout); err != nil {
+			logger.Warnf("Failed to close test resources cleanly: %v\n", err)
+		}
+	}()
+
+	captured := captureBatches(mgr, sinks)
+
+	feed := &feedInput{tranChan: make(chan types.Transaction)}
+	out, layers, err := buildStream(conf, feed, mgr, logger)
+	if err != nil {
+		captured.Close()
+		return nil, fmt.Errorf("failed to initialise stream: %v", err)
+	}
+
+	// Batches are all sent before waiting for responses, as batching policies
+	// might hold on to messages until the input closes.
+	resChans := make([]chan types.Response, 0, len(c.InputBatches))
+	deadline := time.After(streamTestTimeout)
+sendLoop:
+	for i, inBatch := range c.InputBatches {
+		resChan := make(chan types.Response, 1)
+		select {
+		case feed.tranChan <- types.NewTransaction(newInputMessage(inBatch), resChan):
+			resChans = append(resChans, resChan)
+		case <-deadline:
+			reportFailure(fmt.Sprintf("timed out sending input batch %v", i))
+			break sendLoop
+		}
+	}
+	close(feed.tranChan)
+
+	if werr := out.WaitForClose(streamTestTimeout); werr != nil {
+		reportFailure(fmt.Sprintf("stream failed to shut down within %v, messages might be stuck within the stream: %v", streamTestTimeout, werr))
+		for _, l := range layers {
+			l.CloseAsync()
+		}
+	}
+	batches := captured.Close()
+
+	for i, resChan := range resChans {
+		select {
+		case res := <-resChan:
+			if res.Error() != nil {
+				reportFailure(fmt.Sprintf("input batch %v was rejected: %v", i, res.Error()))
+			}
+		default:
+			reportFailure(fmt.Sprintf("input batch %v was not acknowledged", i))
+		}
+	}
+
+	for _, path := range sinkPaths {
+		path := path
+		expected, exists := c.Outputs[path]
+		if !exists && len(batches[path]) == 0 {
+			continue
+		}
+		checkOutputBatches(expected, batches[path], func(reason string) {
+			reportFailure(fmt.Sprintf("output '%v': %v", path, reason))
+		})
+	}
+	return
+}
+
+//------------------------------------------------------------------------------
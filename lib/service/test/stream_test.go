@@ -0,0 +1,159 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/output"
+	"github.com/fatih/color"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	yaml "gopkg.in/yaml.v3"
+)
+
+func TestCaptureOutputs(t *testing.T) {
+	conf := output.NewConfig()
+	require.NoError(t, yaml.Unmarshal([]byte(`
+switch:
+  cases:
+    - check: this.foo == "bar"
+      output:
+        broker:
+          outputs:
+            - kafka:
+                addresses: [ localhost:9092 ]
+                topic: foo
+                batching:
+                  count: 10
+            - retry:
+                output:
+                  http_client:
+                    url: http://localhost:4195
+    - output:
+        try:
+          - reject: nope
+          - resource: foo
+          - drop: {}
+            processors:
+              - bloblang: 'root = "dropped"'
+`), &conf))
+
+	sinks := map[string]string{}
+	require.NoError(t, captureOutputs("/output", &conf, sinks))
+
+	var paths []string
+	for k := range sinks {
+		paths = append(paths, k)
+	}
+	sort.Strings(paths)
+	assert.Equal(t, []string{
+		"/output/switch/cases/0/output/broker/outputs/0",
+		"/output/switch/cases/0/output/broker/outputs/1/retry/output",
+		"/output/switch/cases/1/output/try/2",
+	}, paths)
+
+	kafkaConf := conf.Switch.Cases[0].Output.Broker.Outputs[0]
+	assert.Equal(t, output.TypeBroker, kafkaConf.Type)
+	assert.Equal(t, 10, kafkaConf.Broker.Batching.Count)
+	assert.Equal(t, output.TypeInproc, kafkaConf.Broker.Outputs[0].Type)
+
+	assert.Equal(t, output.TypeInproc, conf.Switch.Cases[0].Output.Broker.Outputs[1].Retry.Output.Type)
+
+	tryConf := conf.Switch.Cases[1].Output.Try
+	assert.Equal(t, output.TypeReject, tryConf[0].Type)
+	assert.Equal(t, output.TypeResource, tryConf[1].Type)
+	assert.Equal(t, output.TypeInproc, tryConf[2].Type)
+	require.Len(t, tryConf[2].Processors, 1)
+}
+
+func TestStreamCases(t *testing.T) {
+	color.NoColor = true
+
+	testDir, err := initTestFiles(map[string]string{
+		"config1.yaml": `
+input:
+  kafka:
+    addresses: [ localhost:1 ]
+    topics: [ foo ]
+    consumer_group: foo
+    batching:
+      count: 2
+  processors:
+    - bloblang: 'meta source = "kafka"'
+
+pipeline:
+  processors:
+    - bloblang: 'root.type = this.type.uppercase()'
+
+output:
+  switch:
+    max_in_flight: 10
+    cases:
+      - check: this.type == "A"
+        output:
+          http_client:
+            url: http://localhost:1
+            batching:
+              count: 2
+      - output:
+          resource: fallback
+
+resources:
+  outputs:
+    fallback:
+      http_client:
+        url: http://localhost:1
+`,
+		"config1_benthos_test.yaml": `
+tests:
+  - name: routes correctly
+    input_batches:
+      - - content: '{"type":"a"}'
+        - content: '{"type":"b"}'
+      - - content: '{"type":"a"}'
+    outputs:
+      /output/switch/cases/0/output:
+        - - json_equals: { "type": "A" }
+            metadata_equals:
+              source: kafka
+          - json_equals: { "type": "A" }
+      /resources/outputs/fallback:
+        - - json_equals: { "type": "B" }
+  - name: routes incorrectly
+    input_batches:
+      - - content: '{"type":"b"}'
+    outputs:
+      /output/switch/cases/0/output:
+        - - json_equals: { "type": "B" }
+  - name: unknown output
+    input_batches:
+      - - content: '{"type":"b"}'
+    outputs:
+      /output/nope:
+        - - content_equals: nope
+`,
+	})
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	def, err := getDefinition(filepath.Join(testDir, "config1.yaml"), filepath.Join(testDir, "config1_benthos_test.yaml"))
+	require.NoError(t, err)
+
+	for _, parallel := range []bool{false, true} {
+		def.Parallel = parallel
+		failures, err := def.Execute(filepath.Join(testDir, "config1.yaml"))
+		require.NoError(t, err)
+
+		var reasons []string
+		for _, f := range failures {
+			reasons = append(reasons, f.Name+": "+f.Reason)
+		}
+		assert.Equal(t, []string{
+			"routes incorrectly: output '/output/switch/cases/0/output': wrong batch count, expected 1, got 0",
+			`routes incorrectly: output '/resources/outputs/fallback': unexpected batch: [{"type":"B"}]`,
+			"unknown output: output '/output/nope' was not found, outputs that can be targeted are: /output/switch/cases/0/output, /resources/outputs/fallback",
+		}, reasons)
+	}
+}
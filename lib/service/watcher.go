@@ -0,0 +1,237 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/config"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/manager"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/stream"
+	"github.com/fsnotify/fsnotify"
+)
+
+//------------------------------------------------------------------------------
+
+// The period of time to wait after a file change before triggering a reload,
+// as editors often write files in several steps.
+var watcherDebounce = time.Millisecond * 200
+
+// configWatcher watches a set of files for changes and calls a closure once
+// they have settled.
+type configWatcher struct {
+	watcher  *fsnotify.Watcher
+	paths    map[string]struct{}
+	onChange func()
+	log      log.Modular
+
+	closeChan  chan struct{}
+	closedChan chan struct{}
+}
+
+func newConfigWatcher(paths []string, log log.Modular, onChange func()) (*configWatcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &configWatcher{
+		watcher:    watcher,
+		paths:      map[string]struct{}{},
+		onChange:   onChange,
+		log:        log,
+		closeChan:  make(chan struct{}),
+		closedChan: make(chan struct{}),
+	}
+
+	// Directories are watched rather than the files themselves so that files
+	// replaced by editors with a rename are still detected.
+	dirs := map[string]struct{}{}
+	for _, p := range paths {
+		absPath, err := filepath.Abs(p)
+		if err != nil {
+			watcher.Close()
+			return nil, err
+		}
+		w.paths[absPath] = struct{}{}
+		dirs[filepath.Dir(absPath)] = struct{}{}
+	}
+	for dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return nil, fmt.Errorf("failed to watch directory '%v': %w", dir, err)
+		}
+	}
+
+	go w.loop()
+	return w, nil
+}
+
+func (w *configWatcher) loop() {
+	defer close(w.closedChan)
+
+	var debounce <-chan time.Time
+	for {
+		select {
+		case event, open := <-w.watcher.Events:
+			if !open {
+				return
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+				continue
+			}
+			if _, exists := w.paths[filepath.Clean(event.Name)]; !exists {
+				continue
+			}
+			w.log.Debugf("Detected change to file: %v\n", event.Name)
+			debounce = time.After(watcherDebounce)
+		case err, open := <-w.watcher.Errors:
+			if !open {
+				return
+			}
+			w.log.Errorf("Config watcher error: %v\n", err)
+		case <-debounce:
+			debounce = nil
+			w.onChange()
+		case <-w.closeChan:
+			return
+		}
+	}
+}
+
+// Close stops watching files and blocks until any ongoing call of the change
+// closure has returned.
+func (w *configWatcher) Close() {
+	close(w.closeChan)
+	<-w.closedChan
+	w.watcher.Close()
+}
+
+//------------------------------------------------------------------------------
+
+// streamReloader reads the config of a service again and applies the changes to
+// a running stream, replacing the resources of the service when they change.
+type streamReloader struct {
+	confPath         string
+	resourcesPaths   []string
+	overrideLogLevel string
+	strict           bool
+	timeout          time.Duration
+
+	apiReg manager.APIReg
+	logger log.Modular
+	stats  metrics.Type
+	strm   *stream.Type
+
+	mut  sync.Mutex
+	conf config.Type
+	mgr  *manager.Type
+}
+
+// Manager returns the current resource manager of the service.
+func (r *streamReloader) Manager() *manager.Type {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	return r.mgr
+}
+
+// readConfig reads the config of the service from its files. The config of a
+// running service cannot be used for comparisons as components are permitted
+// to modify it.
+func (r *streamReloader) readConfig() (config.Type, []string, error) {
+	newConf := newDefaultConfig()
+	lints, err := readConfigInto(r.confPath, r.resourcesPaths, &newConf)
+	if err != nil {
+		return newConf, nil, err
+	}
+	if len(r.overrideLogLevel) > 0 {
+		newConf.Logger.LogLevel = strings.ToUpper(r.overrideLogLevel)
+	}
+	return newConf, lints, nil
+}
+
+// Reload reads the config of the service and applies any changes to the running
+// stream. If the new config is invalid or fails to start the previous config
+// remains active and an error is returned.
+func (r *streamReloader) Reload() error {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	newConf, lints, err := r.readConfig()
+	if err != nil {
+		return err
+	}
+	if len(lints) > 0 {
+		lintlog := r.logger.NewModule(".linter")
+		for _, lint := range lints {
+			lintlog.Infoln(lint)
+		}
+		if r.strict {
+			return errors.New("config contains linter errors, to apply it regardless run Benthos with --chilled")
+		}
+	}
+
+	var ignored []string
+	if !reflect.DeepEqual(newConf.HTTP, r.conf.HTTP) {
+		ignored = append(ignored, "http")
+	}
+	if !reflect.DeepEqual(newConf.Logger, r.conf.Logger) {
+		ignored = append(ignored, "logger")
+	}
+	if !reflect.DeepEqual(newConf.Metrics, r.conf.Metrics) {
+		ignored = append(ignored, "metrics")
+	}
+	if !reflect.DeepEqual(newConf.Tracer, r.conf.Tracer) {
+		ignored = append(ignored, "tracer")
+	}
+	if newConf.SystemCloseTimeout != r.conf.SystemCloseTimeout {
+		ignored = append(ignored, "shutdown_timeout")
+	}
+	if len(ignored) > 0 {
+		r.logger.Warnf("Changes to the following sections require a restart and have been ignored: %v\n", strings.Join(ignored, ", "))
+	}
+
+	resourcesChanged := !reflect.DeepEqual(newConf.Manager, r.conf.Manager)
+	if !resourcesChanged && reflect.DeepEqual(newConf.Config, r.conf.Config) {
+		r.logger.Infoln("Config has not changed, skipping reload.")
+		return nil
+	}
+
+	mgr := r.mgr
+	if resourcesChanged {
+		if mgr, err = manager.New(newConf.Manager, r.apiReg, r.logger, r.stats); err != nil {
+			return fmt.Errorf("failed to create resources: %w", err)
+		}
+		if err = onManagerInit(mgr, r.logger, r.stats); err != nil {
+			mgr.CloseAsync()
+			return fmt.Errorf("failed to initialise manager: %w", err)
+		}
+	}
+
+	if err = r.strm.Reload(newConf.Config, mgr, r.timeout); err != nil {
+		if mgr != r.mgr {
+			mgr.CloseAsync()
+		}
+		return err
+	}
+
+	if mgr != r.mgr {
+		r.mgr.CloseAsync()
+		if err = r.mgr.WaitForClose(r.timeout); err != nil {
+			r.logger.Warnf("Previous resources failed to close cleanly: %v\n", err)
+		}
+		r.mgr = mgr
+	}
+	r.conf = newConf
+
+	r.logger.Infoln("Config reloaded successfully.")
+	return nil
+}
+
+//------------------------------------------------------------------------------
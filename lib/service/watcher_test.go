@@ -0,0 +1,226 @@
+package service
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/manager"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/stream"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func tmpWatchDir(t *testing.T) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "benthos_watcher_test")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+	return dir
+}
+
+func TestConfigWatcher(t *testing.T) {
+	dir := tmpWatchDir(t)
+
+	confPath := filepath.Join(dir, "config.yaml")
+	resPath := filepath.Join(dir, "resources.yaml")
+	otherPath := filepath.Join(dir, "other.yaml")
+	for _, p := range []string{confPath, resPath, otherPath} {
+		require.NoError(t, ioutil.WriteFile(p, []byte("foo"), 0644))
+	}
+
+	var changes int32
+	w, err := newConfigWatcher([]string{confPath, resPath}, log.Noop(), func() {
+		atomic.AddInt32(&changes, 1)
+	})
+	require.NoError(t, err)
+	defer w.Close()
+
+	// Several writes in quick succession result in a single change.
+	for i := 0; i < 3; i++ {
+		require.NoError(t, ioutil.WriteFile(confPath, []byte("bar"), 0644))
+	}
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&changes) == 1
+	}, time.Second*5, time.Millisecond*10)
+
+	// Files replaced via a rename are detected.
+	tmpPath := filepath.Join(dir, ".resources.yaml.tmp")
+	require.NoError(t, ioutil.WriteFile(tmpPath, []byte("baz"), 0644))
+	require.NoError(t, os.Rename(tmpPath, resPath))
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&changes) == 2
+	}, time.Second*5, time.Millisecond*10)
+
+	// Other files are ignored.
+	require.NoError(t, ioutil.WriteFile(otherPath, []byte("bar"), 0644))
+	<-time.After(watcherDebounce * 2)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&changes))
+}
+
+// drainInproc continuously reads from an inproc pipe of the manager returned by
+// a closure, and returns a func for obtaining the most recent message.
+func drainInproc(t *testing.T, getMgr func() *manager.Type, pipe string) func() string {
+	t.Helper()
+
+	var latest atomic.Value
+	latest.Store("")
+
+	done := make(chan struct{})
+	t.Cleanup(func() {
+		close(done)
+	})
+
+	go func() {
+		for {
+			tChan, err := getMgr().GetPipe(pipe)
+			if err != nil {
+				select {
+				case <-time.After(time.Millisecond * 10):
+					continue
+				case <-done:
+					return
+				}
+			}
+		readLoop:
+			for {
+				select {
+				case tran, open := <-tChan:
+					if !open {
+						break readLoop
+					}
+					latest.Store(string(tran.Payload.Get(0).Get()))
+					select {
+					case tran.ResponseChan <- response.NewAck():
+					case <-done:
+						return
+					}
+				case <-done:
+					return
+				}
+			}
+		}
+	}()
+
+	return func() string {
+		return latest.Load().(string)
+	}
+}
+
+func TestStreamReloader(t *testing.T) {
+	dir := tmpWatchDir(t)
+	confPath := filepath.Join(dir, "config.yaml")
+	resPath := filepath.Join(dir, "resources.yaml")
+
+	writeConf := func(mapping string) {
+		require.NoError(t, ioutil.WriteFile(confPath, []byte(`
+input:
+  generate:
+    mapping: 'root = "hello"'
+    interval: 1ms
+pipeline:
+  processors:
+    - bloblang: '`+mapping+`'
+output:
+  inproc: out
+`), 0644))
+	}
+	writeConf(`root = content().uppercase()`)
+	require.NoError(t, ioutil.WriteFile(resPath, []byte(`
+resources:
+  caches:
+    foo:
+      memory: {}
+`), 0644))
+
+	r := &streamReloader{
+		confPath:       confPath,
+		resourcesPaths: []string{resPath},
+		strict:         true,
+		timeout:        time.Second * 5,
+		apiReg:         types.NoopMgr(),
+		logger:         log.Noop(),
+		stats:          metrics.Noop(),
+	}
+
+	var err error
+	r.conf, _, err = r.readConfig()
+	require.NoError(t, err)
+
+	r.mgr, err = manager.New(r.conf.Manager, types.NoopMgr(), log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	r.strm, err = stream.New(r.conf.Config, stream.OptSetManager(r.mgr), stream.OptEnableReload())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, r.strm.Stop(time.Second*5))
+		r.Manager().CloseAsync()
+		assert.NoError(t, r.Manager().WaitForClose(time.Second*5))
+	})
+
+	// The manager is tracked separately as reading it from the reloader blocks
+	// during reloads, which rely on the output being drained.
+	var curMgr atomic.Value
+	curMgr.Store(r.mgr)
+	latest := drainInproc(t, func() *manager.Type {
+		return curMgr.Load().(*manager.Type)
+	}, "out")
+
+	assert.Eventually(t, func() bool {
+		return latest() == "HELLO"
+	}, time.Second*5, time.Millisecond*10)
+
+	// A change to the pipeline keeps the resources.
+	writeConf(`root = content() + " v2"`)
+	require.NoError(t, r.Reload())
+	initMgr := r.Manager()
+	assert.Eventually(t, func() bool {
+		return latest() == "hello v2"
+	}, time.Second*5, time.Millisecond*10)
+
+	// Configs with linting errors are rejected in strict mode.
+	writeConf(`root = content() + " v3"`)
+	require.NoError(t, ioutil.WriteFile(confPath, append(mustReadFile(t, confPath), []byte("nope: true\n")...), 0644))
+	assert.Error(t, r.Reload())
+
+	// Configs that fail to start are rejected.
+	writeConf(`root = nope(`)
+	assert.Error(t, r.Reload())
+	assert.Equal(t, initMgr, r.Manager())
+	<-time.After(time.Millisecond * 50)
+	assert.Equal(t, "hello v2", latest())
+
+	// A change to the resources replaces them.
+	writeConf(`root = content() + " v4"`)
+	require.NoError(t, ioutil.WriteFile(resPath, []byte(`
+resources:
+  caches:
+    foo:
+      memory:
+        ttl: 10
+`), 0644))
+	require.NoError(t, r.Reload())
+	assert.NotEqual(t, initMgr, r.Manager())
+	curMgr.Store(r.Manager())
+	assert.Eventually(t, func() bool {
+		return latest() == "hello v4"
+	}, time.Second*5, time.Millisecond*10)
+}
+
+func mustReadFile(t *testing.T, path string) []byte {
+	t.Helper()
+
+	b, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	return b
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -70,19 +71,28 @@ func (m *Type) HandleStreamsCRUD(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 
+	if r.Method != "GET" {
+		m.apiWriteLock.Lock()
+		defer m.apiWriteLock.Unlock()
+	}
+
 	type confInfo struct {
 		Active    bool    `json:"active"`
 		Uptime    float64 `json:"uptime"`
 		UptimeStr string  `json:"uptime_str"`
+		Revision  int64   `json:"revision"`
 	}
 	infos := map[string]confInfo{}
+	prevConfs := map[string]stream.Config{}
 
 	m.lock.Lock()
 	for id, strInfo := range m.streams {
+		prevConfs[id] = strInfo.Config()
 		infos[id] = confInfo{
 			Active:    strInfo.IsRunning(),
 			Uptime:    strInfo.Uptime().Seconds(),
 			UptimeStr: strInfo.Uptime().String(),
+			Revision:  strInfo.Revision(),
 		}
 	}
 	m.lock.Unlock()
@@ -112,19 +122,19 @@ func (m *Type) HandleStreamsCRUD(w http.ResponseWriter, r *http.Request) {
 	}
 
 	toDelete := []string{}
-	toUpdate := map[string]stream.Config{}
-	toCreate := map[string]stream.Config{}
+	toUpdate := []string{}
+	toCreate := []string{}
 
 	for id := range infos {
-		if newConf, exists := newSet[id]; !exists {
+		if _, exists := newSet[id]; !exists {
 			toDelete = append(toDelete, id)
 		} else {
-			toUpdate[id] = newConf
+			toUpdate = append(toUpdate, id)
 		}
 	}
-	for id, conf := range newSet {
+	for id := range newSet {
 		if _, exists := infos[id]; !exists {
-			toCreate[id] = conf
+			toCreate = append(toCreate, id)
 		}
 	}
 
@@ -148,46 +158,50 @@ func (m *Type) HandleStreamsCRUD(w http.ResponseWriter, r *http.Request) {
 			wg.Done()
 		}(id, i)
 	}
-	i := 0
-	for id, conf := range toUpdate {
-		newConf := conf
-		go func(sid string, sconf *stream.Config, j int) {
-			errUpdate[j] = m.Update(sid, *sconf, time.Until(deadline))
+	for i, id := range toUpdate {
+		go func(sid string, sconf stream.Config, j int) {
+			errUpdate[j] = m.Update(sid, sconf, time.Until(deadline))
 			wg.Done()
-		}(id, &newConf, i)
-		i++
-	}
-	i = 0
-	for id, conf := range toCreate {
-		newConf := conf
-		go func(sid string, sconf *stream.Config, j int) {
-			errCreate[j] = m.Create(sid, *sconf)
+		}(id, newSet[id], i)
+	}
+	for i, id := range toCreate {
+		go func(sid string, sconf stream.Config, j int) {
+			errCreate[j] = m.Create(sid, sconf)
 			wg.Done()
-		}(id, &newConf, i)
-		i++
+		}(id, newSet[id], i)
 	}
 
 	wg.Wait()
 
 	errs := []string{}
-	for _, err := range errDelete {
+	persistErrs := []string{}
+	for i, err := range errDelete {
 		if err != nil {
 			errs = append(errs, fmt.Sprintf("failed to delete stream: %v", err))
+		} else if err = m.unpersist(toDelete[i]); err != nil {
+			persistErrs = append(persistErrs, err.Error())
 		}
 	}
-	for _, err := range errUpdate {
+	for i, err := range errUpdate {
+		id := toUpdate[i]
 		if err != nil {
 			errs = append(errs, fmt.Sprintf("failed to update stream: %v", err))
+		} else if err = m.persistUpdated(id, prevConfs[id], infos[id].Revision, time.Until(deadline)); err != nil {
+			persistErrs = append(persistErrs, err.Error())
 		}
 	}
-	for _, err := range errCreate {
+	for i, err := range errCreate {
 		if err != nil {
 			errs = append(errs, fmt.Sprintf("failed to create stream: %v", err))
+		} else if err = m.persistCreated(toCreate[i], time.Until(deadline)); err != nil {
+			persistErrs = append(persistErrs, err.Error())
 		}
 	}
 
 	if len(errs) > 0 {
 		requestErr = errors.New(strings.Join(errs, "\n"))
+	} else if len(persistErrs) > 0 {
+		serverErr = errors.New(strings.Join(persistErrs, "\n"))
 	}
 }
 
@@ -268,18 +282,70 @@ func (m *Type) HandleStreamCRUD(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Checks the If-Match header of the request, if present, against the
+	// current revision of the stream.
+	checkRevision := func() error {
+		ifMatch := r.Header.Get("If-Match")
+		if len(ifMatch) == 0 {
+			return nil
+		}
+		info, err := m.Read(id)
+		if err != nil {
+			return err
+		}
+		if !matchesRevision(ifMatch, info.Revision()) {
+			return ErrRevisionMismatch
+		}
+		return nil
+	}
+	setETag := func() {
+		if info, err := m.Read(id); err == nil {
+			w.Header().Set("ETag", revisionETag(info.Revision()))
+		}
+	}
+
 	deadline, hasDeadline := r.Context().Deadline()
 	if !hasDeadline {
 		deadline = time.Now().Add(m.apiTimeout)
 	}
 
+	createAndPersist := func(conf stream.Config) error {
+		if err := m.Create(id, conf); err != nil {
+			return err
+		}
+		if err := m.persistCreated(id, time.Until(deadline)); err != nil {
+			return err
+		}
+		setETag()
+		return nil
+	}
+	updateAndPersist := func(conf stream.Config) error {
+		prev, err := m.Read(id)
+		if err != nil {
+			return err
+		}
+		if err = m.Update(id, conf, time.Until(deadline)); err != nil {
+			return err
+		}
+		if err = m.persistUpdated(id, prev.Config(), prev.Revision(), time.Until(deadline)); err != nil {
+			return err
+		}
+		setETag()
+		return nil
+	}
+
+	if r.Method != "GET" {
+		m.apiWriteLock.Lock()
+		defer m.apiWriteLock.Unlock()
+	}
+
 	var conf stream.Config
 	switch r.Method {
 	case "POST":
 		if conf, requestErr = readConfig(); requestErr != nil {
 			return
 		}
-		serverErr = m.Create(id, conf)
+		serverErr = createAndPersist(conf)
 	case "GET":
 		var info *StreamStatus
 		if info, serverErr = m.Read(id); serverErr == nil {
@@ -290,33 +356,47 @@ func (m *Type) HandleStreamCRUD(w http.ResponseWriter, r *http.Request) {
 				Active    bool        `json:"active"`
 				Uptime    float64     `json:"uptime"`
 				UptimeStr string      `json:"uptime_str"`
+				Revision  int64       `json:"revision"`
 				Config    interface{} `json:"config"`
 			}{
 				Active:    info.IsRunning(),
 				Uptime:    info.Uptime().Seconds(),
 				UptimeStr: info.Uptime().String(),
+				Revision:  info.Revision(),
 				Config:    sanit,
 			}); serverErr != nil {
 				return
 			}
 
 			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("ETag", revisionETag(info.Revision()))
 			w.Write(bodyBytes)
 		}
 	case "PUT":
 		if conf, requestErr = readConfig(); requestErr != nil {
 			return
 		}
-		serverErr = m.Update(id, conf, time.Until(deadline))
+		if serverErr = checkRevision(); serverErr != nil {
+			break
+		}
+		serverErr = updateAndPersist(conf)
 	case "DELETE":
-		serverErr = m.Delete(id, time.Until(deadline))
+		if serverErr = checkRevision(); serverErr != nil {
+			break
+		}
+		if serverErr = m.Delete(id, time.Until(deadline)); serverErr == nil {
+			serverErr = m.unpersist(id)
+		}
 	case "PATCH":
+		if serverErr = checkRevision(); serverErr != nil {
+			break
+		}
 		var info *StreamStatus
 		if info, serverErr = m.Read(id); serverErr == nil {
 			if conf, requestErr = patchConfig(info.Config()); requestErr != nil {
 				return
 			}
-			serverErr = m.Update(id, conf, time.Until(deadline))
+			serverErr = updateAndPersist(conf)
 		}
 	default:
 		requestErr = fmt.Errorf("verb not supported: %v", r.Method)
@@ -330,6 +410,57 @@ func (m *Type) HandleStreamCRUD(w http.ResponseWriter, r *http.Request) {
 		serverErr = nil
 		http.Error(w, "Stream already exists", http.StatusBadRequest)
 	}
+	if serverErr == ErrRevisionMismatch {
+		serverErr = nil
+		http.Error(w, "Stream revision does not match", http.StatusPreconditionFailed)
+	}
+}
+
+// persistCreated writes a stream created via the API to the store, deleting the
+// stream again if it cannot be persisted so that a failed request leaves both
+// the running streams and the store unchanged.
+func (m *Type) persistCreated(id string, timeout time.Duration) error {
+	err := m.persist(id)
+	if err == nil {
+		return nil
+	}
+	if rerr := m.Delete(id, timeout); rerr != nil {
+		return fmt.Errorf("%v, and failed to roll back stream: %v", err, rerr)
+	}
+	return err
+}
+
+// persistUpdated writes a stream updated via the API to the store, restoring
+// the previous config and revision of the stream if it cannot be persisted.
+func (m *Type) persistUpdated(id string, prevConf stream.Config, prevRevision int64, timeout time.Duration) error {
+	err := m.persist(id)
+	if err == nil {
+		return nil
+	}
+	if cur, rerr := m.Read(id); rerr == nil && cur.Revision() == prevRevision {
+		return err
+	}
+	if rerr := m.restore(id, prevConf, prevRevision, timeout); rerr != nil {
+		return fmt.Errorf("%v, and failed to roll back stream: %v", err, rerr)
+	}
+	return err
+}
+
+// revisionETag formats a stream revision as an ETag header value.
+func revisionETag(revision int64) string {
+	return strconv.Quote(strconv.FormatInt(revision, 10))
+}
+
+// matchesRevision returns whether the value of an If-Match header, which may
+// contain a list of ETags, matches the revision of a stream.
+func matchesRevision(ifMatch string, revision int64) bool {
+	for _, tag := range strings.Split(ifMatch, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || strings.Trim(tag, `"`) == strconv.FormatInt(revision, 10) {
+			return true
+		}
+	}
+	return false
 }
 
 // HandleStreamStats is an http.HandleFunc for obtaining metrics for a stream.
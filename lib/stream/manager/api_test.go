@@ -3,6 +3,7 @@ package manager
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -11,12 +12,15 @@ import (
 	"testing"
 	"time"
 
+	"github.com/Jeffail/benthos/v3/lib/cache"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/stream"
 	"github.com/Jeffail/benthos/v3/lib/types"
 	"github.com/Jeffail/gabs/v2"
 	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	yaml "gopkg.in/yaml.v3"
 )
 
@@ -626,3 +630,270 @@ func TestTypeAPIGetStats(t *testing.T) {
 		t.Logf("Metrics: %v", stats)
 	}
 }
+
+func TestTypeAPIRevisions(t *testing.T) {
+	mgr := New(
+		OptSetLogger(log.Noop()),
+		OptSetStats(metrics.Noop()),
+		OptSetManager(types.NoopMgr()),
+		OptSetAPITimeout(time.Second*10),
+	)
+	t.Cleanup(func() {
+		assert.NoError(t, mgr.Stop(time.Second*5))
+	})
+
+	r := router(mgr)
+	conf := harmlessConf()
+
+	do := func(req *http.Request, ifMatch string) *httptest.ResponseRecorder {
+		t.Helper()
+		if len(ifMatch) > 0 {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		response := httptest.NewRecorder()
+		r.ServeHTTP(response, req)
+		return response
+	}
+
+	response := do(genRequest("POST", "/streams/foo", conf), "")
+	require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+	assert.Equal(t, `"1"`, response.Header().Get("ETag"))
+
+	response = do(genRequest("GET", "/streams/foo", nil), "")
+	require.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, `"1"`, response.Header().Get("ETag"))
+
+	var info struct {
+		Revision int64 `json:"revision"`
+	}
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &info))
+	assert.Equal(t, int64(1), info.Revision)
+
+	newConf := harmlessConf()
+	newConf.Buffer.Type = "memory"
+
+	response = do(genRequest("PUT", "/streams/foo", newConf), `"2"`)
+	assert.Equal(t, http.StatusPreconditionFailed, response.Code)
+
+	response = do(genRequest("PUT", "/streams/foo", newConf), `"1"`)
+	require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+	assert.Equal(t, `"2"`, response.Header().Get("ETag"))
+
+	// Identical configs do not result in a new revision.
+	response = do(genRequest("PUT", "/streams/foo", newConf), "")
+	require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+	assert.Equal(t, `"2"`, response.Header().Get("ETag"))
+
+	response = do(genRequest("PATCH", "/streams/foo", map[string]interface{}{
+		"buffer": map[string]interface{}{"type": "none"},
+	}), `"1", "3"`)
+	assert.Equal(t, http.StatusPreconditionFailed, response.Code)
+
+	response = do(genRequest("PATCH", "/streams/foo", map[string]interface{}{
+		"buffer": map[string]interface{}{"type": "none"},
+	}), `"1", W/"2"`)
+	require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+	assert.Equal(t, `"3"`, response.Header().Get("ETag"))
+
+	response = do(genRequest("GET", "/streams", nil), "")
+	require.Equal(t, http.StatusOK, response.Code)
+	var list map[string]struct {
+		Revision int64 `json:"revision"`
+	}
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &list))
+	assert.Equal(t, int64(3), list["foo"].Revision)
+
+	response = do(genRequest("DELETE", "/streams/foo", nil), `"2"`)
+	assert.Equal(t, http.StatusPreconditionFailed, response.Code)
+
+	response = do(genRequest("DELETE", "/streams/foo", nil), `*`)
+	require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+
+	response = do(genRequest("DELETE", "/streams/foo", nil), `*`)
+	assert.Equal(t, http.StatusNotFound, response.Code)
+
+	// Revisions are not reused when a stream is created again.
+	response = do(genRequest("POST", "/streams/foo", conf), "")
+	require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+	assert.Equal(t, `"4"`, response.Header().Get("ETag"))
+
+	response = do(genRequest("DELETE", "/streams/foo", nil), `"1"`)
+	assert.Equal(t, http.StatusPreconditionFailed, response.Code)
+}
+
+func TestTypeAPIPersistence(t *testing.T) {
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	store := NewCacheStore(memCache, "")
+
+	newMgr := func() *Type {
+		t.Helper()
+		mgr := New(
+			OptSetLogger(log.Noop()),
+			OptSetStats(metrics.Noop()),
+			OptSetManager(types.NoopMgr()),
+			OptSetAPITimeout(time.Second*10),
+			OptSetStore(store),
+		)
+		t.Cleanup(func() {
+			assert.NoError(t, mgr.Stop(time.Second*5))
+		})
+		return mgr
+	}
+	do := func(mgr *Type, req *http.Request) {
+		t.Helper()
+		response := httptest.NewRecorder()
+		router(mgr).ServeHTTP(response, req)
+		require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+	}
+
+	mgr := newMgr()
+
+	// Streams that are not created via the API are not persisted.
+	require.NoError(t, mgr.Create("static", harmlessConf()))
+
+	conf := harmlessConf()
+	newConf := harmlessConf()
+	newConf.Buffer.Type = "memory"
+
+	do(mgr, genRequest("POST", "/streams/foo", conf))
+	do(mgr, genRequest("POST", "/streams/bar", conf))
+	do(mgr, genRequest("POST", "/streams/baz", conf))
+	do(mgr, genRequest("PUT", "/streams/bar", newConf))
+	do(mgr, genRequest("DELETE", "/streams/baz", nil))
+
+	stored, err := store.List()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]StoredStream{
+		"foo": {Revision: 1, Config: conf},
+		"bar": {Revision: 2, Config: newConf},
+	}, stored)
+
+	mgr = newMgr()
+	ids, err := mgr.LoadFromStore(time.Second * 5)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bar", "foo"}, ids)
+
+	info, err := mgr.Read("bar")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), info.Revision())
+	assert.Equal(t, newConf, info.Config())
+
+	_, err = mgr.Read("static")
+	assert.Equal(t, ErrStreamDoesNotExist, err)
+
+	// Replacing the full set of streams also persists the changes.
+	do(mgr, genYAMLRequest("POST", "/streams", map[string]interface{}{
+		"bar": conf,
+		"qux": conf,
+	}))
+
+	stored, err = store.List()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]StoredStream{
+		"bar": {Revision: 3, Config: conf},
+		"qux": {Revision: 1, Config: conf},
+	}, stored)
+}
+
+type failingStore struct {
+	Store
+	fail bool
+}
+
+func (f *failingStore) Set(id string, strm StoredStream) error {
+	if f.fail {
+		return errors.New("nope")
+	}
+	return f.Store.Set(id, strm)
+}
+
+func TestTypeAPIPersistenceFailure(t *testing.T) {
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	store := &failingStore{Store: NewCacheStore(memCache, "")}
+
+	mgr := New(
+		OptSetLogger(log.Noop()),
+		OptSetStats(metrics.Noop()),
+		OptSetManager(types.NoopMgr()),
+		OptSetAPITimeout(time.Second*10),
+		OptSetStore(store),
+	)
+	t.Cleanup(func() {
+		assert.NoError(t, mgr.Stop(time.Second*5))
+	})
+
+	do := func(req *http.Request) *httptest.ResponseRecorder {
+		t.Helper()
+		response := httptest.NewRecorder()
+		router(mgr).ServeHTTP(response, req)
+		return response
+	}
+
+	conf := harmlessConf()
+	newConf := harmlessConf()
+	newConf.Buffer.Type = "memory"
+
+	// A stream that cannot be persisted is not created.
+	store.fail = true
+	response := do(genRequest("POST", "/streams/foo", conf))
+	assert.Equal(t, http.StatusBadGateway, response.Code)
+	assert.Empty(t, response.Header().Get("ETag"))
+
+	_, err = mgr.Read("foo")
+	assert.Equal(t, ErrStreamDoesNotExist, err)
+
+	store.fail = false
+	response = do(genRequest("POST", "/streams/foo", conf))
+	require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+
+	info, err := mgr.Read("foo")
+	require.NoError(t, err)
+	prevConf := info.Config()
+
+	// An update that cannot be persisted is rolled back to the previous
+	// config and revision.
+	store.fail = true
+	response = do(genRequest("PUT", "/streams/foo", newConf))
+	assert.Equal(t, http.StatusBadGateway, response.Code)
+	assert.Empty(t, response.Header().Get("ETag"))
+
+	response = do(genRequest("PATCH", "/streams/foo", map[string]interface{}{
+		"buffer": map[string]interface{}{"type": "memory"},
+	}))
+	assert.Equal(t, http.StatusBadGateway, response.Code)
+
+	info, err = mgr.Read("foo")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), info.Revision())
+	assert.Equal(t, prevConf, info.Config())
+
+	stored, err := store.List()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]StoredStream{
+		"foo": {Revision: 2, Config: conf},
+	}, stored)
+
+	// The same applies to changes made to the entire set of streams.
+	store.fail = true
+	response = do(genRequest("POST", "/streams", map[string]interface{}{
+		"foo": newConf,
+		"bar": conf,
+	}))
+	assert.Equal(t, http.StatusBadGateway, response.Code)
+
+	_, err = mgr.Read("bar")
+	assert.Equal(t, ErrStreamDoesNotExist, err)
+
+	info, err = mgr.Read("foo")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), info.Revision())
+	assert.Equal(t, prevConf, info.Config())
+
+	stored, err = store.List()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]StoredStream{
+		"foo": {Revision: 2, Config: conf},
+	}, stored)
+}
@@ -0,0 +1,291 @@
+package manager
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/Jeffail/benthos/v3/lib/stream"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	yaml "gopkg.in/yaml.v3"
+)
+
+//------------------------------------------------------------------------------
+
+// StoredStream is a stream config persisted within a Store along with its
+// revision.
+type StoredStream struct {
+	Revision int64
+	Config   stream.Config
+}
+
+// Store is a persistence backend for streams created, updated or deleted via
+// the HTTP API, allowing them to survive restarts.
+type Store interface {
+	// List returns all streams within the store keyed by their IDs.
+	List() (map[string]StoredStream, error)
+
+	// Set writes a stream to the store, replacing any existing version.
+	Set(id string, strm StoredStream) error
+
+	// Delete removes a stream from the store. Deleting a stream that does not
+	// exist is not an error.
+	Delete(id string) error
+}
+
+//------------------------------------------------------------------------------
+
+type storedStreamDoc struct {
+	Revision int64       `yaml:"revision"`
+	Config   interface{} `yaml:"config"`
+}
+
+func marshalStoredStream(strm StoredStream) ([]byte, error) {
+	sanit, err := strm.Config.Sanitised()
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(storedStreamDoc{
+		Revision: strm.Revision,
+		Config:   sanit,
+	})
+}
+
+func unmarshalStoredStream(data []byte) (StoredStream, error) {
+	var doc struct {
+		Revision int64     `yaml:"revision"`
+		Config   yaml.Node `yaml:"config"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return StoredStream{}, err
+	}
+	conf := stream.NewConfig()
+	if err := doc.Config.Decode(&conf); err != nil {
+		return StoredStream{}, err
+	}
+	return StoredStream{
+		Revision: doc.Revision,
+		Config:   conf,
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+type directoryStore struct {
+	dir string
+	mut sync.Mutex
+}
+
+// NewDirectoryStore returns a Store that persists each stream as a YAML file
+// within a directory, named after the stream ID. Files are written atomically
+// by writing to a temporary file and then renaming it.
+func NewDirectoryStore(dir string) (Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create streams store directory: %w", err)
+	}
+	return &directoryStore{dir: dir}, nil
+}
+
+func (d *directoryStore) path(id string) (string, error) {
+	if len(id) == 0 || strings.ContainsAny(id, `/\`) || strings.HasPrefix(id, ".") {
+		return "", fmt.Errorf("stream id '%v' cannot be stored as a file", id)
+	}
+	return filepath.Join(d.dir, id+".yaml"), nil
+}
+
+func (d *directoryStore) List() (map[string]StoredStream, error) {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+
+	infos, err := ioutil.ReadDir(d.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	streams := map[string]StoredStream{}
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() || strings.HasPrefix(name, ".") || filepath.Ext(name) != ".yaml" {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(d.dir, name))
+		if err != nil {
+			return nil, err
+		}
+		strm, err := unmarshalStoredStream(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse stored stream '%v': %w", name, err)
+		}
+		streams[strings.TrimSuffix(name, ".yaml")] = strm
+	}
+	return streams, nil
+}
+
+func (d *directoryStore) Set(id string, strm StoredStream) error {
+	path, err := d.path(id)
+	if err != nil {
+		return err
+	}
+	data, err := marshalStoredStream(strm)
+	if err != nil {
+		return err
+	}
+
+	d.mut.Lock()
+	defer d.mut.Unlock()
+
+	tmp, err := ioutil.TempFile(d.dir, "."+id+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (d *directoryStore) Delete(id string) error {
+	path, err := d.path(id)
+	if err != nil {
+		return err
+	}
+
+	d.mut.Lock()
+	defer d.mut.Unlock()
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+type cacheStore struct {
+	cache  types.Cache
+	prefix string
+	mut    sync.Mutex
+}
+
+// NewCacheStore returns a Store that persists streams within a cache resource.
+// Since caches cannot list their keys an index of stream IDs is stored under
+// the key `<prefix>index`, and each stream is stored under the key
+// `<prefix>stream/<id>`.
+func NewCacheStore(cache types.Cache, prefix string) Store {
+	return &cacheStore{cache: cache, prefix: prefix}
+}
+
+func (c *cacheStore) readIndex() ([]string, error) {
+	data, err := c.cache.Get(c.prefix + "index")
+	if err != nil {
+		if errors.Is(err, types.ErrKeyNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var ids []string
+	if err := json.Unmarshal(data, &ids); err != nil {
+		return nil, fmt.Errorf("failed to parse streams index: %w", err)
+	}
+	return ids, nil
+}
+
+func (c *cacheStore) writeIndex(ids []string) error {
+	data, err := json.Marshal(ids)
+	if err != nil {
+		return err
+	}
+	return c.cache.Set(c.prefix+"index", data)
+}
+
+func (c *cacheStore) List() (map[string]StoredStream, error) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	ids, err := c.readIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	streams := map[string]StoredStream{}
+	for _, id := range ids {
+		data, err := c.cache.Get(c.prefix + "stream/" + id)
+		if err != nil {
+			if errors.Is(err, types.ErrKeyNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		strm, err := unmarshalStoredStream(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse stored stream '%v': %w", id, err)
+		}
+		streams[id] = strm
+	}
+	return streams, nil
+}
+
+func (c *cacheStore) Set(id string, strm StoredStream) error {
+	data, err := marshalStoredStream(strm)
+	if err != nil {
+		return err
+	}
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if err = c.cache.Set(c.prefix+"stream/"+id, data); err != nil {
+		return err
+	}
+
+	ids, err := c.readIndex()
+	if err != nil {
+		return err
+	}
+	for _, existing := range ids {
+		if existing == id {
+			return nil
+		}
+	}
+	return c.writeIndex(append(ids, id))
+}
+
+func (c *cacheStore) Delete(id string) error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	ids, err := c.readIndex()
+	if err != nil {
+		return err
+	}
+	newIDs := make([]string, 0, len(ids))
+	for _, existing := range ids {
+		if existing != id {
+			newIDs = append(newIDs, existing)
+		}
+	}
+	if len(newIDs) != len(ids) {
+		if err = c.writeIndex(newIDs); err != nil {
+			return err
+		}
+	}
+	if err = c.cache.Delete(c.prefix + "stream/" + id); err != nil && !errors.Is(err, types.ErrKeyNotFound) {
+		return err
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,89 @@
+package manager
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/cache"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testStore(t *testing.T, store Store) {
+	t.Helper()
+
+	streams, err := store.List()
+	require.NoError(t, err)
+	assert.Empty(t, streams)
+
+	fooConf := harmlessConf()
+	barConf := harmlessConf()
+	barConf.Buffer.Type = "memory"
+
+	require.NoError(t, store.Set("foo", StoredStream{Revision: 1, Config: fooConf}))
+	require.NoError(t, store.Set("bar", StoredStream{Revision: 1, Config: barConf}))
+	require.NoError(t, store.Set("bar", StoredStream{Revision: 2, Config: barConf}))
+
+	streams, err = store.List()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]StoredStream{
+		"foo": {Revision: 1, Config: fooConf},
+		"bar": {Revision: 2, Config: barConf},
+	}, streams)
+
+	require.NoError(t, store.Delete("foo"))
+	require.NoError(t, store.Delete("foo"))
+	require.NoError(t, store.Delete("baz"))
+
+	streams, err = store.List()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]StoredStream{
+		"bar": {Revision: 2, Config: barConf},
+	}, streams)
+}
+
+func TestDirectoryStore(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "benthos_streams_store_test")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	dir := filepath.Join(tmpDir, "streams")
+	store, err := NewDirectoryStore(dir)
+	require.NoError(t, err)
+
+	// Files that aren't stream configs are ignored.
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "README.md"), []byte("nope"), 0644))
+
+	testStore(t, store)
+
+	infos, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	assert.Equal(t, []string{"README.md", "bar.yaml"}, names)
+
+	assert.Error(t, store.Set("../foo", StoredStream{Revision: 1, Config: harmlessConf()}))
+	assert.Error(t, store.Set(".foo", StoredStream{Revision: 1, Config: harmlessConf()}))
+}
+
+func TestCacheStore(t *testing.T) {
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	testStore(t, NewCacheStore(memCache, "foo/"))
+
+	index, err := memCache.Get("foo/index")
+	require.NoError(t, err)
+	assert.Equal(t, `["bar"]`, string(index))
+
+	_, err = memCache.Get("foo/stream/bar")
+	require.NoError(t, err)
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -24,6 +25,7 @@ type StreamStatus struct {
 	logger       log.Modular
 	metrics      *metrics.Local
 	createdAt    time.Time
+	revision     int64
 }
 
 // NewStreamStatus creates a new StreamStatus.
@@ -39,6 +41,7 @@ func NewStreamStatus(
 		logger:    logger,
 		metrics:   stats,
 		createdAt: time.Now(),
+		revision:  1,
 	}
 }
 
@@ -67,6 +70,13 @@ func (s *StreamStatus) Config() stream.Config {
 	return s.config
 }
 
+// Revision returns the revision of the stream config, which is incremented
+// each time the stream is created or updated. Revisions are never reused for
+// the same stream ID, even when the stream is deleted and created again.
+func (s *StreamStatus) Revision() int64 {
+	return s.revision
+}
+
 // Metrics returns a metrics aggregator of the stream.
 func (s *StreamStatus) Metrics() *metrics.Local {
 	return s.metrics
@@ -96,20 +106,30 @@ type Type struct {
 	closed  bool
 	streams map[string]*StreamStatus
 
+	// The latest revision of each stream ID, retained after a stream is
+	// deleted so that revisions are never reused.
+	revisions map[string]int64
+
 	manager    types.Manager
 	stats      metrics.Type
 	logger     log.Modular
 	apiTimeout time.Duration
+	store      Store
 
 	pipelineProcCtors []StreamProcConstructorFunc
 
 	lock sync.Mutex
+
+	// Serialises modifications made via the HTTP API so that revision checks
+	// and persistence are consistent with the changes applied.
+	apiWriteLock sync.Mutex
 }
 
 // New creates a new stream manager.Type.
 func New(opts ...func(*Type)) *Type {
 	t := &Type{
 		streams:    map[string]*StreamStatus{},
+		revisions:  map[string]int64{},
 		manager:    types.DudMgr{},
 		stats:      metrics.Noop(),
 		apiTimeout: time.Second * 5,
@@ -155,6 +175,15 @@ func OptSetAPITimeout(tout time.Duration) func(*Type) {
 	}
 }
 
+// OptSetStore sets a persistence backend for streams that are created, updated
+// or deleted via the HTTP API. Streams within the store can be restored with
+// LoadFromStore.
+func OptSetStore(store Store) func(*Type) {
+	return func(t *Type) {
+		t.store = store
+	}
+}
+
 // OptAddProcessors adds processor constructors that will be called for every
 // new stream and attached to the processor pipelines. The constructor is given
 // the name of the stream as an argument.
@@ -170,6 +199,7 @@ func OptAddProcessors(procs ...StreamProcConstructorFunc) func(*Type) {
 var (
 	ErrStreamExists       = errors.New("stream already exists")
 	ErrStreamDoesNotExist = errors.New("stream does not exist")
+	ErrRevisionMismatch   = errors.New("stream revision does not match")
 )
 
 //------------------------------------------------------------------------------
@@ -177,6 +207,12 @@ var (
 // Create attempts to construct and run a new stream under a unique ID. If the
 // ID already exists an error is returned.
 func (m *Type) Create(id string, conf stream.Config) error {
+	return m.create(id, conf, 0)
+}
+
+// create constructs and runs a new stream with a given revision, or the next
+// revision of the ID when the revision is zero.
+func (m *Type) create(id string, conf stream.Config, revision int64) error {
 	m.lock.Lock()
 	defer m.lock.Unlock()
 
@@ -215,7 +251,15 @@ func (m *Type) Create(id string, conf stream.Config) error {
 		return err
 	}
 
+	if revision == 0 {
+		revision = m.revisions[id] + 1
+	}
+	if revision > m.revisions[id] {
+		m.revisions[id] = revision
+	}
+
 	wrapper = NewStreamStatus(conf, strm, strmLogger, strmFlatMetrics)
+	wrapper.revision = revision
 	m.streams[id] = wrapper
 	return nil
 }
@@ -239,7 +283,8 @@ func (m *Type) Read(id string) (*StreamStatus, error) {
 }
 
 // Update attempts to stop an existing stream and replace it with a new version
-// of the same stream.
+// of the same stream. The revision of the stream is incremented unless the new
+// config is identical to the existing one.
 func (m *Type) Update(id string, conf stream.Config, timeout time.Duration) error {
 	m.lock.Lock()
 	wrapper, exists := m.streams[id]
@@ -260,7 +305,7 @@ func (m *Type) Update(id string, conf stream.Config, timeout time.Duration) erro
 	if err := m.Delete(id, timeout); err != nil {
 		return err
 	}
-	return m.Create(id, conf)
+	return m.create(id, conf, 0)
 }
 
 // Delete attempts to stop and remove a stream by its ID. Returns an error if
@@ -292,6 +337,81 @@ func (m *Type) Delete(id string, timeout time.Duration) error {
 
 //------------------------------------------------------------------------------
 
+// LoadFromStore creates all streams persisted within the store set with
+// OptSetStore, retaining their revisions, and returns the IDs of the streams
+// created. Persisted streams replace any existing streams of the same ID. If
+// no store has been set this is a no-op.
+func (m *Type) LoadFromStore(timeout time.Duration) ([]string, error) {
+	if m.store == nil {
+		return nil, nil
+	}
+
+	stored, err := m.store.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list persisted streams: %w", err)
+	}
+
+	ids := make([]string, 0, len(stored))
+	for id := range stored {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		if _, err := m.Read(id); err == nil {
+			m.logger.Warnf("Stream '%v' config is being replaced by its persisted version\n", id)
+			if err = m.Delete(id, timeout); err != nil {
+				return nil, fmt.Errorf("failed to replace stream '%v': %w", id, err)
+			}
+		}
+		if err := m.create(id, stored[id].Config, stored[id].Revision); err != nil {
+			return nil, fmt.Errorf("failed to create persisted stream '%v': %w", id, err)
+		}
+	}
+	return ids, nil
+}
+
+// persist writes the current config and revision of a stream to the store, if
+// one is set.
+func (m *Type) persist(id string) error {
+	if m.store == nil {
+		return nil
+	}
+	wrapper, err := m.Read(id)
+	if err != nil {
+		return err
+	}
+	if err = m.store.Set(id, StoredStream{
+		Revision: wrapper.Revision(),
+		Config:   wrapper.Config(),
+	}); err != nil {
+		return fmt.Errorf("failed to persist stream '%v': %w", id, err)
+	}
+	return nil
+}
+
+// restore replaces a stream with a previous config and revision, which is used
+// to roll back changes made via the API that could not be persisted.
+func (m *Type) restore(id string, conf stream.Config, revision int64, timeout time.Duration) error {
+	if err := m.Delete(id, timeout); err != nil {
+		return err
+	}
+	return m.create(id, conf, revision)
+}
+
+// unpersist removes a stream from the store, if one is set.
+func (m *Type) unpersist(id string) error {
+	if m.store == nil {
+		return nil
+	}
+	if err := m.store.Delete(id); err != nil {
+		return fmt.Errorf("failed to remove persisted stream '%v': %w", id, err)
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
 // Stop attempts to gracefully shut down all active streams and close the
 // stream manager.
 func (m *Type) Stop(timeout time.Duration) error {
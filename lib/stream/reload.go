@@ -0,0 +1,390 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/buffer"
+	"github.com/Jeffail/benthos/v3/lib/output"
+	"github.com/Jeffail/benthos/v3/lib/pipeline"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// ErrReloadNotEnabled is returned when attempting to reload a stream that was
+// not created with OptEnableReload.
+var ErrReloadNotEnabled = errors.New("stream was not created with reloads enabled")
+
+//------------------------------------------------------------------------------
+
+// relay forwards transactions from one channel to another, and can be stopped
+// without closing either channel so that the layers either side of it can be
+// swapped.
+type relay struct {
+	src <-chan types.Transaction
+	dst chan types.Transaction
+
+	// A transaction that was read but not yet delivered when the relay was
+	// stopped, which is handed over to the next relay.
+	pending *types.Transaction
+
+	// When set the destination channel is closed once the source channel is
+	// closed, propagating the shut down of a stream.
+	closeDst  int32
+	srcClosed int32
+
+	stopChan chan struct{}
+	killChan chan struct{}
+	doneChan chan struct{}
+}
+
+func startRelay(src <-chan types.Transaction, dst chan types.Transaction, pending *types.Transaction) *relay {
+	r := &relay{
+		src:      src,
+		dst:      dst,
+		pending:  pending,
+		closeDst: 1,
+		stopChan: make(chan struct{}),
+		killChan: make(chan struct{}),
+		doneChan: make(chan struct{}),
+	}
+	go r.loop()
+	return r
+}
+
+func (r *relay) loop() {
+	defer close(r.doneChan)
+	for {
+		var tran types.Transaction
+		if r.pending != nil {
+			tran, r.pending = *r.pending, nil
+		} else {
+			var open bool
+			select {
+			case tran, open = <-r.src:
+				if !open {
+					atomic.StoreInt32(&r.srcClosed, 1)
+					if atomic.LoadInt32(&r.closeDst) == 1 {
+						close(r.dst)
+					}
+					return
+				}
+			case <-r.stopChan:
+				return
+			}
+		}
+		select {
+		case r.dst <- tran:
+		case <-r.stopChan:
+			r.pending = &tran
+			return
+		case <-r.killChan:
+			return
+		}
+	}
+}
+
+// stop the relay without closing the destination channel, and returns any
+// transaction that was read but not yet delivered.
+func (r *relay) stop() *types.Transaction {
+	select {
+	case <-r.stopChan:
+	default:
+		close(r.stopChan)
+	}
+	<-r.doneChan
+	return r.pending
+}
+
+// kill the relay, abandoning any transaction being relayed.
+func (r *relay) kill() {
+	select {
+	case <-r.killChan:
+	default:
+		close(r.killChan)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// middleOut returns the channel of transactions leaving the buffer and pipeline
+// layers of the stream.
+func (t *Type) middleOut() <-chan types.Transaction {
+	if t.pipelineLayer != nil {
+		return t.pipelineLayer.TransactionChan()
+	}
+	if t.bufferLayer != nil {
+		return t.bufferLayer.TransactionChan()
+	}
+	return t.inChan
+}
+
+func (t *Type) consumeMiddle(buf buffer.Type, pipe pipeline.Type) error {
+	t.inChan = make(chan types.Transaction)
+
+	var nextTranChan <-chan types.Transaction = t.inChan
+	if buf != nil {
+		if err := buf.Consume(nextTranChan); err != nil {
+			return err
+		}
+		nextTranChan = buf.TransactionChan()
+	}
+	if pipe != nil {
+		if err := pipe.Consume(nextTranChan); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *Type) consumeOutput(out output.Type) error {
+	t.outChan = make(chan types.Transaction)
+	if err := out.Consume(t.outChan); err != nil {
+		return err
+	}
+
+	// The stream is only considered closed when an output closes without
+	// having been replaced.
+	replaced := make(chan struct{})
+	t.outReplace = replaced
+	go func() {
+		for {
+			if err := out.WaitForClose(time.Second); err == nil {
+				select {
+				case <-replaced:
+				default:
+					t.onClose()
+				}
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+func (t *Type) startRelays() error {
+	if err := t.consumeMiddle(t.bufferLayer, t.pipelineLayer); err != nil {
+		return err
+	}
+	if err := t.consumeOutput(t.outputLayer); err != nil {
+		return err
+	}
+	t.inRelay = startRelay(t.inputLayer.TransactionChan(), t.inChan, nil)
+	t.outRelay = startRelay(t.middleOut(), t.outChan, nil)
+	return nil
+}
+
+func (t *Type) killRelays() {
+	t.inRelay.kill()
+	t.outRelay.kill()
+}
+
+// shutdown closes all layers of a stream after a reload has failed at a point
+// where the previous layers have already been drained and can no longer be
+// restored. The stream is reported as closed and further reloads are rejected.
+func (t *Type) shutdown() {
+	t.stopped = true
+	t.killRelays()
+
+	t.layersMut.Lock()
+	t.inputLayer.CloseAsync()
+	if t.bufferLayer != nil {
+		t.bufferLayer.CloseAsync()
+	}
+	if t.pipelineLayer != nil {
+		t.pipelineLayer.CloseAsync()
+	}
+	t.outputLayer.CloseAsync()
+	t.layersMut.Unlock()
+
+	// Prevent the output from also reporting the closure.
+	select {
+	case <-t.outReplace:
+	default:
+		close(t.outReplace)
+	}
+	t.onClose()
+}
+
+//------------------------------------------------------------------------------
+
+// Reload attempts to update a running stream to a new config in place. Only
+// the layers of the stream whose config has changed are replaced, where the
+// buffer and pipeline layers are treated as one. Unchanged inputs and outputs
+// therefore remain connected throughout, and in-flight messages are drained
+// from replaced layers before they are closed.
+//
+// If the provided manager differs from that of the stream then all layers are
+// replaced using the new manager.
+//
+// New layers are constructed before the existing layers are disrupted where
+// possible, and if any new layer fails to be constructed then the stream is
+// rolled back to its previous config and an error is returned. However, if a
+// new layer fails to start after the previous layers have been drained then
+// the stream cannot be rolled back, and is instead shut down before the error
+// is returned.
+func (t *Type) Reload(conf Config, mgr types.Manager, timeout time.Duration) error {
+	if !t.reloadable {
+		return ErrReloadNotEnabled
+	}
+
+	t.reloadMut.Lock()
+	defer t.reloadMut.Unlock()
+
+	if t.stopped {
+		return types.ErrTypeClosed
+	}
+
+	mgrChanged := mgr != t.manager
+	inChanged := mgrChanged || !reflect.DeepEqual(t.conf.Input, conf.Input)
+	midChanged := mgrChanged ||
+		!reflect.DeepEqual(t.conf.Buffer, conf.Buffer) ||
+		!reflect.DeepEqual(t.conf.Pipeline, conf.Pipeline)
+	outChanged := mgrChanged || !reflect.DeepEqual(t.conf.Output, conf.Output)
+	if !inChanged && !midChanged && !outChanged {
+		return nil
+	}
+
+	// Construct the new buffer, pipeline and output layers before disrupting
+	// the stream so that a bad config is rejected without impact. Inputs are
+	// not constructed ahead of time as they begin consuming immediately.
+	var newBuf buffer.Type
+	var newPipe pipeline.Type
+	var newOut output.Type
+	closeNew := func() {
+		if newBuf != nil {
+			newBuf.CloseAsync()
+		}
+		if newPipe != nil {
+			newPipe.CloseAsync()
+		}
+		if newOut != nil {
+			newOut.CloseAsync()
+		}
+	}
+
+	var err error
+	if midChanged {
+		if newBuf, newPipe, err = t.newMiddle(conf, mgr); err != nil {
+			return fmt.Errorf("failed to create new buffer or pipeline: %w", err)
+		}
+	}
+	if outChanged {
+		if newOut, err = t.newOutput(conf, mgr); err != nil {
+			closeNew()
+			return fmt.Errorf("failed to create new output: %w", err)
+		}
+	}
+
+	started := time.Now()
+	remaining := func() time.Duration {
+		return timeout - time.Since(started)
+	}
+
+	// Stop feeding the stream whilst layers are swapped, the input is left to
+	// apply back pressure. Transactions read by relays but not yet delivered
+	// are delivered to the new layers.
+	inPending := t.inRelay.stop()
+	if atomic.LoadInt32(&t.inRelay.srcClosed) == 1 {
+		// The remaining layers are shutting down by proxy, and so the stream
+		// can no longer be reloaded.
+		t.stopped = true
+		closeNew()
+		return errors.New("stream input has closed")
+	}
+	var outPending *types.Transaction
+
+	if inChanged {
+		t.inputLayer.CloseAsync()
+		if err = t.inputLayer.WaitForClose(remaining()); err != nil {
+			t.logger.Warnf("Previous input failed to close cleanly: %v\n", err)
+		}
+
+		newIn, err := t.newInput(conf, mgr)
+		if err != nil {
+			closeNew()
+			oldIn, rerr := t.newInput(t.conf, t.manager)
+			if rerr != nil {
+				// With no input to restore the stream is shut down by proxy.
+				t.stopped = true
+				close(t.inChan)
+				return fmt.Errorf("failed to create new input: %v, and failed to restore previous input: %w", err, rerr)
+			}
+			t.layersMut.Lock()
+			t.inputLayer = oldIn
+			t.layersMut.Unlock()
+			t.inRelay = startRelay(oldIn.TransactionChan(), t.inChan, inPending)
+			return fmt.Errorf("failed to create new input: %w", err)
+		}
+		t.layersMut.Lock()
+		t.inputLayer = newIn
+		t.layersMut.Unlock()
+	}
+
+	if midChanged {
+		// Drain the existing buffer and pipeline into the output.
+		atomic.StoreInt32(&t.outRelay.closeDst, 0)
+		close(t.inChan)
+		select {
+		case <-t.outRelay.doneChan:
+		case <-time.After(remaining()):
+			t.logger.Warnln("Previous buffer and pipeline failed to drain within the target time.")
+			if t.bufferLayer != nil {
+				t.bufferLayer.CloseAsync()
+			}
+			if t.pipelineLayer != nil {
+				t.pipelineLayer.CloseAsync()
+			}
+			outPending = t.outRelay.stop()
+		}
+
+		if err = t.consumeMiddle(newBuf, newPipe); err != nil {
+			closeNew()
+			t.shutdown()
+			return fmt.Errorf("failed to start new buffer or pipeline, stream has been shut down: %w", err)
+		}
+		t.layersMut.Lock()
+		t.bufferLayer, t.pipelineLayer = newBuf, newPipe
+		t.layersMut.Unlock()
+	}
+
+	if outChanged {
+		if !midChanged {
+			outPending = t.outRelay.stop()
+		}
+
+		close(t.outReplace)
+		close(t.outChan)
+		if err = t.outputLayer.WaitForClose(remaining()); err != nil {
+			t.logger.Warnf("Previous output failed to close cleanly: %v\n", err)
+			t.outputLayer.CloseAsync()
+		}
+
+		if err = t.consumeOutput(newOut); err != nil {
+			closeNew()
+			t.shutdown()
+			return fmt.Errorf("failed to start new output, stream has been shut down: %w", err)
+		}
+		t.layersMut.Lock()
+		t.outputLayer = newOut
+		t.layersMut.Unlock()
+	}
+
+	if midChanged || outChanged {
+		t.outRelay = startRelay(t.middleOut(), t.outChan, outPending)
+	}
+	t.inRelay = startRelay(t.inputLayer.TransactionChan(), t.inChan, inPending)
+
+	t.conf = conf
+	if mgrChanged {
+		t.manager = mgr
+		t.registerHealthCheck()
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,399 @@
+package stream
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/input"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/manager"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/output"
+	"github.com/Jeffail/benthos/v3/lib/processor"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type reloadHarness struct {
+	t     *testing.T
+	mgr   *manager.Type
+	in    chan types.Transaction
+	strm  *Type
+	close int32
+}
+
+func newReloadMgr(t *testing.T) *manager.Type {
+	t.Helper()
+
+	mgr, err := manager.New(manager.NewConfig(), types.NoopMgr(), log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		mgr.CloseAsync()
+		assert.NoError(t, mgr.WaitForClose(time.Second*5))
+	})
+	return mgr
+}
+
+func reloadConf(mapping, out string) Config {
+	conf := NewConfig()
+	conf.Input.Type = input.TypeInproc
+	conf.Input.Inproc = input.InprocConfig("in")
+	if len(mapping) > 0 {
+		procConf := processor.NewConfig()
+		procConf.Type = processor.TypeBloblang
+		procConf.Bloblang = processor.BloblangConfig(mapping)
+		conf.Pipeline.Processors = append(conf.Pipeline.Processors, procConf)
+	}
+	conf.Output.Type = output.TypeInproc
+	conf.Output.Inproc = output.InprocConfig(out)
+	return conf
+}
+
+func newReloadHarness(t *testing.T, conf Config) *reloadHarness {
+	t.Helper()
+
+	h := &reloadHarness{
+		t:   t,
+		mgr: newReloadMgr(t),
+		in:  make(chan types.Transaction),
+	}
+	h.mgr.SetPipe("in", h.in)
+
+	var err error
+	h.strm, err = New(
+		conf,
+		OptSetManager(h.mgr),
+		OptEnableReload(),
+		OptOnClose(func() {
+			atomic.AddInt32(&h.close, 1)
+		}),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, h.strm.Stop(time.Second*5))
+	})
+	return h
+}
+
+// send a message through the stream and return the result from an output pipe.
+func (h *reloadHarness) send(content, outPipe string) string {
+	h.t.Helper()
+
+	resChan := make(chan types.Response)
+	select {
+	case h.in <- types.NewTransaction(message.New([][]byte{[]byte(content)}), resChan):
+	case <-time.After(time.Second * 5):
+		h.t.Fatal("timed out")
+	}
+
+	var outChan <-chan types.Transaction
+	require.Eventually(h.t, func() bool {
+		var err error
+		outChan, err = h.mgr.GetPipe(outPipe)
+		return err == nil
+	}, time.Second*5, time.Millisecond*10)
+
+	var tran types.Transaction
+	select {
+	case tran = <-outChan:
+	case <-time.After(time.Second * 5):
+		h.t.Fatal("timed out")
+	}
+
+	// Inproc pipes pass transactions through untouched, so the response is
+	// delivered straight back to the sender.
+	go func() {
+		tran.ResponseChan <- response.NewAck()
+	}()
+	select {
+	case <-resChan:
+	case <-time.After(time.Second * 5):
+		h.t.Fatal("timed out")
+	}
+	return string(tran.Payload.Get(0).Get())
+}
+
+func TestReloadNotEnabled(t *testing.T) {
+	strm, err := New(reloadConf("", "out"), OptSetManager(newReloadMgr(t)))
+	require.NoError(t, err)
+	defer strm.Stop(time.Second * 5)
+
+	assert.Equal(t, ErrReloadNotEnabled, strm.Reload(reloadConf("", "out"), strm.manager, time.Second))
+}
+
+func TestReloadPipeline(t *testing.T) {
+	h := newReloadHarness(t, reloadConf(`root = content().uppercase()`, "out"))
+	assert.Equal(t, "FOO", h.send("foo", "out"))
+
+	inputLayer, outputLayer := h.strm.inputLayer, h.strm.outputLayer
+	outPipe, err := h.mgr.GetPipe("out")
+	require.NoError(t, err)
+
+	require.NoError(t, h.strm.Reload(reloadConf(`root = content() + " v2"`, "out"), h.mgr, time.Second*5))
+	assert.Equal(t, "foo v2", h.send("foo", "out"))
+
+	// The input and output are reused.
+	assert.Equal(t, inputLayer, h.strm.inputLayer)
+	assert.Equal(t, outputLayer, h.strm.outputLayer)
+	newOutPipe, err := h.mgr.GetPipe("out")
+	require.NoError(t, err)
+	assert.Equal(t, outPipe, newOutPipe)
+
+	// Removing the pipeline entirely is also supported.
+	require.NoError(t, h.strm.Reload(reloadConf("", "out"), h.mgr, time.Second*5))
+	assert.Equal(t, "foo", h.send("foo", "out"))
+
+	require.NoError(t, h.strm.Reload(reloadConf(`root = content().uppercase()`, "out"), h.mgr, time.Second*5))
+	assert.Equal(t, "BAR", h.send("bar", "out"))
+
+	assert.Equal(t, int32(0), atomic.LoadInt32(&h.close))
+}
+
+func TestReloadOutput(t *testing.T) {
+	h := newReloadHarness(t, reloadConf(`root = content().uppercase()`, "out"))
+	assert.Equal(t, "FOO", h.send("foo", "out"))
+
+	inputLayer := h.strm.inputLayer
+	pipelineLayer := h.strm.pipelineLayer
+
+	require.NoError(t, h.strm.Reload(reloadConf(`root = content().uppercase()`, "out2"), h.mgr, time.Second*5))
+	assert.Equal(t, "BAR", h.send("bar", "out2"))
+
+	assert.Equal(t, inputLayer, h.strm.inputLayer)
+	assert.Equal(t, pipelineLayer, h.strm.pipelineLayer)
+
+	// Replacing the output does not count as the stream closing.
+	assert.Equal(t, int32(0), atomic.LoadInt32(&h.close))
+}
+
+func TestReloadInput(t *testing.T) {
+	h := newReloadHarness(t, reloadConf("", "out"))
+	assert.Equal(t, "foo", h.send("foo", "out"))
+
+	outputLayer := h.strm.outputLayer
+
+	conf := reloadConf("", "out")
+	conf.Input.Inproc = input.InprocConfig("in2")
+
+	h.in = make(chan types.Transaction)
+	h.mgr.SetPipe("in2", h.in)
+
+	require.NoError(t, h.strm.Reload(conf, h.mgr, time.Second*5))
+	assert.Equal(t, "bar", h.send("bar", "out"))
+	assert.Equal(t, outputLayer, h.strm.outputLayer)
+}
+
+func TestReloadRollback(t *testing.T) {
+	h := newReloadHarness(t, reloadConf(`root = content().uppercase()`, "out"))
+	assert.Equal(t, "FOO", h.send("foo", "out"))
+
+	// A pipeline that fails to be created.
+	err := h.strm.Reload(reloadConf(`root = nope(`, "out"), h.mgr, time.Second*5)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to create new buffer or pipeline")
+	assert.Equal(t, "BAR", h.send("bar", "out"))
+
+	// An output that fails to be created.
+	conf := reloadConf(`root = content() + " v2"`, "out")
+	conf.Output.Type = "nope"
+	err = h.strm.Reload(conf, h.mgr, time.Second*5)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to create new output")
+	assert.Equal(t, "BAZ", h.send("baz", "out"))
+
+	// An input that fails to be created, this happens after the previous input
+	// has been closed and so it is recreated.
+	conf = reloadConf(`root = content() + " v2"`, "out")
+	conf.Input.Type = "nope"
+	err = h.strm.Reload(conf, h.mgr, time.Second*5)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to create new input")
+	assert.Equal(t, "QUX", h.send("qux", "out"))
+
+	assert.Equal(t, int32(0), atomic.LoadInt32(&h.close))
+}
+
+type badConsumeOutput struct {
+	closed chan struct{}
+}
+
+func (b *badConsumeOutput) Consume(<-chan types.Transaction) error {
+	return errors.New("nope")
+}
+
+func (b *badConsumeOutput) Connected() bool {
+	return false
+}
+
+func (b *badConsumeOutput) CloseAsync() {
+	select {
+	case <-b.closed:
+	default:
+		close(b.closed)
+	}
+}
+
+func (b *badConsumeOutput) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-b.closed:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+func TestReloadStartFailureShutsDown(t *testing.T) {
+	output.RegisterPlugin(
+		"reload_bad_consume",
+		func() interface{} {
+			return &struct{}{}
+		},
+		func(iconf interface{}, mgr types.Manager, logger log.Modular, stats metrics.Type) (types.Output, error) {
+			return &badConsumeOutput{closed: make(chan struct{})}, nil
+		},
+	)
+
+	h := newReloadHarness(t, reloadConf(`root = content().uppercase()`, "out"))
+	assert.Equal(t, "FOO", h.send("foo", "out"))
+
+	// The previous output has already been closed by the time the new one
+	// fails to start, and so the stream is shut down instead.
+	conf := reloadConf(`root = content().uppercase()`, "out")
+	conf.Output.Type = "reload_bad_consume"
+	err := h.strm.Reload(conf, h.mgr, time.Second*5)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "stream has been shut down")
+
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&h.close) == 1
+	}, time.Second*5, time.Millisecond*10)
+	assert.Equal(t, types.ErrTypeClosed, h.strm.Reload(reloadConf("", "out"), h.mgr, time.Second))
+}
+
+func TestReloadAfterInputClosed(t *testing.T) {
+	conf := reloadConf("", "out")
+	conf.Input.Type = input.TypeGenerate
+	conf.Input.Generate.Mapping = `root = "foo"`
+	conf.Input.Generate.Interval = ""
+	conf.Input.Generate.Count = 1
+
+	h := newReloadHarness(t, conf)
+
+	var outChan <-chan types.Transaction
+	require.Eventually(t, func() bool {
+		var err error
+		outChan, err = h.mgr.GetPipe("out")
+		return err == nil
+	}, time.Second*5, time.Millisecond*10)
+
+	select {
+	case tran := <-outChan:
+		assert.Equal(t, "foo", string(tran.Payload.Get(0).Get()))
+		tran.ResponseChan <- response.NewAck()
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out")
+	}
+
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&h.strm.inRelay.srcClosed) == 1
+	}, time.Second*5, time.Millisecond*10)
+
+	newConf := reloadConf(`root = content().uppercase()`, "out")
+	newConf.Input = conf.Input
+
+	err := h.strm.Reload(newConf, h.mgr, time.Second*5)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "stream input has closed")
+
+	// Subsequent reloads must be rejected rather than stopping the relays
+	// again.
+	assert.Equal(t, types.ErrTypeClosed, h.strm.Reload(newConf, h.mgr, time.Second*5))
+}
+
+func TestReloadManager(t *testing.T) {
+	h := newReloadHarness(t, reloadConf(`root = content().uppercase()`, "out"))
+	assert.Equal(t, "FOO", h.send("foo", "out"))
+
+	newMgr := newReloadMgr(t)
+	newMgr.SetPipe("in", h.in)
+
+	oldOutput := h.strm.outputLayer
+	require.NoError(t, h.strm.Reload(reloadConf(`root = content().uppercase()`, "out"), newMgr, time.Second*5))
+	assert.NotEqual(t, oldOutput, h.strm.outputLayer)
+
+	h.mgr = newMgr
+	assert.Equal(t, "BAR", h.send("bar", "out"))
+}
+
+func TestReloadStopClosesStream(t *testing.T) {
+	h := newReloadHarness(t, reloadConf(`root = content().uppercase()`, "out"))
+	assert.Equal(t, "FOO", h.send("foo", "out"))
+
+	require.NoError(t, h.strm.Reload(reloadConf(`root = content()`, "out2"), h.mgr, time.Second*5))
+	assert.Equal(t, "bar", h.send("bar", "out2"))
+
+	require.NoError(t, h.strm.Stop(time.Second*5))
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&h.close) == 1
+	}, time.Second*5, time.Millisecond*10)
+
+	assert.Equal(t, types.ErrTypeClosed, h.strm.Reload(reloadConf("", "out"), h.mgr, time.Second))
+}
+
+func TestReloadBlockedOutput(t *testing.T) {
+	h := newReloadHarness(t, reloadConf(`root = content().uppercase()`, "out"))
+
+	// Nothing reads from the output pipe and so the stream applies back
+	// pressure, which must not prevent the output from being replaced.
+	for _, content := range []string{"foo", "bar"} {
+		select {
+		case h.in <- types.NewTransaction(message.New([][]byte{[]byte(content)}), make(chan types.Response, 1)):
+		case <-time.After(time.Second * 5):
+			t.Fatal("timed out")
+		}
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- h.strm.Reload(reloadConf(`root = content().uppercase()`, "out2"), h.mgr, time.Millisecond*500)
+	}()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out")
+	}
+
+	// Transactions held between the pipeline and the previous output are
+	// delivered to the new output ahead of new ones.
+	go func() {
+		select {
+		case h.in <- types.NewTransaction(message.New([][]byte{[]byte("baz")}), make(chan types.Response, 1)):
+		case <-time.After(time.Second * 5):
+		}
+	}()
+
+	var outChan <-chan types.Transaction
+	require.Eventually(t, func() bool {
+		var err error
+		outChan, err = h.mgr.GetPipe("out2")
+		return err == nil
+	}, time.Second*5, time.Millisecond*10)
+
+	var results []string
+	for len(results) == 0 || results[len(results)-1] != "BAZ" {
+		select {
+		case tran := <-outChan:
+			results = append(results, string(tran.Payload.Get(0).Get()))
+			tran.ResponseChan <- response.NewAck()
+		case <-time.After(time.Second * 5):
+			t.Fatalf("timed out with results: %v", results)
+		}
+	}
+	assert.Greater(t, len(results), 1)
+}
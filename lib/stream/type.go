@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"net/http"
 	"runtime/pprof"
+	"sync"
 	"time"
 
 	"github.com/Jeffail/benthos/v3/lib/buffer"
@@ -33,6 +34,18 @@ type Type struct {
 	logger  log.Modular
 
 	onClose func()
+
+	// Fields used when the stream is reloadable, where layers are connected
+	// via relays so that they can be swapped at runtime.
+	reloadable bool
+	reloadMut  sync.Mutex
+	layersMut  sync.RWMutex
+	stopped    bool
+	inChan     chan types.Transaction
+	outChan    chan types.Transaction
+	inRelay    *relay
+	outRelay   *relay
+	outReplace chan struct{}
 }
 
 // New creates a new stream.Type.
@@ -51,14 +64,23 @@ func New(conf Config, opts ...func(*Type)) (*Type, error) {
 		return nil, err
 	}
 
+	t.registerHealthCheck()
+	return t, nil
+}
+
+func (t *Type) registerHealthCheck() {
 	healthCheck := func(w http.ResponseWriter, r *http.Request) {
+		t.layersMut.RLock()
+		inputLayer, outputLayer := t.inputLayer, t.outputLayer
+		t.layersMut.RUnlock()
+
 		connected := true
-		if !t.inputLayer.Connected() {
+		if !inputLayer.Connected() {
 			connected = false
 			w.WriteHeader(http.StatusServiceUnavailable)
 			w.Write([]byte("input not connected\n"))
 		}
-		if !t.outputLayer.Connected() {
+		if !outputLayer.Connected() {
 			connected = false
 			w.WriteHeader(http.StatusServiceUnavailable)
 			w.Write([]byte("output not connected\n"))
@@ -72,7 +94,6 @@ func New(conf Config, opts ...func(*Type)) (*Type, error) {
 		"Returns 200 OK if all inputs and outputs are connected, otherwise a 503 is returned.",
 		healthCheck,
 	)
-	return t, nil
 }
 
 //------------------------------------------------------------------------------
@@ -125,45 +146,78 @@ func OptOnClose(onClose func()) func(*Type) {
 	}
 }
 
+// OptEnableReload allows the stream to be updated to a new config at runtime
+// with Reload. This adds a small overhead to each message as the layers of the
+// stream are connected via relays in order to allow them to be swapped.
+func OptEnableReload() func(*Type) {
+	return func(t *Type) {
+		t.reloadable = true
+	}
+}
+
 //------------------------------------------------------------------------------
 
 // IsReady returns a boolean indicating whether both the input and output layers
 // of the stream are connected.
 func (t *Type) IsReady() bool {
+	t.layersMut.RLock()
+	defer t.layersMut.RUnlock()
 	return t.inputLayer.Connected() && t.outputLayer.Connected()
 }
 
-func (t *Type) start() (err error) {
-	// Constructors
-	if t.inputLayer, err = input.New(
-		t.conf.Input, t.manager,
+func (t *Type) newInput(conf Config, mgr types.Manager) (input.Type, error) {
+	return input.New(
+		conf.Input, mgr,
 		t.logger.NewModule(".input"), metrics.Namespaced(t.stats, "input"),
-	); err != nil {
-		return
-	}
-	if t.conf.Buffer.Type != buffer.TypeNone {
-		if t.bufferLayer, err = buffer.New(
-			t.conf.Buffer, t.manager,
+	)
+}
+
+func (t *Type) newMiddle(conf Config, mgr types.Manager) (buf buffer.Type, pipe pipeline.Type, err error) {
+	if conf.Buffer.Type != buffer.TypeNone {
+		if buf, err = buffer.New(
+			conf.Buffer, mgr,
 			t.logger.NewModule(".buffer"), metrics.Namespaced(t.stats, "buffer"),
 		); err != nil {
 			return
 		}
 	}
-	if tLen := len(t.complementaryProcs) + len(t.conf.Pipeline.Processors); tLen > 0 {
-		if t.pipelineLayer, err = pipeline.New(
-			t.conf.Pipeline, t.manager,
+	if tLen := len(t.complementaryProcs) + len(conf.Pipeline.Processors); tLen > 0 {
+		if pipe, err = pipeline.New(
+			conf.Pipeline, mgr,
 			t.logger.NewModule(".pipeline"), metrics.Namespaced(t.stats, "pipeline"),
 			t.complementaryProcs...,
 		); err != nil {
-			return
+			if buf != nil {
+				buf.CloseAsync()
+			}
+			return nil, nil, err
 		}
 	}
-	if t.outputLayer, err = output.New(
-		t.conf.Output, t.manager,
+	return
+}
+
+func (t *Type) newOutput(conf Config, mgr types.Manager) (output.Type, error) {
+	return output.New(
+		conf.Output, mgr,
 		t.logger.NewModule(".output"), metrics.Namespaced(t.stats, "output"),
-	); err != nil {
+	)
+}
+
+func (t *Type) start() (err error) {
+	// Constructors
+	if t.inputLayer, err = t.newInput(t.conf, t.manager); err != nil {
 		return
 	}
+	if t.bufferLayer, t.pipelineLayer, err = t.newMiddle(t.conf, t.manager); err != nil {
+		return
+	}
+	if t.outputLayer, err = t.newOutput(t.conf, t.manager); err != nil {
+		return
+	}
+
+	if t.reloadable {
+		return t.startRelays()
+	}
 
 	// Start chaining components
 	var nextTranChan <-chan types.Transaction
@@ -349,6 +403,14 @@ func (t *Type) stopUnordered(timeout time.Duration) (err error) {
 // Initially the attempt is graceful, but as the timeout draws close the attempt
 // becomes progressively less graceful.
 func (t *Type) Stop(timeout time.Duration) error {
+	t.reloadMut.Lock()
+	defer t.reloadMut.Unlock()
+
+	t.stopped = true
+	if t.reloadable {
+		defer t.killRelays()
+	}
+
 	tOutUnordered := timeout / 4
 	tOutGraceful := timeout - tOutUnordered
 
@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/cache"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/ory/dockertest/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ = registerIntegrationTest("mongodb", func(t *testing.T) {
+	t.Parallel()
+
+	pool, err := dockertest.NewPool("")
+	require.NoError(t, err)
+
+	pool.MaxWait = time.Second * 30
+
+	resource, err := pool.Run("mongo", "4.4", nil)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, pool.Purge(resource))
+	})
+
+	resource.Expire(900)
+	require.NoError(t, pool.Retry(func() error {
+		conf := cache.NewConfig()
+		conf.MongoDB.URL = fmt.Sprintf("mongodb://localhost:%v", resource.GetPort("27017/tcp"))
+		conf.MongoDB.Database = "testdb"
+		conf.MongoDB.Collection = "benthos_test_mongodb_connect"
+
+		c, cErr := cache.NewMongoDB(conf, nil, log.Noop(), metrics.Noop())
+		if cErr != nil {
+			return cErr
+		}
+		defer c.CloseAsync()
+		return c.Set("benthos_test_mongodb_connect", []byte("foo bar"))
+	}))
+
+	template := `
+resources:
+  caches:
+    testcache:
+      mongodb:
+        url: mongodb://localhost:$PORT
+        database: testdb
+        collection: $ID
+`
+	suite := integrationTests(
+		integrationTestOpenClose(),
+		integrationTestMissingKey(),
+		integrationTestDoubleAdd(),
+		integrationTestDelete(),
+		integrationTestGetAndSet(50),
+	)
+	suite.Run(
+		t, template,
+		testOptPort(resource.GetPort("27017/tcp")),
+	)
+})
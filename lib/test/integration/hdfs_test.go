@@ -79,10 +79,13 @@ input:
     hosts: [ localhost:9000 ]
     user: root
     directory: /$ID
+    codec: $VAR1
 `
-	integrationTests(
+	suite := integrationTests(
 		integrationTestOpenCloseIsolated(),
 		integrationTestStreamIsolated(10),
 		integrationTestSendBatchCountIsolated(10),
-	).Run(t, template)
+	)
+	suite.Run(t, template, testOptVarOne("all-bytes"))
+	suite.Run(t, template, testOptVarOne("lines"))
 })
@@ -625,3 +625,26 @@ func integrationTestOutputOnlyOverride(getFn getMessageFunc) testDefinition {
 		},
 	)
 }
+
+func integrationTestOutputOnlyOverrideBatch(getFn getMessageFunc) testDefinition {
+	return namedTest(
+		"can send to output and override value within a batch",
+		func(t *testing.T, env *testEnvironment) {
+			t.Parallel()
+
+			tranChan := make(chan types.Transaction)
+			output := initOutput(t, tranChan, env)
+			t.Cleanup(func() {
+				closeConnectors(t, nil, output)
+			})
+
+			first := `{"id":1,"content":"this should be overridden"}`
+			exp := `{"id":1,"content":"hello world"}`
+			require.NoError(t, sendBatch(env.ctx, t, tranChan, []string{first, exp}))
+
+			act, _, err := getFn(env, "1")
+			require.NoError(t, err)
+			assert.Equal(t, exp, act)
+		},
+	)
+}
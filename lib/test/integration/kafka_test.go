@@ -273,6 +273,8 @@ var _ = registerIntegrationTest("kafka_old", func(t *testing.T) {
 		"KAFKA_ADVERTISED_LISTENERS=OUTSIDE://" + hostIP + ":" + kafkaPortStr + ",INSIDE://:9092",
 		"KAFKA_INTER_BROKER_LISTENER_NAME=INSIDE",
 		"KAFKA_ZOOKEEPER_CONNECT=" + zkAddr,
+		"KAFKA_TRANSACTION_STATE_LOG_REPLICATION_FACTOR=1",
+		"KAFKA_TRANSACTION_STATE_LOG_MIN_ISR=1",
 	}
 
 	kafkaResource, err := pool.RunWithOptions(&dockertest.RunOptions{
@@ -448,6 +450,32 @@ input:
 		})
 	})
 
+	txnTemplate := fmt.Sprintf(`
+output:
+  kafka:
+    addresses: [ %v ]
+    topic: topic-$ID
+    max_in_flight: 1
+    idempotent_write: true
+    transaction:
+      id: txn-$ID
+    batching:
+      count: $OUTPUT_BATCH_COUNT
+
+input:
+  kafka:
+    addresses: [ %v ]
+    topics: [ topic-$ID ]
+    consumer_group: consumer-$ID
+    batching:
+      count: $INPUT_BATCH_COUNT
+`, address, address)
+
+	t.Run("transactional", func(t *testing.T) {
+		t.Parallel()
+		suiteExt.Run(t, txnTemplate)
+	})
+
 	deprecatedTemplate := fmt.Sprintf(`
 output:
   kafka:
@@ -0,0 +1,211 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/cache"
+	"github.com/Jeffail/benthos/v3/lib/input"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/manager"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/output"
+	"github.com/Jeffail/benthos/v3/lib/processor"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/ory/dockertest/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var _ = registerIntegrationTest("mongodb", func(t *testing.T) {
+	t.Parallel()
+
+	pool, err := dockertest.NewPool("")
+	require.NoError(t, err)
+
+	pool.MaxWait = time.Second * 30
+	resource, err := pool.RunWithOptions(&dockertest.RunOptions{
+		Repository: "mongo",
+		Tag:        "4.4",
+		Cmd:        []string{"--replSet", "rs0"},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, pool.Purge(resource))
+	})
+
+	// Change streams require a replica set, which is initiated with a single
+	// member.
+	url := fmt.Sprintf("mongodb://localhost:%v/?directConnection=true", resource.GetPort("27017/tcp"))
+	resource.Expire(900)
+	require.NoError(t, pool.Retry(func() error {
+		ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+		defer done()
+
+		client, err := mongo.Connect(ctx, options.Client().ApplyURI(url))
+		if err != nil {
+			return err
+		}
+		defer client.Disconnect(context.Background())
+
+		admin := client.Database("admin")
+		err = admin.RunCommand(ctx, bson.D{{Key: "replSetInitiate", Value: bson.D{
+			{Key: "_id", Value: "rs0"},
+			{Key: "members", Value: bson.A{bson.D{{Key: "_id", Value: 0}, {Key: "host", Value: "localhost:27017"}}}},
+		}}}).Err()
+		if err != nil && !strings.Contains(err.Error(), "already initialized") {
+			return err
+		}
+
+		var res struct {
+			IsMaster bool `bson:"ismaster"`
+		}
+		if err = admin.RunCommand(ctx, bson.D{{Key: "isMaster", Value: 1}}).Decode(&res); err != nil {
+			return err
+		}
+		if !res.IsMaster {
+			return errors.New("replica set has no primary yet")
+		}
+		return nil
+	}))
+
+	ctx, done := context.WithTimeout(context.Background(), time.Minute)
+	defer done()
+
+	mgrConf := manager.NewConfig()
+	mgrConf.Caches["resume_tokens"] = cache.NewConfig()
+	mgr, err := manager.New(mgrConf, types.NoopMgr(), log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	inConf := input.NewConfig()
+	inConf.Type = input.TypeMongoDB
+	inConf.MongoDB.URL = url
+	inConf.MongoDB.Database = "testdb"
+	inConf.MongoDB.Collection = "testcoll"
+	inConf.MongoDB.Cache = "resume_tokens"
+	inConf.MongoDB.FullDocument = "updateLookup"
+
+	initMongoInput := func() types.Input {
+		in, err := input.New(inConf, mgr, log.Noop(), metrics.Noop())
+		require.NoError(t, err)
+
+		// Changes are only consumed once the stream is open.
+		time.Sleep(time.Second)
+		return in
+	}
+
+	receiveEvent := func(in types.Input, res error) map[string]interface{} {
+		p := receiveMessage(ctx, t, in.TransactionChan(), res)
+		var event map[string]interface{}
+		require.NoError(t, json.Unmarshal(p.Get(), &event))
+		assert.Equal(t, event["operationType"], p.Metadata().Get("mongodb_operation_type"))
+		assert.Equal(t, "testdb", p.Metadata().Get("mongodb_database"))
+		assert.Equal(t, "testcoll", p.Metadata().Get("mongodb_collection"))
+		return event
+	}
+
+	in := initMongoInput()
+
+	outConf := output.NewConfig()
+	outConf.Type = output.TypeMongoDB
+	outConf.MongoDB.URL = url
+	outConf.MongoDB.Database = "testdb"
+	outConf.MongoDB.Collection = "testcoll"
+	outConf.MongoDB.Operation = "insert-one"
+	outConf.MongoDB.DocumentMap = `root = this`
+
+	tranChan := make(chan types.Transaction)
+	out, err := output.New(outConf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	require.NoError(t, out.Consume(tranChan))
+	t.Cleanup(func() {
+		closeConnectors(t, nil, out)
+	})
+
+	require.NoError(t, sendBatch(ctx, t, tranChan, []string{
+		`{"_id":"foo","value":1}`,
+		`{"_id":"bar","value":2}`,
+	}))
+
+	// Duplicate documents are rejected.
+	require.Error(t, sendMessage(ctx, t, tranChan, `{"_id":"foo","value":3}`))
+
+	procConf := processor.NewConfig()
+	procConf.Type = processor.TypeMongoDB
+	procConf.MongoDB.URL = url
+	procConf.MongoDB.Database = "testdb"
+	procConf.MongoDB.Collection = "testcoll"
+	procConf.MongoDB.Operation = "update-one"
+	procConf.MongoDB.FilterMap = `root._id = this.id`
+	procConf.MongoDB.DocumentMap = `root = {"$set": {"value": this.value}}`
+
+	updateProc, err := processor.New(procConf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		updateProc.CloseAsync()
+		assert.NoError(t, updateProc.WaitForClose(time.Second*10))
+	})
+
+	msgs, res := updateProc.ProcessMessage(message.New([][]byte{[]byte(`{"id":"foo","value":10}`)}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.Empty(t, processor.GetFail(msgs[0].Get(0)))
+
+	procConf.MongoDB.Operation = "find-one"
+	procConf.MongoDB.DocumentMap = ""
+	findProc, err := processor.New(procConf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		findProc.CloseAsync()
+		assert.NoError(t, findProc.WaitForClose(time.Second*10))
+	})
+
+	msgs, res = findProc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"id":"foo"}`),
+		[]byte(`{"id":"nope"}`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, `{"_id":"foo","value":10}`, string(msgs[0].Get(0).Get()))
+	assert.Empty(t, processor.GetFail(msgs[0].Get(0)))
+	assert.NotEmpty(t, processor.GetFail(msgs[0].Get(1)))
+
+	event := receiveEvent(in, nil)
+	assert.Equal(t, "insert", event["operationType"])
+	assert.Equal(t, map[string]interface{}{"_id": "foo", "value": float64(1)}, event["fullDocument"])
+
+	event = receiveEvent(in, nil)
+	assert.Equal(t, "insert", event["operationType"])
+	assert.Equal(t, map[string]interface{}{"_id": "bar", "value": float64(2)}, event["fullDocument"])
+
+	event = receiveEvent(in, nil)
+	assert.Equal(t, "update", event["operationType"])
+	assert.Equal(t, map[string]interface{}{"_id": "foo", "value": float64(10)}, event["fullDocument"])
+
+	closeConnectors(t, in, nil)
+
+	// Changes made while the input is stopped are consumed after a restart,
+	// and changes that were not acknowledged are consumed again.
+	require.NoError(t, sendMessage(ctx, t, tranChan, `{"_id":"baz","value":3}`))
+
+	in = initMongoInput()
+	event = receiveEvent(in, errors.New("nope"))
+	assert.Equal(t, map[string]interface{}{"_id": "baz", "value": float64(3)}, event["fullDocument"])
+	closeConnectors(t, in, nil)
+
+	in = initMongoInput()
+	t.Cleanup(func() {
+		closeConnectors(t, in, nil)
+	})
+	event = receiveEvent(in, nil)
+	assert.Equal(t, map[string]interface{}{"_id": "baz", "value": float64(3)}, event["fullDocument"])
+})
@@ -0,0 +1,101 @@
+package integration
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/ory/dockertest/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ = registerIntegrationTest("nats_jetstream", func(t *testing.T) {
+	t.Parallel()
+
+	pool, err := dockertest.NewPool("")
+	require.NoError(t, err)
+
+	pool.MaxWait = time.Second * 30
+	resource, err := pool.RunWithOptions(&dockertest.RunOptions{
+		Repository: "nats",
+		Tag:        "latest",
+		Cmd:        []string{"-js"},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, pool.Purge(resource))
+	})
+
+	var natsConn *nats.Conn
+	resource.Expire(900)
+	require.NoError(t, pool.Retry(func() error {
+		var err error
+		if natsConn, err = nats.Connect(fmt.Sprintf("tcp://localhost:%v", resource.GetPort("4222/tcp"))); err != nil {
+			return err
+		}
+		if _, err = natsConn.JetStream(); err != nil {
+			natsConn.Close()
+		}
+		return err
+	}))
+	t.Cleanup(func() {
+		natsConn.Close()
+	})
+
+	createStream := func(t *testing.T, env *testEnvironment) {
+		js, err := natsConn.JetStream()
+		require.NoError(t, err)
+
+		_, err = js.AddStream(&nats.StreamConfig{
+			Name:     "stream-" + env.configVars.id,
+			Subjects: []string{"subject-" + env.configVars.id},
+		})
+		require.NoError(t, err)
+	}
+
+	template := `
+output:
+  nats_jetstream:
+    urls: [ tcp://localhost:$PORT ]
+    subject: subject-$ID
+    headers:
+      foo: ${! meta("foo") }
+      bar: ${! meta("bar") }
+    max_in_flight: $MAX_IN_FLIGHT
+
+input:
+  nats_jetstream:
+    urls: [ tcp://localhost:$PORT ]
+    subject: subject-$ID
+    durable: durable-$ID
+    deliver: all
+`
+	suite := integrationTests(
+		integrationTestOpenClose(),
+		integrationTestMetadata(),
+		integrationTestSendBatch(10),
+		integrationTestStreamSequential(1000),
+		integrationTestStreamParallel(1000),
+		integrationTestStreamParallelLossy(1000),
+	)
+	suite.Run(
+		t, template,
+		testOptPreTest(createStream),
+		testOptSleepAfterInput(100*time.Millisecond),
+		testOptSleepAfterOutput(100*time.Millisecond),
+		testOptPort(resource.GetPort("4222/tcp")),
+	)
+	t.Run("with max in flight", func(t *testing.T) {
+		t.Parallel()
+		suite.Run(
+			t, template,
+			testOptPreTest(createStream),
+			testOptSleepAfterInput(100*time.Millisecond),
+			testOptSleepAfterOutput(100*time.Millisecond),
+			testOptPort(resource.GetPort("4222/tcp")),
+			testOptMaxInFlight(10),
+		)
+	})
+})
@@ -0,0 +1,198 @@
+package tracer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/message/tracing"
+	"github.com/opentracing/opentracing-go"
+	"go.opentelemetry.io/otel"
+	otbridge "go.opentelemetry.io/otel/bridge/opentracing"
+	"go.opentelemetry.io/otel/exporters/otlp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlphttp"
+	"go.opentelemetry.io/otel/label"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/semconv"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeOpenTelemetry] = TypeSpec{
+		constructor: NewOpenTelemetry,
+		Status:      docs.StatusExperimental,
+		Version:     "3.41.0",
+		Summary: `
+Send spans to an [OpenTelemetry](https://opentelemetry.io/) collector via the
+OTLP protocol.`,
+		Description: `
+Spans are exported over either gRPC or HTTP to an OTLP endpoint such as the
+[OpenTelemetry collector](https://opentelemetry.io/docs/collector/).
+
+### Propagation
+
+When ` + "`propagate_metadata`" + ` is enabled the
+[W3C trace context](https://www.w3.org/TR/trace-context/) of messages is
+propagated through the metadata keys ` + "`traceparent` and `tracestate`" + `.
+Inputs create spans as children of any trace context found within the metadata
+of consumed messages, and outputs set these metadata keys on messages before
+they are written. Outputs that support metadata, such as ` + "`kafka`" + `
+headers or ` + "`http_client`" + ` headers, will therefore carry the trace
+context to downstream services.`,
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("protocol", "The protocol used to send spans to the endpoint.").HasOptions("grpc", "http"),
+			docs.FieldCommon("endpoint", "The address of an OTLP endpoint to send spans to. The default port is `4317` for gRPC and `4318` for HTTP.", "localhost:4317", "otel-collector:4318"),
+			docs.FieldCommon("insecure", "Whether to connect to the endpoint without TLS."),
+			docs.FieldAdvanced("headers", "A map of headers to add to export requests."),
+			docs.FieldCommon("service_name", "A name to provide for this service."),
+			docs.FieldAdvanced("sampler_ratio", "The ratio of traces to sample, where `1` samples all traces and `0` samples none. Traces with a sampled parent are always sampled."),
+			docs.FieldAdvanced("tags", "A map of tags to add to the resource of all tracing spans."),
+			docs.FieldCommon("propagate_metadata", "Whether to propagate W3C trace contexts through message metadata."),
+			docs.FieldAdvanced("flush_interval", "The period of time between each flush of tracing spans."),
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// OpenTelemetryConfig is config for the OpenTelemetry tracer type.
+type OpenTelemetryConfig struct {
+	Protocol          string            `json:"protocol" yaml:"protocol"`
+	Endpoint          string            `json:"endpoint" yaml:"endpoint"`
+	Insecure          bool              `json:"insecure" yaml:"insecure"`
+	Headers           map[string]string `json:"headers" yaml:"headers"`
+	ServiceName       string            `json:"service_name" yaml:"service_name"`
+	SamplerRatio      float64           `json:"sampler_ratio" yaml:"sampler_ratio"`
+	Tags              map[string]string `json:"tags" yaml:"tags"`
+	PropagateMetadata bool              `json:"propagate_metadata" yaml:"propagate_metadata"`
+	FlushInterval     string            `json:"flush_interval" yaml:"flush_interval"`
+}
+
+// NewOpenTelemetryConfig creates an OpenTelemetryConfig struct with default
+// values.
+func NewOpenTelemetryConfig() OpenTelemetryConfig {
+	return OpenTelemetryConfig{
+		Protocol:          "grpc",
+		Endpoint:          "localhost:4317",
+		Insecure:          true,
+		Headers:           map[string]string{},
+		ServiceName:       "benthos",
+		SamplerRatio:      1.0,
+		Tags:              map[string]string{},
+		PropagateMetadata: true,
+		FlushInterval:     "",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// OpenTelemetry is a tracer with the capability to push spans to an OTLP
+// endpoint. Spans created with the opentracing API are bridged to
+// OpenTelemetry.
+type OpenTelemetry struct {
+	provider *sdktrace.TracerProvider
+	exporter *otlp.Exporter
+}
+
+// NewOpenTelemetry creates and returns a new OpenTelemetry tracer.
+func NewOpenTelemetry(config Config, opts ...func(Type)) (Type, error) {
+	o := &OpenTelemetry{}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	conf := config.OpenTelemetry
+
+	var driver otlp.ProtocolDriver
+	switch conf.Protocol {
+	case "grpc":
+		gOpts := []otlpgrpc.Option{otlpgrpc.WithEndpoint(conf.Endpoint)}
+		if conf.Insecure {
+			gOpts = append(gOpts, otlpgrpc.WithInsecure())
+		}
+		if len(conf.Headers) > 0 {
+			gOpts = append(gOpts, otlpgrpc.WithHeaders(conf.Headers))
+		}
+		driver = otlpgrpc.NewDriver(gOpts...)
+	case "http":
+		hOpts := []otlphttp.Option{otlphttp.WithEndpoint(conf.Endpoint)}
+		if conf.Insecure {
+			hOpts = append(hOpts, otlphttp.WithInsecure())
+		}
+		if len(conf.Headers) > 0 {
+			hOpts = append(hOpts, otlphttp.WithHeaders(conf.Headers))
+		}
+		driver = otlphttp.NewDriver(hOpts...)
+	default:
+		return nil, fmt.Errorf("unrecognised protocol: %v", conf.Protocol)
+	}
+
+	var batchOpts []sdktrace.BatchSpanProcessorOption
+	if i := conf.FlushInterval; len(i) > 0 {
+		flushInterval, err := time.ParseDuration(i)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse flush interval '%s': %v", i, err)
+		}
+		batchOpts = append(batchOpts, sdktrace.WithBatchTimeout(flushInterval))
+	}
+
+	exporter, err := otlp.NewExporter(context.Background(), driver)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create exporter: %v", err)
+	}
+	o.exporter = exporter
+
+	attrs := []label.KeyValue{semconv.ServiceNameKey.String(conf.ServiceName)}
+	for k, v := range conf.Tags {
+		attrs = append(attrs, label.String(k, v))
+	}
+
+	o.provider = sdktrace.NewTracerProvider(
+		sdktrace.WithConfig(sdktrace.Config{
+			DefaultSampler: sdktrace.ParentBased(sdktrace.TraceIDRatioBased(conf.SamplerRatio)),
+		}),
+		sdktrace.WithResource(resource.NewWithAttributes(attrs...)),
+		sdktrace.WithBatcher(exporter, batchOpts...),
+	)
+
+	propagator := propagation.TraceContext{}
+	bridgeTracer, wrapperProvider := otbridge.NewTracerPair(o.provider.Tracer("benthos"))
+	bridgeTracer.SetTextMapPropagator(propagator)
+
+	otel.SetTracerProvider(wrapperProvider)
+	otel.SetTextMapPropagator(propagator)
+	opentracing.SetGlobalTracer(bridgeTracer)
+
+	if conf.PropagateMetadata {
+		tracing.SetMetadataPropagation(propagator.Fields()...)
+	}
+	return o, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Close flushes any remaining spans and stops the tracer.
+func (o *OpenTelemetry) Close() error {
+	tracing.SetMetadataPropagation()
+	if o.provider == nil {
+		return nil
+	}
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	err := o.provider.Shutdown(ctx)
+	if sErr := o.exporter.Shutdown(ctx); err == nil {
+		err = sErr
+	}
+	o.provider = nil
+	return err
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,97 @@
+package tracer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/message/tracing"
+	"github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenTelemetryBadProtocol(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeOpenTelemetry
+	conf.OpenTelemetry.Protocol = "nope"
+
+	_, err := New(conf)
+	require.Error(t, err)
+}
+
+func TestOpenTelemetryHTTPExport(t *testing.T) {
+	var exported int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/traces" {
+			atomic.AddInt32(&exported, 1)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	conf := NewConfig()
+	conf.Type = TypeOpenTelemetry
+	conf.OpenTelemetry.Protocol = "http"
+	conf.OpenTelemetry.Endpoint = strings.TrimPrefix(ts.URL, "http://")
+
+	tr, err := New(conf)
+	require.NoError(t, err)
+
+	// Spans are created through the existing opentracing based helpers.
+	msg := message.New([][]byte{[]byte("hello world")})
+	tracing.InitSpans("input_test", msg)
+	_, spans := tracing.WithChildSpans("processor_test", msg)
+	for _, s := range spans {
+		s.Finish()
+	}
+	tracing.FinishSpans(msg)
+
+	require.NoError(t, tr.Close())
+	assert.True(t, atomic.LoadInt32(&exported) > 0)
+}
+
+func TestOpenTelemetryMetadataPropagation(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeOpenTelemetry
+	conf.OpenTelemetry.Protocol = "http"
+	conf.OpenTelemetry.Endpoint = "localhost:1"
+
+	tr, err := New(conf)
+	require.NoError(t, err)
+	defer tr.Close()
+
+	upstream := message.New([][]byte{[]byte("hello world")})
+	tracing.InitSpans("input_upstream", upstream)
+	outSpans := tracing.CreateChildSpans("output_upstream", upstream)
+	injected := tracing.InjectSpans(upstream, outSpans)
+
+	// The original message must not be modified.
+	assert.Empty(t, upstream.Get(0).Metadata().Get("traceparent"))
+
+	traceParent := injected.Get(0).Metadata().Get("traceparent")
+	require.NotEmpty(t, traceParent)
+
+	traceID := strings.Split(traceParent, "-")[1]
+
+	downstream := message.New([][]byte{[]byte("hello world")})
+	downstream.Get(0).Metadata().Set("traceparent", traceParent)
+	tracing.InitSpans("input_downstream", downstream)
+
+	downstreamSpans := tracing.CreateChildSpans("output_downstream", downstream)
+	injected = tracing.InjectSpans(downstream, downstreamSpans)
+
+	// The downstream trace must continue the upstream one.
+	downstreamParent := injected.Get(0).Metadata().Get("traceparent")
+	assert.Equal(t, traceID, strings.Split(downstreamParent, "-")[1])
+	assert.NotEqual(t, traceParent, downstreamParent)
+
+	_, err = opentracing.GlobalTracer().Extract(
+		opentracing.HTTPHeaders,
+		opentracing.HTTPHeadersCarrier(http.Header{"Traceparent": []string{downstreamParent}}),
+	)
+	assert.NoError(t, err)
+}
@@ -340,8 +340,10 @@ func (h *Type) waitForAccess() bool {
 
 // CreateRequest creates an HTTP request out of a single message.
 func (h *Type) CreateRequest(msg types.Message) (req *http.Request, err error) {
-	url := h.url.String(0, msg)
+	return h.createRequest(h.url.String(0, msg), msg)
+}
 
+func (h *Type) createRequest(url string, msg types.Message) (req *http.Request, err error) {
 	if msg == nil || msg.Len() == 0 {
 		if req, err = http.NewRequest(h.conf.Verb, url, nil); err == nil {
 			for k, v := range h.headers {
@@ -524,7 +526,22 @@ func (h *Type) Do(msg types.Message) (*http.Response, error) {
 }
 
 // DoWithContext is the context aware version of Do
-func (h *Type) DoWithContext(ctx context.Context, msg types.Message) (res *http.Response, err error) {
+func (h *Type) DoWithContext(ctx context.Context, msg types.Message) (*http.Response, error) {
+	return h.do(ctx, func() (*http.Request, error) {
+		return h.CreateRequest(msg)
+	}, msg)
+}
+
+// DoURLWithContext attempts to send a message to an HTTP server in the same
+// way as DoWithContext, but the request is sent to the provided URL rather than
+// the URL of the client config.
+func (h *Type) DoURLWithContext(ctx context.Context, url string, msg types.Message) (*http.Response, error) {
+	return h.do(ctx, func() (*http.Request, error) {
+		return h.createRequest(url, msg)
+	}, msg)
+}
+
+func (h *Type) do(ctx context.Context, createRequest func() (*http.Request, error), msg types.Message) (res *http.Response, err error) {
 	h.mCount.Incr(1)
 
 	var spans []opentracing.Span
@@ -550,7 +567,7 @@ func (h *Type) DoWithContext(ctx context.Context, msg types.Message) (res *http.
 	}
 
 	var req *http.Request
-	if req, err = h.CreateRequest(msg); err != nil {
+	if req, err = createRequest(); err != nil {
 		h.mErrReq.Incr(1)
 		h.mErr.Incr(1)
 		logErr(err)
@@ -587,7 +604,7 @@ func (h *Type) DoWithContext(ctx context.Context, msg types.Message) (res *http.
 		h.mErr.Incr(1)
 		logErr(err)
 
-		req, err = h.CreateRequest(msg)
+		req, err = createRequest()
 		if err != nil {
 			h.mErrReq.Incr(1)
 			h.mErr.Incr(1)
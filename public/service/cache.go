@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/cache"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+// Cache is an interface implemented by Benthos caches.
+type Cache interface {
+	// Get a cache item.
+	Get(ctx context.Context, key string) ([]byte, error)
+
+	// Set a cache item, specifying an optional TTL. It is okay for caches to
+	// ignore the ttl parameter if it isn't possible to implement.
+	Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error
+
+	// Add is the same operation as Set except that it returns an error if the
+	// key already exists. It is okay for caches to return nil on duplicates if
+	// it isn't possible to implement.
+	Add(ctx context.Context, key string, value []byte, ttl *time.Duration) error
+
+	// Delete attempts to remove a key. If the key does not exist then it is
+	// considered correct to return an error, however, for cache
+	// implementations where it is difficult to determine this then it is
+	// acceptable to return nil.
+	Delete(ctx context.Context, key string) error
+
+	Closer
+}
+
+// CacheConstructor is a func that's provided a configuration type and access
+// to a service manager and must return an instantiation of a cache based on
+// the config, or an error.
+type CacheConstructor func(conf *ParsedConfig, mgr *Resources) (Cache, error)
+
+// RegisterCache attempts to register a new cache plugin by providing a
+// description of the configuration for the plugin as well as a constructor
+// for the cache itself. The constructor will be called for each instantiation
+// of the component within a config.
+//
+// Plugins are configured with the field `type` set to the plugin name, and
+// the fields described by the spec within a `plugin` object.
+func RegisterCache(name string, spec *ConfigSpec, ctor CacheConstructor) error {
+	if _, exists := cache.Constructors[name]; exists {
+		return fmt.Errorf("cache type '%v' conflicts with a native component", name)
+	}
+	if spec == nil {
+		spec = NewConfigSpec()
+	}
+	cache.RegisterPlugin(name, spec.configConstructor(), func(
+		conf interface{},
+		mgr types.Manager,
+		logger log.Modular,
+		stats metrics.Type,
+	) (types.Cache, error) {
+		c, err := ctor(newParsedConfig(conf), newResources(mgr, logger, stats))
+		if err != nil {
+			return nil, err
+		}
+		return newAirGapCache(c), nil
+	})
+	cache.DocumentPlugin(name, spec.pluginDescription(), nil)
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// airGapCache adapts a Cache plugin into a types.CacheWithTTL.
+type airGapCache struct {
+	c Cache
+
+	*airGapCloser
+}
+
+func newAirGapCache(c Cache) types.CacheWithTTL {
+	return &airGapCache{
+		c:            c,
+		airGapCloser: newAirGapCloser(c),
+	}
+}
+
+func (a *airGapCache) Get(key string) ([]byte, error) {
+	return a.c.Get(context.Background(), key)
+}
+
+func (a *airGapCache) Set(key string, value []byte) error {
+	return a.c.Set(context.Background(), key, value, nil)
+}
+
+func (a *airGapCache) SetWithTTL(key string, value []byte, ttl *time.Duration) error {
+	return a.c.Set(context.Background(), key, value, ttl)
+}
+
+func (a *airGapCache) SetMulti(items map[string][]byte) error {
+	for k, v := range items {
+		if err := a.c.Set(context.Background(), k, v, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *airGapCache) SetMultiWithTTL(items map[string]types.CacheTTLItem) error {
+	for k, v := range items {
+		if err := a.c.Set(context.Background(), k, v.Value, v.TTL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *airGapCache) Add(key string, value []byte) error {
+	return a.c.Add(context.Background(), key, value, nil)
+}
+
+func (a *airGapCache) AddWithTTL(key string, value []byte, ttl *time.Duration) error {
+	return a.c.Add(context.Background(), key, value, ttl)
+}
+
+func (a *airGapCache) Delete(key string) error {
+	return a.c.Delete(context.Background(), key)
+}
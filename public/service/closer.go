@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+// airGapCloser adapts a Closer plugin into the CloseAsync and WaitForClose
+// semantics of components. The context given to Close is cancelled when a
+// call to WaitForClose times out.
+type airGapCloser struct {
+	c Closer
+
+	closeOnce  sync.Once
+	closeCtx   context.Context
+	closeFn    func()
+	closedChan chan struct{}
+}
+
+func newAirGapCloser(c Closer) *airGapCloser {
+	ctx, done := context.WithCancel(context.Background())
+	return &airGapCloser{
+		c:          c,
+		closeCtx:   ctx,
+		closeFn:    done,
+		closedChan: make(chan struct{}),
+	}
+}
+
+func (a *airGapCloser) CloseAsync() {
+	a.closeOnce.Do(func() {
+		go func() {
+			_ = a.c.Close(a.closeCtx)
+			close(a.closedChan)
+		}()
+	})
+}
+
+func (a *airGapCloser) WaitForClose(tout time.Duration) error {
+	select {
+	case <-a.closedChan:
+	case <-time.After(tout):
+		a.closeFn()
+		return types.ErrTimeout
+	}
+	return nil
+}
@@ -0,0 +1,476 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"gopkg.in/yaml.v3"
+)
+
+type fieldKind int
+
+const (
+	fieldKindString fieldKind = iota
+	fieldKindDuration
+	fieldKindInterpolatedString
+	fieldKindInt
+	fieldKindFloat
+	fieldKindBool
+	fieldKindStringList
+)
+
+func (k fieldKind) String() string {
+	switch k {
+	case fieldKindDuration:
+		return "duration"
+	case fieldKindInterpolatedString:
+		return "interpolated string"
+	case fieldKindInt:
+		return "int"
+	case fieldKindFloat:
+		return "float"
+	case fieldKindBool:
+		return "bool"
+	case fieldKindStringList:
+		return "string list"
+	}
+	return "string"
+}
+
+// ConfigField describes a field within a component configuration, to be added
+// to a ConfigSpec.
+type ConfigField struct {
+	field docs.FieldSpec
+	kind  fieldKind
+}
+
+func newConfigField(name string, kind fieldKind, t docs.FieldType) *ConfigField {
+	return &ConfigField{
+		field: docs.FieldCommon(name, "").HasType(t),
+		kind:  kind,
+	}
+}
+
+// NewStringField describes a new string type config field.
+func NewStringField(name string) *ConfigField {
+	return newConfigField(name, fieldKindString, docs.FieldString)
+}
+
+// NewDurationField describes a new duration string type config field, allowing
+// users to define a time interval with strings of the form 60s, 3m, etc.
+func NewDurationField(name string) *ConfigField {
+	return newConfigField(name, fieldKindDuration, docs.FieldString)
+}
+
+// NewInterpolatedStringField describes a new config field consisting of a
+// string that supports Bloblang interpolation functions.
+func NewInterpolatedStringField(name string) *ConfigField {
+	f := newConfigField(name, fieldKindInterpolatedString, docs.FieldString)
+	f.field = f.field.SupportsInterpolation(false)
+	return f
+}
+
+// NewIntField describes a new int type config field.
+func NewIntField(name string) *ConfigField {
+	return newConfigField(name, fieldKindInt, docs.FieldNumber)
+}
+
+// NewFloatField describes a new float type config field.
+func NewFloatField(name string) *ConfigField {
+	return newConfigField(name, fieldKindFloat, docs.FieldNumber)
+}
+
+// NewBoolField describes a new bool type config field.
+func NewBoolField(name string) *ConfigField {
+	return newConfigField(name, fieldKindBool, docs.FieldBool)
+}
+
+// NewStringListField describes a new config field consisting of a list of
+// strings.
+func NewStringListField(name string) *ConfigField {
+	return newConfigField(name, fieldKindStringList, docs.FieldArray)
+}
+
+// Description sets a description for the field, which is used within
+// generated documentation and should be formatted as markdown.
+func (c *ConfigField) Description(d string) *ConfigField {
+	c.field.Description = d
+	return c
+}
+
+// Advanced marks the field as being advanced, meaning it is omitted from
+// common example configs.
+func (c *ConfigField) Advanced() *ConfigField {
+	c.field.Advanced = true
+	return c
+}
+
+// Default specifies a default value that this field will assume if it is
+// omitted from a provided config. Fields that do not have a default value are
+// considered mandatory, and so parsing a config will fail in their absence.
+func (c *ConfigField) Default(v interface{}) *ConfigField {
+	c.field.Default = v
+	return c
+}
+
+// Example adds an example value to the field which will be shown when
+// printing documentation for the component config spec.
+func (c *ConfigField) Example(e interface{}) *ConfigField {
+	c.field.Examples = append(c.field.Examples, e)
+	return c
+}
+
+// decode parses a YAML node into the Go type that corresponds to the kind of
+// the field.
+func (c *ConfigField) decode(node *yaml.Node) (interface{}, error) {
+	var err error
+	var v interface{}
+	switch c.kind {
+	case fieldKindString, fieldKindInterpolatedString:
+		var s string
+		err = node.Decode(&s)
+		v = s
+	case fieldKindDuration:
+		var s string
+		if err = node.Decode(&s); err == nil {
+			_, err = time.ParseDuration(s)
+		}
+		v = s
+	case fieldKindInt:
+		var i int
+		err = node.Decode(&i)
+		v = i
+	case fieldKindFloat:
+		var f float64
+		err = node.Decode(&f)
+		v = f
+	case fieldKindBool:
+		var b bool
+		err = node.Decode(&b)
+		v = b
+	case fieldKindStringList:
+		var l []string
+		err = node.Decode(&l)
+		v = l
+	}
+	if err != nil {
+		return nil, fmt.Errorf("line %v: field '%v': expected %v: %w", node.Line, c.field.Name, c.kind, err)
+	}
+	return v, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ConfigSpec describes the configuration specification for a plugin
+// component. This will be used for validating and linting configuration files
+// and providing a parsed configuration struct to the plugin constructor.
+type ConfigSpec struct {
+	summary     string
+	description string
+	fields      []*ConfigField
+}
+
+// NewConfigSpec creates a new empty component configuration spec. If the
+// plugin does not require configuration fields the result of this call is
+// enough.
+func NewConfigSpec() *ConfigSpec {
+	return &ConfigSpec{}
+}
+
+// Summary adds a short summary to the plugin configuration spec that describes
+// the general purpose of the component.
+func (c *ConfigSpec) Summary(summary string) *ConfigSpec {
+	c.summary = summary
+	return c
+}
+
+// Description adds a description to the plugin configuration spec that
+// describes in more detail the behaviour of the component and how it should be
+// used.
+func (c *ConfigSpec) Description(description string) *ConfigSpec {
+	c.description = description
+	return c
+}
+
+// Field adds a field to the plugin configuration spec.
+func (c *ConfigSpec) Field(f *ConfigField) *ConfigSpec {
+	c.fields = append(c.fields, f)
+	return c
+}
+
+func (c *ConfigSpec) getField(name string) (*ConfigField, bool) {
+	for _, f := range c.fields {
+		if f.field.Name == name {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+// configConstructor returns a func that creates a fresh plugin config
+// populated with the default values of each field, used as the
+// PluginConfigConstructor of registered plugins.
+func (c *ConfigSpec) configConstructor() func() interface{} {
+	return func() interface{} {
+		conf := &pluginConfig{
+			spec:   c,
+			values: map[string]interface{}{},
+		}
+		for _, f := range c.fields {
+			if f.field.Default != nil {
+				conf.values[f.field.Name] = f.field.Default
+			}
+		}
+		return conf
+	}
+}
+
+// pluginDescription generates a markdown description of the plugin, including
+// each of its fields, which is shown when listing plugins.
+func (c *ConfigSpec) pluginDescription() string {
+	var buf bytes.Buffer
+	buf.WriteString(strings.TrimSpace(c.summary))
+	if desc := strings.TrimSpace(c.description); len(desc) > 0 {
+		if buf.Len() > 0 {
+			buf.WriteString("\n\n")
+		}
+		buf.WriteString(desc)
+	}
+	if len(c.fields) == 0 {
+		return buf.String()
+	}
+	if buf.Len() > 0 {
+		buf.WriteString("\n\n")
+	}
+	buf.WriteString("### Fields\n")
+	for _, f := range c.fields {
+		fmt.Fprintf(&buf, "\n#### `%v`\n\n", f.field.Name)
+		if desc := strings.TrimSpace(f.field.Description); len(desc) > 0 {
+			buf.WriteString(desc)
+			buf.WriteString("\n\n")
+		}
+		fmt.Fprintf(&buf, "Type: `%v`  \n", f.kind)
+		if f.field.Default != nil {
+			defBytes, err := yaml.Marshal(f.field.Default)
+			if err == nil {
+				fmt.Fprintf(&buf, "Default: `%v`  \n", strings.TrimSpace(string(defBytes)))
+			}
+		} else {
+			buf.WriteString("Required: `true`  \n")
+		}
+	}
+	return strings.TrimSpace(buf.String())
+}
+
+//------------------------------------------------------------------------------
+
+// pluginConfig is the configuration type given to the constructors of plugins
+// registered with a ConfigSpec. Only fields described by the spec are parsed,
+// which means any other fields are reported by the config linter.
+type pluginConfig struct {
+	spec   *ConfigSpec
+	values map[string]interface{}
+}
+
+// UnmarshalYAML parses each field of the spec from a YAML object.
+func (p *pluginConfig) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %v: expected object", value.Line)
+	}
+	for i := 0; i < len(value.Content)-1; i += 2 {
+		f, exists := p.spec.getField(value.Content[i].Value)
+		if !exists {
+			continue
+		}
+		v, err := f.decode(value.Content[i+1])
+		if err != nil {
+			return err
+		}
+		p.values[f.field.Name] = v
+	}
+	return nil
+}
+
+// MarshalYAML returns the parsed fields of the config.
+func (p *pluginConfig) MarshalYAML() (interface{}, error) {
+	return p.values, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ParsedConfig represents a plugin configuration that has been validated and
+// parsed from a ConfigSpec, and allows plugin constructors to access
+// configuration fields.
+type ParsedConfig struct {
+	spec   *ConfigSpec
+	values map[string]interface{}
+}
+
+func newParsedConfig(v interface{}) *ParsedConfig {
+	if conf, ok := v.(*pluginConfig); ok {
+		return &ParsedConfig{
+			spec:   conf.spec,
+			values: conf.values,
+		}
+	}
+	return &ParsedConfig{
+		spec:   NewConfigSpec(),
+		values: map[string]interface{}{},
+	}
+}
+
+// Contains checks whether the parsed config contains a value for a given field
+// name, either because it was set explicitly or because it has a default.
+func (p *ParsedConfig) Contains(name string) bool {
+	_, exists := p.values[name]
+	return exists
+}
+
+func (p *ParsedConfig) field(name string, kind fieldKind) (interface{}, error) {
+	f, exists := p.spec.getField(name)
+	if !exists {
+		return nil, fmt.Errorf("field '%v' was not found in the config spec", name)
+	}
+	if f.kind != kind {
+		return nil, fmt.Errorf("field '%v' is a %v field, not %v", name, f.kind, kind)
+	}
+	v, exists := p.values[name]
+	if !exists {
+		return nil, fmt.Errorf("field '%v' is required and was not present in the config", name)
+	}
+	return v, nil
+}
+
+// FieldString accesses a string field from the parsed config by its name. If
+// the field is not found or is not a string field an error is returned.
+func (p *ParsedConfig) FieldString(name string) (string, error) {
+	v, err := p.field(name, fieldKindString)
+	if err != nil {
+		return "", err
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("expected field '%v' to be a string, got %T", name, v)
+	}
+	return s, nil
+}
+
+// FieldDuration accesses a duration string field from the parsed config by
+// its name. If the field is not found or is not a valid duration an error is
+// returned.
+func (p *ParsedConfig) FieldDuration(name string) (time.Duration, error) {
+	v, err := p.field(name, fieldKindDuration)
+	if err != nil {
+		return 0, err
+	}
+	s, ok := v.(string)
+	if !ok {
+		return 0, fmt.Errorf("expected field '%v' to be a duration string, got %T", name, v)
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse field '%v' as a duration: %w", name, err)
+	}
+	return d, nil
+}
+
+// FieldInterpolatedString accesses a field containing a Bloblang interpolated
+// string from the parsed config by its name. If the field is not found or is
+// not a valid interpolated string an error is returned.
+func (p *ParsedConfig) FieldInterpolatedString(name string) (*InterpolatedString, error) {
+	v, err := p.field(name, fieldKindInterpolatedString)
+	if err != nil {
+		return nil, err
+	}
+	s, ok := v.(string)
+	if !ok {
+		return nil, fmt.Errorf("expected field '%v' to be a string, got %T", name, v)
+	}
+	i, err := NewInterpolatedString(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse field '%v' interpolation: %w", name, err)
+	}
+	return i, nil
+}
+
+// FieldInt accesses an int field from the parsed config by its name. If the
+// field is not found or is not an int field an error is returned.
+func (p *ParsedConfig) FieldInt(name string) (int, error) {
+	v, err := p.field(name, fieldKindInt)
+	if err != nil {
+		return 0, err
+	}
+	switch t := v.(type) {
+	case int:
+		return t, nil
+	case int64:
+		return int(t), nil
+	}
+	return 0, fmt.Errorf("expected field '%v' to be an int, got %T", name, v)
+}
+
+// FieldFloat accesses a float field from the parsed config by its name. If the
+// field is not found or is not a float field an error is returned.
+func (p *ParsedConfig) FieldFloat(name string) (float64, error) {
+	v, err := p.field(name, fieldKindFloat)
+	if err != nil {
+		return 0, err
+	}
+	switch t := v.(type) {
+	case float64:
+		return t, nil
+	case float32:
+		return float64(t), nil
+	case int:
+		return float64(t), nil
+	case int64:
+		return float64(t), nil
+	}
+	return 0, fmt.Errorf("expected field '%v' to be a float, got %T", name, v)
+}
+
+// FieldBool accesses a bool field from the parsed config by its name. If the
+// field is not found or is not a bool field an error is returned.
+func (p *ParsedConfig) FieldBool(name string) (bool, error) {
+	v, err := p.field(name, fieldKindBool)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("expected field '%v' to be a bool, got %T", name, v)
+	}
+	return b, nil
+}
+
+// FieldStringList accesses a field that is a list of strings from the parsed
+// config by its name. If the field is not found or is not a string list field
+// an error is returned.
+func (p *ParsedConfig) FieldStringList(name string) ([]string, error) {
+	v, err := p.field(name, fieldKindStringList)
+	if err != nil {
+		return nil, err
+	}
+	switch t := v.(type) {
+	case []string:
+		return t, nil
+	case []interface{}:
+		l := make([]string, 0, len(t))
+		for i, e := range t {
+			s, ok := e.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected field '%v' element %v to be a string, got %T", name, i, e)
+			}
+			l = append(l, s)
+		}
+		return l, nil
+	}
+	return nil, fmt.Errorf("expected field '%v' to be a string list, got %T", name, v)
+}
+
+// errNoConfigSpec is returned when registering a plugin without a spec.
+var errNoConfigSpec = errors.New("a config spec must be provided")
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/config"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/processor"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+type noopProcessor struct{}
+
+func (n noopProcessor) Process(ctx context.Context, m *Message) (MessageBatch, error) {
+	return MessageBatch{m}, nil
+}
+
+func (n noopProcessor) Close(ctx context.Context) error {
+	return nil
+}
+
+func testConfigSpec() *ConfigSpec {
+	return NewConfigSpec().
+		Summary("A test processor.").
+		Field(NewStringField("a").Description("A string.")).
+		Field(NewIntField("b").Default(10)).
+		Field(NewFloatField("c").Default(1.5)).
+		Field(NewBoolField("d").Default(true)).
+		Field(NewStringListField("e").Default([]string{})).
+		Field(NewDurationField("f").Default("1s")).
+		Field(NewInterpolatedStringField("g").Default(`${! content() }`).Advanced())
+}
+
+func TestConfigParsing(t *testing.T) {
+	var parsed *ParsedConfig
+	require.NoError(t, RegisterProcessor("service_test_config_parsing", testConfigSpec(),
+		func(conf *ParsedConfig, mgr *Resources) (Processor, error) {
+			parsed = conf
+			return noopProcessor{}, nil
+		}))
+
+	conf := processor.NewConfig()
+	require.NoError(t, yaml.Unmarshal([]byte(`
+type: service_test_config_parsing
+plugin:
+  a: foo
+  c: 2
+  e: [ bar, baz ]
+  f: 5m
+  g: ${! meta("foo") }
+`), &conf))
+
+	_, err := processor.New(conf, types.NoopMgr(), log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	require.NotNil(t, parsed)
+
+	a, err := parsed.FieldString("a")
+	require.NoError(t, err)
+	assert.Equal(t, "foo", a)
+
+	b, err := parsed.FieldInt("b")
+	require.NoError(t, err)
+	assert.Equal(t, 10, b)
+
+	c, err := parsed.FieldFloat("c")
+	require.NoError(t, err)
+	assert.Equal(t, 2.0, c)
+
+	d, err := parsed.FieldBool("d")
+	require.NoError(t, err)
+	assert.True(t, d)
+
+	e, err := parsed.FieldStringList("e")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bar", "baz"}, e)
+
+	f, err := parsed.FieldDuration("f")
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute*5, f)
+
+	g, err := parsed.FieldInterpolatedString("g")
+	require.NoError(t, err)
+	msg := NewMessage([]byte("hello"))
+	msg.MetaSet("foo", "bar")
+	assert.Equal(t, "bar", g.String(msg))
+
+	_, err = parsed.FieldInt("a")
+	assert.EqualError(t, err, "field 'a' is a string field, not int")
+
+	_, err = parsed.FieldString("nope")
+	assert.EqualError(t, err, "field 'nope' was not found in the config spec")
+}
+
+func TestConfigErrors(t *testing.T) {
+	var parsed *ParsedConfig
+	require.NoError(t, RegisterProcessor("service_test_config_errors", testConfigSpec(),
+		func(conf *ParsedConfig, mgr *Resources) (Processor, error) {
+			parsed = conf
+			return noopProcessor{}, nil
+		}))
+
+	conf := processor.NewConfig()
+	err := yaml.Unmarshal([]byte(`
+type: service_test_config_errors
+plugin:
+  b: not a number
+`), &conf)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "field 'b': expected int")
+
+	conf = processor.NewConfig()
+	err = yaml.Unmarshal([]byte(`
+type: service_test_config_errors
+plugin:
+  f: not a duration
+`), &conf)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "field 'f': expected duration")
+
+	conf = processor.NewConfig()
+	require.NoError(t, yaml.Unmarshal([]byte(`
+type: service_test_config_errors
+plugin:
+  b: 20
+`), &conf))
+
+	_, err = processor.New(conf, types.NoopMgr(), log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	require.NotNil(t, parsed)
+
+	assert.False(t, parsed.Contains("a"))
+	assert.True(t, parsed.Contains("b"))
+
+	_, err = parsed.FieldString("a")
+	assert.EqualError(t, err, "field 'a' is required and was not present in the config")
+}
+
+func TestConfigLinting(t *testing.T) {
+	require.NoError(t, RegisterProcessor("service_test_config_linting", testConfigSpec(),
+		func(conf *ParsedConfig, mgr *Resources) (Processor, error) {
+			return noopProcessor{}, nil
+		}))
+
+	confStr := `
+pipeline:
+  processors:
+    - type: service_test_config_linting
+      plugin:
+        a: foo
+        nope: bar
+`
+
+	conf := config.New()
+	require.NoError(t, yaml.Unmarshal([]byte(confStr), &conf))
+
+	lints, err := config.Lint([]byte(confStr), conf)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"line 7: path 'pipeline.processors[0].plugin': Key 'nope' found but is ignored",
+	}, lints)
+}
+
+func TestConfigPluginDescription(t *testing.T) {
+	desc := testConfigSpec().Description("Some more details.").pluginDescription()
+	assert.Contains(t, desc, "A test processor.\n\nSome more details.\n\n### Fields")
+	assert.Contains(t, desc, "#### `a`\n\nA string.\n\nType: `string`  \nRequired: `true`")
+	assert.Contains(t, desc, "#### `b`\n\nType: `int`  \nDefault: `10`")
+}
+
+func TestRegisterConflicts(t *testing.T) {
+	ctor := func(conf *ParsedConfig, mgr *Resources) (Processor, error) {
+		return noopProcessor{}, nil
+	}
+	assert.EqualError(t, RegisterProcessor("bloblang", nil, ctor), "processor type 'bloblang' conflicts with a native component")
+}
@@ -0,0 +1,27 @@
+package service
+
+import (
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+var (
+	// ErrNotConnected is returned by inputs and outputs when their Read or
+	// Write methods are called and the connection that they maintain is lost.
+	// This error prompts the upstream component to call Connect until the
+	// connection is re-established.
+	ErrNotConnected = types.ErrNotConnected
+
+	// ErrEndOfInput is returned by inputs that have exhausted their source of
+	// data to the point where subsequent Read calls will be ineffective. This
+	// error prompts the upstream component to gracefully terminate the
+	// pipeline.
+	ErrEndOfInput = types.ErrTypeClosed
+
+	// ErrKeyAlreadyExists is returned by caches when an Add call is made and
+	// the key already exists.
+	ErrKeyAlreadyExists = types.ErrKeyAlreadyExists
+
+	// ErrKeyNotFound is returned by caches when a Get or Delete call is made
+	// and the key does not exist.
+	ErrKeyNotFound = types.ErrKeyNotFound
+)
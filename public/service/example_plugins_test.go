@@ -0,0 +1,115 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+)
+
+type reverseProcessor struct {
+	suffix string
+}
+
+func (r *reverseProcessor) Process(ctx context.Context, m *service.Message) (service.MessageBatch, error) {
+	b, err := m.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+	reversed := make([]byte, 0, len(b)+len(r.suffix))
+	for i := len(b) - 1; i >= 0; i-- {
+		reversed = append(reversed, b[i])
+	}
+	m.SetBytes(append(reversed, r.suffix...))
+	return service.MessageBatch{m}, nil
+}
+
+func (r *reverseProcessor) Close(ctx context.Context) error {
+	return nil
+}
+
+// Example_processorPlugin demonstrates how to register a processor plugin with
+// a typed config spec, and then execute it within a stream built
+// programmatically, where messages are written into the stream with a
+// producer func and read from it with a consumer func.
+func Example_processorPlugin() {
+	spec := service.NewConfigSpec().
+		Summary("Reverses the contents of messages.").
+		Field(service.NewStringField("suffix").
+			Description("A suffix to add to each reversed message.").
+			Default(""))
+
+	if err := service.RegisterProcessor("reverse", spec, func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+		suffix, err := conf.FieldString("suffix")
+		if err != nil {
+			return nil, err
+		}
+		return &reverseProcessor{suffix: suffix}, nil
+	}); err != nil {
+		panic(err)
+	}
+
+	builder := service.NewStreamBuilder()
+	if err := builder.SetLoggerYAML(`level: NONE`); err != nil {
+		panic(err)
+	}
+	if err := builder.AddProcessorYAML(`
+type: reverse
+plugin:
+  suffix: "!"
+`); err != nil {
+		panic(err)
+	}
+
+	produce, err := builder.AddProducerFunc()
+	if err != nil {
+		panic(err)
+	}
+
+	var results bytes.Buffer
+	if err := builder.AddConsumerFunc(func(ctx context.Context, m *service.Message) error {
+		b, err := m.AsBytes()
+		if err != nil {
+			return err
+		}
+		results.Write(b)
+		results.WriteByte('\n')
+		return nil
+	}); err != nil {
+		panic(err)
+	}
+
+	stream, err := builder.Build()
+	if err != nil {
+		panic(err)
+	}
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- stream.Run(ctx)
+	}()
+
+	for _, content := range []string{"hello world", "sdrawkcab"} {
+		if err := produce(ctx, service.NewMessage([]byte(content))); err != nil {
+			panic(err)
+		}
+	}
+
+	if err := stream.StopWithin(time.Second * 5); err != nil {
+		panic(err)
+	}
+	if err := <-runErr; err != nil {
+		panic(err)
+	}
+
+	fmt.Print(results.String())
+
+	// Output:
+	// dlrow olleh!
+	// backwards!
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Jeffail/benthos/v3/lib/input"
+	"github.com/Jeffail/benthos/v3/lib/input/reader"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+// AckFunc is a common function returned by inputs that must be called once for
+// each message consumed. This function ensures that the source of the message
+// receives either an acknowledgement (err is nil) or an error that can either
+// be propagated upstream as a nack, or trigger a reattempt at delivering the
+// same message.
+type AckFunc func(ctx context.Context, err error) error
+
+// Input is an interface implemented by Benthos inputs. Calls to Read should
+// block until either a message has been received, the connection is lost, or
+// the provided context is cancelled.
+type Input interface {
+	// Establish a connection to the upstream service. Connect will always be
+	// called first when a reader is instantiated, and will be continuously
+	// called with back off until a nil error is returned.
+	//
+	// Once Connect returns a nil error the Read method will be called until
+	// either ErrNotConnected is returned, or the reader is closed.
+	Connect(ctx context.Context) error
+
+	// Read a single message from a source, along with a function to be called
+	// once the message can be either acked (successfully sent or intentionally
+	// filtered) or nacked (failed to be processed or dispatched to the
+	// output).
+	//
+	// If this method returns ErrNotConnected then Read will not be called again
+	// until Connect has returned a nil error. If ErrEndOfInput is returned then
+	// Read will no longer be called and the pipeline will gracefully
+	// terminate.
+	Read(ctx context.Context) (*Message, AckFunc, error)
+
+	Closer
+}
+
+// Closer is implemented by components that support stopping and cleaning up
+// their underlying resources.
+type Closer interface {
+	// Close the component, blocks until either the underlying resources are
+	// cleaned up or the context is cancelled. Returns an error if the context
+	// is cancelled.
+	Close(ctx context.Context) error
+}
+
+// InputConstructor is a func that's provided a configuration type and access
+// to a service manager and must return an instantiation of an input based on
+// the config, or an error.
+type InputConstructor func(conf *ParsedConfig, mgr *Resources) (Input, error)
+
+// RegisterInput attempts to register a new input plugin by providing a
+// description of the configuration for the plugin as well as a constructor
+// for the input itself. The constructor will be called for each instantiation
+// of the component within a config.
+//
+// Plugins are configured with the field `type` set to the plugin name, and
+// the fields described by the spec within a `plugin` object.
+func RegisterInput(name string, spec *ConfigSpec, ctor InputConstructor) error {
+	if _, exists := input.Constructors[name]; exists {
+		return fmt.Errorf("input type '%v' conflicts with a native component", name)
+	}
+	if spec == nil {
+		spec = NewConfigSpec()
+	}
+	input.RegisterPlugin(name, spec.configConstructor(), func(
+		conf interface{},
+		mgr types.Manager,
+		logger log.Modular,
+		stats metrics.Type,
+	) (types.Input, error) {
+		i, err := ctor(newParsedConfig(conf), newResources(mgr, logger, stats))
+		if err != nil {
+			return nil, err
+		}
+		return input.NewAsyncReader(name, false, newAirGapReader(i), logger, stats)
+	})
+	input.DocumentPlugin(name, spec.pluginDescription(), nil)
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// airGapReader adapts an Input plugin into a reader.Async.
+type airGapReader struct {
+	r Input
+
+	*airGapCloser
+}
+
+func newAirGapReader(r Input) reader.Async {
+	return &airGapReader{
+		r:            r,
+		airGapCloser: newAirGapCloser(r),
+	}
+}
+
+func (a *airGapReader) ConnectWithContext(ctx context.Context) error {
+	return a.r.Connect(ctx)
+}
+
+func (a *airGapReader) ReadWithContext(ctx context.Context) (types.Message, reader.AsyncAckFn, error) {
+	msg, ackFn, err := a.r.Read(ctx)
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			err = types.ErrTimeout
+		}
+		return nil, nil, err
+	}
+	tMsg := message.New(nil)
+	tMsg.Append(msg.part)
+	return tMsg, func(rctx context.Context, res types.Response) error {
+		return ackFn(rctx, res.Error())
+	}, nil
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	"github.com/Jeffail/benthos/v3/lib/message"
+)
+
+// InterpolatedString resolves a templated string expression, which may contain
+// Bloblang interpolation functions, against a message.
+type InterpolatedString struct {
+	expr field.Expression
+}
+
+// NewInterpolatedString parses an interpolated string expression.
+func NewInterpolatedString(expr string) (*InterpolatedString, error) {
+	e, err := bloblang.NewField(expr)
+	if err != nil {
+		return nil, err
+	}
+	return &InterpolatedString{expr: e}, nil
+}
+
+// String resolves the interpolated string expression against a message.
+func (i *InterpolatedString) String(m *Message) string {
+	return i.expr.String(0, singlePartMsg(m))
+}
+
+// Bytes resolves the interpolated string expression against a message and
+// returns the result as a byte slice.
+func (i *InterpolatedString) Bytes(m *Message) []byte {
+	return i.expr.Bytes(0, singlePartMsg(m))
+}
+
+func singlePartMsg(m *Message) *message.Type {
+	msg := message.New(nil)
+	msg.Append(m.part)
+	return msg
+}
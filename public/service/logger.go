@@ -0,0 +1,35 @@
+package service
+
+import (
+	"github.com/Jeffail/benthos/v3/lib/log"
+)
+
+// Logger allows plugin authors to write custom logs from components that are
+// consistent with the rest of Benthos.
+type Logger struct {
+	l log.Modular
+}
+
+func newLogger(l log.Modular) *Logger {
+	return &Logger{l}
+}
+
+// Debugf logs a debug message using fmt.Sprintf when args are specified.
+func (l *Logger) Debugf(template string, args ...interface{}) {
+	l.l.Debugf(template+"\n", args...)
+}
+
+// Infof logs an info message using fmt.Sprintf when args are specified.
+func (l *Logger) Infof(template string, args ...interface{}) {
+	l.l.Infof(template+"\n", args...)
+}
+
+// Warnf logs a warning message using fmt.Sprintf when args are specified.
+func (l *Logger) Warnf(template string, args ...interface{}) {
+	l.l.Warnf(template+"\n", args...)
+}
+
+// Errorf logs an error message using fmt.Sprintf when args are specified.
+func (l *Logger) Errorf(template string, args ...interface{}) {
+	l.l.Errorf(template+"\n", args...)
+}
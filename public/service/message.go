@@ -0,0 +1,165 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/processor"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+// MessageBatch describes a collection of one or more messages.
+type MessageBatch []*Message
+
+// Copy creates a new slice of the same messages, which can be modified without
+// changing the contents of the original batch.
+func (b MessageBatch) Copy() MessageBatch {
+	bCopy := make(MessageBatch, len(b))
+	for i, m := range b {
+		bCopy[i] = m.Copy()
+	}
+	return bCopy
+}
+
+// Message represents a single discrete message passing through a Benthos
+// pipeline. It is safe to mutate the message contents, but the same message
+// must not be mutated from multiple goroutines at the same time.
+type Message struct {
+	part types.Part
+}
+
+// NewMessage creates a new message with an initial raw bytes content. The
+// initial content can be nil, which is recommended if you intend to set it
+// with structured contents.
+func NewMessage(content []byte) *Message {
+	return &Message{
+		part: message.NewPart(content),
+	}
+}
+
+func newMessageFromPart(part types.Part) *Message {
+	return &Message{part}
+}
+
+// Copy creates a shallow copy of a message that is safe to mutate with Set
+// methods without mutating the original. Both messages will share a context,
+// and therefore a tracing ID, if one has been associated with them.
+//
+// Note that this does not perform a deep copy of the byte or structured
+// contents of the message, and therefore it is not safe to perform inline
+// mutations on those values without copying them.
+func (m *Message) Copy() *Message {
+	return &Message{
+		part: m.part.Copy(),
+	}
+}
+
+// AsBytes returns the underlying byte array contents of a message or, if the
+// contents are a structured type, attempts to marshal the contents as a JSON
+// document and returns either the byte array result or an error.
+//
+// It is NOT safe to mutate the contents of the returned slice.
+func (m *Message) AsBytes() ([]byte, error) {
+	b := m.part.Get()
+	if b == nil && !m.part.IsEmpty() {
+		return nil, errors.New("failed to marshal structured contents as a JSON document")
+	}
+	return b, nil
+}
+
+// AsStructured returns the underlying structured contents of a message or, if
+// the contents are a byte array, attempts to parse the bytes contents as a
+// JSON document and returns either the structured result or an error.
+//
+// It is NOT safe to mutate the contents of the returned value if it is a
+// reference type (slice or map). In order to safely mutate the structured
+// contents of a message use AsStructuredMut.
+func (m *Message) AsStructured() (interface{}, error) {
+	return m.part.JSON()
+}
+
+// AsStructuredMut returns the underlying structured contents of a message or,
+// if the contents are a byte array, attempts to parse the bytes contents as a
+// JSON document and returns either the structured result or an error.
+//
+// It is safe to mutate the contents of the returned value even if it is a
+// reference type (slice or map), as the structured contents are deep copied
+// before being returned.
+func (m *Message) AsStructuredMut() (interface{}, error) {
+	v, err := m.part.JSON()
+	if err != nil {
+		return nil, err
+	}
+	return message.CopyJSON(v)
+}
+
+// SetBytes sets the underlying contents of the message as a byte slice.
+func (m *Message) SetBytes(b []byte) {
+	m.part.Set(b)
+}
+
+// SetStructured sets the underlying contents of the message as a structured
+// type. This structured value should be a scalar Go type, or either a
+// map[string]interface{} or []interface{} containing the same types all the
+// way through the hierarchy, this ensures that other processors are able to
+// work with the contents and that they can be JSON marshalled when coerced
+// into a byte array.
+func (m *Message) SetStructured(i interface{}) {
+	_ = m.part.SetJSON(i)
+}
+
+// SetError marks the message as having failed a processing step and adds the
+// error to it as context. Messages marked with errors can be handled using a
+// range of methods outlined in https://www.benthos.dev/docs/configuration/error_handling.
+func (m *Message) SetError(err error) {
+	processor.FlagErr(m.part, err)
+}
+
+// GetError returns an error associated with a message, or nil if there isn't
+// one. Messages marked with errors can be handled using a range of methods
+// outlined in https://www.benthos.dev/docs/configuration/error_handling.
+func (m *Message) GetError() error {
+	if failStr := processor.GetFail(m.part); len(failStr) > 0 {
+		return errors.New(failStr)
+	}
+	return nil
+}
+
+// MetaGet attempts to find a metadata key from the message and returns a
+// string result and a boolean indicating whether it was found.
+func (m *Message) MetaGet(key string) (string, bool) {
+	v := m.part.Metadata().Get(key)
+	if len(v) > 0 {
+		return v, true
+	}
+	found := false
+	_ = m.part.Metadata().Iter(func(k, _ string) error {
+		if k == key {
+			found = true
+		}
+		return nil
+	})
+	return v, found
+}
+
+// MetaSet sets the value of a metadata key. If the value is an empty string
+// the metadata key is deleted.
+func (m *Message) MetaSet(key, value string) {
+	if value == "" {
+		m.part.Metadata().Delete(key)
+	} else {
+		m.part.Metadata().Set(key, value)
+	}
+}
+
+// MetaDelete removes a key from the message metadata.
+func (m *Message) MetaDelete(key string) {
+	m.part.Metadata().Delete(key)
+}
+
+// MetaWalk iterates each metadata key/value pair and executes a provided
+// closure on each iteration. To stop iterating, return an error from the
+// closure. An error returned by the closure will be returned by this function.
+func (m *Message) MetaWalk(fn func(string, string) error) error {
+	return m.part.Metadata().Iter(fn)
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageCopy(t *testing.T) {
+	msg := NewMessage([]byte(`{"foo":"bar"}`))
+	msg.MetaSet("foo", "bar")
+
+	msgCopy := msg.Copy()
+	msgCopy.SetBytes([]byte(`{"foo":"baz"}`))
+	msgCopy.MetaSet("foo", "baz")
+
+	b, err := msg.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, `{"foo":"bar"}`, string(b))
+
+	v, exists := msg.MetaGet("foo")
+	assert.True(t, exists)
+	assert.Equal(t, "bar", v)
+
+	b, err = msgCopy.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, `{"foo":"baz"}`, string(b))
+
+	v, exists = msgCopy.MetaGet("foo")
+	assert.True(t, exists)
+	assert.Equal(t, "baz", v)
+}
+
+func TestMessageStructured(t *testing.T) {
+	msg := NewMessage([]byte(`{"foo":"bar"}`))
+
+	v, err := msg.AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"foo": "bar"}, v)
+
+	vMut, err := msg.AsStructuredMut()
+	require.NoError(t, err)
+	vMut.(map[string]interface{})["foo"] = "baz"
+
+	v, err = msg.AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"foo": "bar"}, v)
+
+	msg.SetStructured(map[string]interface{}{"foo": "<baz>"})
+	b, err := msg.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, `{"foo":"<baz>"}`, string(b))
+
+	_, err = NewMessage([]byte(`not json`)).AsStructured()
+	assert.Error(t, err)
+}
+
+func TestMessageMetadata(t *testing.T) {
+	msg := NewMessage(nil)
+	msg.MetaSet("foo", "bar")
+	msg.MetaSet("baz", "buz")
+	msg.MetaSet("bev", "")
+
+	_, exists := msg.MetaGet("bev")
+	assert.False(t, exists)
+
+	msg.MetaDelete("baz")
+	_, exists = msg.MetaGet("baz")
+	assert.False(t, exists)
+
+	seen := map[string]string{}
+	require.NoError(t, msg.MetaWalk(func(k, v string) error {
+		seen[k] = v
+		return nil
+	}))
+	assert.Equal(t, map[string]string{"foo": "bar"}, seen)
+}
+
+func TestMessageError(t *testing.T) {
+	msg := NewMessage([]byte("foo"))
+	assert.NoError(t, msg.GetError())
+
+	msg.SetError(errors.New("nope"))
+	assert.EqualError(t, msg.GetError(), "nope")
+	assert.EqualError(t, msg.Copy().GetError(), "nope")
+}
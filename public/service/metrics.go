@@ -0,0 +1,63 @@
+package service
+
+import (
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+)
+
+// Metrics allows plugin authors to emit custom metrics from components that
+// are exported the same way as native Benthos metrics.
+type Metrics struct {
+	m metrics.Type
+}
+
+func newMetrics(m metrics.Type) *Metrics {
+	return &Metrics{m}
+}
+
+// NewCounter creates a new counter metric with a name.
+func (m *Metrics) NewCounter(name string) *MetricCounter {
+	return &MetricCounter{m.m.GetCounter(name)}
+}
+
+// NewTimer creates a new timer metric with a name.
+func (m *Metrics) NewTimer(name string) *MetricTimer {
+	return &MetricTimer{m.m.GetTimer(name)}
+}
+
+// NewGauge creates a new gauge metric with a name.
+func (m *Metrics) NewGauge(name string) *MetricGauge {
+	return &MetricGauge{m.m.GetGauge(name)}
+}
+
+//------------------------------------------------------------------------------
+
+// MetricCounter represents a counter metric of a given name.
+type MetricCounter struct {
+	c metrics.StatCounter
+}
+
+// Incr increments a counter metric by an amount.
+func (c *MetricCounter) Incr(count int64) {
+	_ = c.c.Incr(count)
+}
+
+// MetricTimer represents a timing metric of a given name.
+type MetricTimer struct {
+	t metrics.StatTimer
+}
+
+// Timing adds a delta to a timing metric. Delta should be measured in
+// nanoseconds for consistency with other Benthos timing metrics.
+func (t *MetricTimer) Timing(delta int64) {
+	_ = t.t.Timing(delta)
+}
+
+// MetricGauge represents a gauge metric of a given name.
+type MetricGauge struct {
+	g metrics.StatGauge
+}
+
+// Set a gauge metric to a value.
+func (g *MetricGauge) Set(value int64) {
+	_ = g.g.Set(value)
+}
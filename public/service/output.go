@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/output"
+	"github.com/Jeffail/benthos/v3/lib/output/writer"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+// Output is an interface implemented by Benthos outputs. Calls to Write should
+// block until either the message has been successfully or unsuccessfully sent,
+// or the context is cancelled.
+//
+// Multiple write calls can be performed in parallel, and the constructor of an
+// output must provide a MaxInFlight parameter indicating the maximum number of
+// parallel write calls the output supports.
+type Output interface {
+	// Establish a connection to the downstream service. Connect will always be
+	// called first when a writer is instantiated, and will be continuously
+	// called with back off until a nil error is returned.
+	//
+	// Once Connect returns a nil error the write method will be called until
+	// either ErrNotConnected is returned, or the writer is closed.
+	Connect(ctx context.Context) error
+
+	// Write a message to a sink, or return an error if delivery is not
+	// possible.
+	//
+	// If this method returns ErrNotConnected then write will not be called
+	// again until Connect has returned a nil error.
+	Write(ctx context.Context, msg *Message) error
+
+	Closer
+}
+
+// OutputConstructor is a func that's provided a configuration type and access
+// to a service manager, and must return an instantiation of an output based on
+// the config, along with the maximum number of parallel write calls that the
+// output supports, or an error.
+type OutputConstructor func(conf *ParsedConfig, mgr *Resources) (out Output, maxInFlight int, err error)
+
+// RegisterOutput attempts to register a new output plugin by providing a
+// description of the configuration for the plugin as well as a constructor
+// for the output itself. The constructor will be called for each
+// instantiation of the component within a config.
+//
+// Plugins are configured with the field `type` set to the plugin name, and
+// the fields described by the spec within a `plugin` object.
+func RegisterOutput(name string, spec *ConfigSpec, ctor OutputConstructor) error {
+	if _, exists := output.Constructors[name]; exists {
+		return fmt.Errorf("output type '%v' conflicts with a native component", name)
+	}
+	if spec == nil {
+		spec = NewConfigSpec()
+	}
+	output.RegisterPlugin(name, spec.configConstructor(), func(
+		conf interface{},
+		mgr types.Manager,
+		logger log.Modular,
+		stats metrics.Type,
+	) (types.Output, error) {
+		o, maxInFlight, err := ctor(newParsedConfig(conf), newResources(mgr, logger, stats))
+		if err != nil {
+			return nil, err
+		}
+		if maxInFlight < 1 {
+			return nil, fmt.Errorf("invalid maxInFlight parameter: %v", maxInFlight)
+		}
+		return output.NewAsyncWriter(name, maxInFlight, newAirGapWriter(o), logger, stats)
+	})
+	output.DocumentPlugin(name, spec.pluginDescription(), nil)
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// airGapWriter adapts an Output plugin into an output.AsyncSink.
+type airGapWriter struct {
+	w Output
+
+	*airGapCloser
+}
+
+func newAirGapWriter(w Output) output.AsyncSink {
+	return &airGapWriter{
+		w:            w,
+		airGapCloser: newAirGapCloser(w),
+	}
+}
+
+func (a *airGapWriter) ConnectWithContext(ctx context.Context) error {
+	return a.w.Connect(ctx)
+}
+
+func (a *airGapWriter) WriteWithContext(ctx context.Context, msg types.Message) error {
+	return writer.IterateBatchedSend(msg, func(i int, p types.Part) error {
+		return a.w.Write(ctx, newMessageFromPart(p))
+	})
+}
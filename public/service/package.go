@@ -0,0 +1,25 @@
+// Package service provides a high level API for registering custom plugin
+// components and executing either a standard Benthos CLI, or programmatically
+// building isolated pipelines with a StreamBuilder API.
+//
+// Plugins implement simple interfaces that are isolated from the internal
+// packages of Benthos, and are therefore expected to remain compatible across
+// minor releases. The configuration of a plugin is described with a ConfigSpec,
+// which is used in order to lint configs, apply default values and generate
+// plugin documentation.
+package service
+
+import (
+	"github.com/Jeffail/benthos/v3/lib/service"
+)
+
+// RunCLI executes Benthos as a CLI, allowing users to specify a configuration
+// file path(s) and execute subcommands for linting configs, testing configs,
+// etc. This is how a custom distribution of Benthos can be built with plugins
+// registered beforehand.
+//
+// This call blocks until either the pipeline shuts down or a termination
+// signal is received.
+func RunCLI() {
+	service.Run()
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/processor"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+// Processor is a Benthos processor implementation that works against single
+// messages.
+type Processor interface {
+	// Process a message into one or more resulting messages, or return an
+	// error if the message could not be processed. If zero messages are
+	// returned and the error is nil then the message is filtered.
+	//
+	// When an error is returned the input message will continue down the
+	// pipeline but will be marked with the error (see Message.SetError), and
+	// can be handled using the usual Benthos error handling mechanisms.
+	Process(context.Context, *Message) (MessageBatch, error)
+
+	Closer
+}
+
+// ProcessorConstructor is a func that's provided a configuration type and
+// access to a service manager and must return an instantiation of a processor
+// based on the config, or an error.
+type ProcessorConstructor func(conf *ParsedConfig, mgr *Resources) (Processor, error)
+
+// RegisterProcessor attempts to register a new processor plugin by providing
+// a description of the configuration for the plugin as well as a constructor
+// for the processor itself. The constructor will be called for each
+// instantiation of the component within a config.
+//
+// Plugins are configured with the field `type` set to the plugin name, and
+// the fields described by the spec within a `plugin` object.
+func RegisterProcessor(name string, spec *ConfigSpec, ctor ProcessorConstructor) error {
+	if _, exists := processor.Constructors[name]; exists {
+		return fmt.Errorf("processor type '%v' conflicts with a native component", name)
+	}
+	if spec == nil {
+		spec = NewConfigSpec()
+	}
+	processor.RegisterPlugin(name, spec.configConstructor(), func(
+		conf interface{},
+		mgr types.Manager,
+		logger log.Modular,
+		stats metrics.Type,
+	) (types.Processor, error) {
+		p, err := ctor(newParsedConfig(conf), newResources(mgr, logger, stats))
+		if err != nil {
+			return nil, err
+		}
+		return newAirGapProcessor(p, logger, stats), nil
+	})
+	processor.DocumentPlugin(name, spec.pluginDescription(), nil)
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// airGapProcessor adapts a Processor plugin into a types.Processor.
+type airGapProcessor struct {
+	p   Processor
+	log log.Modular
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+
+	*airGapCloser
+}
+
+func newAirGapProcessor(p Processor, log log.Modular, stats metrics.Type) types.Processor {
+	return &airGapProcessor{
+		p:   p,
+		log: log,
+
+		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+
+		airGapCloser: newAirGapCloser(p),
+	}
+}
+
+// ProcessMessage applies the plugin to each message of a batch, the resulting
+// messages are combined into a single batch.
+func (a *airGapProcessor) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	a.mCount.Incr(1)
+
+	newMsg := message.New(nil)
+	msg.Iter(func(i int, part types.Part) error {
+		batch, err := a.p.Process(context.Background(), newMessageFromPart(part.Copy()))
+		if err != nil {
+			a.mErr.Incr(1)
+			a.log.Debugf("Processor failed: %v\n", err)
+
+			errPart := part.Copy()
+			processor.FlagErr(errPart, err)
+			newMsg.Append(errPart)
+			return nil
+		}
+		for _, m := range batch {
+			newMsg.Append(m.part)
+		}
+		return nil
+	})
+
+	if newMsg.Len() == 0 {
+		return nil, response.NewAck()
+	}
+
+	a.mBatchSent.Incr(1)
+	a.mSent.Incr(int64(newMsg.Len()))
+	return []types.Message{newMsg}, nil
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+// Resources provides access to service-wide resources, such as a logger and
+// metrics aggregator, to plugin constructors.
+type Resources struct {
+	mgr    types.Manager
+	logger *Logger
+	stats  *Metrics
+}
+
+func newResources(mgr types.Manager, l log.Modular, stats metrics.Type) *Resources {
+	return &Resources{
+		mgr:    mgr,
+		logger: newLogger(l),
+		stats:  newMetrics(stats),
+	}
+}
+
+// Logger returns a logger preset with the component context of the plugin
+// being constructed.
+func (r *Resources) Logger() *Logger {
+	return r.logger
+}
+
+// Metrics returns a metrics aggregator preset with the component namespace of
+// the plugin being constructed.
+func (r *Resources) Metrics() *Metrics {
+	return r.stats
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/manager"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/stream"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+// Stream is a Benthos stream pipeline that can be started and stopped.
+type Stream struct {
+	conf      stream.Config
+	resources manager.Config
+	logConf   log.Config
+
+	producerChan chan types.Transaction
+	consumerFunc MessageHandlerFunc
+
+	strmMut sync.Mutex
+	strm    *stream.Type
+}
+
+func newStream(
+	conf stream.Config,
+	resources manager.Config,
+	logConf log.Config,
+	producerChan chan types.Transaction,
+	consumerFunc MessageHandlerFunc,
+) *Stream {
+	return &Stream{
+		conf:         conf,
+		resources:    resources,
+		logConf:      logConf,
+		producerChan: producerChan,
+		consumerFunc: consumerFunc,
+	}
+}
+
+// Run attempts to start the stream pipeline and blocks until either the
+// pipeline has gracefully come to a stop, or the provided context is
+// cancelled, in which case the stream is stopped and the context error is
+// returned.
+func (s *Stream) Run(ctx context.Context) error {
+	logger, err := log.NewV2(os.Stdout, s.logConf)
+	if err != nil {
+		return err
+	}
+	stats := metrics.Noop()
+
+	mgr, err := manager.New(s.resources, types.NoopMgr(), logger, stats)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		mgr.CloseAsync()
+		_ = mgr.WaitForClose(time.Second)
+	}()
+
+	if s.producerChan != nil {
+		mgr.SetPipe(producerPipeName, s.producerChan)
+	}
+
+	closedChan := make(chan struct{})
+	var closeOnce sync.Once
+
+	s.strmMut.Lock()
+	if s.strm != nil {
+		s.strmMut.Unlock()
+		return errors.New("stream has already been run")
+	}
+	s.strm, err = stream.New(
+		s.conf,
+		stream.OptSetLogger(logger),
+		stream.OptSetStats(stats),
+		stream.OptSetManager(mgr),
+		stream.OptOnClose(func() {
+			closeOnce.Do(func() {
+				close(closedChan)
+			})
+		}),
+	)
+	strm := s.strm
+	s.strmMut.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if s.consumerFunc != nil {
+		go s.runConsumer(ctx, mgr, closedChan)
+	}
+
+	select {
+	case <-closedChan:
+		return strm.Stop(time.Second * 5)
+	case <-ctx.Done():
+	}
+	if err := strm.Stop(time.Second * 5); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// runConsumer waits for the consumer pipe to be registered by the stream
+// output and then feeds each message into the consumer func.
+func (s *Stream) runConsumer(ctx context.Context, mgr types.Manager, closedChan <-chan struct{}) {
+	var tChan <-chan types.Transaction
+	for tChan == nil {
+		var err error
+		if tChan, err = mgr.GetPipe(consumerPipeName); err != nil {
+			select {
+			case <-time.After(time.Millisecond * 10):
+			case <-closedChan:
+				return
+			}
+		}
+	}
+
+	for {
+		var tran types.Transaction
+		var open bool
+		select {
+		case tran, open = <-tChan:
+			if !open {
+				return
+			}
+		case <-closedChan:
+			return
+		}
+
+		var err error
+		_ = tran.Payload.Iter(func(i int, p types.Part) error {
+			if err = s.consumerFunc(ctx, newMessageFromPart(p)); err != nil {
+				return err
+			}
+			return nil
+		})
+
+		select {
+		case tran.ResponseChan <- response.NewError(err):
+		case <-closedChan:
+			return
+		}
+	}
+}
+
+// StopWithin attempts to close the stream within the specified timeout period.
+// Initially the attempt is graceful, but as the timeout draws close the attempt
+// becomes progressively less graceful.
+func (s *Stream) StopWithin(timeout time.Duration) error {
+	s.strmMut.Lock()
+	strm := s.strm
+	s.strmMut.Unlock()
+	if strm == nil {
+		return errors.New("stream has not been run yet")
+	}
+	return strm.Stop(timeout)
+}
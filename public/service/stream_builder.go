@@ -0,0 +1,260 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Jeffail/benthos/v3/lib/buffer"
+	"github.com/Jeffail/benthos/v3/lib/input"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/manager"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/output"
+	"github.com/Jeffail/benthos/v3/lib/processor"
+	"github.com/Jeffail/benthos/v3/lib/stream"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"gopkg.in/yaml.v3"
+)
+
+// MessageHandlerFunc is a function signature defining a component that
+// consumes Benthos messages. An error must be returned if the context is
+// cancelled, or if the message could not be delivered or processed.
+type MessageHandlerFunc func(context.Context, *Message) error
+
+// Pipe names used for connecting producer and consumer funcs to a stream,
+// these are scoped to the resources of the stream and therefore do not need to
+// be unique across streams.
+const (
+	producerPipeName = "stream_builder_producer"
+	consumerPipeName = "stream_builder_consumer"
+)
+
+// StreamBuilder provides methods for building a Benthos stream configuration.
+// When parsing Benthos configs this builder follows the schema and field
+// defaults of a standard Benthos configuration.
+type StreamBuilder struct {
+	threads    int
+	inputs     []input.Config
+	buffer     buffer.Config
+	processors []processor.Config
+	outputs    []output.Config
+	resources  manager.Config
+	logger     log.Config
+
+	producerChan chan types.Transaction
+	consumerFunc MessageHandlerFunc
+}
+
+// NewStreamBuilder creates a new StreamBuilder.
+func NewStreamBuilder() *StreamBuilder {
+	return &StreamBuilder{
+		threads:   1,
+		buffer:    buffer.NewConfig(),
+		resources: manager.NewConfig(),
+		logger:    log.NewConfig(),
+	}
+}
+
+// SetThreads configures the number of pipeline processor threads.
+func (s *StreamBuilder) SetThreads(n int) {
+	s.threads = n
+}
+
+// AddInputYAML parses an input YAML configuration and adds it to the builder.
+// If more than one input configuration is added they will automatically be
+// composed within a broker when the pipeline is built.
+func (s *StreamBuilder) AddInputYAML(conf string) error {
+	iconf := input.NewConfig()
+	if err := yaml.Unmarshal([]byte(conf), &iconf); err != nil {
+		return err
+	}
+	s.inputs = append(s.inputs, iconf)
+	return nil
+}
+
+// SetBufferYAML parses a buffer YAML configuration and sets it to the builder
+// to be used as the buffer of the stream.
+func (s *StreamBuilder) SetBufferYAML(conf string) error {
+	bconf := buffer.NewConfig()
+	if err := yaml.Unmarshal([]byte(conf), &bconf); err != nil {
+		return err
+	}
+	s.buffer = bconf
+	return nil
+}
+
+// AddProcessorYAML parses a processor YAML configuration and adds it to the
+// builder to be executed within the pipeline.processors section, after all
+// prior added processor configs.
+func (s *StreamBuilder) AddProcessorYAML(conf string) error {
+	pconf := processor.NewConfig()
+	if err := yaml.Unmarshal([]byte(conf), &pconf); err != nil {
+		return err
+	}
+	s.processors = append(s.processors, pconf)
+	return nil
+}
+
+// AddOutputYAML parses an output YAML configuration and adds it to the
+// builder. If more than one output configuration is added they will
+// automatically be composed within a fan out broker when the pipeline is
+// built.
+func (s *StreamBuilder) AddOutputYAML(conf string) error {
+	oconf := output.NewConfig()
+	if err := yaml.Unmarshal([]byte(conf), &oconf); err != nil {
+		return err
+	}
+	s.outputs = append(s.outputs, oconf)
+	return nil
+}
+
+// AddResourcesYAML parses resource configurations, in the same form as the
+// `resources` section of a standard Benthos config, and adds them to the
+// builder. Resources added this way can then be referenced by components of
+// the stream, such as a cache referenced by a processor.
+func (s *StreamBuilder) AddResourcesYAML(conf string) error {
+	rconf := manager.NewConfig()
+	if err := yaml.Unmarshal([]byte(conf), &rconf); err != nil {
+		return err
+	}
+	return s.resources.AddFrom(&rconf)
+}
+
+// SetLoggerYAML parses a logger YAML configuration and adds it to the builder
+// such that all stream components emit logs through it.
+func (s *StreamBuilder) SetLoggerYAML(conf string) error {
+	lconf := log.NewConfig()
+	if err := yaml.Unmarshal([]byte(conf), &lconf); err != nil {
+		return err
+	}
+	s.logger = lconf
+	return nil
+}
+
+// AddProducerFunc adds an input to the builder that allows you to write
+// messages directly into the stream with a closure function. If any other
+// input has or will be added to the stream builder they will be automatically
+// composed within a broker when the pipeline is built.
+//
+// The returned MessageHandlerFunc can be called concurrently from any number
+// of goroutines, and each call will block until the message is either
+// successfully delivered to the outputs of the stream, or a delivery error
+// occurs, or the context is cancelled.
+//
+// Only one producer func can be added to a stream builder, and subsequent
+// calls will return an error.
+func (s *StreamBuilder) AddProducerFunc() (MessageHandlerFunc, error) {
+	if s.producerChan != nil {
+		return nil, errors.New("unable to add multiple producer funcs to a stream builder")
+	}
+
+	tChan := make(chan types.Transaction)
+	s.producerChan = tChan
+
+	iconf := input.NewConfig()
+	iconf.Type = input.TypeInproc
+	iconf.Inproc = input.InprocConfig(producerPipeName)
+	s.inputs = append(s.inputs, iconf)
+
+	return func(ctx context.Context, m *Message) error {
+		tMsg := message.New(nil)
+		tMsg.Append(m.part)
+
+		resChan := make(chan types.Response, 1)
+		select {
+		case tChan <- types.NewTransaction(tMsg, resChan):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		select {
+		case res := <-resChan:
+			return res.Error()
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}, nil
+}
+
+// AddConsumerFunc adds an output to the builder that executes a closure
+// function argument for each message. If any other output has or will be added
+// to the stream builder they will be automatically composed within a fan out
+// broker when the pipeline is built.
+//
+// The provided MessageHandlerFunc may be called from any number of goroutines,
+// and therefore it is recommended to implement some form of throttling or
+// mutex locking in cases where the call is non-blocking.
+//
+// Only one consumer can be added to a stream builder, and subsequent calls
+// will return an error.
+func (s *StreamBuilder) AddConsumerFunc(fn MessageHandlerFunc) error {
+	if s.consumerFunc != nil {
+		return errors.New("unable to add multiple consumer funcs to a stream builder")
+	}
+	s.consumerFunc = fn
+
+	oconf := output.NewConfig()
+	oconf.Type = output.TypeInproc
+	oconf.Inproc = output.InprocConfig(consumerPipeName)
+	s.outputs = append(s.outputs, oconf)
+	return nil
+}
+
+// Build a Benthos stream pipeline according to the components specified by
+// this stream builder.
+func (s *StreamBuilder) Build() (*Stream, error) {
+	conf, err := s.buildConfig()
+	if err != nil {
+		return nil, err
+	}
+	return newStream(conf, s.resources, s.logger, s.producerChan, s.consumerFunc), nil
+}
+
+func (s *StreamBuilder) buildConfig() (stream.Config, error) {
+	conf := stream.NewConfig()
+
+	switch len(s.inputs) {
+	case 0:
+		return conf, errors.New("an input or producer func must be added to the stream builder")
+	case 1:
+		conf.Input = s.inputs[0]
+	default:
+		conf.Input.Type = input.TypeBroker
+		conf.Input.Broker.Inputs = append(conf.Input.Broker.Inputs, s.inputs...)
+	}
+
+	conf.Buffer = s.buffer
+	conf.Pipeline.Threads = s.threads
+	conf.Pipeline.Processors = append(conf.Pipeline.Processors, s.processors...)
+
+	switch len(s.outputs) {
+	case 0:
+		return conf, errors.New("an output or consumer func must be added to the stream builder")
+	case 1:
+		conf.Output = s.outputs[0]
+	default:
+		conf.Output.Type = output.TypeBroker
+		conf.Output.Broker.Pattern = "fan_out"
+		conf.Output.Broker.Outputs = append(conf.Output.Broker.Outputs, s.outputs...)
+	}
+	return conf, nil
+}
+
+// AsYAML prints a YAML representation of the stream config as it has been
+// currently built.
+func (s *StreamBuilder) AsYAML() (string, error) {
+	conf, err := s.buildConfig()
+	if err != nil {
+		return "", err
+	}
+	sanit, err := conf.Sanitised()
+	if err != nil {
+		return "", err
+	}
+	b, err := yaml.Marshal(sanit)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal config: %w", err)
+	}
+	return string(b), nil
+}
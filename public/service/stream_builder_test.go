@@ -0,0 +1,325 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type countingInput struct {
+	n, count int
+
+	mut   sync.Mutex
+	acked []int
+}
+
+func (c *countingInput) Connect(ctx context.Context) error {
+	return nil
+}
+
+func (c *countingInput) Read(ctx context.Context) (*Message, AckFunc, error) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if c.count >= c.n {
+		return nil, nil, ErrEndOfInput
+	}
+	c.count++
+
+	i := c.count
+	return NewMessage([]byte(fmt.Sprintf("hello world %v", i))), func(ctx context.Context, err error) error {
+		c.mut.Lock()
+		if err == nil {
+			c.acked = append(c.acked, i)
+		}
+		c.mut.Unlock()
+		return nil
+	}, nil
+}
+
+func (c *countingInput) Close(ctx context.Context) error {
+	return nil
+}
+
+type upperProcessor struct {
+	prefix string
+}
+
+func (u *upperProcessor) Process(ctx context.Context, m *Message) (MessageBatch, error) {
+	b, err := m.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+	if strings.Contains(string(b), "drop") {
+		return nil, nil
+	}
+	if strings.Contains(string(b), "fail") {
+		return nil, errors.New("failed on purpose")
+	}
+	m.SetBytes([]byte(u.prefix + strings.ToUpper(string(b))))
+	return MessageBatch{m}, nil
+}
+
+func (u *upperProcessor) Close(ctx context.Context) error {
+	return nil
+}
+
+func registerUpperProcessor(t *testing.T) {
+	t.Helper()
+
+	require.NoError(t, RegisterProcessor(
+		"service_test_upper",
+		NewConfigSpec().Field(NewStringField("prefix").Default("")),
+		func(conf *ParsedConfig, mgr *Resources) (Processor, error) {
+			prefix, err := conf.FieldString("prefix")
+			if err != nil {
+				return nil, err
+			}
+			return &upperProcessor{prefix: prefix}, nil
+		}))
+}
+
+type memoryOutput struct {
+	mut  sync.Mutex
+	msgs []string
+}
+
+func (m *memoryOutput) Connect(ctx context.Context) error {
+	return nil
+}
+
+func (m *memoryOutput) Write(ctx context.Context, msg *Message) error {
+	b, err := msg.AsBytes()
+	if err != nil {
+		return err
+	}
+	m.mut.Lock()
+	m.msgs = append(m.msgs, string(b))
+	m.mut.Unlock()
+	return nil
+}
+
+func (m *memoryOutput) Close(ctx context.Context) error {
+	return nil
+}
+
+type memoryCache struct {
+	mut   sync.Mutex
+	items map[string][]byte
+}
+
+func (m *memoryCache) Get(ctx context.Context, key string) ([]byte, error) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	v, exists := m.items[key]
+	if !exists {
+		return nil, ErrKeyNotFound
+	}
+	return v, nil
+}
+
+func (m *memoryCache) Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
+	m.mut.Lock()
+	m.items[key] = value
+	m.mut.Unlock()
+	return nil
+}
+
+func (m *memoryCache) Add(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	if _, exists := m.items[key]; exists {
+		return ErrKeyAlreadyExists
+	}
+	m.items[key] = value
+	return nil
+}
+
+func (m *memoryCache) Delete(ctx context.Context, key string) error {
+	m.mut.Lock()
+	delete(m.items, key)
+	m.mut.Unlock()
+	return nil
+}
+
+func (m *memoryCache) Close(ctx context.Context) error {
+	return nil
+}
+
+func TestStreamBuilderPlugins(t *testing.T) {
+	in := &countingInput{n: 5}
+	require.NoError(t, RegisterInput(
+		"service_test_counting",
+		NewConfigSpec().Field(NewIntField("count")),
+		func(conf *ParsedConfig, mgr *Resources) (Input, error) {
+			n, err := conf.FieldInt("count")
+			if err != nil {
+				return nil, err
+			}
+			in.n = n
+			return in, nil
+		}))
+
+	registerUpperProcessor(t)
+
+	out := &memoryOutput{}
+	require.NoError(t, RegisterOutput(
+		"service_test_memory",
+		nil,
+		func(conf *ParsedConfig, mgr *Resources) (Output, int, error) {
+			return out, 1, nil
+		}))
+
+	builder := NewStreamBuilder()
+	require.NoError(t, builder.SetLoggerYAML(`level: NONE`))
+	require.NoError(t, builder.AddInputYAML(`
+type: service_test_counting
+plugin:
+  count: 3
+`))
+	require.NoError(t, builder.AddProcessorYAML(`
+type: service_test_upper
+plugin:
+  prefix: "foo: "
+`))
+	require.NoError(t, builder.AddOutputYAML(`
+type: service_test_memory
+`))
+
+	strm, err := builder.Build()
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+	require.NoError(t, strm.Run(ctx))
+
+	assert.Equal(t, []string{
+		"foo: HELLO WORLD 1",
+		"foo: HELLO WORLD 2",
+		"foo: HELLO WORLD 3",
+	}, out.msgs)
+	assert.Equal(t, []int{1, 2, 3}, in.acked)
+}
+
+func TestStreamBuilderProducerConsumer(t *testing.T) {
+	registerUpperProcessor(t)
+
+	cache := &memoryCache{items: map[string][]byte{}}
+	require.NoError(t, RegisterCache(
+		"service_test_memory_cache",
+		nil,
+		func(conf *ParsedConfig, mgr *Resources) (Cache, error) {
+			return cache, nil
+		}))
+
+	builder := NewStreamBuilder()
+	require.NoError(t, builder.SetLoggerYAML(`level: NONE`))
+	require.NoError(t, builder.AddResourcesYAML(`
+caches:
+  foocache:
+    type: service_test_memory_cache
+`))
+	require.NoError(t, builder.AddProcessorYAML(`
+cache:
+  resource: foocache
+  operator: set
+  key: ${! content() }
+  value: ${! meta("value") }
+`))
+	require.NoError(t, builder.AddProcessorYAML(`
+type: service_test_upper
+`))
+
+	produce, err := builder.AddProducerFunc()
+	require.NoError(t, err)
+
+	_, err = builder.AddProducerFunc()
+	require.EqualError(t, err, "unable to add multiple producer funcs to a stream builder")
+
+	var consumedMut sync.Mutex
+	var consumed []string
+	require.NoError(t, builder.AddConsumerFunc(func(ctx context.Context, m *Message) error {
+		b, err := m.AsBytes()
+		if err != nil {
+			return err
+		}
+		if err := m.GetError(); err != nil {
+			return err
+		}
+		consumedMut.Lock()
+		consumed = append(consumed, string(b))
+		consumedMut.Unlock()
+		return nil
+	}))
+
+	strm, err := builder.Build()
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	runErrChan := make(chan error, 1)
+	go func() {
+		runErrChan <- strm.Run(ctx)
+	}()
+
+	for _, content := range []string{"foo", "bar"} {
+		msg := NewMessage([]byte(content))
+		msg.MetaSet("value", content+" value")
+		require.NoError(t, produce(ctx, msg))
+	}
+
+	require.NoError(t, produce(ctx, NewMessage([]byte("drop me"))))
+	require.EqualError(t, produce(ctx, NewMessage([]byte("fail me"))), "failed on purpose")
+
+	require.NoError(t, strm.StopWithin(time.Second*5))
+	require.NoError(t, <-runErrChan)
+
+	assert.Equal(t, []string{"FOO", "BAR"}, consumed)
+	assert.Equal(t, map[string][]byte{
+		"foo":     []byte("foo value"),
+		"bar":     []byte("bar value"),
+		"drop me": []byte(""),
+		"fail me": []byte(""),
+	}, cache.items)
+}
+
+func TestStreamBuilderErrors(t *testing.T) {
+	builder := NewStreamBuilder()
+	_, err := builder.Build()
+	require.EqualError(t, err, "an input or producer func must be added to the stream builder")
+
+	require.NoError(t, builder.AddInputYAML(`
+generate:
+  mapping: root = "hello world"
+`))
+	_, err = builder.Build()
+	require.EqualError(t, err, "an output or consumer func must be added to the stream builder")
+
+	require.Error(t, builder.AddOutputYAML(`not_a_real_output: {}`))
+}
+
+func TestStreamBuilderAsYAML(t *testing.T) {
+	builder := NewStreamBuilder()
+	require.NoError(t, builder.AddInputYAML(`
+generate:
+  mapping: root = "hello world"
+`))
+	require.NoError(t, builder.AddOutputYAML(`drop: {}`))
+	require.NoError(t, builder.AddConsumerFunc(func(context.Context, *Message) error {
+		return nil
+	}))
+
+	confStr, err := builder.AsYAML()
+	require.NoError(t, err)
+	assert.Contains(t, confStr, `root = "hello world"`)
+	assert.Contains(t, confStr, "pattern: fan_out")
+	assert.Contains(t, confStr, "inproc: stream_builder_consumer")
+}